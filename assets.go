@@ -0,0 +1,11 @@
+// Package conformancesuite embeds the files the suite reads at runtime, so
+// the binaries can be run from any directory. Use pkg/assets to read them.
+package conformancesuite
+
+import "embed"
+
+// Assets - swagger specs, components, manifests, assertions, data and
+// configuration, with paths relative to the repository root.
+//
+//go:embed components manifests config pkg/schema/spec pkg/schema/codelists pkg/lint/rules
+var Assets embed.FS
@@ -45,5 +45,8 @@ func newRootCommand(service client.Service) *cobra.Command {
 	}
 	rootCmd.AddCommand(runCmd(service))
 	rootCmd.AddCommand(versionCmd(service))
+	rootCmd.AddCommand(mockAspspCmd())
+	rootCmd.AddCommand(vaultCmd())
+	rootCmd.AddCommand(signingDaemonCmd())
 	return rootCmd
 }
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/mockaspsp"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func mockAspspCmd() *cobra.Command {
+	mockCmd := &cobra.Command{
+		Use:   "mock-aspsp",
+		Short: "Run a mock ASPSP serving the bundled Open Banking specs",
+		Long: `Run a local ASPSP on mutual TLS with an OpenID provider, token, consent and resource endpoints.
+Responses are generated from the bundled swagger specs and seed data, and signed with x-jws-signature.
+Consents are authorised without PSU interaction, use a headless discovery template.`,
+		RunE: mockAspsp,
+	}
+	mockCmd.Flags().StringP("address", "a", "0.0.0.0:4501", "Address to listen on")
+	mockCmd.Flags().String("cert", "certs/conformancesuite_cert.pem", "TLS and signing certificate filename")
+	mockCmd.Flags().String("key", "certs/conformancesuite_key.pem", "TLS and signing private key filename")
+	mockCmd.Flags().String("client-ca", "", "Verify client certificates against the CA certificates of this filename")
+	mockCmd.Flags().String("spec-version", mockaspsp.DefaultVersion, "Version of the bundled specs served")
+	mockCmd.Flags().String("seed", mockaspsp.DefaultSeedFilename, "Seed data filename")
+	mockCmd.Flags().String("client-id", "", "Client ID allowed, any client when empty")
+	mockCmd.Flags().String("client-secret", "", "Client secret of client_secret_basic, any secret when empty")
+	mockCmd.Flags().String("org-id", "", "Organisation ID of response signatures, defaults to the certificate OU")
+	mockCmd.Flags().String("profile", "conformant", "Fault profile, one of "+strings.Join(mockaspsp.ProfileNames(), ", "))
+	mockCmd.Flags().Duration("delay", 0, "Delay of every response, overrides the delay of the profile")
+	mockCmd.Flags().Bool("require-pkce", false, "Reject authorization requests without an S256 code challenge")
+	mockCmd.Flags().Bool("require-par", false, "Reject authorization requests not pushed to the pushed authorization request endpoint")
+	mockCmd.Flags().Duration("request-uri-lifetime", 0, "Lifetime of the request_uri of pushed authorization requests, one minute when zero")
+	return mockCmd
+}
+
+// mockAspsp serves the mock ASPSP until it fails
+func mockAspsp(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	address, _ := flags.GetString("address")
+	certFile, _ := flags.GetString("cert")
+	keyFile, _ := flags.GetString("key")
+	clientCAFile, _ := flags.GetString("client-ca")
+	seedFile, _ := flags.GetString("seed")
+	profileName, _ := flags.GetString("profile")
+	delay, _ := flags.GetDuration("delay")
+
+	config := mockaspsp.Config{}
+	config.Version, _ = flags.GetString("spec-version")
+	config.ClientID, _ = flags.GetString("client-id")
+	config.ClientSecret, _ = flags.GetString("client-secret")
+	config.OrgID, _ = flags.GetString("org-id")
+	config.RequirePKCE, _ = flags.GetBool("require-pkce")
+	config.RequirePAR, _ = flags.GetBool("require-par")
+	config.RequestURILifetime, _ = flags.GetDuration("request-uri-lifetime")
+
+	profile, err := mockaspsp.LookupProfile(profileName)
+	if err != nil {
+		return err
+	}
+	if delay > 0 {
+		profile.Delay = delay
+	}
+	config.Profile = profile
+
+	cert, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return errors.Wrap(err, "reading certificate")
+	}
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return errors.Wrap(err, "reading private key")
+	}
+	config.Certificate, err = authentication.NewCertificate(string(cert), string(key))
+	if err != nil {
+		return err
+	}
+	if clientCAFile != "" {
+		clientCAs, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "reading client CA certificates")
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(clientCAs) {
+			return fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+	}
+	config.Seed, err = mockaspsp.LoadSeed(seedFile)
+	if err != nil {
+		return err
+	}
+
+	logger := logrus.StandardLogger().WithField("app", "mock-aspsp")
+	server, err := mockaspsp.NewServer(config, logger)
+	if err != nil {
+		return err
+	}
+	logger.Infof("listening on https://%s, OpenID configuration at /.well-known/openid-configuration", address)
+	return server.ListenAndServeTLS(address)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/signer"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func signingDaemonCmd() *cobra.Command {
+	daemonCmd := &cobra.Command{
+		Use:   "signing-daemon",
+		Short: "Run a signing daemon holding the signing keys of the suite",
+		Long: `Run a local signing daemon on a Unix socket, so the suite signs without holding the private keys.
+Run fcs_server with --signing_daemon_socket set to the socket, then set signing_signer of the config to
+daemon:<name> and signing_private is not needed.`,
+		RunE: signingDaemon,
+	}
+	daemonCmd.Flags().String("socket", "fcs-signing.sock", "Unix socket filename to listen on")
+	daemonCmd.Flags().StringArray("key", nil, "Signing key as <name>=<private key filename>, repeatable")
+	return daemonCmd
+}
+
+// signingDaemon serves the signing daemon until it fails
+func signingDaemon(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	socket, _ := flags.GetString("socket")
+	keyFlags, _ := flags.GetStringArray("key")
+	if len(keyFlags) == 0 {
+		return errors.New("no signing keys, set --key")
+	}
+
+	keys := map[string]crypto.Signer{}
+	for _, keyFlag := range keyFlags {
+		parts := strings.SplitN(keyFlag, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return errors.Errorf("invalid key %q, expected <name>=<private key filename>", keyFlag)
+		}
+		pem, err := ioutil.ReadFile(parts[1])
+		if err != nil {
+			return errors.Wrap(err, "reading private key")
+		}
+		keys[parts[0]], err = authentication.ParsePrivateKey(string(pem))
+		if err != nil {
+			return errors.Wrapf(err, "private key %s", parts[1])
+		}
+	}
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	// only the user running the daemon and the suite signs
+	if err := os.Chmod(socket, 0600); err != nil {
+		return err
+	}
+
+	logrus.StandardLogger().WithField("app", "signing-daemon").Infof("listening on unix://%s", socket)
+	return signer.Serve(listener, keys)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/vault"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// vaultPassphraseEnv - environment variable of the token vault passphrase, kept out of the command line
+const vaultPassphraseEnv = "FCS_VAULT_PASSPHRASE"
+
+func vaultCmd() *cobra.Command {
+	vaultCmd := &cobra.Command{
+		Use:   "vault",
+		Short: "Manage token vaults of the store token acquisition",
+		Long: `A token vault holds pre-provisioned access tokens, with their refresh tokens and consent IDs,
+encrypted with the passphrase of ` + vaultPassphraseEnv + `.`,
+	}
+
+	sealCmd := &cobra.Command{
+		Use:   "seal",
+		Short: "Encrypt a JSON file of tokens into a token vault",
+		RunE:  sealVault,
+	}
+	sealCmd.Flags().StringP("in", "i", "", "JSON tokens filename")
+	sealCmd.Flags().StringP("out", "o", "", "Token vault filename")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the tokens of a token vault, without the tokens themselves",
+		RunE:  listVault,
+	}
+	listCmd.Flags().StringP("vault", "v", "", "Token vault filename")
+
+	vaultCmd.AddCommand(sealCmd, listCmd)
+	return vaultCmd
+}
+
+func vaultPassphrase() (string, error) {
+	passphrase := os.Getenv(vaultPassphraseEnv)
+	if passphrase == "" {
+		return "", fmt.Errorf("%s isn't set", vaultPassphraseEnv)
+	}
+	return passphrase, nil
+}
+
+// sealVault encrypts the tokens of a JSON file into a vault file
+func sealVault(cmd *cobra.Command, _ []string) error {
+	in, _ := cmd.Flags().GetString("in")
+	out, _ := cmd.Flags().GetString("out")
+	if in == "" || out == "" {
+		return errors.New("you need to provide --in and --out filenames")
+	}
+	passphrase, err := vaultPassphrase()
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(in)
+	if err != nil {
+		return errors.Wrap(err, "reading tokens")
+	}
+	tokens := vault.Vault{}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return errors.Wrap(err, "parsing tokens")
+	}
+	if err := vault.WriteFile(out, tokens, passphrase); err != nil {
+		return err
+	}
+	fmt.Printf("sealed %d tokens into %s\n", len(tokens.Tokens), out)
+	return nil
+}
+
+// listVault prints the names, permissions, consent IDs and expiry of the tokens of a vault file
+func listVault(cmd *cobra.Command, _ []string) error {
+	filename, _ := cmd.Flags().GetString("vault")
+	if filename == "" {
+		return errors.New("you need to provide a --vault filename")
+	}
+	passphrase, err := vaultPassphrase()
+	if err != nil {
+		return err
+	}
+
+	tokens, err := vault.ReadFile(filename, passphrase)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens.Tokens {
+		expiry := "never"
+		if !token.ExpiresAt.IsZero() {
+			expiry = token.ExpiresAt.String()
+		}
+		fmt.Printf("name: %q, permissions: %v, consent ID: %q, refreshable: %t, expires: %s\n",
+			token.Name, token.Permissions, token.ConsentID, token.RefreshToken != "", expiry)
+	}
+	return nil
+}
@@ -7,12 +7,19 @@ import (
 	"strings"
 	"time"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/headless"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/lint"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/server"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/signer"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/tracer"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/version"
 	"github.com/pkg/errors"
@@ -26,6 +33,14 @@ import (
 const (
 	certFile = "./certs/conformancesuite_cert.pem"
 	keyFile  = "./certs/conformancesuite_key.pem"
+	// vaultPassphraseEnv - environment variable of the passphrase of the token vaults of configs,
+	// as of the vault command of the CLI
+	vaultPassphraseEnv = "FCS_VAULT_PASSPHRASE"
+	// pkcs11PINEnv - environment variable of the user PIN of the PKCS#11 tokens of signing_signer
+	pkcs11PINEnv = "FCS_PKCS11_PIN"
+	// operatorTokenEnv - environment variable of the bearer token of the operator listing the sessions
+	// of the server at `GET /api/sessions`, listing is disabled when unset
+	operatorTokenEnv = "FCS_OPERATOR_TOKEN"
 )
 
 var (
@@ -47,9 +62,37 @@ Complete documentation is available at https://bitbucket.org/openbankingteam/con
 			validatorEngine := discovery.NewFuncValidator(model.NewConditionalityChecker())
 			testGenerator := generation.NewGenerator()
 			tlsValidator := discovery.NewStdTLSValidator(tls.VersionTLS11)
-			journey := server.NewJourney(logger, testGenerator, validatorEngine, tlsValidator, viper.GetBool("dynres"))
+			dynamicResourceIDs := viper.GetBool("dynres")
+			var store *server.Store
+			if stateDir := viper.GetString("state_dir"); stateDir != "" {
+				var err error
+				if store, err = server.NewStore(stateDir, viper.GetString("state_key")); err != nil {
+					return err
+				}
+			}
+			runOptions, err := runOptions(logger)
+			if err != nil {
+				return err
+			}
+			sessions := server.NewSessions(func(id string) server.Journey {
+				journey := server.NewJourney(logger, testGenerator, validatorEngine, tlsValidator, dynamicResourceIDs)
+				journey.SetRunOptions(runOptions)
+				journey.SetTokenVaultPassphrase(os.Getenv(vaultPassphraseEnv))
+				if store != nil {
+					if err := journey.Persist(store, id); err != nil {
+						logger.WithError(err).WithField("session", id).Error("restoring journey")
+					}
+				}
+				return journey
+			}, viper.GetDuration("session_idle_timeout"))
+			sessions.SetOperatorToken(os.Getenv(operatorTokenEnv))
+			if store != nil {
+				if err := sessions.Restore(store); err != nil {
+					return err
+				}
+			}
 
-			echoServer := server.NewServer(journey, logger, ver)
+			echoServer := server.NewSessionServer(sessions, logger, ver)
 			address := fmt.Sprintf("%s:%d", server.ListenHost, viper.GetInt("port"))
 			logger.Infof("listening on https://%s", address)
 			return echoServer.StartTLS(address, certFile, keyFile)
@@ -57,6 +100,38 @@ Complete documentation is available at https://bitbucket.org/openbankingteam/con
 	}
 )
 
+// runOptions - the options of the runs of every journey, of which the rate limit is shared by
+// all journeys as they test the same ASPSP
+func runOptions(logger *logrus.Entry) (executors.RunOptions, error) {
+	options := executors.NewRunOptions()
+	options.Concurrency = executors.Concurrency{
+		PerSpec: viper.GetInt("concurrency_per_spec"),
+		PerHost: viper.GetInt("concurrency_per_host"),
+	}
+	options.RateLimiter = executors.NewRequestLimiter(executors.RateLimit{
+		RequestsPerSecond: viper.GetFloat64("rate_limit"),
+		Burst:             viper.GetInt("rate_limit_burst"),
+		PerHost:           viper.GetBool("rate_limit_per_host"),
+	})
+	options.ThrottleRetries = executors.ThrottleRetries{
+		MaxRetries: viper.GetInt("throttle_retries"),
+		MaxWait:    viper.GetDuration("throttle_max_wait"),
+	}
+
+	if replay := viper.GetString("replay"); replay != "" {
+		recording, err := har.Load(replay)
+		if err != nil {
+			return executors.RunOptions{}, err
+		}
+		// the recording has the responses of the requests throttled and retried
+		options.RateLimiter = nil
+		options.ThrottleRetries = executors.ThrottleRetries{}
+		options.Replay = &recording
+		logger.WithField("exchanges", len(recording.Log.Entries)).Warn("replay mode, responses are served from a recording")
+	}
+	return options, nil
+}
+
 func printVersionInfo(ver version.BitBucket, logger *logrus.Entry) {
 	v, err := ver.VersionFormatter(version.FullVersion)
 	if err != nil {
@@ -88,6 +163,23 @@ func init() {
 	rootCmd.PersistentFlags().Bool("dynres", false, "Use Dynamic Resource IDs - accounts")
 	rootCmd.PersistentFlags().Bool("dumpcontexts", false, "Dump contexts when trace enabled")
 	rootCmd.PersistentFlags().Bool("tlscheck", true, "enable tls version checking - default enabled")
+	rootCmd.PersistentFlags().Bool("strict_schema", false, "Report response properties not declared in the schema as warnings")
+	rootCmd.PersistentFlags().StringSlice("lint_errors", nil, "Response lint rules failing test cases, e.g.: OBLINT001,OBLINT003 or all, other rules are reported as warnings")
+	rootCmd.PersistentFlags().Int("concurrency_per_spec", 1, "Test cases of a specification run at the same time, 1 runs them in order")
+	rootCmd.PersistentFlags().Int("concurrency_per_host", 1, "Requests to the same host run at the same time when concurrency_per_spec is greater than 1")
+	rootCmd.PersistentFlags().Float64("rate_limit", 0, "Requests per second sent to the ASPSP, 0 disables rate limiting")
+	rootCmd.PersistentFlags().Int("rate_limit_burst", 1, "Requests sent at once before rate limiting")
+	rootCmd.PersistentFlags().Bool("rate_limit_per_host", true, "Rate limit each host separately")
+	rootCmd.PersistentFlags().Int("throttle_retries", 3, "Retries of a request throttled by the ASPSP with 429 and Retry-After")
+	rootCmd.PersistentFlags().Duration("throttle_max_wait", 30*time.Second, "Total time waiting for Retry-After of a throttled request")
+	rootCmd.PersistentFlags().String("replay", "", "HAR file of a previous run, e.g.: exchanges.har of a report export, the responses of every request are served from it instead of the ASPSP")
+	rootCmd.PersistentFlags().Duration("session_idle_timeout", server.DefaultSessionIdleTimeout, "Time without requests after which a session and its journey are removed, 0 keeps sessions")
+	rootCmd.PersistentFlags().String("state_dir", "", "Directory saving the journeys of sessions, so a restarted server resumes them, empty disables saving")
+	rootCmd.PersistentFlags().String("state_key", "", "Passphrase encrypting the journeys saved in state_dir, preferably set by the STATE_KEY environment variable")
+	rootCmd.PersistentFlags().StringSlice("assets_dir", nil, "Directories overriding the bundled specs, components and manifests")
+	rootCmd.PersistentFlags().String("headless_script_dir", "", "Directory of headless consent scripts loaded by name when not bundled in components/headless")
+	rootCmd.PersistentFlags().String("pkcs11_module", "", "PKCS#11 module of the pkcs11 signing_signer of configs, e.g.: /usr/lib/softhsm/libsofthsm2.so, the PIN is set by "+pkcs11PINEnv)
+	rootCmd.PersistentFlags().String("signing_daemon_socket", "", "Unix socket of the signing daemon of the daemon signing_signer of configs")
 	rootCmd.PersistentFlags().String("eadas_issuer", "", "Signing issuer when using EIDAS certificates")
 	rootCmd.PersistentFlags().String("eidas_siging_kid", "", "Signing Key Id when using EIDAS signing certification")
 
@@ -163,6 +255,19 @@ func initConfig() {
 		server.EnableTLSCheck(false)
 	}
 
+	if viper.GetBool("strict_schema") {
+		schema.EnableStrictMode()
+	}
+
+	if lintErrors := viper.GetStringSlice("lint_errors"); len(lintErrors) > 0 {
+		lint.EnableErrorRules(lintErrors...)
+	}
+
+	assets.SetOverrideDirs(viper.GetStringSlice("assets_dir")...)
+	headless.SetScriptDir(viper.GetString("headless_script_dir"))
+	signer.SetPKCS11(viper.GetString("pkcs11_module"), os.Getenv(pkcs11PINEnv))
+	signer.SetDaemonSocket(viper.GetString("signing_daemon_socket"))
+
 	resty.SetDebug(viper.GetBool("log_http_trace"))
 	resty.SetRedirectPolicy(resty.FlexibleRedirectPolicy(15))
 	eisas_issuer := viper.GetString("eidas_issuer")
@@ -174,18 +279,34 @@ func initConfig() {
 
 func printConfigurationFlags() {
 	logger.WithFields(logrus.Fields{
-		"log_level":      viper.GetString("log_level"),
-		"log_tracer":     viper.GetBool("log_tracer"),
-		"log_http_trace": viper.GetBool("log_http_trace"),
-		"log_http_file":  viper.GetBool("log_http_file"),
-		"log_to_file":    viper.GetBool("log_to_file"),
-		"port":           viper.GetInt("port"),
-		"tracer.Silent":  tracer.Silent,
-		"disable_jws":    viper.GetBool("disable_jws"),
-		"dynres":         viper.GetBool("dynres"),
-		"dumpcontexts":   viper.GetBool("dumpcontexts"),
-		"tlscheck":       viper.GetBool("tlscheck"),
-		"eidas_issuer":   viper.GetString("eidas_issuer"),
-		"eidas_keyid":    viper.GetString("eidas_kid"),
+		"log_level":             viper.GetString("log_level"),
+		"log_tracer":            viper.GetBool("log_tracer"),
+		"log_http_trace":        viper.GetBool("log_http_trace"),
+		"log_http_file":         viper.GetBool("log_http_file"),
+		"log_to_file":           viper.GetBool("log_to_file"),
+		"port":                  viper.GetInt("port"),
+		"tracer.Silent":         tracer.Silent,
+		"disable_jws":           viper.GetBool("disable_jws"),
+		"dynres":                viper.GetBool("dynres"),
+		"dumpcontexts":          viper.GetBool("dumpcontexts"),
+		"tlscheck":              viper.GetBool("tlscheck"),
+		"strict_schema":         viper.GetBool("strict_schema"),
+		"lint_errors":           viper.GetStringSlice("lint_errors"),
+		"concurrency_per_spec":  viper.GetInt("concurrency_per_spec"),
+		"concurrency_per_host":  viper.GetInt("concurrency_per_host"),
+		"rate_limit":            viper.GetFloat64("rate_limit"),
+		"rate_limit_burst":      viper.GetInt("rate_limit_burst"),
+		"rate_limit_per_host":   viper.GetBool("rate_limit_per_host"),
+		"throttle_retries":      viper.GetInt("throttle_retries"),
+		"throttle_max_wait":     viper.GetDuration("throttle_max_wait"),
+		"replay":                viper.GetString("replay"),
+		"session_idle_timeout":  viper.GetDuration("session_idle_timeout"),
+		"state_dir":             viper.GetString("state_dir"),
+		"assets_dir":            viper.GetStringSlice("assets_dir"),
+		"headless_script_dir":   viper.GetString("headless_script_dir"),
+		"pkcs11_module":         viper.GetString("pkcs11_module"),
+		"signing_daemon_socket": viper.GetString("signing_daemon_socket"),
+		"eidas_issuer":          viper.GetString("eidas_issuer"),
+		"eidas_keyid":           viper.GetString("eidas_kid"),
 	}).Info("configuration flags")
 }
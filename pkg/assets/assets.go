@@ -0,0 +1,120 @@
+// Package assets reads the files bundled with the suite, e.g. swagger specs,
+// components and manifests, by their path relative to the repository root.
+//
+// Files are embedded in the binaries so the suite doesn't depend on the
+// working directory. Override directories, with the same layout as the
+// repository, are searched first and allow replacing any bundled file.
+package assets
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	conformancesuite "bitbucket.org/openbankingteam/conformance-suite"
+)
+
+var (
+	overrideDirs []string
+	lock         = &sync.RWMutex{}
+)
+
+// SetOverrideDirs - directories to read assets from before the embedded ones, in order
+// For example: SetOverrideDirs("/etc/fcs") reads "manifests/assertions.json"
+// from "/etc/fcs/manifests/assertions.json" when the file exists
+func SetOverrideDirs(dirs ...string) {
+	lock.Lock()
+	defer lock.Unlock()
+	overrideDirs = dirs
+}
+
+// OverrideDirs - returns the directories set by SetOverrideDirs
+func OverrideDirs() []string {
+	lock.RLock()
+	defer lock.RUnlock()
+	return overrideDirs
+}
+
+// ReadFile - reads an asset, e.g. ReadFile("components/psu_exchange.json")
+func ReadFile(name string) ([]byte, error) {
+	name = clean(name)
+	for _, dir := range OverrideDirs() {
+		content, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+
+	content, err := fs.ReadFile(conformancesuite.Assets, name)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return content, nil
+}
+
+// ReadFileOrPath - reads an asset or, when it isn't bundled, the file at path
+// Used for files that can be either, e.g. manifests referenced by a discovery file
+func ReadFileOrPath(path string) ([]byte, error) {
+	content, err := ReadFile(path)
+	if err == nil || !os.IsNotExist(err) {
+		return content, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+// ReadDir - returns the sorted names of the files in an asset directory
+// including files found in override directories
+func ReadDir(name string) ([]string, error) {
+	name = clean(name)
+	found := false
+	names := map[string]bool{}
+	for _, dir := range OverrideDirs() {
+		infos, err := ioutil.ReadDir(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		for _, info := range infos {
+			if !info.IsDir() {
+				names[info.Name()] = true
+			}
+		}
+	}
+
+	entries, err := fs.ReadDir(conformancesuite.Assets, name)
+	if err == nil {
+		found = true
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names[entry.Name()] = true
+			}
+		}
+	}
+
+	if !found {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted, nil
+}
+
+// clean - asset names are always relative to the repository root, e.g.: "./manifests/" is "manifests"
+func clean(name string) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "./")
+}
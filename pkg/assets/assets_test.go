@@ -0,0 +1,98 @@
+package assets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadFile(t *testing.T) {
+	content, err := ReadFile("./components/psu_exchange.json")
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, content)
+}
+
+func TestReadFile_NotFound(t *testing.T) {
+	_, err := ReadFile("components/not_a_component.json")
+
+	require.Error(t, err)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadFile_OverrideDir(t *testing.T) {
+	dir := overrideDir(t, "components/psu_exchange.json", `{"name": "override"}`)
+	SetOverrideDirs(dir)
+	defer SetOverrideDirs()
+
+	content, err := ReadFile("components/psu_exchange.json")
+	require.NoError(t, err)
+	assert.Equal(t, `{"name": "override"}`, string(content))
+
+	// files not in the override directory are still read from the embedded assets
+	content, err = ReadFile("components/account_consent.json")
+	require.NoError(t, err)
+	assert.NotEmpty(t, content)
+}
+
+func TestReadFileOrPath(t *testing.T) {
+	dir := overrideDir(t, "custom.json", `{}`)
+
+	content, err := ReadFileOrPath(filepath.Join(dir, "custom.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(content))
+
+	content, err = ReadFileOrPath("manifests/assertions.json")
+	require.NoError(t, err)
+	assert.NotEmpty(t, content)
+}
+
+func TestReadDir(t *testing.T) {
+	names, err := ReadDir("pkg/schema/spec/v3.1.5")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"account-info-swagger-flattened.json",
+		"confirmation-funds-swagger-flattened.json",
+		"payment-initiation-swagger-flattened.json",
+	}, names)
+}
+
+func TestReadDir_OverrideDir(t *testing.T) {
+	dir := overrideDir(t, "pkg/schema/spec/v3.1.5/extra-swagger.json", `{}`)
+	SetOverrideDirs(dir)
+	defer SetOverrideDirs()
+
+	names, err := ReadDir("pkg/schema/spec/v3.1.5")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"account-info-swagger-flattened.json",
+		"confirmation-funds-swagger-flattened.json",
+		"extra-swagger.json",
+		"payment-initiation-swagger-flattened.json",
+	}, names)
+}
+
+func TestReadDir_NotFound(t *testing.T) {
+	_, err := ReadDir("pkg/schema/spec/v9.9.9")
+
+	require.Error(t, err)
+	assert.True(t, os.IsNotExist(err))
+}
+
+// overrideDir creates a temporary override directory containing a single file
+func overrideDir(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "assets")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	filename := filepath.Join(dir, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+	return dir
+}
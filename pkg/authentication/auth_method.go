@@ -17,6 +17,8 @@ import (
 const (
 	TlsClientAuth     = "tls_client_auth"
 	PrivateKeyJwt     = "private_key_jwt"
+	ClientSecretJwt   = "client_secret_jwt"
+	ClientSecretPost  = "client_secret_post"
 	ClientSecretBasic = "client_secret_basic"
 )
 
@@ -27,6 +29,8 @@ func SuiteSupportedAuthMethodsMostSecureFirst() []string {
 	return []string{
 		TlsClientAuth,
 		PrivateKeyJwt,
+		ClientSecretJwt,
+		ClientSecretPost,
 		ClientSecretBasic,
 	}
 }
@@ -2,6 +2,8 @@ package authentication
 
 import (
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
@@ -13,12 +15,15 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/signer"
 )
 
 // Certificate - create new Certificate.
+// Keys are RSA or EC P-256 keys, see SigningAlgForKey for the alg they sign with by default
 type Certificate interface {
-	PublicKey() *rsa.PublicKey
-	PrivateKey() *rsa.PrivateKey
+	PublicKey() crypto.PublicKey
+	PrivateKey() crypto.Signer
 	TLSCert() tls.Certificate
 	DN() (string, string, string, error)
 	SignatureIssuer(bool) (string, error)
@@ -26,8 +31,8 @@ type Certificate interface {
 
 // certificate implements Certificate
 type certificate struct {
-	publicKey     *rsa.PublicKey
-	privateKey    *rsa.PrivateKey
+	publicKey     crypto.PublicKey
+	privateKey    crypto.Signer
 	tlsCert       tls.Certificate
 	publicCertPem []byte
 }
@@ -35,18 +40,18 @@ type certificate struct {
 // NewCertificate - create new Certificate.
 //
 // Parameters:
-// * publicKeyPem=PEM encoded public key.
-// * privateKeyPem=PEM encoded private key.
+// * publicKeyPem=PEM encoded certificate or public key, RSA or EC P-256.
+// * privateKeyPem=PEM encoded PKCS1, PKCS8 or SEC1 private key, of the same type.
 //
 // Returns Certificate, or nil with error set if something is invalid.
 func NewCertificate(publicKeyPem, privateKeyPem string) (Certificate, error) {
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicKeyPem))
+	publicKey, err := parsePublicKeyFromPEM([]byte(publicKeyPem))
 	if err != nil {
 		return nil, errors.Wrap(err, "error with public key")
 	}
 	publicPem := []byte(publicKeyPem)
 
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPem))
+	privateKey, err := parsePrivateKeyFromPEM([]byte(privateKeyPem))
 	if err != nil {
 		return nil, errors.Wrap(err, "error with private key")
 	}
@@ -68,9 +73,54 @@ func NewCertificate(publicKeyPem, privateKeyPem string) (Certificate, error) {
 	}, nil
 }
 
+// NewExternalCertificate - create new Certificate of which the private key is held by an external
+// signer, the suite only holds the public key.
+//
+// Parameters:
+// * publicKeyPem=PEM encoded certificate or public key, RSA or EC P-256.
+// * signerURI=URI of the signer of the private key, see package signer.
+//
+// Returns Certificate, or nil with error set if the signer is unavailable or doesn't sign with the
+// private key of the public key.
+func NewExternalCertificate(publicKeyPem, signerURI string) (Certificate, error) {
+	publicKey, err := parsePublicKeyFromPEM([]byte(publicKeyPem))
+	if err != nil {
+		return nil, errors.Wrap(err, "error with public key")
+	}
+	publicPem := []byte(publicKeyPem)
+
+	privateKey, err := signer.Open(signerURI, publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "error with signer")
+	}
+
+	if err := validateKeys(publicKey, privateKey); err != nil {
+		return nil, err
+	}
+
+	return &certificate{
+		publicKey:     publicKey,
+		privateKey:    privateKey,
+		tlsCert:       externalTLSCert(publicPem, privateKey),
+		publicCertPem: publicPem,
+	}, nil
+}
+
+// externalTLSCert - the TLS certificate of the certificate chain of publicPem, of which the
+// private key is held by an external signer
+func externalTLSCert(publicPem []byte, privateKey crypto.Signer) tls.Certificate {
+	tlsCert := tls.Certificate{PrivateKey: privateKey}
+	for block, rest := pem.Decode(publicPem); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			tlsCert.Certificate = append(tlsCert.Certificate, block.Bytes)
+		}
+	}
+	return tlsCert
+}
+
 // creates a certificate from only the public key, in the case of the aspsp public cert to validate signatures
 func NewPublicCertificate(publicKeyPem string) (Certificate, error) {
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicKeyPem))
+	publicKey, err := parsePublicKeyFromPEM([]byte(publicKeyPem))
 	if err != nil {
 		return nil, errors.Wrap(err, "error with public key")
 	}
@@ -82,11 +132,11 @@ func NewPublicCertificate(publicKeyPem string) (Certificate, error) {
 	}, nil
 }
 
-func (c certificate) PublicKey() *rsa.PublicKey {
+func (c certificate) PublicKey() crypto.PublicKey {
 	return c.publicKey
 }
 
-func (c certificate) PrivateKey() *rsa.PrivateKey {
+func (c certificate) PrivateKey() crypto.Signer {
 	return c.privateKey
 }
 
@@ -94,7 +144,7 @@ func (c certificate) TLSCert() tls.Certificate {
 	return c.tlsCert
 }
 
-func validateKeys(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey) error {
+func validateKeys(publicKey crypto.PublicKey, privateKey crypto.Signer) error {
 	// validate public and private key pair
 	// see:
 	// * https://stackoverflow.com/questions/20655702/signing-and-decoding-with-rsa-sha-in-go
@@ -102,18 +152,100 @@ func validateKeys(publicKey *rsa.PublicKey, privateKey *rsa.PrivateKey) error {
 	plaintext := []byte(`date: Thu, 05 Jan 2012 21:31:40 GMT`)
 
 	hashed := sha256.Sum256(plaintext)
-	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hashed[:])
+	signature, err := privateKey.Sign(rand.Reader, hashed[:], crypto.SHA256)
 	if err != nil {
 		return errors.Wrap(err, "error signing")
 	}
 
-	if err := rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, hashed[:], signature); err != nil {
+	if err := verifySHA256(publicKey, hashed[:], signature); err != nil {
 		return errors.Wrap(err, "error verifying")
 	}
 
 	return nil
 }
 
+// verifySHA256 - verifies the signature of a SHA-256 digest made by crypto.Signer.Sign: PKCS1v15
+// for RSA keys, ASN.1 for EC keys
+func verifySHA256(publicKey crypto.PublicKey, hashed, signature []byte) error {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed, signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hashed, signature) {
+			return errors.New("crypto/ecdsa: verification error")
+		}
+		return nil
+	}
+	return errNotSupportedPublicKey
+}
+
+var (
+	errNotSupportedPublicKey  = errors.New("Key is not a valid RSA or EC P-256 public key")
+	errNotSupportedPrivateKey = errors.New("Key is not a valid RSA or EC P-256 private key")
+)
+
+// parsePublicKeyFromPEM - the RSA or EC P-256 public key of a PEM encoded PKIX public key or certificate
+func parsePublicKeyFromPEM(key []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		parsed = cert.PublicKey
+	}
+
+	if !supportedPublicKey(parsed) {
+		return nil, errNotSupportedPublicKey
+	}
+	return parsed, nil
+}
+
+// ParsePrivateKey - the RSA or EC P-256 private key of a PEM encoded private key
+func ParsePrivateKey(privateKeyPem string) (crypto.Signer, error) {
+	return parsePrivateKeyFromPEM([]byte(privateKeyPem))
+}
+
+// parsePrivateKeyFromPEM - the RSA or EC P-256 key of a PEM encoded PKCS1, PKCS8 or SEC1 private key
+func parsePrivateKeyFromPEM(key []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return nil, jwt.ErrKeyMustBePEMEncoded
+	}
+
+	var parsed interface{}
+	var err error
+	if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+			if parsed, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	signer, ok := parsed.(crypto.Signer)
+	if !ok || !supportedPublicKey(signer.Public()) {
+		return nil, errNotSupportedPrivateKey
+	}
+	return signer, nil
+}
+
+// supportedPublicKey - whether key is an RSA or EC P-256 public key
+func supportedPublicKey(key interface{}) bool {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return true
+	case *ecdsa.PublicKey:
+		return k.Curve == elliptic.P256()
+	}
+	return false
+}
+
 func (c certificate) DN() (string, string, string, error) {
 	co, o, ou, cn, err := c.nameComponents()
 	if err != nil {
@@ -1,13 +1,20 @@
 package authentication
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"net"
+	"path/filepath"
 	"testing"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/signer"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+	"github.com/dgrijalva/jwt-go"
 )
 
 const (
@@ -192,7 +199,7 @@ yEh6Szz2in47Tv5n52m9dLYyPCbqZkOB5nTSqtscpkQD/HpykCggvx09iQ==
 	cert, err := NewCertificate(publicCert, privateCert)
 
 	require.Nil(cert)
-	require.EqualError(err, `error with public key: Key is not a valid RSA public key`)
+	require.EqualError(err, `error verifying: crypto/ecdsa: verification error`)
 }
 
 func TestCertificateValidateInvalidPrivateKeyRSA(t *testing.T) {
@@ -207,5 +214,71 @@ VZ4E4JhiGX3fT07ucdN8nDXufQ8WplCX0nWFPh3P3Z7snLq2E3m8yqIQ
 	cert, err := NewCertificate(publicCert, privateCert)
 
 	require.Nil(cert)
-	require.EqualError(err, `error with private key: Key is not a valid RSA private key`)
+	require.EqualError(err, `error verifying: crypto/rsa: verification error`)
+}
+
+func TestCertificateValidateValidKeysEC(t *testing.T) {
+	require := test.NewRequire(t)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(err)
+	privateKeyBytes, err := x509.MarshalECPrivateKey(privateKey)
+	require.NoError(err)
+
+	publicCert := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}))
+	privateCert := string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: privateKeyBytes}))
+	cert, err := NewCertificate(publicCert, privateCert)
+
+	require.NoError(err)
+	require.Equal(&privateKey.PublicKey, cert.PublicKey())
+	require.Equal(SigningMethodES256, SigningAlgForKey(cert.PublicKey()))
+}
+
+func TestCertificateValidateInvalidPublicKeyCurve(t *testing.T) {
+	require := test.NewRequire(t)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(err)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(err)
+
+	publicCert := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}))
+	cert, err := NewCertificate(publicCert, privateCertValid)
+
+	require.Nil(cert)
+	require.EqualError(err, `error with public key: Key is not a valid RSA or EC P-256 public key`)
+}
+
+func TestNewExternalCertificate(t *testing.T) {
+	require := test.NewRequire(t)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(err)
+	publicCert := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}))
+
+	socket := filepath.Join(t.TempDir(), "signing.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(err)
+	defer listener.Close()
+	go signer.Serve(listener, map[string]crypto.Signer{"signing": privateKey}) // nolint:errcheck
+	signer.SetDaemonSocket(socket)
+	defer signer.SetDaemonSocket("")
+
+	cert, err := NewExternalCertificate(publicCert, "daemon:signing")
+	require.NoError(err)
+	require.Equal(&privateKey.PublicKey, cert.PublicKey())
+
+	token := jwt.NewWithClaims(SigningAlgForKey(cert.PublicKey()), jwt.MapClaims{"iss": "fcs"})
+	signed, err := token.SignedString(cert.PrivateKey())
+	require.NoError(err)
+	_, err = jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return &privateKey.PublicKey, nil })
+	require.NoError(err)
+
+	cert, err = NewExternalCertificate(publicCertValid, "daemon:signing")
+	require.Nil(cert)
+	require.EqualError(err, "error verifying: crypto/rsa: verification error")
 }
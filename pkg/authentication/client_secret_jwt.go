@@ -0,0 +1,35 @@
+package authentication
+
+import (
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// clientSecretJWTLifetime - validity of a `client_secret_jwt` client assertion
+const clientSecretJWTLifetime = 5 * time.Minute
+
+// ClientSecretJWT - the `client_assertion` of the `client_secret_jwt` authentication: a JWT of
+// the client for the token endpoint, signed with HS256 keyed by the client secret, as per
+// https://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication
+func ClientSecretJWT(clientID, clientSecret, tokenEndpoint string) (string, error) {
+	if clientID == "" {
+		return "", errors.New("clientID cannot be empty")
+	}
+	if clientSecret == "" {
+		return "", errors.New("clientSecret cannot be empty")
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": clientID,
+		"sub": clientID,
+		"aud": tokenEndpoint,
+		"iat": now.Unix(),
+		"exp": now.Add(clientSecretJWTLifetime).Unix(),
+		"jti": uuid.New().String(),
+	}
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(clientSecret))
+	return assertion, errors.Wrap(err, "authentication.ClientSecretJWT")
+}
@@ -0,0 +1,33 @@
+package authentication
+
+import (
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientSecretJWT(t *testing.T) {
+	assertion, err := ClientSecretJWT("client", "secret", "https://as.example.com/token")
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(assertion, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte("secret"), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, jwt.SigningMethodHS256, token.Method)
+	assert.Equal(t, "client", claims["iss"])
+	assert.Equal(t, "client", claims["sub"])
+	assert.Equal(t, "https://as.example.com/token", claims["aud"])
+	assert.NotEmpty(t, claims["jti"])
+
+	_, err = jwt.Parse(assertion, func(token *jwt.Token) (interface{}, error) {
+		return []byte("other secret"), nil
+	})
+	assert.Error(t, err, "keyed by the client secret")
+
+	_, err = ClientSecretJWT("client", "", "https://as.example.com/token")
+	assert.EqualError(t, err, "clientSecret cannot be empty")
+}
@@ -2,8 +2,8 @@ package authentication
 
 import (
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"regexp"
@@ -18,12 +18,17 @@ import (
 
 // Workaround for default PS256 signing parameter issue
 // https://github.com/dgrijalva/jwt-go/issues/285
-var SigningMethodPS256 = &jwt.SigningMethodRSAPSS{
-	SigningMethodRSA: jwt.SigningMethodPS256.SigningMethodRSA,
-	Options: &rsa.PSSOptions{
-		SaltLength: rsa.PSSSaltLengthEqualsHash,
-		Hash:       crypto.SHA256,
+var SigningMethodPS256 = &SignerMethod{
+	SigningMethod: &jwt.SigningMethodRSAPSS{
+		SigningMethodRSA: jwt.SigningMethodPS256.SigningMethodRSA,
+		Options:          pssOptions,
 	},
+	opts: pssOptions,
+}
+
+var pssOptions = &rsa.PSSOptions{
+	SaltLength: rsa.PSSSaltLengthEqualsHash,
+	Hash:       crypto.SHA256,
 }
 
 var b64Status bool      // for report export
@@ -35,7 +40,9 @@ func GetSigningAlg(alg string) (jwt.SigningMethod, error) {
 	case "PS256":
 		return SigningMethodPS256, nil
 	case "RS256":
-		return jwt.SigningMethodRS256, nil
+		return SigningMethodRS256, nil
+	case "ES256":
+		return SigningMethodES256, nil
 	case "NONE":
 		fallthrough
 	default:
@@ -43,7 +50,29 @@ func GetSigningAlg(alg string) (jwt.SigningMethod, error) {
 	}
 }
 
+// SigningAlgForKey - the alg signing with the private key of publicKey by default: ES256 for EC
+// P-256 keys, PS256 for RSA keys
+func SigningAlgForKey(publicKey crypto.PublicKey) jwt.SigningMethod {
+	if _, ok := publicKey.(*ecdsa.PublicKey); ok {
+		return SigningMethodES256
+	}
+	return SigningMethodPS256
+}
+
+// SigningCertFromContext - the signing certificate of the context, of which the private key is held
+// by the external signer of `signingSigner` when set
 func SigningCertFromContext(ctx ContextInterface) (Certificate, error) {
+	if signerURI, err := ctx.GetString("signingSigner"); err == nil && signerURI != "" {
+		pubKey, err := ctx.GetString("signingPublic")
+		if err != nil {
+			return nil, errors.New("authentication.SigningCertFromContext: couldn't find `SigningPublic` in context")
+		}
+		cert, err := NewExternalCertificate(pubKey, signerURI)
+		if err != nil {
+			return nil, errors.Wrap(err, "authentication.SigningCertFromContext: couldn't create `certificate` from public key and signer")
+		}
+		return cert, nil
+	}
 	privKey, err := ctx.GetString("signingPrivate")
 	if err != nil {
 		return nil, errors.New("authentication.SigningCertFromContext: couldn't find `SigningPrivate` in context")
@@ -260,10 +289,7 @@ func minifiyJSONBody(body string) (string, error) {
 }
 
 func getKidFromCertificate(cert Certificate) (string, error) {
-	modulus := cert.PublicKey().N.Bytes()
-	modulusBase64 := base64.RawURLEncoding.EncodeToString(modulus)
-	kid, err := CalcKid(modulusBase64)
-	return kid, err
+	return KeyID(cert.PublicKey())
 }
 
 // Gets the payment api version from the context
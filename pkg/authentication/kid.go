@@ -1,6 +1,9 @@
 package authentication
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
 	"crypto/sha1"
 	"encoding/base64"
 	"fmt"
@@ -12,7 +15,30 @@ import (
 
 func CalcKid(modulus string) (string, error) {
 	canonicalInput := fmt.Sprintf(`{"e":"AQAB","kty":"RSA","n":"%s"}`, modulus)
+	return canonicalKid(canonicalInput)
+}
+
+// calcECKid - the kid of an EC P-256 public key, calculated from its canonical JWK like CalcKid
+func calcECKid(publicKey *ecdsa.PublicKey) (string, error) {
+	x := base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32)))
+	y := base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32)))
+	canonicalInput := fmt.Sprintf(`{"crv":"P-256","kty":"EC","x":"%s","y":"%s"}`, x, y)
+	return canonicalKid(canonicalInput)
+}
+
+// KeyID - the kid of an RSA or EC P-256 public key
+func KeyID(publicKey crypto.PublicKey) (string, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return CalcKid(base64.RawURLEncoding.EncodeToString(key.N.Bytes()))
+	case *ecdsa.PublicKey:
+		return calcECKid(key)
+	}
+	return "", fmt.Errorf("authentication.KeyID: unsupported public key type %T", publicKey)
+}
 
+// canonicalKid - the base64url encoded SHA-1 of the canonical JWK of a key
+func canonicalKid(canonicalInput string) (string, error) {
 	sumer := sha1.New()
 	_, err := io.WriteString(sumer, canonicalInput)
 	if err != nil {
@@ -27,11 +53,10 @@ func CalcKid(modulus string) (string, error) {
 }
 
 // GetKID determines the value of the JWS Key ID
-func GetKID(ctx ContextInterface, modulus []byte) (string, error) {
-	modulusBase64 := base64.RawURLEncoding.EncodeToString(modulus)
-	kid, err := CalcKid(modulusBase64)
+func GetKID(ctx ContextInterface, publicKey crypto.PublicKey) (string, error) {
+	kid, err := KeyID(publicKey)
 	if err != nil {
-		return "", errors.Wrap(err, "authentication.GetKID: CalcKid(modulusBase64) failed")
+		return "", errors.Wrap(err, "authentication.GetKID: KeyID(publicKey) failed")
 	}
 	nonOBDirectory, exists := ctx.Get("nonOBDirectory")
 	if !exists {
@@ -1,6 +1,10 @@
 package authentication
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/base64"
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,3 +20,20 @@ func TestCalcKid(t *testing.T) {
 	expected := "QuFYBRJnWdI6_NHFgamuXNr5R20"
 	assert.Equal(t, expected, kid)
 }
+
+func TestKeyID(t *testing.T) {
+	// the EC P-256 key of RFC 7517 appendix A.1
+	x, err := base64.RawURLEncoding.DecodeString("MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4")
+	require.NoError(t, err)
+	y, err := base64.RawURLEncoding.DecodeString("4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM")
+	require.NoError(t, err)
+	publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+	kid, err := KeyID(publicKey)
+
+	require.NoError(t, err)
+	assert.Equal(t, "VHriznG7vJAFpXMXRmGgAkA5sEE", kid)
+
+	_, err = KeyID("not a key")
+	assert.EqualError(t, err, "authentication.KeyID: unsupported public key type string")
+}
@@ -22,6 +22,9 @@ type OpenIDConfiguration struct {
 	ResponseTypesSupported                 []string `json:"response_types_supported,omitempty"`
 	AcrValuesSupported                     []string `json:"acr_values_supported,omitempty"`
 	JwksURI                                string   `json:"jwks_uri,omitempty"`
+	PushedAuthorizationRequestEndpoint     string   `json:"pushed_authorization_request_endpoint,omitempty"`
+	RequirePushedAuthorizationRequests     bool     `json:"require_pushed_authorization_requests,omitempty"`
+	RegistrationEndpoint                   string   `json:"registration_endpoint,omitempty"`
 }
 
 var jwks_uri_accessor = ""
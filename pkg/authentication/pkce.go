@@ -0,0 +1,38 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+// PKCE parameters, https://tools.ietf.org/html/rfc7636
+const (
+	CodeChallenge       = "code_challenge"
+	CodeChallengeMethod = "code_challenge_method"
+	CodeVerifier        = "code_verifier"
+
+	// CodeChallengeMethodS256 - the only method allowed by FAPI 1.0 Advanced
+	CodeChallengeMethodS256 = "S256"
+)
+
+// codeVerifierEntropy - random bytes of a code verifier, encoded as 43 characters,
+// the minimum length of RFC 7636
+const codeVerifierEntropy = 32
+
+// NewCodeVerifier - a random PKCE code verifier, a new one is required for each authorisation request
+func NewCodeVerifier() (string, error) {
+	entropy := make([]byte, codeVerifierEntropy)
+	if _, err := rand.Read(entropy); err != nil {
+		return "", errors.Wrap(err, "authentication.NewCodeVerifier")
+	}
+	return base64.RawURLEncoding.EncodeToString(entropy), nil
+}
+
+// CodeChallengeS256 - the S256 code challenge of verifier: BASE64URL(SHA256(verifier))
+func CodeChallengeS256(verifier string) string {
+	hash := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
@@ -0,0 +1,24 @@
+package authentication
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCodeVerifier(t *testing.T) {
+	verifier, err := NewCodeVerifier()
+	require.NoError(t, err)
+	// https://tools.ietf.org/html/rfc7636#section-4.1
+	assert.Regexp(t, regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`), verifier)
+
+	other, err := NewCodeVerifier()
+	require.NoError(t, err)
+	assert.NotEqual(t, verifier, other)
+}
+
+func TestCodeChallengeS256(t *testing.T) {
+	assert.Equal(t, "XABcLJf_6Jy1aeshBVLMSd2Vr4Npg16CqbNFI1Vy-l0", CodeChallengeS256("dBjftJeZ4CVP-mB92K27uSlDRa1vNTnOXKSmsWiWdHk"))
+}
@@ -17,6 +17,7 @@ type PSUConsentClaims struct {
 	RedirectURI           string
 	ConsentId             string
 	State                 string // {test_id}
+	CodeVerifier          string // PKCE, the S256 code challenge is sent when not empty
 }
 
 // PSUURLGenerate generates a PSU Consent URL based on claims
@@ -37,6 +38,10 @@ func PSUURLGenerate(claims PSUConsentClaims) (*url.URL, error) {
 	consentUrlQuery.Set("scope", claims.Scope)
 	consentUrlQuery.Set("request", token)
 	consentUrlQuery.Set("state", claims.State)
+	if claims.CodeVerifier != "" {
+		consentUrlQuery.Set(CodeChallenge, CodeChallengeS256(claims.CodeVerifier))
+		consentUrlQuery.Set(CodeChallengeMethod, CodeChallengeMethodS256)
+	}
 	consentUrl.RawQuery = consentUrlQuery.Encode()
 
 	return consentUrl, nil
@@ -82,7 +87,7 @@ type intentId struct {
 }
 
 func makeOpenBankingJWTClaims(claims PSUConsentClaims) jwt.MapClaims {
-	return jwt.MapClaims{
+	jwtClaims := jwt.MapClaims{
 		"iss":          claims.Iss,
 		"scope":        claims.Scope,
 		"aud":          claims.Aud,
@@ -96,4 +101,9 @@ func makeOpenBankingJWTClaims(claims PSUConsentClaims) jwt.MapClaims {
 			},
 		},
 	}
+	if claims.CodeVerifier != "" {
+		jwtClaims[CodeChallenge] = CodeChallengeS256(claims.CodeVerifier)
+		jwtClaims[CodeChallengeMethod] = CodeChallengeMethodS256
+	}
+	return jwtClaims
 }
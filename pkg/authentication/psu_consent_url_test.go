@@ -31,6 +31,21 @@ func TestPSUURLGenerate(t *testing.T) {
 	assert.Equal(t, token, url.Query().Get("request"))
 }
 
+func TestPSUURLGenerateCodeChallenge(t *testing.T) {
+	claims := PSUConsentClaims{State: "state", CodeVerifier: "dBjftJeZ4CVP-mB92K27uSlDRa1vNTnOXKSmsWiWdHk"}
+
+	url, err := PSUURLGenerate(claims)
+
+	require.NoError(t, err)
+	assert.Equal(t, "XABcLJf_6Jy1aeshBVLMSd2Vr4Npg16CqbNFI1Vy-l0", url.Query().Get("code_challenge"))
+	assert.Equal(t, "S256", url.Query().Get("code_challenge_method"))
+	c := jwt.MapClaims{}
+	_, _, err = new(jwt.Parser).ParseUnverified(url.Query().Get("request"), c)
+	require.NoError(t, err)
+	assert.Equal(t, "XABcLJf_6Jy1aeshBVLMSd2Vr4Npg16CqbNFI1Vy-l0", c["code_challenge"])
+	assert.Equal(t, "S256", c["code_challenge_method"])
+}
+
 func TestCreateAlgNoneJWTEmpty(t *testing.T) {
 	claims := PSUConsentClaims{}
 
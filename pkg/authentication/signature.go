@@ -1,7 +1,7 @@
 package authentication
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
@@ -30,6 +30,9 @@ type JWK struct {
 	X5c []string `json:"x5c,omitempty"`
 	N   string   `json:"n,omitempty"`
 	E   string   `json:"e,omitempty"`
+	Crv string   `json:"crv,omitempty"`
+	X   string   `json:"x,omitempty"`
+	Y   string   `json:"y,omitempty"`
 	Kid string   `json:"kid,omitempty"`
 	X5t string   `json:"x5t,omitempty"`
 	X5u string   `json:"x5u,omitempty"`
@@ -71,7 +74,11 @@ func ValidateSignature(jwtToken, body, jwksUri string, b64 bool) (bool, error) {
 	}
 	logrus.Trace("Signature with payload: " + signature)
 
-	verified, err := MyJwsVerify(signature, jwa.PS256, cert.PublicKey, b64)
+	alg, err := getAlgFromToken(jwtToken)
+	if err != nil {
+		return false, err
+	}
+	verified, err := MyJwsVerify(signature, jwa.SignatureAlgorithm(alg), cert.PublicKey, b64)
 	if err != nil {
 		logrus.Errorf("failed to verify message: %v", err)
 		return false, err
@@ -120,6 +127,22 @@ func getKidFromToken(token string) (string, error) {
 	return kid, nil
 }
 
+func getAlgFromToken(token string) (string, error) {
+	var tokenHeader map[string]interface{}
+	segments := strings.Split(token, ".")
+
+	decodedPayload, _ := base64.RawURLEncoding.DecodeString(segments[0])
+
+	json.Unmarshal(decodedPayload, &tokenHeader)
+
+	alg, ok := tokenHeader["alg"].(string)
+	if !ok {
+		return "", fmt.Errorf("GetAlgFromToken: error getting alg string from header")
+	}
+
+	return alg, nil
+}
+
 var jwkCache = make(map[string]JWK)
 
 // Get JWK from JWKS_URI - cache responses
@@ -179,7 +202,7 @@ func GetJwks(url string) (JWKS, error) {
 
 // buildSignature - takes all the token parameters and assembles a detached header signed token string which is returned
 // Handles api versions v3.1.4 and above, v3.1.3 and prior, plus v3.0 which has a slightly different JWT header
-func buildSignature(b64 bool, kid, issuer, trustAnchor, body string, alg jwt.SigningMethod, privKey *rsa.PrivateKey) (string, error) {
+func buildSignature(b64 bool, kid, issuer, trustAnchor, body string, alg jwt.SigningMethod, privKey crypto.Signer) (string, error) {
 	var token jwt.Token
 
 	if b64 {
@@ -320,8 +343,8 @@ func (s signatureHeader) validateSignatureHeader(b64 bool) error {
 		}
 	}
 
-	if s.Alg != "PS256" { // Mandatory must be "PS256"
-		return errors.New("Validate Signature - alg claim MUST equal `PS256`")
+	if s.Alg != "PS256" && s.Alg != "ES256" { // Mandatory must be "PS256" or "ES256"
+		return errors.New("Validate Signature - alg claim MUST equal `PS256` or `ES256`")
 	}
 
 	if s.Kid == "" { // Mandatory - must be present
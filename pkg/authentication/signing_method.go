@@ -0,0 +1,67 @@
+package authentication
+
+import (
+	"crypto"
+	"crypto/rand"
+	"encoding/asn1"
+	"math/big"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+)
+
+// SignerMethod - a jwt.SigningMethod signing with the crypto.Signer of a Certificate, so JWTs are
+// signed the same way whether the private key is in memory or held by an external signer.
+// jwt-go only signs with *rsa.PrivateKey and *ecdsa.PrivateKey keys, verification is left to it
+type SignerMethod struct {
+	jwt.SigningMethod
+	opts crypto.SignerOpts
+}
+
+// SigningMethodRS256 - RS256 signing with any crypto.Signer
+var SigningMethodRS256 = &SignerMethod{SigningMethod: jwt.SigningMethodRS256, opts: crypto.SHA256}
+
+// SigningMethodES256 - ES256 signing with any crypto.Signer
+var SigningMethodES256 = &SignerMethod{SigningMethod: jwt.SigningMethodES256, opts: crypto.SHA256}
+
+// Sign - the signature of signingString by key, which is a crypto.Signer, other keys are
+// signed with by the jwt-go method
+func (m *SignerMethod) Sign(signingString string, key interface{}) (string, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return m.SigningMethod.Sign(signingString, key)
+	}
+
+	hasher := m.opts.HashFunc().New()
+	if _, err := hasher.Write([]byte(signingString)); err != nil {
+		return "", errors.Wrap(err, "authentication.SignerMethod.Sign")
+	}
+	signature, err := signer.Sign(rand.Reader, hasher.Sum(nil), m.opts)
+	if err != nil {
+		return "", errors.Wrapf(err, "authentication.SignerMethod.Sign: %s signing failed", m.Alg())
+	}
+	if m.SigningMethod == jwt.SigningMethodES256 {
+		if signature, err = rawECSignature(signature, 32); err != nil {
+			return "", errors.Wrap(err, "authentication.SignerMethod.Sign")
+		}
+	}
+	return jwt.EncodeSegment(signature), nil
+}
+
+// rawECSignature - the JWS encoding of an ASN.1 ECDSA signature: r and s as big-endian
+// unsigned integers of keySize bytes each
+func rawECSignature(der []byte, keySize int) ([]byte, error) {
+	var signature struct {
+		R, S *big.Int
+	}
+	if _, err := asn1.Unmarshal(der, &signature); err != nil {
+		return nil, errors.Wrap(err, "invalid ECDSA signature")
+	}
+	if signature.R.BitLen() > keySize*8 || signature.S.BitLen() > keySize*8 {
+		return nil, errors.New("invalid ECDSA signature: r or s too large")
+	}
+	raw := make([]byte, 2*keySize)
+	signature.R.FillBytes(raw[:keySize])
+	signature.S.FillBytes(raw[keySize:])
+	return raw, nil
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"net/http"
+	"net/http/cookiejar"
 	"time"
 )
 
@@ -17,10 +18,13 @@ func NewConnection() (*Connection, error) {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
+	// keeps the session cookie of the server, so every request of a run uses the same journey
+	jar, _ := cookiejar.New(nil)
 	return &Connection{
 		&http.Client{
 			Transport: tr,
 			Timeout:   5 * time.Minute,
+			Jar:       jar,
 		},
 	}, ErrInsecure
 }
@@ -266,6 +266,7 @@ func (s service) wsDialer() (*websocket.Conn, error) {
 		Proxy:            http.ProxyFromEnvironment,
 		HandshakeTimeout: 5 * time.Second,
 		TLSClientConfig:  &tls.Config{InsecureSkipVerify: true},
+		Jar:              s.conn.Jar,
 	}
 	c, _, err := dialer.Dial(s.wsHost+runTestCasesResultsWS, nil)
 	return c, err
@@ -0,0 +1,438 @@
+package dcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
+)
+
+const (
+	// APIName - the API the results of the DCR conformance tests are reported under
+	APIName    = "Dynamic Client Registration"
+	APIVersion = "v3.2"
+	refURI     = "https://openbankinguk.github.io/dcr-docs-pub/v3.2/dynamic-client-registration.html"
+	// specName - the title of the bundled OB DCR swagger, of which specPathRegister and
+	// specPathClient are the paths of the registration endpoint and of a registration
+	specName         = "Dynamic Client Registration API"
+	specPathRegister = "/register"
+	specPathClient   = "/register/{ClientId}"
+	// contentTypeJWT - the content type of signed registration requests
+	contentTypeJWT = "application/jwt"
+	// invalidRedirectURI - a redirect URI no software statement has
+	invalidRedirectURI = "https://conformance-suite.invalid/callback"
+)
+
+// Run - runs the DCR conformance tests of config with client, which presents the transport
+// certificate of the software. The client registered is returned when config keeps its
+// registration, else it is deleted by the tests
+func Run(config Config, client *resty.Client, recorder *har.Recorder) ([]results.TestCase, *ClientRegistration) {
+	env := &testEnv{config: config, client: client, recorder: recorder}
+	testResults := []results.TestCase{}
+	for _, test := range dcrTests {
+		warning, err := test.run(env)
+		errs := []error{}
+		if err != nil {
+			errs = append(errs, err)
+		}
+		result := results.NewTestCaseResult(test.id, err == nil, results.NoMetrics(), errs, config.RegistrationEndpoint, APIName, APIVersion, test.detail, refURI, "")
+		if warning != "" {
+			result.Warnings = append(result.Warnings, warning)
+		}
+		logrus.WithFields(logrus.Fields{
+			"id":      test.id,
+			"pass":    result.Pass,
+			"err":     err,
+			"warning": warning,
+		}).Debug("dynamic client registration conformance test")
+		testResults = append(testResults, result)
+	}
+	if env.deleted {
+		return testResults, nil
+	}
+	return testResults, env.registration
+}
+
+// dcrTest - a dynamic client registration conformance test, a warning is reported when it
+// passes without testing the ASPSP
+type dcrTest struct {
+	id     string
+	detail string
+	run    func(env *testEnv) (warning string, err error)
+}
+
+// testEnv - the software registered and the state the tests share: the client registered by
+// the first test is read, updated and deleted by the following ones
+type testEnv struct {
+	config       Config
+	client       *resty.Client
+	recorder     *har.Recorder
+	registration *ClientRegistration
+	accessToken  string
+	deleted      bool
+}
+
+var dcrTests = []dcrTest{
+	{
+		id:     "#dcr001",
+		detail: "Registration request signed with the software's signing key registers a client",
+		run: func(env *testEnv) (string, error) {
+			request, err := NewRegistrationRequest(env.config, time.Now())
+			if err != nil {
+				return "", err
+			}
+			resp, err := env.post(request, env.config)
+			if err := expectStatus(resp, err, http.StatusCreated); err != nil {
+				return "", err
+			}
+			registration, err := registrationResponse(resp, request)
+			if err != nil {
+				return "", err
+			}
+			env.registration = registration
+			return "", nil
+		},
+	},
+	{
+		id:     "#dcr002",
+		detail: "Unsigned registration request is rejected",
+		run: func(env *testEnv) (string, error) {
+			request, err := NewRegistrationRequest(env.config, time.Now())
+			if err != nil {
+				return "", err
+			}
+			unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, request).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				return "", errors.Wrap(err, "unsigned registration request")
+			}
+			resp, err := env.send(http.MethodPost, env.config.RegistrationEndpoint, "", unsigned, "RegistrationRequest unsigned")
+			return "", expectStatus(resp, err, http.StatusBadRequest)
+		},
+	},
+	{
+		id:     "#dcr003",
+		detail: "Registration request with a redirect URI not in the software statement is rejected",
+		run: func(env *testEnv) (string, error) {
+			config := env.config
+			config.RedirectURIs = []string{invalidRedirectURI}
+			request, err := NewRegistrationRequest(config, time.Now())
+			if err != nil {
+				return "", err
+			}
+			resp, err := env.post(request, config)
+			return "", expectStatus(resp, err, http.StatusBadRequest)
+		},
+	},
+	{
+		id:     "#dcr004",
+		detail: "Client registration is read with the registration management token",
+		run: func(env *testEnv) (string, error) {
+			if env.registration == nil {
+				return "", errors.New("no client registered")
+			}
+			token, err := env.managementToken()
+			if err != nil {
+				return "", err
+			}
+			resp, err := env.send(http.MethodGet, env.registration.clientURI(env.config.RegistrationEndpoint), token, "", "RegistrationRead")
+			if err := expectStatus(resp, err, http.StatusOK); err != nil {
+				return "", err
+			}
+			registration, err := decodeRegistration(resp)
+			if err != nil {
+				return "", err
+			}
+			return "", sameClient(env.registration, registration)
+		},
+	},
+	{
+		id:     "#dcr005",
+		detail: "Client registration is updated with a signed registration request",
+		run: func(env *testEnv) (string, error) {
+			if env.registration == nil {
+				return "", errors.New("no client registered")
+			}
+			token, err := env.managementToken()
+			if err != nil {
+				return "", err
+			}
+			request, err := NewRegistrationRequest(env.config, time.Now())
+			if err != nil {
+				return "", err
+			}
+			signed, err := request.Sign(env.config)
+			if err != nil {
+				return "", err
+			}
+			resp, err := env.send(http.MethodPut, env.registration.clientURI(env.config.RegistrationEndpoint), token, signed, "RegistrationUpdate")
+			if err := expectStatus(resp, err, http.StatusOK); err != nil {
+				return "", err
+			}
+			registration, err := registrationResponse(resp, request)
+			if err != nil {
+				return "", err
+			}
+			if err := sameClient(env.registration, registration); err != nil {
+				return "", err
+			}
+			env.registration = registration
+			return "", nil
+		},
+	},
+	{
+		id:     "#dcr006",
+		detail: "Client registration is deleted with the registration management token",
+		run: func(env *testEnv) (string, error) {
+			if env.config.KeepRegistration {
+				return "not tested, the registration is kept", nil
+			}
+			if env.registration == nil {
+				return "", errors.New("no client registered")
+			}
+			token, err := env.managementToken()
+			if err != nil {
+				return "", err
+			}
+			resp, err := env.send(http.MethodDelete, env.registration.clientURI(env.config.RegistrationEndpoint), token, "", "RegistrationDelete")
+			if err := expectStatus(resp, err, http.StatusNoContent); err != nil {
+				return "", err
+			}
+			env.deleted = true
+			return "", nil
+		},
+	},
+	{
+		id:     "#dcr007",
+		detail: "Deleted client registration cannot be read",
+		run: func(env *testEnv) (string, error) {
+			if !env.deleted {
+				return "not tested, the registration is not deleted", nil
+			}
+			resp, err := env.send(http.MethodGet, env.registration.clientURI(env.config.RegistrationEndpoint), env.accessToken, "", "RegistrationRead deleted")
+			return "", expectStatus(resp, err, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound)
+		},
+	},
+}
+
+// post - posts request signed with the signing key of config to the registration endpoint
+func (env *testEnv) post(request RegistrationRequest, config Config) (*resty.Response, error) {
+	signed, err := request.Sign(config)
+	if err != nil {
+		return nil, err
+	}
+	return env.send(http.MethodPost, config.RegistrationEndpoint, "", signed, "RegistrationRequest")
+}
+
+// send - sends a registration management request, with the bearer token and the signed
+// registration request body when they are not empty
+func (env *testEnv) send(method, url, token, body, comment string) (*resty.Response, error) {
+	request := env.client.R().SetHeader("accept", "application/json")
+	if token != "" {
+		request.SetHeader("authorization", "Bearer "+token)
+	}
+	if body != "" {
+		request.SetHeader("content-type", contentTypeJWT).SetBody(body)
+	}
+	resp, err := request.Execute(method, url)
+	env.recorder.Record(comment, resp)
+	return resp, err
+}
+
+// managementToken - the token of the registration management requests: the
+// `registration_access_token` of the registration, else an access token of the client
+// credentials grant of the client registered
+func (env *testEnv) managementToken() (string, error) {
+	if env.accessToken != "" {
+		return env.accessToken, nil
+	}
+	if env.registration.RegistrationAccessToken != "" {
+		env.accessToken = env.registration.RegistrationAccessToken
+		return env.accessToken, nil
+	}
+	form, err := env.clientAuthentication()
+	if err != nil {
+		return "", err
+	}
+	form["grant_type"] = "client_credentials"
+	form["scope"] = env.registration.Scope
+	request := env.client.R().
+		SetHeader("content-type", "application/x-www-form-urlencoded").
+		SetHeader("accept", "application/json").
+		SetFormData(form)
+	if env.registration.TokenEndpointAuthMethod == authentication.ClientSecretBasic {
+		request.SetBasicAuth(env.registration.ClientID, env.registration.ClientSecret)
+	}
+	resp, err := request.Post(env.config.TokenEndpoint)
+	env.recorder.Record("RegistrationManagementToken", resp)
+	if err := expectStatus(resp, err, http.StatusOK); err != nil {
+		return "", errors.Wrap(err, "client credentials grant of the client registered")
+	}
+	token := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.Unmarshal(resp.Body(), &token); err != nil || token.AccessToken == "" {
+		return "", errors.New("access_token missing from the token response of the client registered")
+	}
+	env.accessToken = token.AccessToken
+	return env.accessToken, nil
+}
+
+// clientAuthentication - the form parameters authenticating the client registered with its
+// token endpoint auth method, apart from the basic authorization header
+func (env *testEnv) clientAuthentication() (map[string]string, error) {
+	registration := env.registration
+	switch registration.TokenEndpointAuthMethod {
+	case authentication.ClientSecretBasic:
+		return map[string]string{}, nil
+	case authentication.ClientSecretPost:
+		return map[string]string{"client_id": registration.ClientID, "client_secret": registration.ClientSecret}, nil
+	case authentication.ClientSecretJwt:
+		assertion, err := authentication.ClientSecretJWT(registration.ClientID, registration.ClientSecret, env.config.TokenEndpoint)
+		if err != nil {
+			return nil, err
+		}
+		return clientAssertionForm(assertion), nil
+	case authentication.PrivateKeyJwt:
+		assertion, err := env.privateKeyJWT()
+		if err != nil {
+			return nil, err
+		}
+		return clientAssertionForm(assertion), nil
+	default:
+		return map[string]string{"client_id": registration.ClientID}, nil
+	}
+}
+
+// privateKeyJWT - a private_key_jwt client assertion of the client registered
+func (env *testEnv) privateKeyJWT() (string, error) {
+	alg, err := authentication.GetSigningAlg(env.config.signingAlg())
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(alg, jwt.StandardClaims{
+		Issuer:    env.registration.ClientID,
+		Subject:   env.registration.ClientID,
+		Audience:  env.config.TokenEndpoint,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(requestLifetime).Unix(),
+		Id:        uuid.New().String(),
+	})
+	token.Header["kid"] = env.config.Kid
+	signed, err := token.SignedString(env.config.SigningCert.PrivateKey())
+	return signed, errors.Wrap(err, "private_key_jwt client assertion")
+}
+
+func clientAssertionForm(assertion string) map[string]string {
+	return map[string]string{
+		"client_assertion_type": "urn:ietf:params:oauth:client-assertion-type:jwt-bearer",
+		"client_assertion":      assertion,
+	}
+}
+
+// clientURI - the url of the registration of r at the registration endpoint
+func (r *ClientRegistration) clientURI(registrationEndpoint string) string {
+	if r.RegistrationClientURI != "" {
+		return r.RegistrationClientURI
+	}
+	return strings.TrimSuffix(registrationEndpoint, "/") + "/" + r.ClientID
+}
+
+// registrationResponse - the valid client registration of resp, registered with request
+func registrationResponse(resp *resty.Response, request RegistrationRequest) (*ClientRegistration, error) {
+	registration, err := decodeRegistration(resp)
+	if err != nil {
+		return nil, err
+	}
+	if registration.SoftwareID != "" && registration.SoftwareID != request.SoftwareID {
+		return nil, fmt.Errorf("software_id %q registered, expected %q", registration.SoftwareID, request.SoftwareID)
+	}
+	if registration.TokenEndpointAuthMethod != request.TokenEndpointAuthMethod {
+		return nil, fmt.Errorf("token_endpoint_auth_method %q registered, expected %q", registration.TokenEndpointAuthMethod, request.TokenEndpointAuthMethod)
+	}
+	return registration, nil
+}
+
+// decodeRegistration - the client registration of resp, validated against the OB DCR spec
+func decodeRegistration(resp *resty.Response) (*ClientRegistration, error) {
+	if err := validateRegistration(resp); err != nil {
+		return nil, errors.Wrap(err, "invalid client registration")
+	}
+	registration := &ClientRegistration{}
+	if err := json.Unmarshal(resp.Body(), registration); err != nil {
+		return nil, errors.Wrap(err, "cannot unmarshal client registration")
+	}
+	return registration, nil
+}
+
+// validateRegistration - an error listing the failures of the registration response resp against
+// the bundled OB DCR swagger. ASPSPs serve the registration endpoint from any URL, so the
+// response is matched with the operation of the swagger path of its method
+func validateRegistration(resp *resty.Response) error {
+	validator, err := schema.NewSwaggerOBSpecValidator(specName, APIVersion)
+	if err != nil {
+		return err
+	}
+	path := specPathRegister
+	if resp.Request.Method != http.MethodPost {
+		path = specPathClient
+	}
+	failures, err := validator.Validate(schema.Response{
+		Method:     resp.Request.Method,
+		Path:       path,
+		Header:     resp.Header(),
+		Body:       bytes.NewReader(resp.Body()),
+		StatusCode: resp.StatusCode(),
+	})
+	if err != nil {
+		return err
+	}
+	messages := []string{}
+	for _, failure := range failures {
+		if !failure.Warning {
+			messages = append(messages, failure.Message)
+		}
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// sameClient - an error unless registration is of the client registered
+func sameClient(registered, registration *ClientRegistration) error {
+	if registration.ClientID != registered.ClientID {
+		return fmt.Errorf("client_id %q, expected %q", registration.ClientID, registered.ClientID)
+	}
+	return nil
+}
+
+// expectStatus - an error unless resp has one of statuses, err is the error of
+// sending the request of resp
+func expectStatus(resp *resty.Response, err error, statuses ...int) error {
+	if resp == nil || resp.RawResponse == nil {
+		if err == nil {
+			err = errors.New("request not sent")
+		}
+		return errors.Wrap(err, "no response")
+	}
+	for _, status := range statuses {
+		if resp.StatusCode() == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("status code %d, expected one of %v: %s", resp.StatusCode(), statuses, resp.String())
+}
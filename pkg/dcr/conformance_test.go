@@ -0,0 +1,241 @@
+package dcr
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/mockaspsp"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+)
+
+const (
+	certFile    = "../../certs/conformancesuite_cert.pem"
+	keyFile     = "../../certs/conformancesuite_key.pem"
+	redirectURI = "https://tpp.example.com/callback"
+)
+
+func loadCertificate(t *testing.T) authentication.Certificate {
+	cert, err := ioutil.ReadFile(certFile)
+	require.NoError(t, err)
+	key, err := ioutil.ReadFile(keyFile)
+	require.NoError(t, err)
+	certificate, err := authentication.NewCertificate(string(cert), string(key))
+	require.NoError(t, err)
+	return certificate
+}
+
+// ecCertificate - a self-signed certificate of a generated EC P-256 key
+func ecCertificate(t *testing.T) authentication.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{OrganizationalUnit: []string{"org"}, CommonName: "software"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	certificate, err := authentication.NewCertificate(
+		string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	)
+	require.NoError(t, err)
+	return certificate
+}
+
+// testSSA - a software statement of the software `software`, signed by a test directory
+func testSSA(t *testing.T) string {
+	ssa, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss":                    "OpenBanking Ltd",
+		"software_id":            "software",
+		"software_redirect_uris": []string{redirectURI, "https://tpp.example.com/other"},
+		"org_id":                 "org",
+	}).SignedString([]byte("directory"))
+	require.NoError(t, err)
+	return ssa
+}
+
+// newMockASPSP - the config registering the software with a mock ASPSP, and the client
+// presenting the test transport certificate
+func newMockASPSP(t *testing.T, authMethod string) (*httptest.Server, Config, *resty.Client) {
+	return newMockASPSPWithCertificate(t, authMethod, loadCertificate(t))
+}
+
+// newMockASPSPWithCertificate - newMockASPSP, the software and the ASPSP using certificate
+func newMockASPSPWithCertificate(t *testing.T, authMethod string, certificate authentication.Certificate) (*httptest.Server, Config, *resty.Client) {
+	mock, err := mockaspsp.NewServer(mockaspsp.Config{Certificate: certificate, ClientID: "tpp"}, test.NullLogger())
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(mock)
+	server.TLS = mock.TLSConfig()
+	server.StartTLS()
+
+	client := resty.New().SetTLSClientConfig(&tls.Config{
+		Certificates:       []tls.Certificate{certificate.TLSCert()},
+		InsecureSkipVerify: true,
+	})
+	config := Config{
+		SSA:                     testSSA(t),
+		Kid:                     "kid",
+		SigningCert:             certificate,
+		Issuer:                  server.URL,
+		RegistrationEndpoint:    server.URL + "/register",
+		TokenEndpoint:           server.URL + "/token",
+		TokenEndpointAuthMethod: authMethod,
+		RedirectURIs:            []string{redirectURI},
+		TransportSubjectDN:      "CN=software",
+	}
+	return server, config, client
+}
+
+func TestRun(t *testing.T) {
+	server, config, client := newMockASPSP(t, authentication.TlsClientAuth)
+	defer server.Close()
+
+	testResults, registration := Run(config, client, nil)
+	require.Len(t, testResults, len(dcrTests))
+	for _, result := range testResults {
+		assert.True(t, result.Pass, "%s %v", result.Id, result.Fail)
+		assert.Empty(t, result.Warnings, result.Id)
+		assert.Equal(t, APIName, result.API)
+		assert.Equal(t, config.RegistrationEndpoint, result.Endpoint)
+	}
+	assert.Nil(t, registration, "registration deleted")
+}
+
+func TestRun_ES256(t *testing.T) {
+	server, config, client := newMockASPSPWithCertificate(t, authentication.PrivateKeyJwt, ecCertificate(t))
+	defer server.Close()
+
+	request, err := NewRegistrationRequest(config, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "ES256", request.RequestObjectSigningAlg)
+	assert.Equal(t, "ES256", request.TokenEndpointAuthSigningAlg)
+
+	testResults, _ := Run(config, client, nil)
+	for _, result := range testResults {
+		assert.True(t, result.Pass, "%s %v", result.Id, result.Fail)
+	}
+}
+
+func TestRun_KeepRegistration(t *testing.T) {
+	server, config, client := newMockASPSP(t, authentication.ClientSecretBasic)
+	defer server.Close()
+	config.KeepRegistration = true
+
+	testResults, registration := Run(config, client, nil)
+	for _, result := range testResults {
+		assert.True(t, result.Pass, "%s %v", result.Id, result.Fail)
+	}
+	assert.Equal(t, []string{"not tested, the registration is kept"}, testResults[5].Warnings)
+	assert.Equal(t, []string{"not tested, the registration is not deleted"}, testResults[6].Warnings)
+	require.NotNil(t, registration)
+	assert.NotEmpty(t, registration.ClientID)
+	assert.NotEmpty(t, registration.ClientSecret)
+	assert.Equal(t, []string{redirectURI}, registration.RedirectURIs)
+
+	resp, err := client.R().
+		SetBasicAuth(registration.ClientID, registration.ClientSecret).
+		SetFormData(map[string]string{"grant_type": "client_credentials"}).
+		Post(config.TokenEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode(), "registered client authenticates")
+}
+
+func TestRun_RegistrationEndpointUnavailable(t *testing.T) {
+	server, config, client := newMockASPSP(t, authentication.TlsClientAuth)
+	server.Close()
+
+	testResults, registration := Run(config, client, nil)
+	assert.Nil(t, registration)
+	for _, result := range testResults[:5] {
+		assert.False(t, result.Pass, result.Id)
+	}
+	assert.Equal(t, []string{"no client registered"}, testResults[3].Fail)
+}
+
+func TestManagementToken_ClientCredentials(t *testing.T) {
+	server, config, client := newMockASPSP(t, authentication.PrivateKeyJwt)
+	defer server.Close()
+	env := &testEnv{config: config, client: client}
+	_, err := dcrTests[0].run(env)
+	require.NoError(t, err)
+
+	// without a registration access token, the client registered gets an access token
+	env.registration.RegistrationAccessToken = ""
+	token, err := env.managementToken()
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	_, err = dcrTests[3].run(env)
+	assert.NoError(t, err)
+}
+
+func TestDecodeRegistration(t *testing.T) {
+	registration := map[string]interface{}{
+		"client_id":                    "client",
+		"redirect_uris":                []string{redirectURI},
+		"token_endpoint_auth_method":   authentication.TlsClientAuth,
+		"grant_types":                  []string{"authorization_code", "client_credentials"},
+		"response_types":               []string{"code id_token"},
+		"software_statement":           "ssa",
+		"application_type":             "web",
+		"id_token_signed_response_alg": "PS256",
+		"request_object_signing_alg":   "PS256",
+		"tls_client_auth_subject_dn":   "CN=software",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		for name, value := range registration {
+			body[name] = value
+		}
+		switch r.URL.Query().Get("invalid") {
+		case "grant_types":
+			body["grant_types"] = []string{"implicit"}
+		case "client_id":
+			delete(body, "client_id")
+		case "alg":
+			body["request_object_signing_alg"] = "RS256"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}))
+	defer server.Close()
+	read := func(invalid string) (*ClientRegistration, error) {
+		resp, err := resty.New().R().SetQueryParam("invalid", invalid).Get(server.URL + "/connect/register/client")
+		require.NoError(t, err)
+		return decodeRegistration(resp)
+	}
+
+	decoded, err := read("")
+	require.NoError(t, err)
+	assert.Equal(t, "client", decoded.ClientID)
+	assert.Equal(t, "CN=software", decoded.TLSClientAuthSubjectDN)
+
+	_, err = read("grant_types")
+	assert.EqualError(t, err, "invalid client registration: grant_types in body should be one of [client_credentials authorization_code refresh_token]")
+	_, err = read("client_id")
+	assert.EqualError(t, err, "invalid client registration: .client_id in body is required")
+	_, err = read("alg")
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+// Package dcr registers the suite's client with an ASPSP by Open Banking dynamic client registration:
+// registration requests are built from a software statement assertion (SSA), signed with the signing
+// key of the software, and posted to the registration endpoint of the ASPSP.
+// https://openbankinguk.github.io/dcr-docs-pub/v3.2/dynamic-client-registration.html
+package dcr
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+const (
+	// requestLifetime - validity of a signed registration request
+	requestLifetime = 5 * time.Minute
+	// defaultSigningAlg - the alg of registration requests, request objects and id tokens when
+	// the config has neither an alg nor a signing key
+	defaultSigningAlg = "PS256"
+	defaultScope      = "openid accounts payments fundsconfirmations"
+	applicationWeb    = "web"
+)
+
+// SoftwareStatement - the claims of a software statement assertion registrations rely on
+type SoftwareStatement struct {
+	ID           string   `json:"software_id"`
+	ClientID     string   `json:"software_client_id,omitempty"`
+	RedirectURIs []string `json:"software_redirect_uris"`
+	Roles        []string `json:"software_roles,omitempty"`
+	OrgID        string   `json:"org_id,omitempty"`
+}
+
+// ParseSoftwareStatement - the claims of ssa, which isn't verified: the ASPSP verifies it
+// with the keys of the directory that issued it
+func ParseSoftwareStatement(ssa string) (SoftwareStatement, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(ssa, claims); err != nil {
+		return SoftwareStatement{}, errors.Wrap(err, "dcr.ParseSoftwareStatement: invalid software statement")
+	}
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return SoftwareStatement{}, errors.Wrap(err, "dcr.ParseSoftwareStatement")
+	}
+	statement := SoftwareStatement{}
+	if err := json.Unmarshal(data, &statement); err != nil {
+		return SoftwareStatement{}, errors.Wrap(err, "dcr.ParseSoftwareStatement: invalid software statement claims")
+	}
+	if statement.ID == "" {
+		return SoftwareStatement{}, errors.New("dcr.ParseSoftwareStatement: software_id missing")
+	}
+	return statement, nil
+}
+
+// Config - the software registered and the ASPSP it registers with
+type Config struct {
+	SSA                     string                     // Software statement assertion issued by the directory
+	Kid                     string                     // Key ID of the signing key in the directory
+	SigningCert             authentication.Certificate // Signing key of the software
+	SigningAlg              string                     // e.g.: PS256, defaults to the alg of the signing key
+	Issuer                  string                     // Issuer of the ASPSP, the audience of registration requests
+	RegistrationEndpoint    string
+	TokenEndpoint           string
+	TokenEndpointAuthMethod string
+	RedirectURIs            []string // Redirect URIs registered, those of the SSA when empty
+	TransportSubjectDN      string   // Subject DN of the transport certificate, registered for tls_client_auth
+	KeepRegistration        bool     // The client registered is used afterwards, so isn't deleted
+}
+
+// signingAlg - the alg of the registration requests of c: ES256 for EC signing keys, PS256 for RSA
+// signing keys when not set
+func (c Config) signingAlg() string {
+	switch {
+	case c.SigningAlg != "":
+		return c.SigningAlg
+	case c.SigningCert != nil:
+		return authentication.SigningAlgForKey(c.SigningCert.PublicKey()).Alg()
+	}
+	return defaultSigningAlg
+}
+
+// RegistrationRequest - the claims of a registration request
+type RegistrationRequest struct {
+	jwt.StandardClaims
+	RedirectURIs                []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod     string   `json:"token_endpoint_auth_method"`
+	GrantTypes                  []string `json:"grant_types"`
+	ResponseTypes               []string `json:"response_types"`
+	SoftwareID                  string   `json:"software_id"`
+	Scope                       string   `json:"scope"`
+	SoftwareStatement           string   `json:"software_statement"`
+	ApplicationType             string   `json:"application_type"`
+	IDTokenSignedResponseAlg    string   `json:"id_token_signed_response_alg"`
+	RequestObjectSigningAlg     string   `json:"request_object_signing_alg"`
+	TokenEndpointAuthSigningAlg string   `json:"token_endpoint_auth_signing_alg,omitempty"`
+	TLSClientAuthSubjectDN      string   `json:"tls_client_auth_subject_dn,omitempty"`
+}
+
+// NewRegistrationRequest - the registration request of the software of config, valid from now
+func NewRegistrationRequest(config Config, now time.Time) (RegistrationRequest, error) {
+	statement, err := ParseSoftwareStatement(config.SSA)
+	if err != nil {
+		return RegistrationRequest{}, err
+	}
+	redirectURIs := config.RedirectURIs
+	if len(redirectURIs) == 0 {
+		redirectURIs = statement.RedirectURIs
+	}
+	request := RegistrationRequest{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    statement.ID,
+			Audience:  config.Issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(requestLifetime).Unix(),
+			Id:        uuid.New().String(),
+		},
+		RedirectURIs:             redirectURIs,
+		TokenEndpointAuthMethod:  config.TokenEndpointAuthMethod,
+		GrantTypes:               []string{"authorization_code", "client_credentials", "refresh_token"},
+		ResponseTypes:            []string{"code id_token"},
+		SoftwareID:               statement.ID,
+		Scope:                    defaultScope,
+		SoftwareStatement:        config.SSA,
+		ApplicationType:          applicationWeb,
+		IDTokenSignedResponseAlg: config.signingAlg(),
+		RequestObjectSigningAlg:  config.signingAlg(),
+	}
+	switch config.TokenEndpointAuthMethod {
+	case authentication.PrivateKeyJwt:
+		request.TokenEndpointAuthSigningAlg = config.signingAlg()
+	case authentication.TlsClientAuth:
+		request.TLSClientAuthSubjectDN = config.TransportSubjectDN
+	}
+	return request, nil
+}
+
+// Sign - request signed with the signing key of config, identified by its kid
+func (r RegistrationRequest) Sign(config Config) (string, error) {
+	if config.SigningCert == nil {
+		return "", errors.New("dcr.Sign: no signing key")
+	}
+	alg, err := authentication.GetSigningAlg(config.signingAlg())
+	if err != nil {
+		return "", errors.Wrap(err, "dcr.Sign")
+	}
+	token := jwt.NewWithClaims(alg, r)
+	token.Header["kid"] = config.Kid
+	signed, err := token.SignedString(config.SigningCert.PrivateKey())
+	return signed, errors.Wrap(err, "dcr.Sign")
+}
+
+// ClientRegistration - the client registered, as per the OB DCR response
+type ClientRegistration struct {
+	ClientID                    string   `json:"client_id"`
+	ClientSecret                string   `json:"client_secret,omitempty"`
+	ClientIDIssuedAt            int64    `json:"client_id_issued_at,omitempty"`
+	ClientSecretExpiresAt       int64    `json:"client_secret_expires_at,omitempty"`
+	RedirectURIs                []string `json:"redirect_uris"`
+	TokenEndpointAuthMethod     string   `json:"token_endpoint_auth_method"`
+	GrantTypes                  []string `json:"grant_types"`
+	ResponseTypes               []string `json:"response_types,omitempty"`
+	SoftwareID                  string   `json:"software_id,omitempty"`
+	Scope                       string   `json:"scope,omitempty"`
+	SoftwareStatement           string   `json:"software_statement"`
+	ApplicationType             string   `json:"application_type"`
+	IDTokenSignedResponseAlg    string   `json:"id_token_signed_response_alg"`
+	RequestObjectSigningAlg     string   `json:"request_object_signing_alg"`
+	TokenEndpointAuthSigningAlg string   `json:"token_endpoint_auth_signing_alg,omitempty"`
+	TLSClientAuthSubjectDN      string   `json:"tls_client_auth_subject_dn,omitempty"`
+	RegistrationAccessToken     string   `json:"registration_access_token,omitempty"`
+	RegistrationClientURI       string   `json:"registration_client_uri,omitempty"`
+}
@@ -0,0 +1,66 @@
+package dcr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+func TestParseSoftwareStatement(t *testing.T) {
+	statement, err := ParseSoftwareStatement(testSSA(t))
+	require.NoError(t, err)
+	assert.Equal(t, "software", statement.ID)
+	assert.Equal(t, "org", statement.OrgID)
+	assert.Equal(t, []string{redirectURI, "https://tpp.example.com/other"}, statement.RedirectURIs)
+
+	_, err = ParseSoftwareStatement("not a jwt")
+	assert.Error(t, err)
+
+	noSoftwareID, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"org_id": "org"}).SignedString([]byte("directory"))
+	require.NoError(t, err)
+	_, err = ParseSoftwareStatement(noSoftwareID)
+	assert.EqualError(t, err, "dcr.ParseSoftwareStatement: software_id missing")
+}
+
+func TestRegistrationRequest_Sign(t *testing.T) {
+	certificate := loadCertificate(t)
+	config := Config{
+		SSA:                     testSSA(t),
+		Kid:                     "kid",
+		SigningCert:             certificate,
+		Issuer:                  "https://aspsp.example.com",
+		TokenEndpointAuthMethod: authentication.PrivateKeyJwt,
+	}
+	now := time.Unix(1600000000, 0)
+	request, err := NewRegistrationRequest(config, now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{redirectURI, "https://tpp.example.com/other"}, request.RedirectURIs, "redirect URIs of the SSA")
+	assert.Equal(t, "PS256", request.TokenEndpointAuthSigningAlg)
+	assert.Empty(t, request.TLSClientAuthSubjectDN)
+
+	signed, err := request.Sign(config)
+	require.NoError(t, err)
+	claims := &RegistrationRequest{}
+	token, err := jwt.ParseWithClaims(signed, claims, func(*jwt.Token) (interface{}, error) {
+		return certificate.PublicKey(), nil
+	})
+	require.NotNil(t, token)
+	// expired as it was issued in the past, but verified
+	require.IsType(t, &jwt.ValidationError{}, err)
+	assert.Equal(t, jwt.ValidationErrorExpired, err.(*jwt.ValidationError).Errors)
+	assert.Equal(t, "kid", token.Header["kid"])
+	assert.Equal(t, "PS256", token.Header["alg"])
+	assert.Equal(t, "software", claims.Issuer)
+	assert.Equal(t, "https://aspsp.example.com", claims.Audience)
+	assert.Equal(t, int64(1600000300), claims.ExpiresAt)
+	assert.Equal(t, config.SSA, claims.SoftwareStatement)
+
+	config.SigningCert = nil
+	_, err = request.Sign(config)
+	assert.EqualError(t, err, "dcr.Sign: no signing key")
+}
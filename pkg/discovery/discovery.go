@@ -20,6 +20,7 @@ type ModelDiscovery struct {
 	Description      string               `json:"description" validate:"required"`
 	DiscoveryVersion string               `json:"discoveryVersion" validate:"required"`
 	TokenAcquisition string               `json:"tokenAcquisition" validate:"required"`
+	HeadlessScript   string               `json:"headlessConsentScript,omitempty" validate:"-"` // Script of the "headless" token acquisition, e.g.: "ozone.json"
 	DiscoveryItems   []ModelDiscoveryItem `json:"discoveryItems" validate:"required,dive"`
 	CustomTests      []CustomTest         `json:"customTests" validate:"-"`
 }
@@ -30,6 +31,7 @@ type ModelDiscoveryItem struct {
 	OpenidConfigurationURI string                `json:"openidConfigurationUri,omitempty" validate:"required,url"`
 	ResourceBaseURI        string                `json:"resourceBaseUri,omitempty" validate:"required,url"`
 	ResourceIds            ResourceIds           `json:"resourceIds,omitempty" validate:"-"`
+	PushedAuthRequests     bool                  `json:"pushedAuthorizationRequests,omitempty" validate:"-"` // Push the authorization requests of the consents of the item
 	Endpoints              []ModelEndpoint       `json:"endpoints,omitempty" validate:"required,gt=0,dive"`
 }
 
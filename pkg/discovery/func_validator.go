@@ -8,6 +8,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/headless"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 
 	validation "gopkg.in/go-playground/validator.v9"
@@ -72,6 +73,7 @@ func Validate(checker model.ConditionalityChecker, discovery *Model) (bool, []Va
 	}
 	failures = appendOtherValidationErrors(failures, checker, discovery, hasValidDiscoveryVersion)
 	failures = appendOtherValidationErrors(failures, checker, discovery, hasValidTokenAcquisitionMethod)
+	failures = appendOtherValidationErrors(failures, checker, discovery, hasValidHeadlessConsentScript)
 	failures = appendOtherValidationErrors(failures, checker, discovery, hasValidAPISpecifications)
 	failures = appendOtherValidationErrors(failures, checker, discovery, HasValidEndpoints)
 	failures = appendOtherValidationErrors(failures, checker, discovery, HasMandatoryEndpoints)
@@ -146,6 +148,24 @@ func hasValidTokenAcquisitionMethod(_ model.ConditionalityChecker, discovery *Mo
 	return false, failures
 }
 
+// checker passed to match function definition expectation in appendOtherValidationErrors function.
+func hasValidHeadlessConsentScript(_ model.ConditionalityChecker, discovery *Model) (bool, []ValidationFailure) {
+	var failures []ValidationFailure
+	script := discovery.DiscoveryModel.HeadlessScript
+	if script == "" {
+		return true, failures
+	}
+	if _, err := headless.Load(script); err != nil {
+		failure := ValidationFailure{
+			Key:   "DiscoveryModel.HeadlessScript",
+			Error: err.Error(),
+		}
+		failures = append(failures, failure)
+		return false, failures
+	}
+	return true, failures
+}
+
 // checker passed to match function definition expectation in appendOtherValidationErrors function.
 func hasValidAPISpecifications(_ model.ConditionalityChecker, discoveryConfig *Model) (bool, []ValidationFailure) {
 	var failures []ValidationFailure
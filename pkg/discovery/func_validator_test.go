@@ -7,6 +7,7 @@ import (
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // conditionalityCheckerMock - implements model.ConditionalityChecker interface for tests
@@ -247,6 +248,22 @@ func TestValidate(t *testing.T) {
 			}})
 	})
 
+	t.Run("when headlessConsentScript can't be loaded returns failure", func(t *testing.T) {
+		discovery := testUnmarshalDiscoveryJSON(t, discoveryStub("tokenAcquisition", "headless"))
+		discovery.DiscoveryModel.HeadlessScript = "ozone.json"
+		result, failures, err := Validate(conditionalityCheckerMock{isPresent: true}, discovery)
+		require.NoError(t, err)
+		assert.True(t, result, "bundled script")
+		assert.Empty(t, failures)
+
+		discovery.DiscoveryModel.HeadlessScript = "missing.json"
+		result, failures, err = Validate(conditionalityCheckerMock{isPresent: true}, discovery)
+		require.NoError(t, err)
+		assert.False(t, result)
+		require.Len(t, failures, 1)
+		assert.Equal(t, "DiscoveryModel.HeadlessScript", failures[0].Key)
+	})
+
 	t.Run("when discoveryItems missing returns failure", func(t *testing.T) {
 		testValidateFailures(t, conditionalityCheckerMock{}, &invalidTest{
 			discoveryJSON: discoveryStub("discoveryItems", ""),
@@ -4,11 +4,15 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"encoding/json"
+	"encoding/pem"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -20,9 +24,12 @@ import (
 	prefixed "github.com/x-cray/logrus-prefixed-formatter"
 	"gopkg.in/resty.v1"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/client"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/mockaspsp"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/server"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/version"
@@ -62,7 +69,12 @@ func init() {
 	viper.AutomaticEnv()
 }
 
-func disableTestRun(t *testing.T) {
+// TestRun - runs the headless v3.1 template against the mock ASPSP
+func TestRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("end to end run")
+	}
+
 	debug := viper.GetBool("LOG_HTTP_TRACE")
 	logLevel, err := logrus.ParseLevel(viper.GetString("LOG_LEVEL"))
 	if err != nil {
@@ -75,49 +87,82 @@ func disableTestRun(t *testing.T) {
 	logger.SetLevel(logLevel)
 	resty.SetDebug(debug)
 
+	cert, err := ioutil.ReadFile(certFile)
+	require.NoError(t, err)
+	key, err := ioutil.ReadFile(keyFile)
+	require.NoError(t, err)
+	certificate, err := authentication.NewCertificate(string(cert), string(key))
+	require.NoError(t, err)
+	seed, err := mockaspsp.LoadSeed(mockaspsp.DefaultSeedFilename)
+	require.NoError(t, err)
+
+	mock, err := mockaspsp.NewServer(mockaspsp.Config{
+		Version:     "v3.1.0",
+		Certificate: certificate,
+		Seed:        seed,
+	}, logger.WithField("app", "mock-aspsp"))
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "e2e")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the mock is served with the certificate of httptest, trusted by the suite as the
+	// only system root, the suite certificate still signs responses
+	aspsp := httptest.NewUnstartedServer(mock)
+	aspsp.TLS = mock.TLSConfig()
+	aspsp.TLS.Certificates = nil
+	aspsp.StartTLS()
+	defer aspsp.Close()
+	rootFile := filepath.Join(dir, "root.pem")
+	root := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: aspsp.Certificate().Raw})
+	require.NoError(t, ioutil.WriteFile(rootFile, root, 0600))
+	require.NoError(t, os.Setenv("SSL_CERT_FILE", rootFile))
+	defer os.Unsetenv("SSL_CERT_FILE")
+
+	templateFile := filepath.Join(dir, "discovery.json")
+	configFile := filepath.Join(dir, "config.json")
+	writeMockDiscovery(t, templateFile, aspsp.URL)
+	writeMockConfig(t, configFile, aspsp.URL, string(cert), string(key))
+
 	logger := logger.WithFields(logrus.Fields{"test": "TestRun"})
 	ver := version.NewBitBucket(version.BitBucketAPIRepository)
 	validatorEngine := discovery.NewFuncValidator(model.NewConditionalityChecker())
-	testGenerator := generation.NewGenerator()
-	tlsValidator := discovery.NewStdTLSValidator(tls.VersionTLS11)
-	journey := server.NewJourney(logger, testGenerator, validatorEngine, tlsValidator, false)
-
-	echoServer := server.NewServer(journey, logger, ver)
-
+	sessions := server.NewSessions(func(string) server.Journey {
+		journey := server.NewJourney(logger, generation.NewGenerator(), validatorEngine, discovery.NewStdTLSValidator(tls.VersionTLS11), false)
+		// the headless consent code is read from the redirect to the callback, which isn't served
+		runOptions := executors.NewRunOptions()
+		runOptions.RedirectPolicy = resty.NoRedirectPolicy()
+		journey.SetRunOptions(runOptions)
+		return journey
+	}, server.DefaultSessionIdleTimeout)
+	echoServer := server.NewSessionServer(sessions, logger, ver)
 	go func() {
-		require.EqualError(t, echoServer.StartTLS(":0", certFile, keyFile), "http: Server closed")
+		_ = echoServer.StartTLS("127.0.0.1:0", certFile, keyFile)
 	}()
-	time.Sleep(100 * time.Millisecond)
-
 	defer func() {
-		errEcho := echoServer.Shutdown(context.TODO())
-		require.NoError(t, errEcho)
+		require.NoError(t, echoServer.Shutdown(context.TODO()))
 	}()
-
+	for echoServer.TLSListener == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
 	tcpAddr, ok := echoServer.TLSListener.Addr().(*net.TCPAddr)
 	require.True(t, ok)
 	serverHost := fmt.Sprintf("localhost:%d", tcpAddr.Port)
 	waitForServerReady(t, serverHost)
 
 	insecureConn, err := client.NewConnection()
-	if err == client.ErrInsecure {
-		logger.Println("server's certificate chain and host name not verified")
-	} else {
-		require.NoError(t, err)
-	}
+	require.Equal(t, client.ErrInsecure, err)
 	service := client.NewService("https://"+serverHost, "wss://"+serverHost, insecureConn)
 
-	goldenFile := filepath.Join("testdata", "ozone-results.golden")
-
-	results, err := service.Run(
-		"../discovery/templates/ob-v3.1-ozone-headless.json",
-		"../../config/config-ozone-run_test.json",
-		"../../config/report.json")
+	results, err := service.Run(templateFile, configFile, "../../config/report.json")
 	require.NoError(t, err)
+	require.NotEmpty(t, results)
 
 	w := bytes.NewBufferString("")
 	client.ResultWriter(w, results)
 
+	goldenFile := filepath.Join("testdata", "mock-aspsp-results.golden")
 	if *update {
 		t.Log("update golden file")
 		require.NoError(t, ioutil.WriteFile(goldenFile, w.Bytes(), 0644), "failed to update golden file")
@@ -149,3 +194,58 @@ func waitForServerReady(t *testing.T, address string) {
 		}
 	}
 }
+
+// writeMockDiscovery - the ozone headless template with the ozone URLs replaced by the mock
+func writeMockDiscovery(t *testing.T, filename, baseURL string) {
+	content, err := ioutil.ReadFile("../discovery/templates/ob-v3.1-ozone-headless.json")
+	require.NoError(t, err)
+	template := strings.NewReplacer(
+		"https://ob19-auth1-ui.o3bank.co.uk", baseURL,
+		"https://ob19-rs1.o3bank.co.uk:4501", baseURL,
+	).Replace(string(content))
+	require.NoError(t, ioutil.WriteFile(filename, []byte(template), 0600))
+}
+
+// writeMockConfig - a global configuration of the suite for the mock
+func writeMockConfig(t *testing.T, filename, baseURL, cert, key string) {
+	tomorrow := time.Now().Add(24 * time.Hour).Format("2006-01-02T15:04:05-07:00")
+	account := map[string]string{
+		"scheme_name":    "UK.OBIE.SortCodeAccountNumber",
+		"identification": "20202010981789",
+		"name":           "Mock",
+	}
+	config := map[string]interface{}{
+		"signing_private":            key,
+		"signing_public":             cert,
+		"transport_private":          key,
+		"transport_public":           cert,
+		"client_id":                  "e2e",
+		"client_secret":              "e2e",
+		"token_endpoint":             baseURL + "/token",
+		"response_type":              "code id_token",
+		"token_endpoint_auth_method": "client_secret_basic",
+		"authorization_endpoint":     baseURL + "/authorize",
+		"resource_base_url":          baseURL,
+		"x_fapi_financial_id":        "mock-aspsp",
+		"issuer":                     baseURL,
+		"redirect_url":               "https://127.0.0.1:8443/conformancesuite/callback",
+		"resource_ids": map[string]interface{}{
+			"account_ids":   []map[string]string{{"account_id": "700004000000000000000001"}},
+			"statement_ids": []map[string]string{{"statement_id": "140000000000000000000001"}},
+		},
+		"creditor_account":               account,
+		"international_creditor_account": account,
+		"transaction_from_date":          "2016-01-01T10:40:00+02:00",
+		"transaction_to_date":            "2025-12-31T10:40:00+02:00",
+		"request_object_signing_alg":     "PS256",
+		"instructed_amount":              map[string]string{"currency": "GBP", "value": "1.00"},
+		"payment_frequency":              "EvryDay",
+		"first_payment_date_time":        tomorrow,
+		"requested_execution_date_time":  tomorrow,
+		"currency_of_transfer":           "USD",
+		"cbpii_debtor_account":           account,
+	}
+	content, err := json.Marshal(config)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filename, content, 0600))
+}
@@ -15,19 +15,22 @@ func getCbpiiConsents(
 	requiredTokens []manifest.RequiredTokens,
 	ctx *model.Context,
 ) (TokenConsentIDs, error) {
-	executor := &Executor{}
+	executor := NewExecutor()
 	err := executor.SetCertificates(definition.SigningCert, definition.TransportCert)
 	if err != nil {
 		logrus.Error(fmt.Sprintf("error running cbpii consent acquisition: %s", err))
 		return nil, err
 	}
+	executor.SetPropertyCollector(definition.Collector)
+	executor.SetRecorder(definition.Recorder)
+	executor.SetThrottle(definition.RateLimiter, definition.ThrottleRetries)
 
 	logrus.Debugf("we have %d cbpii consent required tokens", len(requiredTokens))
 	for _, rt := range requiredTokens {
 		logrus.Tracef("%#v", rt)
 	}
 
-	requiredTokens, err = runCbpiiConsents(requiredTokens, ctx, executor)
+	requiredTokens, err = runCbpiiConsents(definition, requiredTokens, ctx, executor)
 	if err != nil {
 		logrus.Errorf("getCbpiiConsents error: %s", err)
 	}
@@ -42,7 +45,7 @@ func getCbpiiConsents(
 	return consentItems, err
 }
 
-func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor *Executor) ([]manifest.RequiredTokens, error) {
+func runCbpiiConsents(definition RunDefinition, rt []manifest.RequiredTokens, ctx *model.Context, executor TestCaseExecutor) ([]manifest.RequiredTokens, error) {
 	localCtx := model.Context{}
 	localCtx.PutContext(ctx)
 	localCtx.PutString("scope", "fundsconfirmations")
@@ -56,42 +59,14 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 	// Check for MTLS vs client basic authentication
 	authMethod, err := ctx.GetString("token_endpoint_auth_method")
 	if err != nil {
-		authMethod = "client_secret_basic"
+		authMethod = authentication.ClientSecretBasic
 	}
-	switch authMethod {
-	case authentication.ClientSecretBasic:
-		tc.Input.SetHeader("authorization", "Basic $basic_authentication")
-	case authentication.PrivateKeyJwt:
-		clientID, err := ctx.GetString("client_id")
-		if err != nil {
-			return nil, errors.Wrap(err, "cannot find client_id for private_key_jwt form field")
-		}
-		tokenEndpoint, err := ctx.GetString("token_endpoint")
-		if err != nil {
-			return nil, errors.Wrap(err, "cannot find token_endpoint for private_key_jwt form field")
-		}
-		if tc.Input.Claims == nil {
-			tc.Input.Claims = map[string]string{}
-		}
-		tc.Input.Claims["iss"] = clientID
-		tc.Input.Claims["sub"] = clientID
-		tc.Input.Claims["aud"] = tokenEndpoint
-		clientAssertion, err := tc.Input.GenerateRequestToken(ctx)
-		if err != nil {
-			return nil, errors.Wrap(err, "cannot generate request token for private_key_jwt form field")
-		}
-		tc.Input.SetFormField(authentication.ClientAssertionType, authentication.ClientAssertionTypeValue)
-		tc.Input.SetFormField(authentication.ClientAssertion, clientAssertion)
-	case authentication.TlsClientAuth:
-		clientid, err := ctx.GetString("client_id")
-		if err != nil {
-			logrus.Warn("cannot locate client_id for tls_client_auth form field")
-		}
-		tc.Input.SetFormField("client_id", clientid)
+	if err := setClientAuthentication(&tc, ctx, authMethod); err != nil {
+		return nil, errors.Wrap(err, "cbpii PSU consent client credentials grant")
 	}
 
 	tc.ProcessReplacementFields(&localCtx, true)
-	err = executePaymentTest(&tc, &localCtx, executor)
+	err = executePaymentTest(&tc, &localCtx, definition.HTTPClient, executor)
 	if err != nil {
 		return nil, errors.Wrap(err, "Cbpii PSU consent execute clientCredential grant testcase failed")
 	}
@@ -115,7 +90,7 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 		test.InjectBearerToken(ccgBearerToken)
 		test.Input.Headers["Content-Type"] = "application/json"
 
-		err = executePaymentTest(&test, &localCtx, executor)
+		err = executePaymentTest(&test, &localCtx, definition.HTTPClient, executor)
 		if err != nil {
 			return nil, errors.Wrap(err, "Cbpii PSU consent test case failed")
 		}
@@ -142,7 +117,7 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 		}
 
 		localCtx.DumpContext("before exchange", "token_name", "consent_id")
-		err = executePaymentTest(&exchange, &localCtx, executor)
+		err = executePaymentTest(&exchange, &localCtx, definition.HTTPClient, executor)
 		if err != nil {
 			return nil, errors.Wrap(err, "Cbpii PSU consent exchange code failed")
 		}
@@ -150,6 +125,10 @@ func runCbpiiConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor
 		if err != nil {
 			return nil, errors.Wrap(err, "Cbpii PSU exchange test case failed - cannot find `consent_url` in context")
 		}
+		v.ConsentURL, err = definition.PushedAuthorization.Push(ctx, "cbpii", v.ConsentURL, definition.HTTPClient, definition.Recorder)
+		if err != nil {
+			return nil, errors.Wrap(err, "Cbpii PSU consent push authorization request failed")
+		}
 		localCtx.Delete("consent_url")
 		ctx.PutContext(&localCtx)
 		rt[k] = v
@@ -0,0 +1,74 @@
+package executors
+
+import (
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// setClientAuthentication - sets the client authentication of authMethod on tc, a request to
+// the token endpoint, replacing the `client_secret_basic` authorization header tc may have
+func setClientAuthentication(tc *model.TestCase, ctx *model.Context, authMethod string) error {
+	if authMethod == authentication.ClientSecretBasic {
+		tc.Input.SetHeader("authorization", "Basic $basic_authentication")
+		return nil
+	}
+	delete(tc.Input.Headers, "authorization")
+
+	clientID, err := ctx.GetString("client_id")
+	if err != nil {
+		return errors.Wrapf(err, "cannot find client_id for %s", authMethod)
+	}
+	switch authMethod {
+	case authentication.TlsClientAuth:
+		tc.Input.SetFormField("client_id", clientID)
+
+	case authentication.ClientSecretPost:
+		clientSecret, err := ctx.GetString("client_secret")
+		if err != nil {
+			return errors.Wrap(err, "cannot find client_secret for client_secret_post")
+		}
+		tc.Input.SetFormField("client_id", clientID)
+		tc.Input.SetFormField("client_secret", clientSecret)
+
+	case authentication.PrivateKeyJwt, authentication.ClientSecretJwt:
+		tokenEndpoint, err := ctx.GetString("token_endpoint")
+		if err != nil {
+			return errors.Wrapf(err, "cannot find token_endpoint for %s", authMethod)
+		}
+		clientAssertion, err := clientAssertion(tc, ctx, authMethod, clientID, tokenEndpoint)
+		if err != nil {
+			return err
+		}
+		tc.Input.SetFormField(authentication.ClientAssertionType, authentication.ClientAssertionTypeValue)
+		tc.Input.SetFormField(authentication.ClientAssertion, clientAssertion)
+
+	default:
+		return errors.Errorf("token_endpoint_auth_method %q unsupported", authMethod)
+	}
+	return nil
+}
+
+// clientAssertion - the `client_assertion` of the private_key_jwt or client_secret_jwt
+// authentication of tc
+func clientAssertion(tc *model.TestCase, ctx *model.Context, authMethod, clientID, tokenEndpoint string) (string, error) {
+	if authMethod == authentication.ClientSecretJwt {
+		clientSecret, err := ctx.GetString("client_secret")
+		if err != nil {
+			return "", errors.Wrap(err, "cannot find client_secret for client_secret_jwt")
+		}
+		return authentication.ClientSecretJWT(clientID, clientSecret, tokenEndpoint)
+	}
+
+	if tc.Input.Claims == nil {
+		tc.Input.Claims = map[string]string{}
+	}
+	// https://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication
+	// iss and sub MUST contain the client_id, aud SHOULD be the URL of the token endpoint
+	tc.Input.Claims["iss"] = clientID
+	tc.Input.Claims["sub"] = clientID
+	tc.Input.Claims["aud"] = tokenEndpoint
+	assertion, err := tc.Input.GenerateRequestToken(ctx)
+	return assertion, errors.Wrap(err, "cannot generate request token for private_key_jwt")
+}
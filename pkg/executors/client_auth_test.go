@@ -0,0 +1,103 @@
+package executors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+func clientAuthContext(authMethod string) *model.Context {
+	return &model.Context{
+		"client_id":                  "client",
+		"client_secret":              "secret",
+		"token_endpoint":             "https://as.example.com/token",
+		"token_endpoint_auth_method": authMethod,
+		"requestObjectSigningAlg":    "PS256",
+		"signingPrivate":             signingPrivate,
+		"signingPublic":              signingPublic,
+	}
+}
+
+func tokenTestCase() model.TestCase {
+	tc := model.TestCase{ID: "#ccg0001"}
+	tc.Input.SetHeader("authorization", "Basic $basic_authentication")
+	tc.Input.SetFormField(authentication.GrantType, "client_credentials")
+	return tc
+}
+
+func TestSetClientAuthentication(t *testing.T) {
+	tc := tokenTestCase()
+	require.NoError(t, setClientAuthentication(&tc, clientAuthContext(authentication.ClientSecretBasic), authentication.ClientSecretBasic))
+	assert.Equal(t, "Basic $basic_authentication", tc.Input.Headers["authorization"])
+
+	tc = tokenTestCase()
+	require.NoError(t, setClientAuthentication(&tc, clientAuthContext(authentication.ClientSecretPost), authentication.ClientSecretPost))
+	assert.NotContains(t, tc.Input.Headers, "authorization")
+	assert.Equal(t, map[string]string{
+		authentication.GrantType: "client_credentials",
+		"client_id":              "client",
+		"client_secret":          "secret",
+	}, tc.Input.FormData)
+
+	tc = tokenTestCase()
+	require.NoError(t, setClientAuthentication(&tc, clientAuthContext(authentication.TlsClientAuth), authentication.TlsClientAuth))
+	assert.NotContains(t, tc.Input.Headers, "authorization")
+	assert.Equal(t, "client", tc.Input.FormData["client_id"])
+	assert.NotContains(t, tc.Input.FormData, "client_secret")
+
+	tc = tokenTestCase()
+	require.NoError(t, setClientAuthentication(&tc, clientAuthContext(authentication.ClientSecretJwt), authentication.ClientSecretJwt))
+	assert.Equal(t, authentication.ClientAssertionTypeValue, tc.Input.FormData[authentication.ClientAssertionType])
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tc.Input.FormData[authentication.ClientAssertion], claims, func(*jwt.Token) (interface{}, error) {
+		return []byte("secret"), nil
+	})
+	require.NoError(t, err, "signed with the client secret")
+	assert.Equal(t, "https://as.example.com/token", claims["aud"])
+
+	tc = tokenTestCase()
+	err = setClientAuthentication(&tc, clientAuthContext("client_secret_foo"), "client_secret_foo")
+	assert.EqualError(t, err, `token_endpoint_auth_method "client_secret_foo" unsupported`)
+}
+
+func TestRequestToken_ClientSecretMethods(t *testing.T) {
+	for _, authMethod := range []string{authentication.ClientSecretPost, authentication.ClientSecretJwt} {
+		t.Run(authMethod, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				assert.Empty(t, r.Header.Get("Authorization"), "no client_secret_basic")
+				switch authMethod {
+				case authentication.ClientSecretPost:
+					assert.Equal(t, "client", r.PostForm.Get("client_id"))
+					assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+				case authentication.ClientSecretJwt:
+					assert.Equal(t, authentication.ClientAssertionTypeValue, r.PostForm.Get(authentication.ClientAssertionType))
+					_, err := jwt.Parse(r.PostForm.Get(authentication.ClientAssertion), func(*jwt.Token) (interface{}, error) {
+						return []byte("secret"), nil
+					})
+					assert.NoError(t, err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"access_token": "refreshed", "expires_in": 300}`))
+			}))
+			defer server.Close()
+
+			ctx := tokenContext(server)
+			ctx.PutString("client_secret", "secret")
+			ctx.PutString("token_endpoint_auth_method", authMethod)
+
+			token, err := refreshToken("refresh", ctx, resty.New(), nil, logrus.NewEntry(logrus.New()))
+			require.NoError(t, err)
+			assert.Equal(t, "refreshed", token.AccessToken)
+		})
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"regexp"
 
 	"github.com/pkg/errors"
-	"github.com/tidwall/gjson"
 	resty "gopkg.in/resty.v1"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/headless"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 	"github.com/sirupsen/logrus"
@@ -21,6 +22,11 @@ func GetHeadlessConsent(definition RunDefinition, ctx *model.Context, specRun *g
 		"module": "GetHeadlessConsent",
 	})
 
+	script, err := headlessScript(definition)
+	if err != nil {
+		return nil, err
+	}
+
 	allRequiredTokens := []manifest.RequiredTokens{}
 
 	for specType := range permissions {
@@ -32,13 +38,13 @@ func GetHeadlessConsent(definition RunDefinition, ctx *model.Context, specRun *g
 
 		switch specType {
 		case "accounts":
-			requiredTokens, err := getAccountsHeadlessTokens(tests, ctx, definition, logger)
+			requiredTokens, err := getAccountsHeadlessTokens(tests, ctx, definition, script, logger)
 			if err != nil {
 				return nil, err
 			}
 			allRequiredTokens = append(allRequiredTokens, requiredTokens...)
 		case "payments":
-			requiredTokens, err := getPaymentHeadlessTokens(tests, ctx, definition, permissions["payments"], logger)
+			requiredTokens, err := getPaymentHeadlessTokens(tests, ctx, definition, permissions["payments"], script, logger)
 			if err != nil {
 				return nil, err
 			}
@@ -51,23 +57,39 @@ func GetHeadlessConsent(definition RunDefinition, ctx *model.Context, specRun *g
 	return allRequiredTokens, nil
 }
 
-func getPaymentHeadlessTokens(paymentTests []model.TestCase, ctx *model.Context, definition RunDefinition, requiredTokens []manifest.RequiredTokens, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
+// headlessScript - the headless consent script of the discovery model, nil when the consent
+// url redirects with the code, as Ozone's
+func headlessScript(definition RunDefinition) (*headless.Script, error) {
+	if definition.DiscoModel == nil || definition.DiscoModel.DiscoveryModel.HeadlessScript == "" {
+		return nil, nil
+	}
+	script, err := headless.Load(definition.DiscoModel.DiscoveryModel.HeadlessScript)
+	if err != nil {
+		return nil, err
+	}
+	return &script, nil
+}
+
+func getPaymentHeadlessTokens(paymentTests []model.TestCase, ctx *model.Context, definition RunDefinition, requiredTokens []manifest.RequiredTokens, script *headless.Script, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
 	logger.Debug("getPaymentHeadlessTokens")
 
-	executor := Executor{}
+	executor := NewExecutor()
 	err := executor.SetCertificates(definition.SigningCert, definition.TransportCert)
 	if err != nil {
 		return nil, err
 	}
+	executor.SetPropertyCollector(definition.Collector)
+	executor.SetRecorder(definition.Recorder)
+	executor.SetThrottle(definition.RateLimiter, definition.ThrottleRetries)
 
 	logger.Debugf("we have %d required tokens", len(requiredTokens))
 
-	requiredTokens, err = runPaymentConsents(requiredTokens, ctx, &executor)
+	requiredTokens, err = runPaymentConsents(definition, requiredTokens, ctx, executor)
 	if err != nil {
 		logger.Errorf("getPaymentConsents error: " + err.Error())
 	}
 
-	tokendata, err := CallPaymentHeadlessConsentUrls(&requiredTokens, ctx, logger)
+	tokendata, err := CallPaymentHeadlessConsentUrls(&requiredTokens, ctx, definition, script, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -86,8 +108,14 @@ func getPaymentHeadlessTokens(paymentTests []model.TestCase, ctx *model.Context,
 
 }
 
-// CallPaymentHeadlessConsentUrls -
-func CallPaymentHeadlessConsentUrls(rt *[]manifest.RequiredTokens, ctx *model.Context, logger *logrus.Entry) (map[string]string, error) {
+// CallPaymentHeadlessConsentUrls - the requests are sent by the HTTP client of definition and
+// recorded by its recorder, which may be nil. The code is captured by script, or from the
+// redirect of the consent url when script is nil
+func CallPaymentHeadlessConsentUrls(rt *[]manifest.RequiredTokens, ctx *model.Context, definition RunDefinition, script *headless.Script, logger *logrus.Entry) (map[string]string, error) {
+	client, recorder := definition.HTTPClient, definition.Recorder
+	if client == nil {
+		return nil, errors.New("CallPaymentHeadlessConsentUrls: no HTTP client")
+	}
 	var matchingGroup []string
 	exchangeCode := ""
 	exhangeCodeRegex := "code=([^&]*)&"
@@ -96,10 +124,19 @@ func CallPaymentHeadlessConsentUrls(rt *[]manifest.RequiredTokens, ctx *model.Co
 	for _, tokendata := range *rt {
 		endpoint := tokendata.ConsentURL
 		var resp *resty.Response
+		var err error
 
-		resp, err := resty.R().
-			SetHeader("accept", "*/*").
-			Get(endpoint)
+		if script != nil {
+			exchangeCode, err = script.Run(ctx, client, endpoint, recorder)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			resp, err = client.R().
+				SetHeader("accept", "*/*").
+				Get(endpoint)
+			recorder.Record("CallPaymentHeadlessConsentUrls", resp)
+		}
 
 		if err != nil {
 			if resp != nil && resp.StatusCode() == http.StatusFound { // catch status code 302 redirects and pass back as good response
@@ -132,57 +169,41 @@ func CallPaymentHeadlessConsentUrls(rt *[]manifest.RequiredTokens, ctx *model.Co
 			return nil, fmt.Errorf("Exchange code is empty - cannot complete exchange")
 		}
 
-		params, err := ctx.GetStrings("basic_authentication", "token_endpoint", "redirect_url")
+		redirectURL, err := ctx.GetString("redirect_url")
 		if err != nil {
 			logger.Errorf("Consent Failed to get %s from context", err.Error())
 			return nil, err
 		}
 
-		resp, err = resty.R().
-			SetHeader("content-type", "application/x-www-form-urlencoded").
-			SetHeader("accept", "application/json").
-			SetHeader("authorization", "Basic "+params["basic_authentication"]).
-			SetFormData(map[string]string{
-				"code":         exchangeCode,
-				"redirect_uri": params["redirect_url"],
-				"grant_type":   "authorization_code",
-				"scope":        "payments",
-			}).
-			Post(params["token_endpoint"])
-
+		// the client authenticates with the token_endpoint_auth_method of ctx
+		form := map[string]string{
+			authentication.GrantType: authentication.GrantTypeAuthorizationCode,
+			"code":                   exchangeCode,
+			"redirect_uri":           redirectURL,
+			"scope":                  "payments",
+		}
+		setCodeVerifier(form, ctx, tokendata.Name)
+		grantToken, err := requestToken(ctx, client, form, "CallPaymentHeadlessConsentUrls", recorder, logger)
 		if err != nil {
 			logger.WithFields(logrus.Fields{
-				"tokenEndpoint": params["token_endpoint"],
-				"err":           err,
+				"err": err,
 			}).Debug("Payment headless code exchange failed")
 			return nil, err
 		}
-
-		token, err := getAccessTokenFromJSONResponse(string(resp.Body()), logger)
-		if err != nil {
-			return nil, err
+		if grantToken.AccessToken == "" {
+			return nil, errors.New("Access Token not found in JSON response body")
 		}
+		token := grantToken.AccessToken
 		// Store the token against the token name for returning
 		consentedTokens[tokendata.Name] = token
+		definition.Tokens.Put(tokendata.Name, *grantToken)
 	}
 
 	logger.Tracef("ConsentedTokens: %#v", consentedTokens)
 	return consentedTokens, nil
 }
 
-func getAccessTokenFromJSONResponse(body string, logger *logrus.Entry) (string, error) {
-	token := gjson.Get(body, "access_token")
-	accessToken := token.String()
-	if len(accessToken) == 0 {
-		logger.WithFields(logrus.Fields{
-			"body": body,
-		}).Error("Access Token not found in JSON response body")
-		return "", errors.New("Access Token not found in JSON response body")
-	}
-	return accessToken, nil
-}
-
-func getAccountsHeadlessTokens(tests []model.TestCase, ctx *model.Context, definition RunDefinition, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
+func getAccountsHeadlessTokens(tests []model.TestCase, ctx *model.Context, definition RunDefinition, script *headless.Script, logger *logrus.Entry) ([]manifest.RequiredTokens, error) {
 	logger.Debug("getAccountsHeadlessTokens")
 	bodyDataStart := "{\"Data\": { \"Permissions\": ["
 	//TODO: sort out consent transaction timestamps
@@ -201,6 +222,9 @@ func getAccountsHeadlessTokens(tests []model.TestCase, ctx *model.Context, defin
 	if err != nil {
 		return nil, err
 	}
+	executor.SetPropertyCollector(definition.Collector)
+	executor.SetRecorder(definition.Recorder)
+	executor.SetThrottle(definition.RateLimiter, definition.ThrottleRetries)
 	schemaVersion := definition.DiscoModel.DiscoveryModel.DiscoveryItems[0].APISpecification.SchemaVersion //TODO: Fix for more that one specification
 	specType, err := manifest.GetSpecType(schemaVersion)
 	if err != nil {
@@ -222,7 +246,7 @@ func getAccountsHeadlessTokens(tests []model.TestCase, ctx *model.Context, defin
 		localCtx.PutString("permission_payload", bodyData)
 		localCtx.PutString("result_token", tokenName)
 
-		returnCtx, err := executeComponent(&localCtx, executor)
+		returnCtx, err := executeComponent(&localCtx, executor, script, definition)
 		if err != nil {
 			return nil, err
 		}
@@ -249,8 +273,17 @@ func getHeadlessTokenComponent() (*model.Component, error) {
 
 }
 
-// ExecuteComponent -
-func executeComponent(ctx *model.Context, executor TestCaseExecutor) (*model.Context, error) {
+// Test cases of the headless token component
+const (
+	headlessClientCredentialsTestID = "#ct0001"
+	// headlessConsentURLTestID - calls the consent url, replaced by the headless consent
+	// script when there is one
+	headlessConsentURLTestID = "#ct0003"
+	headlessExchangeTestID   = "#ct0004"
+)
+
+// ExecuteComponent - runs the headless token component, script may be nil
+func executeComponent(ctx *model.Context, executor TestCaseExecutor, script *headless.Script, definition RunDefinition) (*model.Context, error) {
 	comp, err := getHeadlessTokenComponent()
 	if err != nil {
 		return nil, err
@@ -264,20 +297,44 @@ func executeComponent(ctx *model.Context, executor TestCaseExecutor) (*model.Con
 		return &model.Context{}, fmt.Errorf(msg)
 	}
 
+	authMethod, err := ctx.GetString("token_endpoint_auth_method")
+	if err != nil {
+		authMethod = authentication.ClientSecretBasic
+	}
+
 	tests := comp.GetTests()
 	executeCtx := &model.Context{}
 	executeCtx.PutContext(ctx)
 	logrus.Debugf("We have %d tests to run ", len(tests))
 	// run sequentially - don't care about async ... its a startup task, not a run task.
 	for k, test := range tests {
+		if test.ID == headlessClientCredentialsTestID || test.ID == headlessExchangeTestID {
+			if err := setClientAuthentication(&test, executeCtx, authMethod); err != nil {
+				return &model.Context{}, errors.Wrapf(err, "Test case %s client authentication", test.ID)
+			}
+		}
 		test.ProcessReplacementFields(executeCtx, false)
 		_, _ = k, test
 		logrus.Debug("Executing ------->>")
 
-		req, err := test.Prepare(executeCtx)
+		req, err := test.Prepare(executeCtx, definition.HTTPClient)
 		if err != nil {
 			return &model.Context{}, err
 		}
+		if test.ID == headlessConsentURLTestID {
+			req.URL, err = definition.PushedAuthorization.Push(executeCtx, "accounts", req.URL, definition.HTTPClient, definition.Recorder)
+			if err != nil {
+				return &model.Context{}, errors.Wrapf(err, "Test case %s", test.ID)
+			}
+		}
+		if script != nil && test.ID == headlessConsentURLTestID {
+			code, err := script.Run(executeCtx, definition.HTTPClient, req.URL, definition.Recorder)
+			if err != nil {
+				return &model.Context{}, err
+			}
+			executeCtx.PutString("xchange_code", code)
+			continue
+		}
 		resp, _, err := executor.ExecuteTestCase(req, &test, executeCtx)
 		if err != nil {
 			return &model.Context{}, fmt.Errorf("Test case %s failed with error %s", test.ID, err.Error())
@@ -292,6 +349,12 @@ func executeComponent(ctx *model.Context, executor TestCaseExecutor) (*model.Con
 			logrus.Errorf("Component testcase %s failed to Validate", test.ID)
 			return &model.Context{}, errors.New("testcase failed to validate testid:" + test.ID)
 		}
+		if test.ID == headlessExchangeTestID {
+			// the token is kept with its refresh token and expiry, to be refreshed before it expires
+			if err := putGrantToken(definition.Tokens, executeCtx, resp.Body()); err != nil {
+				return &model.Context{}, errors.Wrapf(err, "Test case %s", test.ID)
+			}
+		}
 
 		logrus.Debug("Executed  <<-------")
 		executeCtx.DumpContext("execution loop")
@@ -7,6 +7,7 @@ import (
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 
@@ -14,7 +15,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	resty "gopkg.in/resty.v1"
+	"gopkg.in/resty.v1"
 )
 
 const (
@@ -108,6 +109,9 @@ func getAccountConsents(definition RunDefinition, permissions []manifest.Require
 	for _, v := range consentItems {
 		logger.Debugf("Setting Token: %s, ConsentId: %s", v.TokenName, v.ConsentID)
 		ctx.PutString(v.TokenName, v.ConsentID)
+		if v.CodeVerifier != "" {
+			ctx.PutString(model.CodeVerifierKey(v.TokenName), v.CodeVerifier)
+		}
 	}
 	logrus.Debugf("we have %d consentIds: %#v", len(consentItems), consentItems)
 	return consentItems, tokenParameters, err
@@ -183,69 +187,155 @@ type ExchangeParameters struct {
 }
 
 // ExchangeCodeForAccessToken - runs a testcase to perform this operation
-func ExchangeCodeForAccessToken(tokenName, code string, ctx *model.Context) (accesstoken string, err error) {
+// The token request is sent by the HTTP client of definition and recorded by its recorder, which
+// may be nil. The access token is kept in its token store, which may be nil, to be refreshed
+// before it expires
+func ExchangeCodeForAccessToken(tokenName, code string, ctx *model.Context, definition RunDefinition) (accesstoken string, err error) {
 	logger := logrus.StandardLogger().WithFields(logrus.Fields{
 		"module":    "ExchangeCodeForAccessToken",
 		"tokenName": tokenName,
 		"code":      code,
 	})
 
-	grantToken, err := exchangeCodeForToken(code, ctx, logger)
+	grantToken, err := exchangeCodeForToken(tokenName, code, ctx, definition.HTTPClient, definition.Recorder, logger)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"err": err,
 		}).Error("exchangeCodeForToken failed")
+		return "", err
 	}
 
-	return grantToken.AccessToken, err
+	definition.Tokens.Put(tokenName, *grantToken)
+	return grantToken.AccessToken, nil
 }
 
 type grantToken struct {
-	AccessToken string `json:"access_token,omitempty"`
-	TokenType   string `json:"token_type,omitempty"`
-	Expires     int32  `json:"expires_in,omitempty"`
-	Scope       string `json:"scope,omitempty"`
-	IDToken     string `json:"id_token,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	Expires      int32  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// putGrantToken - keeps the token of the token response body in tokens, under the name of the
+// `result_token` of ctx
+func putGrantToken(tokens *TokenStore, ctx *model.Context, body []byte) error {
+	tokenName, err := ctx.GetString("result_token")
+	if err != nil {
+		return errors.Wrap(err, "executors.putGrantToken: cannot get result_token")
+	}
+	token := grantToken{}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return errors.Wrap(err, "executors.putGrantToken: token response")
+	}
+	tokens.Put(tokenName, token)
+	return nil
 }
 
-func exchangeCodeForToken(code string, ctx *model.Context, logger *logrus.Entry) (*grantToken, error) {
+// exchangeCodeForToken - exchanges the code of the consent of tokenName, with the PKCE code
+// verifier of its consent url
+func exchangeCodeForToken(tokenName, code string, ctx *model.Context, client *resty.Client, recorder *har.Recorder, logger *logrus.Entry) (*grantToken, error) {
 	logger = logger.WithFields(logrus.Fields{
 		"function": "exchangeCodeForToken",
 		"code":     code,
 	})
 	ctx.DumpContext()
 
-	basicAuth, err := ctx.GetString("basic_authentication")
+	redirectURI, err := ctx.GetString("redirect_url")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get basic authentication for code")
+		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get redirect_url for code exchange")
+	}
+
+	form := map[string]string{
+		authentication.GrantType: authentication.GrantTypeAuthorizationCode,
+		"code":                   code,
+		"redirect_uri":           redirectURI,
 	}
+	setCodeVerifier(form, ctx, tokenName)
+	return requestToken(ctx, client, form, "ExchangeCodeForAccessToken", recorder, logger)
+}
+
+// setCodeVerifier - sets the PKCE code verifier of the consent of tokenName in the form of a
+// code exchange, when its consent url was sent with a code challenge
+func setCodeVerifier(form map[string]string, ctx *model.Context, tokenName string) {
+	if verifier, err := ctx.GetString(model.CodeVerifierKey(tokenName)); err == nil {
+		form[authentication.CodeVerifier] = verifier
+	}
+}
+
+// refreshToken - exchanges a refresh token for a new access token
+func refreshToken(refreshToken string, ctx *model.Context, client *resty.Client, recorder *har.Recorder, logger *logrus.Entry) (*grantToken, error) {
+	return requestToken(ctx, client, map[string]string{
+		authentication.GrantType:             authentication.GrantTypeRefreshToken,
+		authentication.GrantTypeRefreshToken: refreshToken,
+	}, "RefreshAccessToken", recorder, logger.WithField("function", "refreshToken"))
+}
+
+// requestToken - posts a grant of form to the token endpoint, authenticating the client
+// with the `token_endpoint_auth_method` of ctx. The request is sent by client and recorded by
+// recorder with comment
+func requestToken(ctx *model.Context, client *resty.Client, form map[string]string, comment string, recorder *har.Recorder, logger *logrus.Entry) (*grantToken, error) {
 	tokenEndpoint, err := ctx.GetString("token_endpoint")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get token_endpoint for code exchange")
+		return nil, errors.Wrap(err, "executors.requestToken: cannot get token_endpoint")
 	}
-	redirectURI, err := ctx.GetString("redirect_url")
+	request, err := clientAuthenticatedRequest(ctx, client, form)
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get redirect_url for code exchange")
+		return nil, err
 	}
-	clientID, err := ctx.GetString("client_id")
+	resp, errResponse := request.Post(tokenEndpoint)
+	recorder.Record(comment, resp)
+
+	if errResponse != nil {
+		logger.WithFields(logrus.Fields{
+			"tokenEndpoint": tokenEndpoint,
+			"errResponse":   errResponse,
+		}).Debug("Error requesting token")
+		return nil, errResponse
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("executors.requestToken: bad status code %d from token endpoint %q", resp.StatusCode(), tokenEndpoint)
+	}
+
+	grantToken := &grantToken{}
+	if err := json.Unmarshal(resp.Body(), grantToken); err != nil {
+		return nil, err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"grantToken": grantToken,
+	}).Tracef("OK")
+
+	return grantToken, nil
+}
+
+// clientAuthenticatedRequest - a form post of form to an endpoint of the authorisation server,
+// authenticating the client with the `token_endpoint_auth_method` of ctx, sent by client
+func clientAuthenticatedRequest(ctx *model.Context, client *resty.Client, form map[string]string) (*resty.Request, error) {
+	if client == nil {
+		return nil, errors.New("executors.clientAuthenticatedRequest: no HTTP client")
+	}
+	basicAuth, err := ctx.GetString("basic_authentication")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get client_id for exchange code")
+		return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get basic authentication")
 	}
-	alg, err := ctx.GetString("requestObjectSigningAlg")
+	tokenEndpoint, err := ctx.GetString("token_endpoint")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get requestObjectSigningAlg for exchange code")
+		return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get token_endpoint")
 	}
-	privKey, err := ctx.GetString("signingPrivate")
+	clientID, err := ctx.GetString("client_id")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get `signingPrivate` in context")
+		return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get client_id")
 	}
-	pubKey, err := ctx.GetString("signingPublic")
+	alg, err := ctx.GetString("requestObjectSigningAlg")
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get `signingPublic` in context")
+		return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get requestObjectSigningAlg")
 	}
-	cert, err := authentication.NewCertificate(pubKey, privKey)
+	cert, err := authentication.SigningCertFromContext(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get `certificate` from pub/priv keys")
+		return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get signing `certificate`")
 	}
 
 	// Check for MTLS vs client basic authentication
@@ -254,31 +344,39 @@ func exchangeCodeForToken(code string, ctx *model.Context, logger *logrus.Entry)
 		authMethod = authentication.ClientSecretBasic
 	}
 
-	var resp *resty.Response
-	var errResponse error
+	request := client.R().
+		SetHeader("content-type", "application/x-www-form-urlencoded").
+		SetHeader("accept", "application/json").
+		SetFormData(form)
 	switch authMethod {
 	case authentication.ClientSecretBasic:
-		resp, errResponse = resty.R().
-			SetHeader("content-type", "application/x-www-form-urlencoded").
-			SetHeader("accept", "application/json").
-			SetHeader("authorization", "Basic "+basicAuth).
-			SetFormData(map[string]string{
-				authentication.GrantType: authentication.GrantTypeAuthorizationCode,
-				"code":                   code,
-				"redirect_uri":           redirectURI,
-			}).
-			Post(tokenEndpoint)
+		request.SetHeader("authorization", "Basic "+basicAuth)
 	case authentication.TlsClientAuth:
-		resp, errResponse = resty.R().
-			SetHeader("content-type", "application/x-www-form-urlencoded").
-			SetHeader("accept", "application/json").
-			SetFormData(map[string]string{
-				authentication.GrantType: authentication.GrantTypeAuthorizationCode,
-				"code":                   code,
-				"redirect_uri":           redirectURI,
-				"client_id":              clientID,
-			}).
-			Post(tokenEndpoint)
+		request.SetFormData(map[string]string{
+			"client_id": clientID,
+		})
+	case authentication.ClientSecretPost:
+		clientSecret, err := ctx.GetString("client_secret")
+		if err != nil {
+			return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get client_secret")
+		}
+		request.SetFormData(map[string]string{
+			"client_id":     clientID,
+			"client_secret": clientSecret,
+		})
+	case authentication.ClientSecretJwt:
+		clientSecret, err := ctx.GetString("client_secret")
+		if err != nil {
+			return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get client_secret")
+		}
+		clientAssertion, err := authentication.ClientSecretJWT(clientID, clientSecret, tokenEndpoint)
+		if err != nil {
+			return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: could not generate client_assertion")
+		}
+		request.SetFormData(map[string]string{
+			authentication.ClientAssertionType: authentication.ClientAssertionTypeValue,
+			authentication.ClientAssertion:     clientAssertion,
+		})
 	case authentication.PrivateKeyJwt:
 		now := time.Now()
 		iat := now.Unix()
@@ -302,57 +400,28 @@ func exchangeCodeForToken(code string, ctx *model.Context, logger *logrus.Entry)
 
 		signingMethod, err := authentication.GetSigningAlg(alg)
 		if err != nil {
-			return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get signingMethod")
+			return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get signingMethod")
 		}
 
 		token := jwt.NewWithClaims(signingMethod, claims) // create new token
 
-		kid, err := authentication.GetKID(ctx, cert.PublicKey().N.Bytes())
+		kid, err := authentication.GetKID(ctx, cert.PublicKey())
 		if err != nil {
-			return nil, errors.Wrap(err, "executors.exchangeCodeForToken: cannot get KID")
+			return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: cannot get KID")
 		}
 		token.Header["kid"] = kid
 
 		clientAssertion, err := token.SignedString(cert.PrivateKey()) // sign the token - get as encoded string
 		if err != nil {
-			return nil, errors.Wrap(err, "executors.exchangeCodeForToken: could not generate client_assertion")
+			return nil, errors.Wrap(err, "executors.clientAuthenticatedRequest: could not generate client_assertion")
 		}
 
-		resp, errResponse = resty.R().
-			SetHeader("content-type", "application/x-www-form-urlencoded").
-			SetHeader("accept", "application/json").
-			SetFormData(map[string]string{
-				authentication.GrantType:           authentication.GrantTypeAuthorizationCode,
-				"code":                             code,
-				"redirect_uri":                     redirectURI,
-				authentication.ClientAssertionType: authentication.ClientAssertionTypeValue,
-				authentication.ClientAssertion:     clientAssertion,
-			}).
-			Post(tokenEndpoint)
+		request.SetFormData(map[string]string{
+			authentication.ClientAssertionType: authentication.ClientAssertionTypeValue,
+			authentication.ClientAssertion:     clientAssertion,
+		})
 	default:
-		return nil, errors.Errorf("executors.exchangeCodeForToken: token_endpoint_auth_method %q unsupported", authMethod)
+		return nil, errors.Errorf("executors.clientAuthenticatedRequest: token_endpoint_auth_method %q unsupported", authMethod)
 	}
-
-	if errResponse != nil {
-		logger.WithFields(logrus.Fields{
-			"tokenEndpoint": tokenEndpoint,
-			"errResponse":   errResponse,
-		}).Debug("Error accessing exchange code")
-		return nil, errResponse
-	}
-
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("executors.exchangeCodeForToken: bad status code %d from exchange token %q", resp.StatusCode(), tokenEndpoint)
-	}
-
-	grantToken := &grantToken{}
-	if err := json.Unmarshal(resp.Body(), grantToken); err != nil {
-		return nil, err
-	}
-
-	logger.WithFields(logrus.Fields{
-		"grantToken": grantToken,
-	}).Tracef("OK")
-
-	return grantToken, nil
+	return request, nil
 }
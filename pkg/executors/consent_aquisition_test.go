@@ -2,9 +2,18 @@ package executors
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 )
 
 func TestBuildParameters(t *testing.T) {
@@ -28,3 +37,69 @@ func TestBuildParameters(t *testing.T) {
 	fmt.Println(buildstr)
 
 }
+
+func TestExchangeCodeForAccessToken_CodeVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "code", r.PostForm.Get("code"))
+		if r.PostForm.Get("code_verifier") != "verifier002" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "invalid_grant"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "token", "expires_in": 300}`))
+	}))
+	defer server.Close()
+
+	ctx := tokenContext(server)
+	ctx.PutString("redirect_url", "https://tpp.example.com/callback")
+	ctx.PutString(model.CodeVerifierKey("Token001"), "verifier001")
+	ctx.PutString(model.CodeVerifierKey("Token002"), "verifier002")
+
+	_, err := ExchangeCodeForAccessToken("Token001", "code", ctx, RunDefinition{HTTPClient: resty.New()})
+	assert.Error(t, err, "verifier of another consent")
+
+	accessToken, err := ExchangeCodeForAccessToken("Token002", "code", ctx, RunDefinition{HTTPClient: resty.New()})
+	require.NoError(t, err)
+	assert.Equal(t, "token", accessToken)
+}
+
+func TestCallPaymentHeadlessConsentUrls_KeepsTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/authorize" {
+			http.Redirect(w, r, "https://tpp.example.com/callback?code=abc&state=PaymentToken001", http.StatusFound)
+			return
+		}
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "abc", r.PostForm.Get("code"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token": "payment-token", "refresh_token": "payment-refresh", "expires_in": 300}`))
+	}))
+	defer server.Close()
+	ctx := tokenContext(server)
+	ctx.PutString("token_endpoint", server.URL+"/token")
+	ctx.PutString("redirect_url", "https://tpp.example.com/callback")
+	now := time.Now()
+	tokens := newTestTokenStore(now)
+	definition := RunDefinition{HTTPClient: resty.New().SetRedirectPolicy(resty.NoRedirectPolicy()), Tokens: tokens}
+	rt := []manifest.RequiredTokens{{Name: "PaymentToken001", ConsentURL: server.URL + "/authorize"}}
+
+	consented, err := CallPaymentHeadlessConsentUrls(&rt, ctx, definition, nil, logrus.NewEntry(logrus.New()))
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"PaymentToken001": "payment-token"}, consented)
+	assert.Equal(t, StoredToken{AccessToken: "payment-token", RefreshToken: "payment-refresh", Expiry: now.Add(300 * time.Second)}, tokens.Tokens()["PaymentToken001"])
+}
+
+func TestPutGrantToken(t *testing.T) {
+	now := time.Now()
+	tokens := newTestTokenStore(now)
+	ctx := &model.Context{"result_token": "accountToken0001"}
+
+	require.NoError(t, putGrantToken(tokens, ctx, []byte(`{"access_token": "account-token", "refresh_token": "account-refresh", "expires_in": 60}`)))
+
+	assert.Equal(t, StoredToken{AccessToken: "account-token", RefreshToken: "account-refresh", Expiry: now.Add(time.Minute)}, tokens.Tokens()["accountToken0001"])
+	assert.Error(t, putGrantToken(tokens, &model.Context{}, []byte(`{}`)), "no result_token")
+	assert.Error(t, putGrantToken(tokens, ctx, []byte(`not json`)))
+}
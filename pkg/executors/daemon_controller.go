@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 )
 
 type DaemonController interface {
@@ -17,6 +18,8 @@ type DaemonController interface {
 	AllResultsGrouped() map[results.ResultKey][]results.TestCase
 	AddResponseFields(string)
 	ResponseFieldsJSON() string
+	AddExchanges(har.HAR)
+	Exchanges() har.HAR
 
 	Results() <-chan results.TestCase
 
@@ -31,9 +34,12 @@ type daemonController struct {
 	resultsGrouped  map[results.ResultKey][]results.TestCase
 	resultChan      chan results.TestCase
 	responseFields  string
+	exchanges       har.HAR
+	resultsLock     *sync.Mutex
 	stopLock        *sync.Mutex
 	shouldStop      bool
 	isCompletedChan chan bool
+	restored        map[restoredKey]bool
 }
 
 // NewBufferedDaemonController new instance to control a background routine with 100 objects
@@ -44,15 +50,43 @@ func NewBufferedDaemonController() *daemonController {
 
 }
 
+// NewRestoredDaemonController - a buffered daemon controller holding the results of a previous
+// run, e.g.: before a restart, which aren't sent on the results channel again.
+// A result of a test case run again replaces its restored result.
+func NewRestoredDaemonController(restored []results.TestCase) *daemonController {
+	controller := NewBufferedDaemonController()
+	controller.restored = map[restoredKey]bool{}
+	for _, result := range restored {
+		controller.addResult(result)
+		controller.restored[newRestoredKey(result)] = true
+	}
+	return controller
+}
+
+// restoredKey - identifies the restored result of a test case
+type restoredKey struct {
+	results.ResultKey
+	id string
+}
+
+func newRestoredKey(result results.TestCase) restoredKey {
+	return restoredKey{
+		ResultKey: results.ResultKey{APIVersion: result.APIVersion, APIName: result.API},
+		id:        result.Id,
+	}
+}
+
 // NewDaemonController new instance to control a background routine
 func NewDaemonController(resultChan chan results.TestCase) *daemonController {
 	return &daemonController{
 		results:         []results.TestCase{},
 		resultChan:      resultChan,
+		resultsLock:     &sync.Mutex{},
 		stopLock:        &sync.Mutex{},
 		shouldStop:      false,
 		isCompletedChan: make(chan bool, 1),
 		resultsGrouped:  make(map[results.ResultKey][]results.TestCase),
+		exchanges:       har.HAR{Log: har.NewLog()},
 	}
 }
 
@@ -80,27 +114,53 @@ func (rc *daemonController) ShouldStop() bool {
 	return shouldStop
 }
 
-// AddResult - add result.
+// AddResult - add result, safe to call from concurrently running test cases.
 func (rc *daemonController) AddResult(result results.TestCase) {
-	rc.results = append(rc.results, result)
+	rc.addResult(result)
+	rc.resultChan <- result
+}
+
+func (rc *daemonController) addResult(result results.TestCase) {
+	rc.resultsLock.Lock()
+	defer rc.resultsLock.Unlock()
 	mpKey := results.ResultKey{
 		APIVersion: result.APIVersion,
 		APIName:    result.API,
 	}
+	if key := newRestoredKey(result); rc.restored[key] {
+		delete(rc.restored, key)
+		replaceResult(rc.results, result)
+		replaceResult(rc.resultsGrouped[mpKey], result)
+		return
+	}
+	rc.results = append(rc.results, result)
 	if _, ok := rc.resultsGrouped[mpKey]; !ok {
 		rc.resultsGrouped[mpKey] = make([]results.TestCase, 0)
 	}
 	rc.resultsGrouped[mpKey] = append(rc.resultsGrouped[mpKey], result)
-	rc.resultChan <- result
+}
+
+// replaceResult - replaces the result of the same test case in testCases
+func replaceResult(testCases []results.TestCase, result results.TestCase) {
+	for i, testCase := range testCases {
+		if newRestoredKey(testCase) == newRestoredKey(result) {
+			testCases[i] = result
+			return
+		}
+	}
 }
 
 // AllResults - returns all the accumulated results.
 func (rc *daemonController) AllResults() []results.TestCase {
+	rc.resultsLock.Lock()
+	defer rc.resultsLock.Unlock()
 	return rc.results
 }
 
 // AllResultsGrouped - returns all the accumulated results Grouped by the type `ResultKey`.
 func (rc *daemonController) AllResultsGrouped() map[results.ResultKey][]results.TestCase {
+	rc.resultsLock.Lock()
+	defer rc.resultsLock.Unlock()
 	return rc.resultsGrouped
 }
 
@@ -112,6 +172,16 @@ func (rc *daemonController) ResponseFieldsJSON() string {
 	return rc.responseFields
 }
 
+// AddExchanges - sets the HTTP exchanges recorded during the run
+func (rc *daemonController) AddExchanges(exchanges har.HAR) {
+	rc.exchanges = exchanges
+}
+
+// Exchanges - HTTP exchanges recorded during the run
+func (rc *daemonController) Exchanges() har.HAR {
+	return rc.exchanges
+}
+
 // ResultsChannel - return channel for receiving results.
 func (rc *daemonController) Results() <-chan results.TestCase {
 	return rc.resultChan
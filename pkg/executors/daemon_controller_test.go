@@ -119,3 +119,25 @@ func TestNewBufferedDaemonControllerSetCompletedAndIsCompleted(t *testing.T) {
 		break
 	}
 }
+
+func TestNewRestoredDaemonControllerDoesNotResendResults(t *testing.T) {
+	assert := test.NewAssert(t)
+	restored := []results.TestCase{
+		{Id: "#t1001", API: "Account and Transaction API Specification", APIVersion: "v3.1"},
+	}
+
+	controller := NewRestoredDaemonController(restored)
+
+	assert.Equal(restored, controller.AllResults())
+	assert.Len(controller.AllResultsGrouped(), 1)
+	select {
+	case <-controller.Results():
+		assert.Fail("restored result sent again")
+	case <-time.After(selectTimeout):
+	}
+
+	result := results.TestCase{Id: "#t1002", API: "Account and Transaction API Specification", APIVersion: "v3.1"}
+	controller.AddResult(result)
+	assert.Len(controller.AllResults(), 2)
+	assert.Equal(result, <-controller.Results())
+}
@@ -9,6 +9,10 @@ type Events interface {
 	AddAcquiredAllAccessTokens(acquiredAllAccessTokens AcquiredAllAccessTokens)
 	AllTokensChannel() <-chan AcquiredAllAccessTokens
 	AllAcquiredAllAccessTokens() []AcquiredAllAccessTokens
+
+	AddRefreshedAccessToken(refreshedAccessToken RefreshedAccessToken)
+	RefreshedTokensChannel() <-chan RefreshedAccessToken
+	AllRefreshedAccessTokens() []RefreshedAccessToken
 }
 
 // NewEvents -
@@ -19,6 +23,8 @@ func NewEvents() Events {
 		acquiredAccessTokensChan:   make(chan AcquiredAccessToken, size),
 		acquiredAllAccessTokens:    []AcquiredAllAccessTokens{},
 		aquiredAllAccessTokensChan: make(chan AcquiredAllAccessTokens, size),
+		refreshedAccessTokens:      []RefreshedAccessToken{},
+		refreshedAccessTokensChan:  make(chan RefreshedAccessToken, size),
 	}
 }
 
@@ -27,6 +33,8 @@ type events struct {
 	acquiredAccessTokensChan   chan AcquiredAccessToken
 	acquiredAllAccessTokens    []AcquiredAllAccessTokens
 	aquiredAllAccessTokensChan chan AcquiredAllAccessTokens
+	refreshedAccessTokens      []RefreshedAccessToken
+	refreshedAccessTokensChan  chan RefreshedAccessToken
 }
 
 func (e *events) AddAcquiredAccessToken(acquiredAccessToken AcquiredAccessToken) {
@@ -54,3 +62,16 @@ func (e *events) AllTokensChannel() <-chan AcquiredAllAccessTokens {
 func (e *events) AllAcquiredAllAccessTokens() []AcquiredAllAccessTokens {
 	return e.acquiredAllAccessTokens
 }
+
+func (e *events) AddRefreshedAccessToken(refreshedAccessToken RefreshedAccessToken) {
+	e.refreshedAccessTokens = append(e.refreshedAccessTokens, refreshedAccessToken)
+	e.refreshedAccessTokensChan <- refreshedAccessToken
+}
+
+func (e *events) RefreshedTokensChannel() <-chan RefreshedAccessToken {
+	return e.refreshedAccessTokensChan
+}
+
+func (e *events) AllRefreshedAccessTokens() []RefreshedAccessToken {
+	return e.refreshedAccessTokens
+}
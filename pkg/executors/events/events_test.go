@@ -74,3 +74,24 @@ func TestEvents(t *testing.T) {
 		acquiredAllAccessTokens,
 	}, events.AllAcquiredAllAccessTokens())
 }
+
+func TestEventsRefreshedAccessToken(t *testing.T) {
+	require := test.NewRequire(t)
+
+	events := NewEvents()
+	require.Empty(events.AllRefreshedAccessTokens())
+
+	expiry := time.Now().Add(5 * time.Minute)
+	refreshed := NewRefreshedAccessToken("to1001", expiry)
+	events.AddRefreshedAccessToken(refreshed)
+
+	select {
+	case msg, ok := <-events.RefreshedTokensChannel():
+		require.True(ok)
+		require.Equal(refreshed, msg)
+	case <-time.After(selectTimeout):
+		require.FailNow("expected RefreshedAccessToken")
+	}
+	require.Equal([]RefreshedAccessToken{refreshed}, events.AllRefreshedAccessTokens())
+	require.Empty(events.AllAcquiredAccessToken())
+}
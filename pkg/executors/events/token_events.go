@@ -1,5 +1,7 @@
 package events
 
+import "time"
+
 // AcquiredAccessToken - When `code` has been exchanged for an `access_token`.
 type AcquiredAccessToken struct {
 	TokenName string `json:"token_name"`
@@ -23,3 +25,26 @@ func NewAcquiredAllAccessTokens(tokenNames []string) AcquiredAllAccessTokens {
 		TokenNames: tokenNames,
 	}
 }
+
+// RefreshedAccessToken - When an expired `access_token` has been refreshed with its `refresh_token`.
+type RefreshedAccessToken struct {
+	TokenName string    `json:"token_name"`
+	Expiry    time.Time `json:"expiry,omitempty"`
+	Error     string    `json:"error,omitempty"` // Set when the refresh failed
+}
+
+// NewRefreshedAccessToken -
+func NewRefreshedAccessToken(tokenName string, expiry time.Time) RefreshedAccessToken {
+	return RefreshedAccessToken{
+		TokenName: tokenName,
+		Expiry:    expiry,
+	}
+}
+
+// NewRefreshedAccessTokenError - a failed refresh
+func NewRefreshedAccessTokenError(tokenName string, err error) RefreshedAccessToken {
+	return RefreshedAccessToken{
+		TokenName: tokenName,
+		Error:     err.Error(),
+	}
+}
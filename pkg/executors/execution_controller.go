@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
@@ -16,8 +17,10 @@ import (
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/events"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/tracer"
 )
@@ -28,6 +31,38 @@ type RunDefinition struct {
 	SpecRun       generation.SpecRun
 	SigningCert   authentication.Certificate
 	TransportCert authentication.Certificate
+	Collector     schemaprops.PropertyCollector // Response fields collected during the run
+	Recorder      *har.Recorder                 // HTTP exchanges recorded during the run
+	TrustStore    []byte                        // PEM CA certificates trusted by the HTTP client of the run
+	Timeout       time.Duration                 // Timeout of each request of the run, none when zero
+	Proxy         string                        // URL of the proxy of the requests of the run
+	UserAgent     string                        // User-Agent of the requests of the run
+	HTTPClient    *resty.Client                 // HTTP client of the run, see `NewHTTPClient`
+	Tokens        *TokenStore                   // Access tokens refreshed before a test case uses an expired one
+	Events        events.Events                 // Events of the run, e.g.: access tokens refreshed
+	// PushedAuthorization pushes the authorization requests of the consent urls, see `PushedAuthorization.Push`
+	PushedAuthorization *PushedAuthorization
+	RunOptions
+}
+
+// RunOptions - options of the runs set by the operator, e.g.: by the flags of the server
+type RunOptions struct {
+	RedirectPolicy  resty.RedirectPolicy // Redirects followed by the HTTP client of the run, up to 15 when nil
+	Concurrency     Concurrency          // Test cases of the run executing at the same time, one when zero
+	RateLimiter     *RequestLimiter      // Limits the requests of the run, shared by the runs of the limiter
+	ThrottleRetries ThrottleRetries      // Budget retrying the requests of the run throttled by the ASPSP
+	// Replay serves the requests of the run the responses recorded, e.g.: by a report export,
+	// instead of sending them to the ASPSP, see `NewHTTPClient`
+	Replay *har.HAR
+}
+
+// NewRunOptions - the options of runs the operator didn't set, test cases run one after the
+// other and throttled requests are retried 3 times within 30s
+func NewRunOptions() RunOptions {
+	return RunOptions{
+		Concurrency:     Concurrency{PerSpec: 1, PerHost: 1},
+		ThrottleRetries: defaultThrottleRetries,
+	}
 }
 
 type TestCaseRunner struct {
@@ -108,16 +143,36 @@ func (r *TestCaseRunner) runTestCasesAsync(ctx *model.Context) {
 	if err != nil {
 		r.logger.WithError(err).Error("running test cases async")
 	}
+	r.executor.SetPropertyCollector(r.definition.Collector)
+	r.executor.SetRecorder(r.definition.Recorder)
+	r.executor.SetThrottle(r.definition.RateLimiter, r.definition.ThrottleRetries)
 
 	ruleCtx := r.makeRuleCtx(ctx)
 
+	// the PAR conformance tests ran when the consents were acquired, but for the expiry test
+	// waiting for a request_uri to expire
+	r.definition.PushedAuthorization.TestExpiry()
+	for _, result := range r.definition.PushedAuthorization.Results() {
+		r.daemonController.AddResult(result)
+	}
+
 	ctxLogger := r.logger.WithField("id", uuid.New())
+	runConcurrency := r.definition.Concurrency.atLeastOne()
+	hosts := newHostSlots(runConcurrency.PerHost)
 	for _, spec := range r.definition.SpecRun.SpecTestCases {
+		if runConcurrency.PerSpec > 1 {
+			r.executeSpecTestsConcurrently(spec, ruleCtx, ctxLogger, hosts, runConcurrency.PerSpec)
+			continue
+		}
 		r.executeSpecTests(spec, ruleCtx, ctxLogger) // Run Tests for each spec
 	}
 
-	collector := schemaprops.GetPropertyCollector()
-	r.daemonController.AddResponseFields(collector.OutputJSON())
+	if r.definition.Collector != nil {
+		r.daemonController.AddResponseFields(r.definition.Collector.OutputJSON())
+	}
+	if r.definition.Recorder != nil {
+		r.daemonController.AddExchanges(r.definition.Recorder.HAR())
+	}
 
 	r.daemonController.SetCompleted()
 
@@ -129,6 +184,9 @@ func (r *TestCaseRunner) runConsentAcquisitionAsync(item TokenConsentIDItem, ctx
 	if err != nil {
 		r.logger.WithError(err).Error("running consent acquisition async")
 	}
+	r.executor.SetPropertyCollector(r.definition.Collector)
+	r.executor.SetRecorder(r.definition.Recorder)
+	r.executor.SetThrottle(r.definition.RateLimiter, r.definition.ThrottleRetries)
 
 	ruleCtx := r.makeRuleCtx(ctx)
 	ruleCtx.PutString("consent_id", item.TokenName)
@@ -201,67 +259,11 @@ func (r *TestCaseRunner) executeComponentTests(comp *model.Component, ruleCtx *m
 		}
 
 		if testcase.ID == "#compPsuConsent01" {
-			switch authMethod {
-			case authentication.ClientSecretBasic:
-				testcase.Input.SetHeader("authorization", "Basic $basic_authentication")
-			case authentication.TlsClientAuth:
-				clientid, err := ruleCtx.GetString("client_id")
-				if err != nil {
-					ctxLogger.WithFields(logrus.Fields{
-						"authMethod": authMethod,
-						"err":        err,
-					}).Error("cannot locate client_id to populate form field")
-					continue
-				}
-
-				testcase.Input.SetFormField("client_id", clientid)
-			case authentication.PrivateKeyJwt:
-				clientID, err := ruleCtx.GetString("client_id")
-				if err != nil {
-					ctxLogger.WithFields(logrus.Fields{
-						"authMethod": authMethod,
-						"err":        err,
-					}).Error("cannot locate client_id to populate form field")
-					continue
-				}
-
-				tokenEndpoint, err := ruleCtx.GetString("token_endpoint")
-				if err != nil {
-					ctxLogger.WithFields(logrus.Fields{
-						"authMethod": authMethod,
-						"err":        err,
-					}).Error("cannot locate token_endpoint to populate form field")
-				}
-
-				if testcase.Input.Claims == nil {
-					testcase.Input.Claims = map[string]string{}
-				}
-
-				// https://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication
-				// iss
-				// REQUIRED. Issuer. This MUST contain the client_id of the OAuth Client.
-				// sub
-				// REQUIRED. Subject. This MUST contain the client_id of the OAuth Client.
-				// aud
-				// REQUIRED. Audience. The aud (audience) Claim. Value that identifies the Authorization Server as an intended audience. The Authorization Server MUST verify that it is an intended audience for the token. The Audience SHOULD be the URL of the Authorization Server's Token Endpoint.
-				testcase.Input.Claims["iss"] = clientID
-				testcase.Input.Claims["sub"] = clientID
-				testcase.Input.Claims["aud"] = tokenEndpoint
-				clientAssertion, err := testcase.Input.GenerateRequestToken(ruleCtx)
-				if err != nil {
-					ctxLogger.WithFields(logrus.Fields{
-						"testcase": testcase,
-						"err":      err,
-					}).Error("failed on testcase.Input.GenerateRequestToken")
-					continue
-				}
-
-				testcase.Input.SetFormField(authentication.ClientAssertionType, authentication.ClientAssertionTypeValue)
-				testcase.Input.SetFormField(authentication.ClientAssertion, clientAssertion)
-			default:
+			if err := setClientAuthentication(&testcase, ruleCtx, authMethod); err != nil {
 				ctxLogger.WithFields(logrus.Fields{
 					"authMethod": authMethod,
-				}).Error("Unsupported token_endpoint_auth_method")
+					"err":        err,
+				}).Error("cannot set client authentication")
 				continue
 			}
 		}
@@ -277,8 +279,16 @@ func (r *TestCaseRunner) executeComponentTests(comp *model.Component, ruleCtx *m
 			if err == model.ErrNotFound {
 				continue
 			}
+			consentURL, err = r.definition.PushedAuthorization.Push(ruleCtx, "accounts", consentURL, r.definition.HTTPClient, r.definition.Recorder)
+			if err != nil {
+				ctxLogger.WithError(err).Error("cannot push authorization request")
+				item.Error = err.Error()
+				consentIDChannel <- item
+				continue
+			}
 
 			item.ConsentURL = consentURL
+			item.CodeVerifier, _ = ruleCtx.GetString(model.CodeVerifierKey(item.TokenName))
 			ruleCtx.DumpContext()
 			consentID, err := ruleCtx.GetString(item.TokenName)
 			if err == model.ErrNotFound {
@@ -325,8 +335,9 @@ func (r *TestCaseRunner) makeRuleCtx(ctx *model.Context) *model.Context {
 
 func (r *TestCaseRunner) executeSpecTests(spec generation.SpecificationTestCases, ruleCtx *model.Context, ctxLogger *logrus.Entry) {
 	ctxLogger = ctxLogger.WithField("spec", spec.Specification.Name)
-	collector := schemaprops.GetPropertyCollector()
-	collector.SetCollectorAPIDetails(spec.Specification.Name, spec.Specification.Version)
+	if r.definition.Collector != nil {
+		r.definition.Collector.SetCollectorAPIDetails(spec.Specification.Name, spec.Specification.Version)
+	}
 
 	for _, testcase := range spec.TestCases {
 		if r.daemonController.ShouldStop() {
@@ -340,9 +351,18 @@ func (r *TestCaseRunner) executeSpecTests(spec generation.SpecificationTestCases
 	}
 }
 
+// executeTest - runs a test case attaching the HTTP exchanges it made to its result
 func (r *TestCaseRunner) executeTest(tc model.TestCase, ruleCtx *model.Context, logger *logrus.Entry) results.TestCase {
+	recorded := r.definition.Recorder.Len()
+	testResult := r.sendTest(tc, ruleCtx, logger)
+	testResult.Exchanges = r.definition.Recorder.Since(recorded, tc.ID)
+	return testResult
+}
+
+func (r *TestCaseRunner) sendTest(tc model.TestCase, ruleCtx *model.Context, logger *logrus.Entry) results.TestCase {
 	ctxLogger := logWithTestCase(logger, tc)
-	req, err := tc.Prepare(ruleCtx)
+	r.definition.Tokens.Refresh(ruleCtx, r.definition.HTTPClient, r.definition.Recorder, r.definition.Events)
+	req, err := tc.Prepare(ruleCtx, r.definition.HTTPClient)
 	if err != nil {
 		ctxLogger.WithError(err).Error("preparing executing test")
 		return results.NewTestCaseFail(tc.ID, results.NoMetrics(), []error{err}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
@@ -358,7 +378,14 @@ func (r *TestCaseRunner) executeTest(tc model.TestCase, ruleCtx *model.Context,
 	if errs != nil {
 		detailedErrors := detailedErrors(errs, resp)
 		ctxLogger.WithField("errs", detailedErrors).WithFields(logrus.Fields{"result": passText()[result], "ID": tc.ID}).Error("test result validate")
-		return results.NewTestCaseFail(tc.ID, metrics, detailedErrors, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		testResult := results.NewTestCaseFail(tc.ID, metrics, detailedErrors, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+		testResult.Warnings = tc.Warnings
+		testResult.RuleViolations = tc.RuleViolations
+		return testResult
+	}
+
+	if len(tc.Warnings) > 0 {
+		ctxLogger.WithField("warnings", tc.Warnings).Warn("test result warnings")
 	}
 
 	if !result {
@@ -367,7 +394,10 @@ func (r *TestCaseRunner) executeTest(tc model.TestCase, ruleCtx *model.Context,
 		ctxLogger.WithError(err).WithFields(logrus.Fields{"result": passText()[result], "ID": tc.ID}).Info("test result")
 	}
 
-	return results.NewTestCaseResult(tc.ID, result, metrics, []error{}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+	testResult := results.NewTestCaseResult(tc.ID, result, metrics, []error{}, tc.Input.Endpoint, tc.APIName, tc.APIVersion, tc.Detail, tc.RefURI, tc.StatusCode)
+	testResult.Warnings = tc.Warnings
+	testResult.RuleViolations = tc.RuleViolations
+	return testResult
 }
 
 type DetailError struct {
@@ -2,19 +2,17 @@ package executors
 
 import (
 	"bytes"
-	"crypto/tls"
-	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
-	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication/certificates"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/tracer"
 
-	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
 	"gopkg.in/resty.v1"
@@ -24,24 +22,49 @@ import (
 type TestCaseExecutor interface {
 	ExecuteTestCase(r *resty.Request, t *model.TestCase, ctx *model.Context) (*resty.Response, results.Metrics, error)
 	SetCertificates(certificateSigning, certificationTransport authentication.Certificate) error
+	SetPropertyCollector(collector schemaprops.PropertyCollector)
+	SetRecorder(recorder *har.Recorder)
+	SetThrottle(limiter *RequestLimiter, retries ThrottleRetries)
 }
 
 // NewExecutor creates an executor
 func NewExecutor() TestCaseExecutor {
-	return &Executor{}
+	return &Executor{throttleRetries: defaultThrottleRetries}
 }
 
 // Executor - passes request to system under test across an matls connection
 type Executor struct {
-	SigningCert   authentication.Certificate
-	TransportCert authentication.Certificate
+	SigningCert     authentication.Certificate
+	TransportCert   authentication.Certificate
+	collector       schemaprops.PropertyCollector
+	recorder        *har.Recorder
+	limiter         *RequestLimiter
+	throttleRetries ThrottleRetries
 }
 
-// SetCertificates receives transport and signing certificates
+// SetCertificates receives transport and signing certificates, requests are sent on mutual TLS
+// by the HTTP client of the run, see `NewHTTPClient`
 func (e *Executor) SetCertificates(certificateSigning, certificationTransport authentication.Certificate) error {
 	e.SigningCert = certificateSigning
 	e.TransportCert = certificationTransport
-	return e.setupTLSCertificate(e.TransportCert.TLSCert())
+	return nil
+}
+
+// SetPropertyCollector receives the collector gathering the response fields of the run
+func (e *Executor) SetPropertyCollector(collector schemaprops.PropertyCollector) {
+	e.collector = collector
+}
+
+// SetRecorder receives the recorder of the HTTP exchanges of the run
+func (e *Executor) SetRecorder(recorder *har.Recorder) {
+	e.recorder = recorder
+}
+
+// SetThrottle receives the rate limiter of the requests of the run, and the budget retrying
+// requests throttled by the ASPSP
+func (e *Executor) SetThrottle(limiter *RequestLimiter, retries ThrottleRetries) {
+	e.limiter = limiter
+	e.throttleRetries = retries
 }
 
 // ExecuteTestCase - makes this a generic executor
@@ -53,14 +76,14 @@ func (e *Executor) ExecuteTestCase(r *resty.Request, t *model.TestCase, ctx *mod
 
 	e.appMsg(fmt.Sprintf("Execute Testcase: %s: %s", t.ID, t.Name))
 	e.appMsg(fmt.Sprintf("attempting %s %s", r.Method, r.URL))
-	resp, err := r.Execute(r.Method, r.URL)
+	resp, throttled, err := executeThrottled(withTestCaseID(r, t.ID), e.limiter, e.throttleRetries, e.recorder, t.ID, logrus.WithField("module", "Executor").WithField("testcase", t.ID))
 	if err != nil {
 		if resp.StatusCode() == http.StatusFound { // catch status code 302 redirects and pass back as good response
 			header := resp.Header()
 			t.StatusCode = resp.Status()
 			logrus.StandardLogger().Printf("redirection headers: %#v\n", header)
 			e.appMsg(fmt.Sprintf("Response: (%.250s)", resp.String()))
-			return resp, metrics(t, resp), nil
+			return resp, metrics(t, resp, throttled), nil
 		}
 	}
 	t.StatusCode = resp.Status()
@@ -69,66 +92,18 @@ func (e *Executor) ExecuteTestCase(r *resty.Request, t *model.TestCase, ctx *mod
 		elipsis = " ..."
 	}
 	e.appMsg(fmt.Sprintf("Response: (%.450s)%s", resp.String(), elipsis))
-	return resp, metrics(t, resp), err
-}
-
-func metrics(testCase *model.TestCase, response *resty.Response) results.Metrics {
-	return results.NewMetricsFromRestyResponse(testCase, response)
-}
 
-func (e *Executor) setupTLSCertificate(tlsCert tls.Certificate) error {
-	caCertPool, err := x509.SystemCertPool()
-	if err != nil {
-		return errors.New("setupTLSCertificate SystemCertPool:" + err.Error())
-	}
-	if ok := caCertPool.AppendCertsFromPEM(certificates.OpenBankingSandBoxIssuingCA()); !ok {
-		return errors.New("setupTLSCertificate failed to append OpenBankingSandBoxIssuingCA")
-	}
-	if ok := caCertPool.AppendCertsFromPEM(certificates.OpenBankingSandBoxRootCA()); !ok {
-		return errors.New("setupTLSCertificate failed to append OpenBankingSandBoxRootCA")
-	}
-	if ok := caCertPool.AppendCertsFromPEM(certificates.OpenBankingIssuingCA()); !ok {
-		return errors.New("setupTLSCertificate failed to append OpenBankingIssuingCA")
-	}
-	if ok := caCertPool.AppendCertsFromPEM(certificates.OpenBankingRootCA()); !ok {
-		return errors.New("setupTLSCertificate failed to append OpenBankingRootCA")
+	// Gather fields within json response - for reporting
+	if err == nil && e.collector != nil {
+		e.collector.CollectProperties(t.Input.Method, t.Input.Endpoint, resp.String(), resp.StatusCode())
 	}
+	return resp, metrics(t, resp, throttled), err
+}
 
-	tlsConfig := &tls.Config{
-		Certificates:       []tls.Certificate{tlsCert},
-		RootCAs:            caCertPool,
-		InsecureSkipVerify: false,
-		MinVersion:         tls.VersionSSL30,
-		Renegotiation:      tls.RenegotiateFreelyAsClient,
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256, // not available by default however used by OB
-			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
-			tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
-			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
-			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
-			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
-			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_RSA_WITH_RC4_128_SHA,
-			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
-			tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
-			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
-			tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
-			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
-		},
-	}
-	tlsConfig.BuildNameToCertificate()
-	resty.SetTLSClientConfig(tlsConfig)
-	return nil
+func metrics(testCase *model.TestCase, response *resty.Response, throttledRetries int) results.Metrics {
+	m := results.NewMetricsFromRestyResponse(testCase, response)
+	m.ThrottledRetries = throttledRetries
+	return m
 }
 
 func (e *Executor) appMsg(msg string) {
@@ -1,10 +1,16 @@
 package executors
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	resty "gopkg.in/resty.v1"
 )
@@ -152,9 +158,11 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 		require.NoError(err)
 
 		require.NoError(executor.SetCertificates(certificateSigning, certificateTransport))
+		client, err := NewHTTPClient(RunDefinition{SigningCert: certificateSigning, TransportCert: certificateTransport})
+		require.NoError(err)
 
 		// https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp
-		res, err := resty.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
+		res, err := client.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
 		require.NotNil(res)
 		require.NoError(err)
 
@@ -179,9 +187,11 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 		require.NoError(err)
 
 		require.NoError(executor.SetCertificates(certificateSigning, certificateTransport))
+		client, err := NewHTTPClient(RunDefinition{SigningCert: certificateSigning, TransportCert: certificateTransport})
+		require.NoError(err)
 
 		// https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp
-		res, err := resty.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
+		res, err := client.R().Get("https://ob19-rs1.o3bank.co.uk:4501/open-banking/v3.1/aisp")
 		require.NotNil(res)
 		require.NoError(err)
 
@@ -191,3 +201,58 @@ func disableTestExecutor_SetCertificates(t *testing.T) {
 		)
 	})
 }
+
+func TestExecutor_ExecuteTestCase_CollectsProperties(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Data": {"Account": [{"AccountId": "22289"}]}}`))
+	}))
+	defer server.Close()
+
+	collector := schemaprops.MakeCollector()
+	collector.SetCollectorAPIDetails("Account and Transaction API Specification", "v3.1.5")
+	executor := NewExecutor()
+	executor.SetPropertyCollector(collector)
+
+	testCase := model.TestCase{Input: model.Input{Method: "GET", Endpoint: "/open-banking/v3.1/aisp/accounts"}}
+	request := resty.R()
+	request.Method = "GET"
+	request.URL = server.URL + testCase.Input.Endpoint
+
+	_, _, err := executor.ExecuteTestCase(request, &testCase, &model.Context{})
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]int{
+		"GET /open-banking/v3.1/aisp/accounts 200": {
+			"Data":                   0,
+			"Data.Account":           0,
+			"Data.Account.AccountId": 0,
+		},
+	}, collector.GetProperties())
+}
+
+func TestExecutor_ExecuteTestCase_RecordsExchanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Data": {"Account": [{"AccountId": "22289"}]}}`))
+	}))
+	defer server.Close()
+
+	recorder := har.NewRecorder()
+	executor := NewExecutor()
+	executor.SetRecorder(recorder)
+
+	testCase := model.TestCase{ID: "OB-301-ACC-100100", Input: model.Input{Method: "GET", Endpoint: "/open-banking/v3.1/aisp/accounts"}}
+	request := resty.R().SetHeader("Authorization", "Bearer secret")
+	request.Method = "GET"
+	request.URL = server.URL + testCase.Input.Endpoint
+
+	_, _, err := executor.ExecuteTestCase(request, &testCase, &model.Context{})
+
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, recorder.Since(0, "OB-301-ACC-100100"))
+	entries := recorder.HAR().Log.Entries
+	assert.Equal(t, server.URL+"/open-banking/v3.1/aisp/accounts", entries[0].Request.URL)
+	assert.Contains(t, entries[0].Request.Headers, har.NameValue{Name: "Authorization", Value: "Bearer " + har.Redacted})
+	assert.Equal(t, `{"Data": {"Account": [{"AccountId": "22289"}]}}`, entries[0].Response.Content.Text)
+}
@@ -3,6 +3,7 @@ package executors
 import (
 	"strings"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 	"github.com/pkg/errors"
@@ -12,14 +13,15 @@ import (
 )
 
 // GetDynamicResourceIds retrieves the accounts and statements resource ids for the current token
-func GetDynamicResourceIds(tokenName, token string, ctx *model.Context, requiredTokens []manifest.RequiredTokens) error {
+// The accounts request is sent by client and recorded by recorder, which may be nil
+func GetDynamicResourceIds(tokenName, token string, ctx *model.Context, requiredTokens []manifest.RequiredTokens, client *resty.Client, recorder *har.Recorder) error {
 	logger := logrus.WithFields(logrus.Fields{
 		"module":    "GetDynamicResourceIds",
 		"tokenName": tokenName,
 		"token":     token,
 	})
 
-	err := getDynamicResourceIds(tokenName, token, ctx, logger, requiredTokens)
+	err := getDynamicResourceIds(tokenName, token, ctx, logger, requiredTokens, client, recorder)
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"err": err,
@@ -29,11 +31,14 @@ func GetDynamicResourceIds(tokenName, token string, ctx *model.Context, required
 	return nil
 }
 
-func getDynamicResourceIds(tokenName, token string, ctx *model.Context, logger *logrus.Entry, requiredTokens []manifest.RequiredTokens) error {
+func getDynamicResourceIds(tokenName, token string, ctx *model.Context, logger *logrus.Entry, requiredTokens []manifest.RequiredTokens, client *resty.Client, recorder *har.Recorder) error {
 
 	if !strings.HasPrefix(tokenName, "account") {
 		return nil
 	}
+	if client == nil {
+		return errors.New("no HTTP client for dynamic_resource_id call")
+	}
 
 	resourceBaseURL, err := ctx.GetString("resource_server")
 	if err != nil {
@@ -50,12 +55,13 @@ func getDynamicResourceIds(tokenName, token string, ctx *model.Context, logger *
 
 	accountsEndpoint := resourceBaseURL + "/open-banking/" + apiVersion + "/aisp/accounts"
 	var resp *resty.Response
-	resp, err = resty.R().
+	resp, err = client.R().
 		SetHeader("Authorization", "Bearer "+token).
 		SetHeader("X-Fapi-Financial-Id", xFapiFinancialID).
 		SetHeader("X-Fapi-Interaction-Id", "c4405450-febe-11e8-80a5-0fcebb157400").
 		SetHeader("X-Fcs-Testcase-Id", "GetDynamicResourceIdsAccounts").
 		Get(accountsEndpoint)
+	recorder.Record("GetDynamicResourceIdsAccounts", resp)
 
 	if err != nil {
 		logger.Errorln("error calling /accounts for account number dynamic resource", err)
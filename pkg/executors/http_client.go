@@ -0,0 +1,132 @@
+package executors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication/certificates"
+)
+
+// maxRedirects - redirects followed by the HTTP clients of runs without a redirect policy
+const maxRedirects = 15
+
+// NewHTTPClient - the HTTP client of a run, on mutual TLS with the transport certificate
+// of the run, trusting the system roots, the Open Banking roots and the run trust store
+// Requests are traced as configured on resty's default client. When the run is replayed, every
+// request is served a recorded response, which redirects aren't followed
+func NewHTTPClient(definition RunDefinition) (*resty.Client, error) {
+	rootCAs, err := runRootCAs(definition.TrustStore)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:            rootCAs,
+		InsecureSkipVerify: false,
+		MinVersion:         tls.VersionSSL30,
+		Renegotiation:      tls.RenegotiateFreelyAsClient,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256, // not available by default however used by OB
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_RC4_128_SHA,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA256,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		},
+	}
+	if definition.TransportCert != nil {
+		tlsConfig.Certificates = []tls.Certificate{definition.TransportCert.TLSCert()}
+	}
+
+	client := resty.New().
+		SetTLSClientConfig(tlsConfig).
+		SetRedirectPolicy(redirectPolicy(definition)).
+		SetDebug(resty.DefaultClient.Debug).
+		SetTimeout(definition.Timeout)
+	client.Log = resty.DefaultClient.Log
+	if definition.Proxy != "" {
+		if _, err := url.Parse(definition.Proxy); err != nil {
+			return nil, errors.Wrapf(err, "executors.NewHTTPClient: invalid proxy %q", definition.Proxy)
+		}
+		client.SetProxy(definition.Proxy)
+	}
+	if definition.UserAgent != "" {
+		client.SetHeader("User-Agent", definition.UserAgent)
+	}
+	if definition.Replay != nil {
+		// set last, the TLS config and proxy are set on the transport sending requests
+		client.SetTransport(newReplayTransport(*definition.Replay)).
+			SetRedirectPolicy(resty.RedirectPolicyFunc(func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			}))
+	}
+	return client, nil
+}
+
+// redirectPolicy - the redirects followed by the HTTP client of definition
+func redirectPolicy(definition RunDefinition) resty.RedirectPolicy {
+	if definition.RedirectPolicy == nil {
+		return resty.FlexibleRedirectPolicy(maxRedirects)
+	}
+	return definition.RedirectPolicy
+}
+
+// withoutRedirects - a client sending the requests of client without following redirects,
+// e.g.: to read the Location of an authorization response
+func withoutRedirects(client *resty.Client) *resty.Client {
+	httpClient := *client.GetClient()
+	noRedirects := resty.NewWithClient(&httpClient).
+		SetRedirectPolicy(resty.RedirectPolicyFunc(func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		})).
+		SetDebug(client.Debug)
+	noRedirects.Log = client.Log
+	for key, values := range client.Header {
+		noRedirects.Header[key] = values
+	}
+	return noRedirects
+}
+
+// runRootCAs - the system roots, the Open Banking roots and the PEM certificates of a trust store
+func runRootCAs(trustStore []byte) (*x509.CertPool, error) {
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, errors.Wrap(err, "executors.NewHTTPClient: SystemCertPool")
+	}
+	for name, pem := range map[string][]byte{
+		"OpenBankingSandBoxIssuingCA": certificates.OpenBankingSandBoxIssuingCA(),
+		"OpenBankingSandBoxRootCA":    certificates.OpenBankingSandBoxRootCA(),
+		"OpenBankingIssuingCA":        certificates.OpenBankingIssuingCA(),
+		"OpenBankingRootCA":           certificates.OpenBankingRootCA(),
+	} {
+		if ok := rootCAs.AppendCertsFromPEM(pem); !ok {
+			return nil, errors.Errorf("executors.NewHTTPClient: failed to append %s", name)
+		}
+	}
+	if len(trustStore) > 0 && !rootCAs.AppendCertsFromPEM(trustStore) {
+		return nil, errors.New("executors.NewHTTPClient: no certificates found in the trust store")
+	}
+	return rootCAs, nil
+}
@@ -0,0 +1,146 @@
+package executors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+// clientCertServer - a TLS server responding with the serial number of the client certificate
+func clientCertServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			fmt.Fprint(w, "none")
+			return
+		}
+		fmt.Fprint(w, r.TLS.PeerCertificates[0].SerialNumber.String())
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func trustStore(server *httptest.Server) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+func serialNumber(t *testing.T, cert authentication.Certificate) string {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.TLSCert().Certificate[0])
+	require.NoError(t, err)
+	return leaf.SerialNumber.String()
+}
+
+func TestNewHTTPClient_TrustStore(t *testing.T) {
+	server := clientCertServer(t)
+
+	client, err := NewHTTPClient(RunDefinition{})
+	require.NoError(t, err)
+	_, err = client.R().Get(server.URL)
+	assert.Error(t, err, "server certificate isn't trusted")
+
+	client, err = NewHTTPClient(RunDefinition{TrustStore: trustStore(server)})
+	require.NoError(t, err)
+	resp, err := client.R().Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "none", resp.String())
+}
+
+func TestNewHTTPClient_InvalidTrustStore(t *testing.T) {
+	_, err := NewHTTPClient(RunDefinition{TrustStore: []byte("not a certificate")})
+	assert.EqualError(t, err, "executors.NewHTTPClient: no certificates found in the trust store")
+}
+
+func TestNewHTTPClient_InvalidProxy(t *testing.T) {
+	_, err := NewHTTPClient(RunDefinition{Proxy: "://proxy"})
+	assert.Error(t, err)
+}
+
+func TestNewHTTPClient_RunsDoNotShareCertificates(t *testing.T) {
+	server := clientCertServer(t)
+	signing, err := authentication.NewCertificate(signingPublic, signingPrivate)
+	require.NoError(t, err)
+	transport, err := authentication.NewCertificate(transportPublic, transportPrivate)
+	require.NoError(t, err)
+	require.NotEqual(t, serialNumber(t, signing), serialNumber(t, transport))
+
+	first, err := NewHTTPClient(RunDefinition{TransportCert: signing, TrustStore: trustStore(server)})
+	require.NoError(t, err)
+	second, err := NewHTTPClient(RunDefinition{TransportCert: transport, TrustStore: trustStore(server)})
+	require.NoError(t, err)
+
+	resp, err := first.R().Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, serialNumber(t, signing), resp.String())
+
+	resp, err = second.R().Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, serialNumber(t, transport), resp.String())
+
+	executor := NewExecutor()
+	require.NoError(t, executor.SetCertificates(signing, transport))
+	client, err := NewHTTPClient(RunDefinition{TrustStore: trustStore(server)})
+	require.NoError(t, err)
+	resp, err = client.R().Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "none", resp.String(), "executor certificates leaked into another client")
+}
+
+func TestNewHTTPClient_UserAgentAndTimeout(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(200 * time.Millisecond)
+		}
+		fmt.Fprint(w, r.UserAgent())
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClient(RunDefinition{
+		TrustStore: trustStore(server),
+		Timeout:    50 * time.Millisecond,
+		UserAgent:  "OpenBankingFCS/test",
+	})
+	require.NoError(t, err)
+
+	resp, err := client.R().Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, "OpenBankingFCS/test", resp.String())
+
+	_, err = client.R().Get(server.URL + "/slow")
+	assert.Error(t, err)
+}
+
+func TestNewHTTPClient_FollowsRedirects(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClient(RunDefinition{TrustStore: trustStore(server), UserAgent: "OpenBankingFCS/test"})
+	require.NoError(t, err)
+	resp, err := client.R().Get(server.URL + "/redirect")
+	require.NoError(t, err)
+	assert.Equal(t, "/target", resp.String())
+
+	resp, err = withoutRedirects(client).R().Get(server.URL + "/redirect")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode())
+	assert.Equal(t, "/target", resp.Header().Get("Location"))
+	assert.Equal(t, "OpenBankingFCS/test", resp.Request.Header.Get("User-Agent"))
+}
@@ -4,6 +4,7 @@ package mocks
 
 import (
 	results "bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	har "bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	mock "github.com/stretchr/testify/mock"
 )
 
@@ -25,6 +26,25 @@ func (_m *DaemonController) ResponseFieldsJSON() string {
 	return ""
 }
 
+// AddExchanges provides a mock function with given fields: exchanges
+func (_m *DaemonController) AddExchanges(exchanges har.HAR) {
+	_m.Called(exchanges)
+}
+
+// Exchanges provides a mock function with given fields:
+func (_m *DaemonController) Exchanges() har.HAR {
+	ret := _m.Called()
+
+	var r0 har.HAR
+	if rf, ok := ret.Get(0).(func() har.HAR); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(har.HAR)
+	}
+
+	return r0
+}
+
 // AllResults provides a mock function with given fields:
 func (_m *DaemonController) AllResults() []results.TestCase {
 	ret := _m.Called()
@@ -7,22 +7,26 @@ import (
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
 )
 
 func getPaymentConsents(definition RunDefinition, requiredTokens []manifest.RequiredTokens, ctx *model.Context) (TokenConsentIDs, error) {
-	executor := &Executor{}
+	executor := NewExecutor()
 	err := executor.SetCertificates(definition.SigningCert, definition.TransportCert)
 	if err != nil {
 		logrus.Error("error running payment consent acquisition async: " + err.Error())
 		return nil, err
 	}
+	executor.SetPropertyCollector(definition.Collector)
+	executor.SetRecorder(definition.Recorder)
+	executor.SetThrottle(definition.RateLimiter, definition.ThrottleRetries)
 
 	logrus.Debugf("we have %d required tokens", len(requiredTokens))
 	for _, rt := range requiredTokens {
 		logrus.Tracef("%#v", rt)
 	}
 
-	requiredTokens, err = runPaymentConsents(requiredTokens, ctx, executor)
+	requiredTokens, err = runPaymentConsents(definition, requiredTokens, ctx, executor)
 	if err != nil {
 		logrus.Errorf("getPaymentConsents error: " + err.Error())
 	}
@@ -37,7 +41,7 @@ func getPaymentConsents(definition RunDefinition, requiredTokens []manifest.Requ
 	return consentItems, err
 }
 
-func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, executor *Executor) ([]manifest.RequiredTokens, error) {
+func runPaymentConsents(definition RunDefinition, rt []manifest.RequiredTokens, ctx *model.Context, executor TestCaseExecutor) ([]manifest.RequiredTokens, error) {
 	localCtx := model.Context{}
 	localCtx.PutContext(ctx)
 	localCtx.PutString("scope", "payments")
@@ -53,43 +57,15 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 	logrus.Tracef("runpaymentconsent auth %s", authMethod)
 
 	if err != nil {
-		authMethod = "client_secret_basic"
+		authMethod = authentication.ClientSecretBasic
 	}
 
-	switch authMethod {
-	case authentication.ClientSecretBasic:
-		tc.Input.SetHeader("authorization", "Basic $basic_authentication")
-	case authentication.PrivateKeyJwt:
-		clientID, err := ctx.GetString("client_id")
-		if err != nil {
-			return nil, errors.Wrap(err, "cannot find client_id for private_key_jwt form field")
-		}
-		tokenEndpoint, err := ctx.GetString("token_endpoint")
-		if err != nil {
-			return nil, errors.Wrap(err, "cannot find token_endpoint for private_key_jwt form field")
-		}
-		if tc.Input.Claims == nil {
-			tc.Input.Claims = map[string]string{}
-		}
-		tc.Input.Claims["iss"] = clientID
-		tc.Input.Claims["sub"] = clientID
-		tc.Input.Claims["aud"] = tokenEndpoint
-		clientAssertion, err := tc.Input.GenerateRequestToken(ctx)
-		if err != nil {
-			return nil, errors.Wrap(err, "cannot generate request token for private_key_jwt form field")
-		}
-		tc.Input.SetFormField(authentication.ClientAssertionType, authentication.ClientAssertionTypeValue)
-		tc.Input.SetFormField(authentication.ClientAssertion, clientAssertion)
-	case authentication.TlsClientAuth:
-		clientid, err := ctx.GetString("client_id")
-		if err != nil {
-			logrus.Warn("cannot locate client_id for tls_client_auth form field")
-		}
-		tc.Input.SetFormField("client_id", clientid)
+	if err := setClientAuthentication(&tc, ctx, authMethod); err != nil {
+		return nil, errors.Wrap(err, "payment PSU consent client credentials grant")
 	}
 
 	tc.ProcessReplacementFields(&localCtx, true)
-	err = executePaymentTest(&tc, &localCtx, executor)
+	err = executePaymentTest(&tc, &localCtx, definition.HTTPClient, executor)
 	if err != nil {
 		return nil, errors.New("Payment PSU consent execute clientCredential grant testcase failed :" + err.Error())
 	}
@@ -112,7 +88,7 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 		test.InjectBearerToken(bearerToken) //client credential grant token
 		test.Input.Headers["Content-Type"] = "application/json"
 
-		err = executePaymentTest(&test, &localCtx, executor)
+		err = executePaymentTest(&test, &localCtx, definition.HTTPClient, executor)
 		if err != nil {
 			return nil, errors.New("Payment PSU consent test case failed " + err.Error())
 		}
@@ -139,7 +115,7 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 		}
 
 		localCtx.DumpContext("before exchange", "token_name", "consent_id")
-		err = executePaymentTest(&exchange, &localCtx, executor)
+		err = executePaymentTest(&exchange, &localCtx, definition.HTTPClient, executor)
 		if err != nil {
 			return nil, errors.New("Payment PSU consent exchange code failed " + err.Error())
 		}
@@ -147,6 +123,10 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 		if err != nil {
 			return nil, errors.New("Payment PSU exchange test case failed - cannot find `consent_url` in context " + err.Error())
 		}
+		v.ConsentURL, err = definition.PushedAuthorization.Push(ctx, "payments", v.ConsentURL, definition.HTTPClient, definition.Recorder)
+		if err != nil {
+			return nil, errors.Wrap(err, "Payment PSU consent push authorization request failed")
+		}
 		localCtx.Delete("consent_url")
 		ctx.PutContext(&localCtx)
 		rt[k] = v
@@ -162,8 +142,8 @@ func runPaymentConsents(rt []manifest.RequiredTokens, ctx *model.Context, execut
 	return rt, nil
 }
 
-func executePaymentTest(tc *model.TestCase, ctx *model.Context, executor *Executor) error {
-	req, err := tc.Prepare(ctx)
+func executePaymentTest(tc *model.TestCase, ctx *model.Context, client *resty.Client, executor TestCaseExecutor) error {
+	req, err := tc.Prepare(ctx, client)
 	if err != nil {
 		logrus.Errorf("preparing to execute test %s: %s", tc.ID, err.Error())
 		return err
@@ -183,19 +163,11 @@ func executePaymentTest(tc *model.TestCase, ctx *model.Context, executor *Execut
 }
 
 func readClientCredentialGrant() (model.TestCase, error) {
-	sc, err := model.LoadTestCaseFromJSONFile("components/clientcredentialgrant.json")
-	if err != nil {
-		sc, err = model.LoadTestCaseFromJSONFile("../../components/clientcredentialgrant.json")
-	}
-	return sc, err
+	return model.LoadTestCaseFromJSONFile("components/clientcredentialgrant.json")
 }
 
 func readPsuExchange() (model.TestCase, error) {
-	sc, err := model.LoadTestCaseFromJSONFile("components/psu_exchange.json")
-	if err != nil {
-		sc, err = model.LoadTestCaseFromJSONFile("../../components/psu_exchange.json")
-	}
-	return sc, err
+	return model.LoadTestCaseFromJSONFile("components/psu_exchange.json")
 }
 
 func (r *TestCaseRunner) executePaymentConsent(tc model.TestCase, ruleCtx *model.Context, log *logrus.Entry) (bool, []string) {
@@ -0,0 +1,390 @@
+package executors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// Context keys of pushed authorization requests, put by the journey
+const (
+	ctxPushedAuthorizationEndpoint  = "pushed_authorization_request_endpoint"
+	ctxPushedAuthorizationSpecTypes = "pushedAuthorizationSpecTypes"
+)
+
+const (
+	// pushedAuthorizationAPIName - the API the results of the PAR conformance tests are reported under
+	pushedAuthorizationAPIName = "Pushed Authorization Requests"
+	pushedAuthorizationRefURI  = "https://tools.ietf.org/html/draft-ietf-oauth-par"
+	// maxRequestURIExpiryWait - the longest the expiry of a request_uri is waited for, the
+	// expiry isn't tested when the request_uri lives longer
+	maxRequestURIExpiryWait = 90 * time.Second
+)
+
+type pushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// PushedAuthorization - pushes the authorization requests of consent urls to the
+// `pushed_authorization_request_endpoint` of the ASPSP, for the spec types of the discovery
+// items enabling it. The PAR conformance tests run before the first request is pushed, but for
+// the expiry test, of which the request_uri is pushed then and tested by `TestExpiry`
+type PushedAuthorization struct {
+	lock          *sync.Mutex
+	tested        bool
+	results       []results.TestCase
+	expiry        *expiryTest
+	maxExpiryWait time.Duration
+	now           func() time.Time
+	sleep         func(time.Duration)
+}
+
+// expiryTest - the request_uri the expiry test pushed, rejected once expired
+type expiryTest struct {
+	env        parTestEnv
+	requestURI string
+	expires    time.Time
+}
+
+// NewPushedAuthorization -
+func NewPushedAuthorization() *PushedAuthorization {
+	return &PushedAuthorization{
+		lock:          &sync.Mutex{},
+		maxExpiryWait: maxRequestURIExpiryWait,
+		now:           time.Now,
+		sleep:         time.Sleep,
+	}
+}
+
+// Push - the consent url the PSU is redirected to for the authorization request of consentURL.
+// When the discovery item of specType enables PAR, the request is pushed and the url only has
+// the `client_id` and the `request_uri` of the pushed request, else consentURL is returned.
+// The requests are sent by client and recorded by recorder.
+// A nil PushedAuthorization pushes requests without running the PAR conformance tests
+func (p *PushedAuthorization) Push(ctx *model.Context, specType, consentURL string, client *resty.Client, recorder *har.Recorder) (string, error) {
+	if !pushedAuthorizationEnabled(ctx, specType) {
+		return consentURL, nil
+	}
+	endpoint, err := ctx.GetString(ctxPushedAuthorizationEndpoint)
+	if err != nil || endpoint == "" {
+		return "", errors.Errorf("executors.Push: %s authorization requests are pushed without a pushed_authorization_request_endpoint", specType)
+	}
+	authorizationURL, err := url.Parse(consentURL)
+	if err != nil {
+		return "", errors.Wrap(err, "executors.Push: cannot parse consent url")
+	}
+	params := authorizationURL.Query()
+
+	if p != nil {
+		p.test(ctx, endpoint, authorizationURL, client, recorder)
+	}
+
+	pushed, _, err := pushAuthorizationRequest(ctx, endpoint, params, client, recorder)
+	if err != nil {
+		return "", errors.Wrap(err, "executors.Push")
+	}
+	logrus.WithFields(logrus.Fields{
+		"specType":   specType,
+		"requestURI": pushed.RequestURI,
+	}).Debug("authorization request pushed")
+	return requestURIConsentURL(authorizationURL, params.Get("client_id"), pushed.RequestURI), nil
+}
+
+// Results - the results of the PAR conformance tests, none when no request was pushed
+func (p *PushedAuthorization) Results() []results.TestCase {
+	if p == nil {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]results.TestCase{}, p.results...)
+}
+
+// pushedAuthorizationEnabled - whether the authorization requests of specType are pushed
+func pushedAuthorizationEnabled(ctx *model.Context, specType string) bool {
+	specTypes, err := ctx.GetStringSlice(ctxPushedAuthorizationSpecTypes)
+	if err != nil {
+		return false
+	}
+	for _, enabled := range specTypes {
+		if enabled == specType {
+			return true
+		}
+	}
+	return false
+}
+
+// pushAuthorizationRequest - posts the authorization request of params to endpoint, authenticating
+// the client with the `token_endpoint_auth_method` of ctx
+func pushAuthorizationRequest(ctx *model.Context, endpoint string, params url.Values, client *resty.Client, recorder *har.Recorder) (*pushedAuthorizationResponse, *resty.Response, error) {
+	form := map[string]string{}
+	for key := range params {
+		form[key] = params.Get(key)
+	}
+	request, err := clientAuthenticatedRequest(ctx, client, form)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := request.Post(endpoint)
+	recorder.Record("PushedAuthorizationRequest", resp)
+	if err != nil {
+		return nil, resp, err
+	}
+	if resp.StatusCode() != http.StatusCreated {
+		return nil, resp, fmt.Errorf("bad status code %d from pushed authorization request endpoint %q", resp.StatusCode(), endpoint)
+	}
+	pushed := &pushedAuthorizationResponse{}
+	if err := json.Unmarshal(resp.Body(), pushed); err != nil {
+		return nil, resp, errors.Wrap(err, "cannot unmarshal pushed authorization response")
+	}
+	if pushed.RequestURI == "" {
+		return nil, resp, errors.New("request_uri missing from pushed authorization response")
+	}
+	if pushed.ExpiresIn <= 0 {
+		return nil, resp, errors.New("expires_in missing from pushed authorization response")
+	}
+	return pushed, resp, nil
+}
+
+// requestURIConsentURL - the url of the authorization endpoint of authorizationURL with
+// the `request_uri` of a pushed authorization request
+func requestURIConsentURL(authorizationURL *url.URL, clientID, requestURI string) string {
+	consentURL := *authorizationURL
+	query := url.Values{}
+	query.Set("client_id", clientID)
+	query.Set("request_uri", requestURI)
+	consentURL.RawQuery = query.Encode()
+	return consentURL.String()
+}
+
+// parTest - a pushed authorization request conformance test, a warning is reported
+// when it passes without testing the ASPSP, or with the ASPSP not following a recommendation
+type parTest struct {
+	id     string
+	detail string
+	run    func(env parTestEnv) (warning string, err error)
+}
+
+// parTestEnv - the authorization request the PAR conformance tests push, and where to
+type parTestEnv struct {
+	ctx              *model.Context
+	endpoint         string
+	authorizationURL *url.URL
+	client           *resty.Client
+	recorder         *har.Recorder
+}
+
+var parTests = []parTest{
+	{
+		id:     "#par001",
+		detail: "Pushed authorization request is accepted with a request_uri and its expiry",
+		run: func(env parTestEnv) (string, error) {
+			_, _, err := pushAuthorizationRequest(env.ctx, env.endpoint, env.authorizationURL.Query(), env.client, env.recorder)
+			return "", err
+		},
+	},
+	{
+		id:     "#par002",
+		detail: "Pushed authorization request with a request_uri is rejected",
+		run: func(env parTestEnv) (string, error) {
+			params := env.authorizationURL.Query()
+			params.Set("request_uri", "urn:ietf:params:oauth:request_uri:conformance-suite")
+			_, resp, err := pushAuthorizationRequest(env.ctx, env.endpoint, params, env.client, env.recorder)
+			if err == nil {
+				return "", errors.New("request_uri accepted in a pushed authorization request")
+			}
+			return "", expectStatus(resp, err, http.StatusBadRequest)
+		},
+	},
+	{
+		id:     "#par003",
+		detail: "Pushed authorization request without client authentication is rejected",
+		run: func(env parTestEnv) (string, error) {
+			authMethod, _ := env.ctx.GetString("token_endpoint_auth_method")
+			if authMethod == authentication.TlsClientAuth {
+				return "not tested, the client authenticates with its transport certificate", nil
+			}
+			// client_id identifies the client, it doesn't authenticate it
+			form := map[string]string{}
+			params := env.authorizationURL.Query()
+			params.Del("client_id")
+			for key := range params {
+				form[key] = params.Get(key)
+			}
+			resp, err := env.client.R().
+				SetHeader("accept", "application/json").
+				SetFormData(form).
+				Post(env.endpoint)
+			env.recorder.Record("PushedAuthorizationRequest without client authentication", resp)
+			return "", expectStatus(resp, err, http.StatusBadRequest, http.StatusUnauthorized)
+		},
+	},
+	{
+		id:     "#par004",
+		detail: "Pushed authorization request_uri can be used once",
+		run: func(env parTestEnv) (string, error) {
+			pushed, _, err := pushAuthorizationRequest(env.ctx, env.endpoint, env.authorizationURL.Query(), env.client, env.recorder)
+			if err != nil {
+				return "", err
+			}
+			consentURL := requestURIConsentURL(env.authorizationURL, env.authorizationURL.Query().Get("client_id"), pushed.RequestURI)
+			rejected, err := authorizationRejected(env, consentURL)
+			if err != nil {
+				return "", err
+			}
+			if rejected {
+				return "", errors.New("request_uri rejected the first time it is used")
+			}
+			rejected, err = authorizationRejected(env, consentURL)
+			if err != nil {
+				return "", err
+			}
+			if !rejected {
+				// reuse should be prevented, it isn't required
+				return "request_uri accepted the second time it is used", nil
+			}
+			return "", nil
+		},
+	},
+}
+
+// expiryTestID, expiryTestDetail - the PAR conformance test of the expiry of a request_uri, see `TestExpiry`
+const (
+	expiryTestID     = "#par005"
+	expiryTestDetail = "Expired pushed authorization request_uri is rejected"
+)
+
+// test - runs the PAR conformance tests with the authorization request of authorizationURL
+// the first time a request is pushed
+func (p *PushedAuthorization) test(ctx *model.Context, endpoint string, authorizationURL *url.URL, client *resty.Client, recorder *har.Recorder) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.tested {
+		return
+	}
+	p.tested = true
+
+	env := parTestEnv{
+		ctx:              ctx,
+		endpoint:         endpoint,
+		authorizationURL: authorizationURL,
+		client:           client,
+		recorder:         recorder,
+	}
+	for _, test := range parTests {
+		warning, err := test.run(env)
+		p.results = append(p.results, parTestResult(test.id, test.detail, endpoint, warning, err))
+	}
+
+	// the expiry is waited for by `TestExpiry`, not while the consents are acquired
+	pushed, _, err := pushAuthorizationRequest(ctx, endpoint, authorizationURL.Query(), client, recorder)
+	if err != nil {
+		p.results = append(p.results, parTestResult(expiryTestID, expiryTestDetail, endpoint, "", err))
+		return
+	}
+	expiry := time.Duration(pushed.ExpiresIn+1) * time.Second
+	if expiry > p.maxExpiryWait {
+		warning := fmt.Sprintf("not tested, request_uri expires in %ds, longer than %s", pushed.ExpiresIn, p.maxExpiryWait)
+		p.results = append(p.results, parTestResult(expiryTestID, expiryTestDetail, endpoint, warning, nil))
+		return
+	}
+	p.expiry = &expiryTest{env: env, requestURI: pushed.RequestURI, expires: p.now().Add(expiry)}
+}
+
+// TestExpiry - tests that the request_uri pushed by the PAR conformance tests is rejected once
+// expired, waiting for its expiry. It's called when the test cases run, after the consents
+// were acquired, as the wait is up to `maxRequestURIExpiryWait`
+func (p *PushedAuthorization) TestExpiry() {
+	if p == nil {
+		return
+	}
+	p.lock.Lock()
+	test := p.expiry
+	p.expiry = nil
+	p.lock.Unlock()
+	if test == nil {
+		return
+	}
+
+	if wait := test.expires.Sub(p.now()); wait > 0 {
+		p.sleep(wait)
+	}
+	env := test.env
+	consentURL := requestURIConsentURL(env.authorizationURL, env.authorizationURL.Query().Get("client_id"), test.requestURI)
+	rejected, err := authorizationRejected(env, consentURL)
+	if err == nil && !rejected {
+		err = errors.New("expired request_uri accepted")
+	}
+	result := parTestResult(expiryTestID, expiryTestDetail, env.endpoint, "", err)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.results = append(p.results, result)
+}
+
+// parTestResult - the result of the PAR conformance test id
+func parTestResult(id, detail, endpoint, warning string, err error) results.TestCase {
+	errs := []error{}
+	if err != nil {
+		errs = append(errs, err)
+	}
+	result := results.NewTestCaseResult(id, err == nil, results.NoMetrics(), errs, endpoint, pushedAuthorizationAPIName, "", detail, pushedAuthorizationRefURI, "")
+	if warning != "" {
+		result.Warnings = append(result.Warnings, warning)
+	}
+	logrus.WithFields(logrus.Fields{
+		"id":      id,
+		"pass":    result.Pass,
+		"err":     err,
+		"warning": warning,
+	}).Debug("pushed authorization request conformance test")
+	return result
+}
+
+// expectStatus - an error unless resp has one of statuses, err is the error of
+// sending the request of resp
+func expectStatus(resp *resty.Response, err error, statuses ...int) error {
+	if resp == nil || resp.RawResponse == nil {
+		return errors.Wrap(err, "no response")
+	}
+	for _, status := range statuses {
+		if resp.StatusCode() == status {
+			return nil
+		}
+	}
+	return fmt.Errorf("status code %d, expected one of %v", resp.StatusCode(), statuses)
+}
+
+// authorizationRejected - whether the authorization endpoint rejects the request of consentURL,
+// with an error status or redirecting with an `error`. The redirect isn't followed
+func authorizationRejected(env parTestEnv, consentURL string) (bool, error) {
+	resp, err := withoutRedirects(env.client).R().
+		SetHeader("accept", "*/*").
+		Get(consentURL)
+	env.recorder.Record("PushedAuthorizationRequest authorization", resp)
+	if resp == nil || resp.RawResponse == nil {
+		return false, errors.Wrap(err, "no response from the authorization endpoint")
+	}
+	if resp.StatusCode() >= http.StatusBadRequest {
+		return true, nil
+	}
+	location, err := url.Parse(resp.Header().Get("Location"))
+	if err != nil {
+		return false, errors.Wrap(err, "cannot parse authorization response location")
+	}
+	fragment, _ := url.ParseQuery(location.Fragment)
+	return location.Query().Get("error") != "" || fragment.Get("error") != "", nil
+}
@@ -0,0 +1,208 @@
+package executors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// parServer - an authorisation server with a pushed authorization request endpoint, of which
+// request URIs can be reused when reusable. Clients authenticate with client_secret_basic, or
+// tls_client_auth trusting the client_id
+type parServer struct {
+	lock     sync.Mutex
+	pushed   map[string]url.Values
+	used     map[string]bool
+	expired  map[string]bool
+	reusable bool
+}
+
+// expire - expires the request URIs pushed
+func (as *parServer) expire() {
+	as.lock.Lock()
+	defer as.lock.Unlock()
+	for requestURI := range as.pushed {
+		as.expired[requestURI] = true
+	}
+}
+
+func newPARServer(t *testing.T, reusable bool) (*httptest.Server, *parServer) {
+	as := &parServer{pushed: map[string]url.Values{}, used: map[string]bool{}, expired: map[string]bool{}, reusable: reusable}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/par", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.Header.Get("Authorization") != "Basic basic-auth" && r.PostForm.Get("client_id") != "client" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.PostForm.Get("request_uri") != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		as.lock.Lock()
+		requestURI := fmt.Sprintf("urn:ietf:params:oauth:request_uri:%d", len(as.pushed))
+		as.pushed[requestURI] = r.PostForm
+		as.lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		require.NoError(t, json.NewEncoder(w).Encode(pushedAuthorizationResponse{RequestURI: requestURI, ExpiresIn: 1}))
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		requestURI := r.URL.Query().Get("request_uri")
+		as.lock.Lock()
+		defer as.lock.Unlock()
+		params, ok := as.pushed[requestURI]
+		if !ok || as.expired[requestURI] || (as.used[requestURI] && !as.reusable) {
+			http.Redirect(w, r, "https://tpp.example.com/callback#error=invalid_request_uri", http.StatusFound)
+			return
+		}
+		as.used[requestURI] = true
+		http.Redirect(w, r, "https://tpp.example.com/callback#code=code&state="+params.Get("state"), http.StatusFound)
+	})
+	return httptest.NewServer(mux), as
+}
+
+func parContext(server *httptest.Server, specTypes ...string) *model.Context {
+	ctx := tokenContext(server)
+	ctx.PutString(ctxPushedAuthorizationEndpoint, server.URL+"/par")
+	ctx.PutStringSlice(ctxPushedAuthorizationSpecTypes, specTypes)
+	return ctx
+}
+
+func newTestPushedAuthorization(as *parServer, slept *time.Duration) *PushedAuthorization {
+	pushed := NewPushedAuthorization()
+	now := time.Now()
+	pushed.now = func() time.Time { return now }
+	pushed.sleep = func(d time.Duration) {
+		*slept += d
+		as.expire()
+	}
+	return pushed
+}
+
+func resultsByID(testResults []results.TestCase) map[string]results.TestCase {
+	byID := map[string]results.TestCase{}
+	for _, result := range testResults {
+		byID[result.Id] = result
+	}
+	return byID
+}
+
+func TestPushedAuthorization_Push(t *testing.T) {
+	server, as := newPARServer(t, false)
+	defer server.Close()
+	ctx := parContext(server, "accounts")
+	consentURL := server.URL + "/authorize?client_id=client&response_type=code&state=Token001&request=signed"
+
+	var slept time.Duration
+	pushed := newTestPushedAuthorization(as, &slept)
+	notPushed, err := pushed.Push(ctx, "payments", consentURL, resty.New(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, consentURL, notPushed, "PAR not enabled for payments")
+	assert.Empty(t, pushed.Results())
+
+	pushedURL, err := pushed.Push(ctx, "accounts", consentURL, resty.New(), nil)
+	require.NoError(t, err)
+	parsed, err := url.Parse(pushedURL)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, url.Values{"client_id": {"client"}, "request_uri": {"urn:ietf:params:oauth:request_uri:3"}}, parsed.Query())
+	assert.Zero(t, slept, "request_uri expiry not waited for while pushing")
+	assert.Len(t, pushed.Results(), 4)
+
+	pushed.TestExpiry()
+	assert.Equal(t, 2*time.Second, slept, "request_uri expiry waited for")
+	testResults := pushed.Results()
+	require.Len(t, testResults, 5)
+	for _, result := range testResults {
+		assert.True(t, result.Pass, "%s %v", result.Id, result.Fail)
+		assert.Equal(t, pushedAuthorizationAPIName, result.API)
+	}
+
+	_, err = pushed.Push(ctx, "accounts", consentURL, resty.New(), nil)
+	require.NoError(t, err)
+	assert.Len(t, pushed.Results(), 5, "conformance tests run once")
+}
+
+func TestPushedAuthorization_TlsClientAuth(t *testing.T) {
+	server, as := newPARServer(t, false)
+	defer server.Close()
+	ctx := parContext(server, "accounts")
+	ctx.PutString("token_endpoint_auth_method", "tls_client_auth")
+
+	var slept time.Duration
+	pushed := newTestPushedAuthorization(as, &slept)
+	_, err := pushed.Push(ctx, "accounts", server.URL+"/authorize?client_id=client&state=Token001", resty.New(), nil)
+	require.NoError(t, err)
+	pushed.TestExpiry()
+
+	for _, result := range pushed.Results() {
+		assert.True(t, result.Pass, "%s %v", result.Id, result.Fail)
+	}
+	par003 := resultsByID(pushed.Results())["#par003"]
+	assert.Equal(t, []string{"not tested, the client authenticates with its transport certificate"}, par003.Warnings)
+}
+
+func TestPushedAuthorization_ReuseAccepted(t *testing.T) {
+	server, as := newPARServer(t, true)
+	defer server.Close()
+	ctx := parContext(server, "cbpii")
+
+	var slept time.Duration
+	pushed := newTestPushedAuthorization(as, &slept)
+	pushed.maxExpiryWait = time.Second
+	_, err := pushed.Push(ctx, "cbpii", server.URL+"/authorize?client_id=client&state=Token001", resty.New(), nil)
+	require.NoError(t, err)
+
+	pushed.TestExpiry()
+
+	byID := resultsByID(pushed.Results())
+	assert.True(t, byID["#par004"].Pass)
+	assert.Equal(t, []string{"request_uri accepted the second time it is used"}, byID["#par004"].Warnings)
+	assert.True(t, byID["#par005"].Pass)
+	assert.Equal(t, []string{"not tested, request_uri expires in 1s, longer than 1s"}, byID["#par005"].Warnings)
+	assert.Zero(t, slept)
+}
+
+func TestPushedAuthorization_PushWithoutEndpoint(t *testing.T) {
+	ctx := &model.Context{}
+	ctx.PutStringSlice(ctxPushedAuthorizationSpecTypes, []string{"accounts"})
+
+	var pushed *PushedAuthorization
+	_, err := pushed.Push(ctx, "accounts", "https://as.example.com/authorize", resty.New(), nil)
+	assert.EqualError(t, err, "executors.Push: accounts authorization requests are pushed without a pushed_authorization_request_endpoint")
+	assert.Nil(t, pushed.Results())
+	pushed.TestExpiry()
+}
+
+func TestPushedAuthorization_ExpiredRequestURIAccepted(t *testing.T) {
+	server, _ := newPARServer(t, false)
+	defer server.Close()
+	ctx := parContext(server, "accounts")
+
+	// the request URIs pushed never expire
+	var slept time.Duration
+	pushed := newTestPushedAuthorization(&parServer{}, &slept)
+	_, err := pushed.Push(ctx, "accounts", server.URL+"/authorize?client_id=client&state=Token001", resty.New(), nil)
+	require.NoError(t, err)
+	pushed.TestExpiry()
+	pushed.TestExpiry()
+
+	byID := resultsByID(pushed.Results())
+	assert.Len(t, pushed.Results(), 5, "expiry tested once")
+	assert.False(t, byID["#par005"].Pass)
+	assert.Equal(t, []string{"expired request_uri accepted"}, byID["#par005"].Fail)
+	assert.Equal(t, 2*time.Second, slept)
+}
@@ -0,0 +1,127 @@
+package executors
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+)
+
+// replayTransport - serves the requests of a run the responses of a recording, e.g.: the
+// exchanges of a report export, instead of sending them to the ASPSP
+// A request of a test case is served the recorded exchanges of its ID in order, other requests,
+// e.g.: token exchanges, are served exchanges recorded with the same request, else with the same
+// request without query, as consent urls have a state and nonce of their own
+type replayTransport struct {
+	lock    *sync.Mutex
+	entries []har.Entry
+	served  []bool
+}
+
+// newReplayTransport - a transport serving responses from recording
+func newReplayTransport(recording har.HAR) *replayTransport {
+	entries := replayEntries(recording.Log.Entries)
+	return &replayTransport{
+		lock:    &sync.Mutex{},
+		entries: entries,
+		served:  make([]bool, len(entries)),
+	}
+}
+
+// replayEntries - recorded entries without the throttled responses that were retried
+func replayEntries(recorded []har.Entry) []har.Entry {
+	entries := []har.Entry{}
+	for i, entry := range recorded {
+		if entry.Response.Status == http.StatusTooManyRequests && retriedLater(recorded[i+1:], entry) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func retriedLater(entries []har.Entry, throttled har.Entry) bool {
+	for _, entry := range entries {
+		if entry.Comment == throttled.Comment && entry.Request.Method == throttled.Request.Method && entry.Request.URL == throttled.Request.URL {
+			return true
+		}
+	}
+	return false
+}
+
+type replayTestCaseKey struct{}
+
+// withTestCaseID - the request r of the test case testCaseID, served the exchanges recorded
+// for the test case when its run is replayed
+func withTestCaseID(r *resty.Request, testCaseID string) *resty.Request {
+	return r.SetContext(context.WithValue(context.Background(), replayTestCaseKey{}, testCaseID))
+}
+
+// RoundTrip - responds with the next exchange recorded for req
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	testCaseID, _ := req.Context().Value(replayTestCaseKey{}).(string)
+	entry, err := t.next(testCaseID, req.Method, req.URL.String())
+	if err != nil {
+		return nil, err
+	}
+	if entry.Response.Status == 0 {
+		return nil, fmt.Errorf("executors: recorded request to %s failed without response", entry.Request.URL)
+	}
+
+	header := http.Header{}
+	for _, h := range entry.Response.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	body := entry.Response.Content.Text
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// next - takes the next exchange recorded for a test case, or for the same request
+func (t *replayTransport) next(testCaseID, method, rawURL string) (har.Entry, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	matches := []func(entry har.Entry) bool{
+		func(entry har.Entry) bool {
+			return testCaseID != "" && entry.Comment == testCaseID
+		},
+		func(entry har.Entry) bool {
+			return entry.Request.Method == method && entry.Request.URL == rawURL
+		},
+		func(entry har.Entry) bool {
+			return entry.Request.Method == method && withoutQuery(entry.Request.URL) == withoutQuery(rawURL)
+		},
+	}
+	for _, match := range matches {
+		for i, entry := range t.entries {
+			if !t.served[i] && match(entry) {
+				t.served[i] = true
+				return entry, nil
+			}
+		}
+	}
+	if testCaseID != "" {
+		return har.Entry{}, fmt.Errorf("executors: no recorded exchange for test case %s, %s %s", testCaseID, method, rawURL)
+	}
+	return har.Entry{}, fmt.Errorf("executors: no recorded exchange for %s %s", method, rawURL)
+}
+
+func withoutQuery(rawURL string) string {
+	return strings.SplitN(rawURL, "?", 2)[0]
+}
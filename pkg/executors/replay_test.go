@@ -0,0 +1,194 @@
+package executors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resty "gopkg.in/resty.v1"
+)
+
+// record runs test cases against handler returning the recorded exchanges
+func record(t *testing.T, handler http.HandlerFunc, testCases ...model.TestCase) har.HAR {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	recorder := har.NewRecorder()
+	executor := &Executor{}
+	executor.SetRecorder(recorder)
+	for _, testCase := range testCases {
+		_, _, err := executor.ExecuteTestCase(replayRequest(resty.New(), server.URL, testCase), &testCase, &model.Context{})
+		require.NoError(t, err)
+	}
+	return recorder.HAR()
+}
+
+func replayRequest(client *resty.Client, baseURL string, testCase model.TestCase) *resty.Request {
+	request := client.R()
+	request.Method = testCase.Input.Method
+	request.URL = baseURL + testCase.Input.Endpoint
+	return request
+}
+
+// replayClient - the HTTP client of a run replaying recording
+func replayClient(t *testing.T, recording har.HAR) *resty.Client {
+	client, err := NewHTTPClient(RunDefinition{RunOptions: RunOptions{Replay: &recording}})
+	require.NoError(t, err)
+	return client
+}
+
+func TestReplay_ExecuteTestCase(t *testing.T) {
+	accounts := model.TestCase{ID: "OB-301-ACC-100100", Input: model.Input{Method: "GET", Endpoint: "/accounts"}}
+	balances := model.TestCase{ID: "OB-301-ACC-100200", Input: model.Input{Method: "GET", Endpoint: "/balances"}}
+	recording := record(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-fapi-interaction-id", "93bac548-d2de-4546-b106-880a5018460d")
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}, accounts, balances)
+
+	client := replayClient(t, recording)
+	executor := NewExecutor()
+	recorder := har.NewRecorder()
+	executor.SetRecorder(recorder)
+
+	resp, _, err := executor.ExecuteTestCase(replayRequest(client, "https://offline.example.com", balances), &balances, &model.Context{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, `{"path":"/balances"}`, resp.String())
+	assert.Equal(t, "93bac548-d2de-4546-b106-880a5018460d", resp.Header().Get("x-fapi-interaction-id"))
+	assert.Equal(t, "200 OK", balances.StatusCode)
+	assert.Equal(t, 1, recorder.Len())
+
+	resp, _, err = executor.ExecuteTestCase(replayRequest(client, "https://offline.example.com", accounts), &accounts, &model.Context{})
+	require.NoError(t, err)
+	assert.Equal(t, `{"path":"/accounts"}`, resp.String())
+
+	_, _, err = executor.ExecuteTestCase(replayRequest(client, "https://offline.example.com", accounts), &accounts, &model.Context{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "executors: no recorded exchange for test case OB-301-ACC-100100, GET https://offline.example.com/accounts")
+}
+
+func TestReplay_ExecuteTestCase_SameRequest(t *testing.T) {
+	recorded := model.TestCase{ID: "OB-301-ACC-100100", Input: model.Input{Method: "GET", Endpoint: "/accounts"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	recorder := har.NewRecorder()
+	executor := &Executor{}
+	executor.SetRecorder(recorder)
+	_, _, err := executor.ExecuteTestCase(replayRequest(resty.New(), server.URL, recorded), &recorded, &model.Context{})
+	require.NoError(t, err)
+	server.Close()
+
+	renamed := model.TestCase{ID: "OB-301-ACC-999999", Input: recorded.Input}
+	client := replayClient(t, recorder.HAR())
+	resp, _, err := NewExecutor().ExecuteTestCase(replayRequest(client, server.URL, renamed), &renamed, &model.Context{})
+
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, resp.String())
+}
+
+func TestReplay_ExecuteTestCase_Redirect(t *testing.T) {
+	consent := model.TestCase{ID: "Consent", Input: model.Input{Method: "GET", Endpoint: "/authorize"}}
+	recording := har.HAR{Log: har.NewLog()}
+	recording.Log.Entries = append(recording.Log.Entries, har.Entry{
+		Comment: "Consent",
+		Request: har.Request{Method: "GET", URL: "https://aspsp.example.com/authorize"},
+		Response: har.Response{
+			Status:     http.StatusFound,
+			StatusText: "Found",
+			Headers:    []har.NameValue{{Name: "Location", Value: "https://tpp.example.com/redirect#state=Token001"}},
+		},
+	})
+
+	client := replayClient(t, recording)
+	resp, _, err := NewExecutor().ExecuteTestCase(replayRequest(client, "https://aspsp.example.com", consent), &consent, &model.Context{})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode())
+	assert.Equal(t, "https://tpp.example.com/redirect#state=Token001", resp.Header().Get("Location"))
+}
+
+func TestReplay_TokenExchange(t *testing.T) {
+	recording := har.HAR{Log: har.NewLog()}
+	recording.Log.Entries = append(recording.Log.Entries, har.Entry{
+		Comment:  "ExchangeCodeForAccessToken",
+		Request:  har.Request{Method: "POST", URL: "https://as.example.com/token"},
+		Response: har.Response{Status: http.StatusOK, StatusText: "OK", Content: har.Content{Text: `{"access_token": "replayed", "expires_in": 300}`}},
+	})
+	ctx := &model.Context{
+		"basic_authentication":    "basic-auth",
+		"token_endpoint":          "https://as.example.com/token",
+		"client_id":               "client",
+		"requestObjectSigningAlg": "PS256",
+		"signingPrivate":          signingPrivate,
+		"signingPublic":           signingPublic,
+		"redirect_url":            "https://tpp.example.com/callback",
+	}
+
+	accessToken, err := ExchangeCodeForAccessToken("Token001", "code", ctx, RunDefinition{HTTPClient: replayClient(t, recording)})
+
+	require.NoError(t, err)
+	assert.Equal(t, "replayed", accessToken)
+}
+
+func TestReplayTransport_ConsentURLWithoutQuery(t *testing.T) {
+	recording := har.HAR{Log: har.NewLog()}
+	recording.Log.Entries = append(recording.Log.Entries, har.Entry{
+		Comment:  "CallPaymentHeadlessConsentUrls",
+		Request:  har.Request{Method: "GET", URL: "https://as.example.com/authorize?state=Token001&nonce=recorded"},
+		Response: har.Response{Status: http.StatusFound, StatusText: "Found"},
+	})
+	client := replayClient(t, recording)
+
+	resp, err := client.R().Get("https://as.example.com/authorize?state=Token001&nonce=replayed")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode())
+
+	_, err = client.R().Get("https://as.example.com/authorize?state=Token001&nonce=replayed")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "executors: no recorded exchange for GET https://as.example.com/authorize")
+}
+
+func TestReplayEntries_SkipsRetriedThrottledResponses(t *testing.T) {
+	entry := func(status int) har.Entry {
+		return har.Entry{
+			Comment:  "OB-301-ACC-100100",
+			Request:  har.Request{Method: "GET", URL: "https://aspsp.example.com/accounts"},
+			Response: har.Response{Status: status},
+		}
+	}
+
+	entries := replayEntries([]har.Entry{entry(429), entry(429), entry(200), entry(429)})
+
+	assert.Equal(t, []har.Entry{entry(200), entry(429)}, entries)
+}
+
+func TestReplayRunsValidation(t *testing.T) {
+	testCase := model.TestCase{
+		ID:        "OB-301-ACC-100100",
+		Input:     model.Input{Method: "GET", Endpoint: "/accounts"},
+		Expect:    model.Expect{StatusCode: http.StatusOK, Matches: []model.Match{{JSON: "Data.Account.0.AccountId", Value: "22289"}}},
+		Context:   model.Context{"baseurl": "https://aspsp.example.com"},
+		Validator: schema.NewNullValidator(),
+	}
+	recording := har.HAR{Log: har.NewLog()}
+	recording.Log.Entries = append(recording.Log.Entries, har.Entry{
+		Comment:  "OB-301-ACC-100100",
+		Request:  har.Request{Method: "GET", URL: "https://aspsp.example.com/accounts"},
+		Response: har.Response{Status: http.StatusOK, StatusText: "OK", Content: har.Content{Text: `{"Data":{"Account":[{"AccountId":"22289"}]}}`}},
+	})
+
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{HTTPClient: replayClient(t, recording)}, NewBufferedDaemonController())
+
+	result := runner.executeTest(testCase, &model.Context{}, test.NullLogger())
+
+	assert.True(t, result.Pass, result.Fail)
+}
@@ -9,9 +9,10 @@ import (
 )
 
 type Metrics struct {
-	TestCase     *model.TestCase
-	ResponseTime time.Duration // Http Response Time
-	ResponseSize int           // Size in bytes of the HTTP Response body
+	TestCase         *model.TestCase
+	ResponseTime     time.Duration // Http Response Time
+	ResponseSize     int           // Size in bytes of the HTTP Response body
+	ThrottledRetries int           // Retries after the ASPSP responded 429 Too Many Requests
 }
 
 // MarshalJSON is a custom marshaler which formats a Metrics struct
@@ -19,14 +20,32 @@ type Metrics struct {
 // response time decimal precision is up the nanosecond eg: 1.234ms
 func (m Metrics) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		ResponseTime float64 `json:"response_time"`
-		ResponseSize int     `json:"response_size"`
+		ResponseTime     float64 `json:"response_time"`
+		ResponseSize     int     `json:"response_size"`
+		ThrottledRetries int     `json:"throttled_retries,omitempty"`
 	}{
-		ResponseTime: float64(m.ResponseTime) / float64(time.Millisecond),
-		ResponseSize: m.ResponseSize,
+		ResponseTime:     float64(m.ResponseTime) / float64(time.Millisecond),
+		ResponseSize:     m.ResponseSize,
+		ThrottledRetries: m.ThrottledRetries,
 	})
 }
 
+// UnmarshalJSON is the inverse of MarshalJSON, the test case isn't marshalled
+func (m *Metrics) UnmarshalJSON(data []byte) error {
+	metrics := struct {
+		ResponseTime     float64 `json:"response_time"`
+		ResponseSize     int     `json:"response_size"`
+		ThrottledRetries int     `json:"throttled_retries,omitempty"`
+	}{}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return err
+	}
+	m.ResponseTime = time.Duration(metrics.ResponseTime * float64(time.Millisecond))
+	m.ResponseSize = metrics.ResponseSize
+	m.ThrottledRetries = metrics.ThrottledRetries
+	return nil
+}
+
 func NoMetrics() Metrics {
 	return Metrics{}
 }
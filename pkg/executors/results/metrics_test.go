@@ -1,6 +1,7 @@
 package results
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -29,3 +30,14 @@ func TestNewMetrics(t *testing.T) {
 	assert.Equal(t, time.Second, metrics.ResponseTime)
 	assert.Equal(t, 1, metrics.ResponseSize)
 }
+
+func TestMetricsUnmarshalJSON(t *testing.T) {
+	metrics := Metrics{ResponseTime: 1234567 * time.Nanosecond, ResponseSize: 42, ThrottledRetries: 2}
+	data, err := json.Marshal(metrics)
+	assert.NoError(t, err)
+
+	unmarshalled := Metrics{}
+	assert.NoError(t, json.Unmarshal(data, &unmarshalled))
+
+	assert.Equal(t, metrics, unmarshalled)
+}
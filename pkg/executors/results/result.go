@@ -1,17 +1,24 @@
 package results
 
+import (
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/lint"
+)
+
 // TestCase result for a run
 type TestCase struct {
-	Id         string   `json:"id"`
-	Pass       bool     `json:"pass"`
-	Metrics    Metrics  `json:"metrics"`
-	Fail       []string `json:"fail,omitempty"`
-	Detail     string   `json:"detail"`
-	RefURI     string   `json:"refURI"`
-	Endpoint   string   `json:"endpoint"`
-	API        string   `json:"-"`
-	APIVersion string   `json:"-"`
-	HttpStatus string   `json:"httpStatusCode"`
+	Id             string           `json:"id"`
+	Pass           bool             `json:"pass"`
+	Metrics        Metrics          `json:"metrics"`
+	Fail           []string         `json:"fail,omitempty"`
+	Warnings       []string         `json:"warnings,omitempty"`
+	RuleViolations []lint.Violation `json:"ruleViolations,omitempty"`
+	Detail         string           `json:"detail"`
+	RefURI         string           `json:"refURI"`
+	Endpoint       string           `json:"endpoint"`
+	API            string           `json:"-"`
+	APIVersion     string           `json:"-"`
+	HttpStatus     string           `json:"httpStatusCode"`
+	Exchanges      []int            `json:"exchanges,omitempty"` // Indexes of the requests sent in the exchanges of the run, e.g.: exchanges.har of a report export
 }
 
 // NewTestCaseFail returns a failed test
@@ -0,0 +1,239 @@
+package executors
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// Concurrency - how many test cases of a run execute at the same time
+// Specifications always run one after the other, with PerSpec set to 1
+// the test cases of a specification run sequentially in manifest order
+type Concurrency struct {
+	PerSpec int // Test cases of a specification running at the same time
+	PerHost int // Requests to the same host running at the same time
+}
+
+// atLeastOne - the concurrency with values lower than 1 set to 1
+func (c Concurrency) atLeastOne() Concurrency {
+	if c.PerSpec < 1 {
+		c.PerSpec = 1
+	}
+	if c.PerHost < 1 {
+		c.PerHost = 1
+	}
+	return c
+}
+
+// contextReferenceRegex matches the context variables used by a test case, e.g.: `$AccountId`
+var contextReferenceRegex = regexp.MustCompile(`\$([\w\-]+)`)
+
+// testChain - indexes of test cases that run in order, one after the other,
+// as later test cases use context values put by earlier ones
+type testChain []int
+
+// buildTestChains - splits test cases into chains, a test case joins the chain of
+// the last test case that put each of the context values it uses, so it sees the
+// same values it would see running sequentially.
+// Also returns, for each context value put by a test case, the chain that put it last.
+func buildTestChains(testCases []model.TestCase) ([]testChain, map[string]int) {
+	parents := make([]int, len(testCases))
+	for i := range parents {
+		parents[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		for parents[i] != i {
+			parents[i] = parents[parents[i]]
+			i = parents[i]
+		}
+		return i
+	}
+
+	lastPut := map[string]int{}
+	for i, testCase := range testCases {
+		for _, name := range contextReferences(testCase) {
+			if producer, ok := lastPut[name]; ok {
+				parents[root(i)] = root(producer)
+			}
+		}
+		for _, name := range contextPuts(testCase) {
+			lastPut[name] = i
+		}
+	}
+
+	chains := []testChain{}
+	chainIndexes := map[int]int{}
+	testChains := make([]int, len(testCases))
+	for i := range testCases {
+		r := root(i)
+		index, ok := chainIndexes[r]
+		if !ok {
+			index = len(chains)
+			chainIndexes[r] = index
+			chains = append(chains, testChain{})
+		}
+		chains[index] = append(chains[index], i)
+		testChains[i] = index
+	}
+
+	lastPutChains := map[string]int{}
+	for name, i := range lastPut {
+		lastPutChains[name] = testChains[i]
+	}
+	return chains, lastPutChains
+}
+
+// ResumedTestCases - which test cases run again to resume a run, completed tells the test cases with a result.
+// A chain with a pending test case runs again from its start, as the context values put by its completed
+// test cases aren't saved.
+func ResumedTestCases(testCases []model.TestCase, completed func(model.TestCase) bool) []bool {
+	resumed := make([]bool, len(testCases))
+	chains, _ := buildTestChains(testCases)
+	for _, chain := range chains {
+		pending := false
+		for _, i := range chain {
+			if !completed(testCases[i]) {
+				pending = true
+				break
+			}
+		}
+		for _, i := range chain {
+			resumed[i] = pending
+		}
+	}
+	return resumed
+}
+
+// contextReferences - names of the context values a test case uses that are not
+// provided by its own context
+func contextReferences(testCase model.TestCase) []string {
+	sources := []interface{}{testCase.Input, testCase.Expect}
+	for _, value := range testCase.Context {
+		sources = append(sources, value)
+	}
+	content, err := json.Marshal(sources)
+	if err != nil {
+		return nil
+	}
+
+	names := []string{}
+	for _, match := range contextReferenceRegex.FindAllStringSubmatch(string(content), -1) {
+		name := match[1]
+		if _, ok := testCase.Context[name]; ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// contextPuts - names of the context values a test case puts into the run context
+func contextPuts(testCase model.TestCase) []string {
+	names := []string{}
+	for name := range testCase.Context {
+		names = append(names, name)
+	}
+	for _, match := range testCase.Expect.ContextPut.Matches {
+		if match.ContextName != "" {
+			names = append(names, match.ContextName)
+		}
+	}
+	return names
+}
+
+// hostSlots - limits the requests running at the same time to each host
+type hostSlots struct {
+	size  int
+	lock  *sync.Mutex
+	slots map[string]chan struct{}
+}
+
+func newHostSlots(size int) *hostSlots {
+	return &hostSlots{
+		size:  size,
+		lock:  &sync.Mutex{},
+		slots: map[string]chan struct{}{},
+	}
+}
+
+// acquire - waits for a free slot for host, the returned func releases it
+func (h *hostSlots) acquire(host string) func() {
+	h.lock.Lock()
+	slots, ok := h.slots[host]
+	if !ok {
+		slots = make(chan struct{}, h.size)
+		h.slots[host] = slots
+	}
+	h.lock.Unlock()
+
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+// testCaseHost - host a test case sends its request to
+func testCaseHost(testCase model.TestCase) string {
+	endpoint := testCase.Input.Endpoint
+	if baseURL, err := testCase.Context.GetString("baseurl"); err == nil && !strings.HasPrefix(endpoint, baseURL) {
+		endpoint = baseURL + endpoint
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	return endpointURL.Host
+}
+
+// executeSpecTestsConcurrently - runs the chains of test cases of a specification in parallel,
+// each chain with its own copy of the run context. Once all chains complete, context values are
+// put back into the run context as they would be running sequentially, for the next specifications.
+func (r *TestCaseRunner) executeSpecTestsConcurrently(spec generation.SpecificationTestCases, ruleCtx *model.Context, ctxLogger *logrus.Entry, hosts *hostSlots, perSpec int) {
+	ctxLogger = ctxLogger.WithField("spec", spec.Specification.Name)
+	if r.definition.Collector != nil {
+		r.definition.Collector.SetCollectorAPIDetails(spec.Specification.Name, spec.Specification.Version)
+	}
+
+	chains, lastPutChains := buildTestChains(spec.TestCases)
+	ctxLogger.WithField("chains", len(chains)).Debug("executing test case chains concurrently")
+
+	specSlots := make(chan struct{}, perSpec)
+	chainCtxs := make([]*model.Context, len(chains))
+	wg := &sync.WaitGroup{}
+	for i, chain := range chains {
+		chainCtxs[i] = r.makeRuleCtx(ruleCtx)
+		wg.Add(1)
+		go func(chain testChain, chainCtx *model.Context) {
+			defer wg.Done()
+			for _, index := range chain {
+				if r.daemonController.ShouldStop() {
+					ctxLogger.Info("stop test run received, aborting runner")
+					return
+				}
+				testcase := spec.TestCases[index]
+				logger := ctxLogger.WithField("ID", testcase.ID)
+
+				specSlots <- struct{}{}
+				release := hosts.acquire(testCaseHost(testcase))
+				testResult := r.executeTest(testcase, chainCtx, logger)
+				release()
+				<-specSlots
+
+				r.daemonController.AddResult(testResult)
+			}
+		}(chain, chainCtxs[i])
+	}
+	wg.Wait()
+
+	for name, chain := range lastPutChains {
+		if value, ok := chainCtxs[chain].Get(name); ok {
+			ruleCtx.Put(name, value)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package executors
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	resty "gopkg.in/resty.v1"
+)
+
+func putsContext(id, name string) model.TestCase {
+	return model.TestCase{
+		ID:      id,
+		Input:   model.Input{Method: "POST", Endpoint: "https://aspsp.example.com/consents"},
+		Context: model.Context{},
+		Expect: model.Expect{ContextPut: model.ContextAccessor{
+			Matches: []model.Match{{ContextName: name, JSON: "Data.ConsentId"}},
+		}},
+	}
+}
+
+func usesEndpoint(id, endpoint string) model.TestCase {
+	return model.TestCase{
+		ID:      id,
+		Input:   model.Input{Method: "GET", Endpoint: endpoint},
+		Context: model.Context{},
+	}
+}
+
+func TestBuildTestChains(t *testing.T) {
+	testCases := []model.TestCase{
+		putsContext("TC-1", "consent_id"),
+		usesEndpoint("TC-2", "https://aspsp.example.com/accounts"),
+		usesEndpoint("TC-3", "https://aspsp.example.com/consents/$consent_id"),
+		putsContext("TC-4", "consent_id"),
+		usesEndpoint("TC-5", "https://aspsp.example.com/consents/$consent_id/funds"),
+		usesEndpoint("TC-6", "https://aspsp.example.com/accounts/$unknown"),
+	}
+
+	chains, lastPutChains := buildTestChains(testCases)
+
+	assert.Equal(t, []testChain{{0, 2}, {1}, {3, 4}, {5}}, chains)
+	assert.Equal(t, map[string]int{"consent_id": 2}, lastPutChains)
+}
+
+func TestBuildTestChains_OwnContextIsNotADependency(t *testing.T) {
+	producer := putsContext("TC-1", "AccountId")
+	consumer := usesEndpoint("TC-2", "https://aspsp.example.com/accounts/$AccountId")
+	consumer.Context.PutString("AccountId", "static")
+	user := usesEndpoint("TC-3", "https://aspsp.example.com/accounts/$AccountId/balances")
+
+	chains, lastPutChains := buildTestChains([]model.TestCase{producer, consumer, user})
+
+	assert.Equal(t, []testChain{{0}, {1, 2}}, chains)
+	assert.Equal(t, map[string]int{"AccountId": 1}, lastPutChains)
+}
+
+func TestResumedTestCases(t *testing.T) {
+	testCases := []model.TestCase{
+		putsContext("TC-1", "consent_id"),
+		usesEndpoint("TC-2", "https://aspsp.example.com/accounts"),
+		usesEndpoint("TC-3", "https://aspsp.example.com/consents/$consent_id"),
+		putsContext("TC-4", "consent_id"),
+		usesEndpoint("TC-5", "https://aspsp.example.com/consents/$consent_id/funds"),
+	}
+	completed := map[string]bool{"TC-1": true, "TC-2": true, "TC-4": true, "TC-5": true}
+
+	resumed := ResumedTestCases(testCases, func(testCase model.TestCase) bool {
+		return completed[testCase.ID]
+	})
+
+	assert.Equal(t, []bool{true, false, true, false, false}, resumed)
+}
+
+func TestTestCaseHost(t *testing.T) {
+	assert.Equal(t, "aspsp.example.com", testCaseHost(usesEndpoint("TC-1", "https://aspsp.example.com/accounts")))
+
+	relative := usesEndpoint("TC-2", "/accounts")
+	relative.Context.PutString("baseurl", "https://other.example.com:8443/open-banking/v3.1")
+	assert.Equal(t, "other.example.com:8443", testCaseHost(relative))
+}
+
+func TestConcurrency_AtLeastOne(t *testing.T) {
+	assert.Equal(t, Concurrency{PerSpec: 1, PerHost: 1}, Concurrency{PerSpec: 0, PerHost: -1}.atLeastOne())
+	assert.Equal(t, Concurrency{PerSpec: 8, PerHost: 2}, Concurrency{PerSpec: 8, PerHost: 2}.atLeastOne())
+}
+
+// contextExecutor puts the test case ID into the context values the test case
+// puts, and records the context values seen by each test case
+type contextExecutor struct {
+	lock    *sync.Mutex
+	seen    map[string]string
+	running int
+	maxRun  int
+}
+
+func (e *contextExecutor) ExecuteTestCase(r *resty.Request, t *model.TestCase, ctx *model.Context) (*resty.Response, results.Metrics, error) {
+	e.lock.Lock()
+	e.running++
+	if e.running > e.maxRun {
+		e.maxRun = e.running
+	}
+	value, _ := ctx.GetString("consent_id")
+	e.seen[t.ID] = value
+	e.lock.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+	for _, match := range t.Expect.ContextPut.Matches {
+		ctx.PutString(match.ContextName, t.ID)
+	}
+
+	e.lock.Lock()
+	e.running--
+	e.lock.Unlock()
+	return nil, results.NoMetrics(), errors.New("not sent")
+}
+
+func (e *contextExecutor) SetCertificates(certificateSigning, certificationTransport authentication.Certificate) error {
+	return nil
+}
+
+func (e *contextExecutor) SetPropertyCollector(collector schemaprops.PropertyCollector) {}
+
+func (e *contextExecutor) SetRecorder(recorder *har.Recorder) {}
+
+func (e *contextExecutor) SetThrottle(limiter *RequestLimiter, retries ThrottleRetries) {}
+
+func TestExecuteSpecTestsConcurrently(t *testing.T) {
+	executor := &contextExecutor{lock: &sync.Mutex{}, seen: map[string]string{}}
+	controller := NewBufferedDaemonController()
+	runner := NewTestCaseRunner(test.NullLogger(), RunDefinition{HTTPClient: resty.New()}, controller)
+	runner.executor = executor
+
+	spec := generation.SpecificationTestCases{
+		Specification: discovery.ModelAPISpecification{Name: "Account and Transaction API Specification"},
+		TestCases: []model.TestCase{
+			putsContext("TC-1", "consent_id"),
+			usesEndpoint("TC-2", "https://aspsp.example.com/accounts"),
+			usesEndpoint("TC-3", "https://aspsp.example.com/consents/$consent_id"),
+			usesEndpoint("TC-4", "https://aspsp.example.com/balances"),
+		},
+	}
+	ruleCtx := &model.Context{}
+
+	runner.executeSpecTestsConcurrently(spec, ruleCtx, test.NullLogger(), newHostSlots(2), 4)
+
+	assert.Len(t, controller.AllResults(), 4)
+	assert.Equal(t, "TC-1", executor.seen["TC-3"])
+	assert.LessOrEqual(t, executor.maxRun, 2)
+	consentID, err := ruleCtx.GetString("consent_id")
+	require.NoError(t, err)
+	assert.Equal(t, "TC-1", consentID)
+}
@@ -0,0 +1,162 @@
+package executors
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+)
+
+// RateLimit - limits the requests sent by the executor
+// A RequestsPerSecond of 0 disables rate limiting
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int  // Requests sent at once before being limited
+	PerHost           bool // Limit each host separately, instead of all requests
+}
+
+// ThrottleRetries - budget retrying requests throttled by the ASPSP,
+// responses with status 429 Too Many Requests and a Retry-After header
+// are retried while the budget allows, other 429 responses are returned
+type ThrottleRetries struct {
+	MaxRetries int           // Retries of a request
+	MaxWait    time.Duration // Total time waiting for Retry-After of a request
+}
+
+// defaultThrottleRetries - the budget retrying throttled requests of runs without run options
+var defaultThrottleRetries = ThrottleRetries{MaxRetries: 3, MaxWait: 30 * time.Second}
+
+var sleep = time.Sleep
+
+// RequestLimiter - token bucket limiting requests, per host or shared by all hosts
+// The runs sharing a limiter share its rate limit, a nil limiter doesn't limit requests
+type RequestLimiter struct {
+	limit   RateLimit
+	lock    *sync.Mutex
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewRequestLimiter - a limiter of the requests of limit
+func NewRequestLimiter(limit RateLimit) *RequestLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &RequestLimiter{
+		limit:   limit,
+		lock:    &sync.Mutex{},
+		buckets: map[string]*tokenBucket{},
+		now:     time.Now,
+	}
+}
+
+// wait - blocks until a request to rawURL is allowed
+func (l *RequestLimiter) wait(rawURL string) {
+	if l == nil {
+		return
+	}
+	if delay := l.reserve(rawURL); delay > 0 {
+		sleep(delay)
+	}
+}
+
+// reserve - takes a token for a request to rawURL returning how long to wait before sending it
+func (l *RequestLimiter) reserve(rawURL string) time.Duration {
+	if l.limit.RequestsPerSecond <= 0 {
+		return 0
+	}
+
+	key := ""
+	if l.limit.PerHost {
+		if requestURL, err := url.Parse(rawURL); err == nil {
+			key = requestURL.Host
+		}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.limit.RequestsPerSecond
+	if bucket.tokens > float64(l.limit.Burst) {
+		bucket.tokens = float64(l.limit.Burst)
+	}
+	bucket.last = now
+	bucket.tokens--
+
+	if bucket.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-bucket.tokens / l.limit.RequestsPerSecond * float64(time.Second))
+}
+
+// executeThrottled - sends a request waiting for limiter, retries requests throttled by the
+// ASPSP within the retry budget of throttleRetries and returns how many retries were made
+// Each exchange, including throttled ones, is recorded for testCaseID
+func executeThrottled(r *resty.Request, limiter *RequestLimiter, throttleRetries ThrottleRetries, recorder *har.Recorder, testCaseID string, logger *logrus.Entry) (*resty.Response, int, error) {
+	retries := 0
+	waited := time.Duration(0)
+	for {
+		limiter.wait(r.URL)
+		resp, err := r.Execute(r.Method, r.URL)
+		recorder.Record(testCaseID, resp)
+		if resp == nil || resp.StatusCode() != http.StatusTooManyRequests || retries >= throttleRetries.MaxRetries {
+			return resp, retries, err
+		}
+
+		delay, ok := retryAfter(resp.Header().Get("Retry-After"), time.Now())
+		if !ok || waited+delay > throttleRetries.MaxWait {
+			return resp, retries, err
+		}
+
+		retries++
+		waited += delay
+		logger.WithFields(logrus.Fields{
+			"url":     r.URL,
+			"retry":   retries,
+			"waiting": delay,
+		}).Warn("request throttled, retrying")
+		sleep(delay)
+	}
+}
+
+// retryAfter - parses a Retry-After header, either seconds or a HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	delay := date.Sub(now)
+	if delay < 0 {
+		delay = 0
+	}
+	return delay, true
+}
@@ -0,0 +1,112 @@
+package executors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2019, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	delay, ok := retryAfter("2", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, delay)
+
+	delay, ok = retryAfter("Mon, 21 Oct 2019 07:28:05 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, delay)
+
+	delay, ok = retryAfter("Mon, 21 Oct 2019 07:27:00 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), delay)
+
+	_, ok = retryAfter("", now)
+	assert.False(t, ok)
+	_, ok = retryAfter("-1", now)
+	assert.False(t, ok)
+	_, ok = retryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestRequestLimiter_Reserve(t *testing.T) {
+	now := time.Date(2019, 10, 21, 7, 28, 0, 0, time.UTC)
+	limiter := NewRequestLimiter(RateLimit{RequestsPerSecond: 2, Burst: 2, PerHost: true})
+	limiter.now = func() time.Time { return now }
+
+	assert.Equal(t, time.Duration(0), limiter.reserve("https://one.example.com/accounts"))
+	assert.Equal(t, time.Duration(0), limiter.reserve("https://one.example.com/balances"))
+	assert.Equal(t, 500*time.Millisecond, limiter.reserve("https://one.example.com/accounts"))
+	assert.Equal(t, time.Duration(0), limiter.reserve("https://two.example.com/accounts"))
+
+	now = now.Add(2 * time.Second)
+	assert.Equal(t, time.Duration(0), limiter.reserve("https://one.example.com/accounts"))
+}
+
+func TestRequestLimiter_Disabled(t *testing.T) {
+	limiter := NewRequestLimiter(RateLimit{})
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, time.Duration(0), limiter.reserve("https://one.example.com/accounts"))
+	}
+}
+
+func TestExecuteThrottled(t *testing.T) {
+	defer func() { sleep = time.Sleep }()
+	slept := []time.Duration{}
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	r := resty.R()
+	r.Method = http.MethodGet
+	r.URL = server.URL
+
+	resp, retries, err := executeThrottled(r, nil, NewRunOptions().ThrottleRetries, nil, "", test.NullLogger())
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, 2, retries)
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, slept)
+}
+
+func TestExecuteThrottled_OverBudget(t *testing.T) {
+	defer func() { sleep = time.Sleep }()
+	sleep = func(d time.Duration) {}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "4")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	r := resty.R()
+	r.Method = http.MethodGet
+	r.URL = server.URL
+
+	resp, retries, err := executeThrottled(r, nil, ThrottleRetries{MaxRetries: 5, MaxWait: 10 * time.Second}, nil, "", test.NullLogger())
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode())
+	assert.Equal(t, 2, retries)
+	assert.Equal(t, 3, calls)
+}
@@ -34,6 +34,8 @@ type TokenConsentIDItem struct {
 	AccessToken string
 	ConsentURL  string
 	Error       string
+	// CodeVerifier - the PKCE code verifier of the consent url
+	CodeVerifier string
 }
 
 // TokenCollector - collects tokens
@@ -51,11 +53,18 @@ type tokenCollector struct {
 	events       events.Events
 }
 
-// NewTokenCollector -
+// NewTokenCollector - the tokens of consentIds with an access token are already collected,
+// e.g.: when restoring a journey
 func NewTokenCollector(log *logrus.Entry, consentIds TokenConsentIDs, doneFunc func(), events events.Events) TokenCollector {
+	collected := 0
+	for _, item := range consentIds {
+		if item.AccessToken != "" {
+			collected++
+		}
+	}
 	return &tokenCollector{
 		tokensLock:   &sync.Mutex{},
-		collected:    0,
+		collected:    collected,
 		doneFunc:     doneFunc,
 		consentTable: consentIds,
 		log:          log.WithField("module", "tokenCollector"),
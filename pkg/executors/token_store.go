@@ -0,0 +1,205 @@
+package executors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/events"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// tokenRefreshMargin - an access token is refreshed when it expires within the margin, so it
+// doesn't expire while its request is sent
+const tokenRefreshMargin = 30 * time.Second
+
+// StoredToken - an access token acquired with its refresh token and expiry
+type StoredToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`   // Zero when the token endpoint didn't return `expires_in`
+	Replaced     []string  `json:"replaced,omitempty"` // Access tokens refreshed, replaced in the contexts still holding them
+}
+
+// expires - true when the token expires within margin of now
+func (t StoredToken) expires(now time.Time) bool {
+	return !t.Expiry.IsZero() && !now.Add(tokenRefreshMargin).Before(t.Expiry)
+}
+
+// TokenStore - the access tokens of a journey keyed by token name, refreshed with the
+// `refresh_token` grant before a request goes out with an expired one. A token is refreshed once
+// however many test cases wait for it, without blocking the test cases using other tokens
+type TokenStore struct {
+	lock       *sync.Mutex
+	tokens     map[string]StoredToken
+	refreshing map[string]chan struct{} // Closed when the refresh of the token name is done
+	now        func() time.Time
+	log        *logrus.Entry
+}
+
+// NewTokenStore - a token store holding tokens, e.g.: when restoring a journey
+func NewTokenStore(log *logrus.Entry, tokens map[string]StoredToken) *TokenStore {
+	if tokens == nil {
+		tokens = map[string]StoredToken{}
+	}
+	return &TokenStore{
+		lock:       &sync.Mutex{},
+		tokens:     tokens,
+		refreshing: map[string]chan struct{}{},
+		now:        time.Now,
+		log:        log.WithField("module", "TokenStore"),
+	}
+}
+
+// Put - keeps the access token of name, replacing the previous one. A nil store keeps nothing
+func (s *TokenStore) Put(name string, token grantToken) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tokens[name] = s.stored(token)
+}
+
+// Set - keeps a token acquired elsewhere, e.g.: from a token vault. A nil store keeps nothing
+func (s *TokenStore) Set(name string, token StoredToken) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tokens[name] = token
+}
+
+// Tokens - a copy of the tokens of the store
+func (s *TokenStore) Tokens() map[string]StoredToken {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tokens := make(map[string]StoredToken, len(s.tokens))
+	for name, token := range s.tokens {
+		tokens[name] = token
+	}
+	return tokens
+}
+
+// Refresh - refreshes the tokens expiring, using the token endpoint and client authentication of
+// ctx, and replaces the expired access tokens ctx holds by the refreshed ones. The refresh
+// requests are sent by client and recorded by recorder, and each refresh is added to tokenEvents,
+// which may be nil. A token being refreshed by another call is waited for instead. A nil store
+// refreshes nothing
+func (s *TokenStore) Refresh(ctx *model.Context, client *resty.Client, recorder *har.Recorder, tokenEvents events.Events) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	now := s.now()
+	expiring := map[string]StoredToken{}
+	waits := []chan struct{}{}
+	for name, token := range s.tokens {
+		if !token.expires(now) {
+			continue
+		}
+		if done, ok := s.refreshing[name]; ok {
+			waits = append(waits, done)
+			continue
+		}
+		if token.RefreshToken == "" {
+			s.log.WithField("tokenName", name).Warn("access token expired without a refresh token")
+			continue
+		}
+		s.refreshing[name] = make(chan struct{})
+		expiring[name] = token
+	}
+	s.lock.Unlock()
+
+	for name, token := range expiring {
+		s.refresh(name, token, ctx, client, recorder, tokenEvents)
+	}
+	for _, done := range waits {
+		<-done
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.replaceExpired(ctx)
+}
+
+// refresh - refreshes token of name, without holding the lock while its request is sent, and
+// ends its refresh
+func (s *TokenStore) refresh(name string, token StoredToken, ctx *model.Context, client *resty.Client, recorder *har.Recorder, tokenEvents events.Events) {
+	logger := s.log.WithFields(logrus.Fields{
+		"tokenName": name,
+		"expiry":    token.Expiry,
+	})
+	refreshed, err := refreshToken(token.RefreshToken, ctx, client, recorder, logger)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	defer func() {
+		close(s.refreshing[name])
+		delete(s.refreshing, name)
+	}()
+	if s.tokens[name].AccessToken != token.AccessToken {
+		logger.Info("access token replaced while refreshed")
+		return
+	}
+	if err != nil {
+		logger.WithError(err).Error("refreshing access token")
+		// not retried, the requests sent with the expired token report the failure
+		token.Expiry = time.Time{}
+		s.tokens[name] = token
+		addRefreshedAccessToken(tokenEvents, events.NewRefreshedAccessTokenError(name, err))
+		return
+	}
+	if refreshed.RefreshToken == "" { // the refresh token is kept when the server doesn't rotate it
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	stored := s.stored(*refreshed)
+	stored.Replaced = append(append([]string{}, token.Replaced...), token.AccessToken)
+	s.tokens[name] = stored
+	logger.WithField("newExpiry", stored.Expiry).Info("refreshed access token")
+	addRefreshedAccessToken(tokenEvents, events.NewRefreshedAccessToken(name, stored.Expiry))
+}
+
+// replaceExpired - replaces the access tokens refreshed held by ctx, e.g.: by the token
+// name and `access_token`. The lock must be held
+func (s *TokenStore) replaceExpired(ctx *model.Context) {
+	current := map[string]string{}
+	for _, token := range s.tokens {
+		for _, replaced := range token.Replaced {
+			current[replaced] = token.AccessToken
+		}
+	}
+	if len(current) == 0 {
+		return
+	}
+	for key, value := range *ctx {
+		if expired, ok := value.(string); ok && current[expired] != "" {
+			ctx.PutString(key, current[expired])
+		}
+	}
+}
+
+func (s *TokenStore) stored(token grantToken) StoredToken {
+	stored := StoredToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+	}
+	if token.Expires > 0 {
+		stored.Expiry = s.now().Add(time.Duration(token.Expires) * time.Second)
+	}
+	return stored
+}
+
+func addRefreshedAccessToken(tokenEvents events.Events, event events.RefreshedAccessToken) {
+	if tokenEvents != nil {
+		tokenEvents.AddRefreshedAccessToken(event)
+	}
+}
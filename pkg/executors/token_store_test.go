@@ -0,0 +1,168 @@
+package executors
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/events"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// tokenEndpoint - a token endpoint granting "refreshed-<n>" access tokens for the refresh
+// token "refresh", counting the refresh grants
+func tokenEndpoint(t *testing.T, refreshes *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, authentication.GrantTypeRefreshToken, r.PostForm.Get(authentication.GrantType))
+		assert.Equal(t, "Basic basic-auth", r.Header.Get("Authorization"))
+		if r.PostForm.Get(authentication.GrantTypeRefreshToken) != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*refreshes++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token": "refreshed-%d", "token_type": "Bearer", "expires_in": 300}`, *refreshes)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func tokenContext(server *httptest.Server) *model.Context {
+	ctx := &model.Context{
+		"basic_authentication":    "basic-auth",
+		"token_endpoint":          server.URL,
+		"client_id":               "client",
+		"requestObjectSigningAlg": "PS256",
+		"signingPrivate":          signingPrivate,
+		"signingPublic":           signingPublic,
+	}
+	return ctx
+}
+
+func newTestTokenStore(now time.Time) *TokenStore {
+	store := NewTokenStore(logrus.NewEntry(logrus.New()), nil)
+	store.now = func() time.Time { return now }
+	return store
+}
+
+func TestTokenStore_RefreshesExpiringTokens(t *testing.T) {
+	refreshes := 0
+	ctx := tokenContext(tokenEndpoint(t, &refreshes))
+	ctx.PutString("Token001", "expiring")
+	ctx.PutString("access_token", "expiring")
+	ctx.PutString("Token002", "valid")
+	tokenEvents := events.NewEvents()
+	now := time.Now()
+	store := newTestTokenStore(now)
+	store.Put("Token001", grantToken{AccessToken: "expiring", RefreshToken: "refresh", Expires: 10})
+	store.Put("Token002", grantToken{AccessToken: "valid", RefreshToken: "refresh", Expires: 300})
+
+	store.Refresh(ctx, resty.New(), nil, tokenEvents)
+
+	assert.Equal(t, 1, refreshes)
+	for _, key := range []string{"Token001", "access_token"} {
+		value, err := ctx.GetString(key)
+		require.NoError(t, err)
+		assert.Equal(t, "refreshed-1", value)
+	}
+	value, err := ctx.GetString("Token002")
+	require.NoError(t, err)
+	assert.Equal(t, "valid", value)
+
+	refreshed := store.Tokens()["Token001"]
+	assert.Equal(t, "refresh", refreshed.RefreshToken, "kept when not rotated")
+	assert.Equal(t, now.Add(300*time.Second), refreshed.Expiry)
+	assert.Equal(t, []events.RefreshedAccessToken{
+		events.NewRefreshedAccessToken("Token001", now.Add(300*time.Second)),
+	}, tokenEvents.AllRefreshedAccessTokens())
+
+	// a context copied before the refresh gets the refreshed token without another refresh
+	copied := &model.Context{"Token001": "expiring"}
+	store.Refresh(copied, resty.New(), nil, tokenEvents)
+	assert.Equal(t, 1, refreshes)
+	value, err = copied.GetString("Token001")
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed-1", value)
+}
+
+func TestTokenStore_RefreshesOnceWithoutHoldingTheLock(t *testing.T) {
+	refreshes := 0
+	requested, release := make(chan struct{}), make(chan struct{})
+	endpoint := tokenEndpoint(t, &refreshes)
+	blocking := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(requested)
+		<-release
+		endpoint.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(blocking.Close)
+	store := newTestTokenStore(time.Now())
+	store.Put("Token001", grantToken{AccessToken: "expiring", RefreshToken: "refresh", Expires: 10})
+
+	contexts := make([]*model.Context, 5)
+	wait := sync.WaitGroup{}
+	for i := range contexts {
+		contexts[i] = tokenContext(blocking)
+		contexts[i].PutString("Token001", "expiring")
+		wait.Add(1)
+		go func(ctx *model.Context) {
+			defer wait.Done()
+			store.Refresh(ctx, resty.New(), nil, nil)
+		}(contexts[i])
+	}
+	<-requested
+	assert.Equal(t, "expiring", store.Tokens()["Token001"].AccessToken, "the store isn't locked while refreshing")
+	close(release)
+	wait.Wait()
+
+	assert.Equal(t, 1, refreshes)
+	for _, ctx := range contexts {
+		value, err := ctx.GetString("Token001")
+		require.NoError(t, err)
+		assert.Equal(t, "refreshed-1", value)
+	}
+}
+
+func TestTokenStore_RefreshFailure(t *testing.T) {
+	refreshes := 0
+	ctx := tokenContext(tokenEndpoint(t, &refreshes))
+	ctx.PutString("Token001", "expired")
+	tokenEvents := events.NewEvents()
+	store := newTestTokenStore(time.Now())
+	store.Put("Token001", grantToken{AccessToken: "expired", RefreshToken: "revoked", Expires: 1})
+	store.Put("Token002", grantToken{AccessToken: "no-refresh", Expires: 1})
+
+	store.Refresh(ctx, resty.New(), nil, tokenEvents)
+	store.Refresh(ctx, resty.New(), nil, tokenEvents)
+
+	assert.Equal(t, 0, refreshes)
+	value, err := ctx.GetString("Token001")
+	require.NoError(t, err)
+	assert.Equal(t, "expired", value)
+	refreshed := tokenEvents.AllRefreshedAccessTokens()
+	require.Len(t, refreshed, 1, "a failed refresh isn't retried")
+	assert.Equal(t, "Token001", refreshed[0].TokenName)
+	assert.Contains(t, refreshed[0].Error, "bad status code 400")
+}
+
+func TestTokenStore_WithoutExpiry(t *testing.T) {
+	store := newTestTokenStore(time.Now())
+	store.Put("Token001", grantToken{AccessToken: "token", RefreshToken: "refresh"})
+
+	store.Refresh(&model.Context{}, resty.New(), nil, nil)
+
+	assert.True(t, store.Tokens()["Token001"].Expiry.IsZero())
+
+	var nilStore *TokenStore
+	nilStore.Put("Token001", grantToken{AccessToken: "token"})
+	nilStore.Refresh(&model.Context{}, resty.New(), nil, nil)
+}
@@ -7,9 +7,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"strings"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/version"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/names"
@@ -118,13 +118,10 @@ func getTemplatedTestCases(path string) (tc []model.TestCase, err error) {
 		return tc, nil
 	}
 
-	filedata, err := ioutil.ReadFile("components/account_consent.json")
+	filedata, err := assets.ReadFile("components/account_consent.json")
 	if err != nil {
-		filedata, err = ioutil.ReadFile("../../components/account_consent.json") // handle testing
-		if err != nil {
-			logrus.StandardLogger().Error("Cannot read: components/account_consent " + err.Error())
-			return nil, err
-		}
+		logrus.StandardLogger().Error("Cannot read: components/account_consent " + err.Error())
+		return nil, err
 	}
 	testcases := []model.TestCase{}
 	err = json.Unmarshal(filedata, &testcases)
@@ -0,0 +1,322 @@
+// Package har records HTTP exchanges with an ASPSP in the HTTP Archive format,
+// see http://www.softwareishard.com/blog/har-12-spec/
+//
+// Secrets such as credentials, tokens and authorisation codes are redacted
+// before an exchange is recorded, so archives can be shared with ASPSPs.
+package har
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/version"
+)
+
+// HAR - root of an HTTP Archive file
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+// Log - exchanges recorded
+type Log struct {
+	Version string  `json:"version"`
+	Creator Creator `json:"creator"`
+	Entries []Entry `json:"entries"`
+}
+
+// Creator - application that recorded the exchanges
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Entry - an HTTP request and its response
+// Comment is the ID of the test case or the name of the flow that sent the request
+type Entry struct {
+	StartedDateTime string   `json:"startedDateTime"`
+	Time            float64  `json:"time"` // Milliseconds
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+	Cache           struct{} `json:"cache"`
+	Timings         Timings  `json:"timings"`
+	TLS             *TLS     `json:"_tls,omitempty"`
+	Comment         string   `json:"comment,omitempty"`
+}
+
+// Request - request sent
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	PostData    *PostData   `json:"postData,omitempty"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// Response - response received
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+// Cookie - cookies are recorded in the (redacted) headers only
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NameValue - a header, query or form parameter
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// PostData - body of a request
+type PostData struct {
+	MimeType string      `json:"mimeType"`
+	Params   []NameValue `json:"params,omitempty"`
+	Text     string      `json:"text"`
+}
+
+// Content - body of a response
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// Timings - only the total time of an exchange is known, it's reported as wait
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// TLS - connection the response was received on
+type TLS struct {
+	Version          string   `json:"version"`
+	CipherSuite      string   `json:"cipherSuite"`
+	ServerName       string   `json:"serverName,omitempty"`
+	PeerCertificates []string `json:"peerCertificates,omitempty"` // Subjects, leaf first
+}
+
+const (
+	harVersion  = "1.2"
+	creatorName = "Functional Conformance Suite"
+)
+
+// NewLog - creates an empty log
+func NewLog() Log {
+	return Log{
+		Version: harVersion,
+		Creator: Creator{Name: creatorName, Version: version.FullVersion},
+		Entries: []Entry{},
+	}
+}
+
+// NewEntry - records the exchange of a resty response with secrets redacted,
+// comment identifies what sent the request, e.g.: a test case ID
+// The fields of secrets are redacted too, e.g.: the form fields a headless script marks secret
+func NewEntry(resp *resty.Response, comment string, secrets ...string) Entry {
+	redact := newRedactor(secrets...)
+	entry := Entry{
+		Request:  newRequest(resp.Request, redact),
+		Response: newResponse(resp, redact),
+		Comment:  comment,
+	}
+
+	elapsed := milliseconds(resp.Time())
+	started := resp.ReceivedAt().Add(-resp.Time())
+	if resp.Request != nil && !resp.Request.Time.IsZero() {
+		started = resp.Request.Time
+	}
+	entry.StartedDateTime = started.Format(time.RFC3339Nano)
+	entry.Time = elapsed
+	entry.Timings = Timings{Wait: elapsed}
+
+	if resp.RawResponse != nil && resp.RawResponse.TLS != nil {
+		entry.TLS = newTLS(resp.RawResponse.TLS)
+	}
+	return entry
+}
+
+func newRequest(r *resty.Request, redact redactor) Request {
+	request := Request{
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []Cookie{},
+		Headers:     []NameValue{},
+		QueryString: []NameValue{},
+		HeadersSize: -1,
+	}
+	if r == nil {
+		return request
+	}
+
+	request.Method = r.Method
+	request.URL = r.URL
+	header := r.Header
+	if r.RawRequest != nil {
+		request.Method = r.RawRequest.Method
+		request.URL = r.RawRequest.URL.String()
+		request.HTTPVersion = r.RawRequest.Proto
+		header = r.RawRequest.Header
+	}
+	request.Headers = headers(header, redact)
+
+	request.URL = redact.redactURL(request.URL)
+	if requestURL, err := url.Parse(request.URL); err == nil {
+		request.QueryString = nameValues(requestURL.Query())
+	}
+
+	request.PostData = newPostData(r, redact)
+	if request.PostData != nil {
+		request.BodySize = len(request.PostData.Text)
+	}
+	return request
+}
+
+func newPostData(r *resty.Request, redact redactor) *PostData {
+	if len(r.FormData) > 0 {
+		form := redact.redactValues(r.FormData)
+		return &PostData{
+			MimeType: "application/x-www-form-urlencoded",
+			Params:   nameValues(form),
+			Text:     form.Encode(),
+		}
+	}
+
+	var body string
+	switch value := r.Body.(type) {
+	case nil:
+		return nil
+	case string:
+		body = value
+	case []byte:
+		body = string(value)
+	default:
+		content, err := json.Marshal(value)
+		if err != nil {
+			body = fmt.Sprintf("%v", value)
+		} else {
+			body = string(content)
+		}
+	}
+	return &PostData{
+		MimeType: r.Header.Get("Content-Type"),
+		Text:     redact.redactBody(body),
+	}
+}
+
+func newResponse(resp *resty.Response, redact redactor) Response {
+	response := Response{
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []Cookie{},
+		Headers:     []NameValue{},
+		HeadersSize: -1,
+	}
+	if resp.RawResponse == nil {
+		return response
+	}
+
+	body := string(resp.Body())
+	response.Status = resp.StatusCode()
+	response.StatusText = http.StatusText(resp.StatusCode())
+	response.HTTPVersion = resp.RawResponse.Proto
+	response.Headers = headers(resp.Header(), redact)
+	response.RedirectURL = redact.redactURL(resp.Header().Get("Location"))
+	response.BodySize = len(body)
+	response.Content = Content{
+		Size:     len(body),
+		MimeType: resp.Header().Get("Content-Type"),
+		Text:     redact.redactBody(body),
+	}
+	return response
+}
+
+func newTLS(state *tls.ConnectionState) *TLS {
+	connection := &TLS{
+		Version:     tlsVersions[state.Version],
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+	for _, cert := range state.PeerCertificates {
+		connection.PeerCertificates = append(connection.PeerCertificates, cert.Subject.String())
+	}
+	return connection
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionSSL30: "SSL 3.0",
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// headers - redacted headers sorted by name
+func headers(header http.Header, redact redactor) []NameValue {
+	values := []NameValue{}
+	for name, headerValues := range header {
+		for _, value := range headerValues {
+			values = append(values, NameValue{Name: name, Value: redact.redactHeader(name, value)})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return strings.ToLower(values[i].Name) < strings.ToLower(values[j].Name)
+	})
+	return values
+}
+
+// nameValues - parameters sorted by name
+func nameValues(params url.Values) []NameValue {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := []NameValue{}
+	for _, name := range names {
+		for _, value := range params[name] {
+			values = append(values, NameValue{Name: name, Value: value})
+		}
+	}
+	return values
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Load - reads an HTTP Archive file, e.g.: the exchanges of a report export
+func Load(filename string) (HAR, error) {
+	content, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return HAR{}, errors.Wrapf(err, "har: reading %q", filename)
+	}
+	archive := HAR{}
+	if err := json.Unmarshal(content, &archive); err != nil {
+		return HAR{}, errors.Wrapf(err, "har: parsing %q", filename)
+	}
+	return archive, nil
+}
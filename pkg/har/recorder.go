@@ -0,0 +1,88 @@
+package har
+
+import (
+	"sync"
+
+	"gopkg.in/resty.v1"
+)
+
+// Recorder - records the exchanges of a run, safe to use concurrently
+// A nil Recorder records nothing
+type Recorder struct {
+	lock    *sync.Mutex
+	entries []Entry
+}
+
+// NewRecorder - creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{
+		lock:    &sync.Mutex{},
+		entries: []Entry{},
+	}
+}
+
+// Record - records the exchange of a response, comment identifies what sent the request
+// The fields of secrets are redacted on top of the secret fields, see `NewEntry`
+// Responses without a request, e.g.: when the connection failed, are not recorded
+func (r *Recorder) Record(comment string, resp *resty.Response, secrets ...string) {
+	if r == nil || resp == nil || resp.Request == nil {
+		return
+	}
+	entry := NewEntry(resp, comment, secrets...)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.entries = append(r.entries, entry)
+}
+
+// Len - number of exchanges recorded, see `Since`
+func (r *Recorder) Len() int {
+	if r == nil {
+		return 0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.entries)
+}
+
+// Since - indexes of the exchanges recorded for comment after the first `from` exchanges, in the
+// entries of `HAR`
+func (r *Recorder) Since(from int, comment string) []int {
+	if r == nil {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	indexes := []int{}
+	for i := from; i < len(r.entries); i++ {
+		if r.entries[i].Comment == comment {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// Copy - creates a recorder with the exchanges recorded so far
+func (r *Recorder) Copy() *Recorder {
+	recorder := NewRecorder()
+	if r == nil {
+		return recorder
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	recorder.entries = append(recorder.entries, r.entries...)
+	return recorder
+}
+
+// HAR - the exchanges recorded as an HTTP Archive
+func (r *Recorder) HAR() HAR {
+	log := NewLog()
+	if r == nil {
+		return HAR{Log: log}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	log.Entries = append(log.Entries, r.entries...)
+	return HAR{Log: log}
+}
@@ -0,0 +1,53 @@
+package har
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+)
+
+func TestRecorder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	get := func() *resty.Response {
+		resp, err := resty.R().Get(server.URL)
+		require.NoError(t, err)
+		return resp
+	}
+
+	recorder := NewRecorder()
+	recorder.Record("ExchangeCodeForAccessToken", get())
+	consentGathering := recorder.Copy()
+
+	from := recorder.Len()
+	recorder.Record("OB-301-ACC-100100", get())
+	recorder.Record("OB-301-ACC-100200", get())
+	recorder.Record("OB-301-ACC-100100", get())
+	recorder.Record("OB-301-ACC-100100", nil)
+
+	assert.Equal(t, 4, recorder.Len())
+	assert.Len(t, recorder.Since(from, "OB-301-ACC-100100"), 2)
+	assert.Len(t, recorder.Since(0, "ExchangeCodeForAccessToken"), 1)
+	assert.Equal(t, 1, consentGathering.Len())
+
+	log := recorder.HAR().Log
+	assert.Equal(t, "1.2", log.Version)
+	assert.Len(t, log.Entries, 4)
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var recorder *Recorder
+
+	recorder.Record("OB-301-ACC-100100", &resty.Response{Request: resty.R()})
+
+	assert.Equal(t, 0, recorder.Len())
+	assert.Nil(t, recorder.Since(0, "OB-301-ACC-100100"))
+	assert.Equal(t, 0, recorder.Copy().Len())
+	assert.Equal(t, []Entry{}, recorder.HAR().Log.Entries)
+}
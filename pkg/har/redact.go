@@ -0,0 +1,151 @@
+package har
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces the value of secrets
+const Redacted = "[REDACTED]"
+
+// secretHeaders - headers holding credentials, tokens or sessions
+var secretHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"set-cookie":          true,
+	"x-api-key":           true,
+}
+
+// secretFields - form, query and JSON body fields holding credentials or tokens
+var secretFields = map[string]bool{
+	"access_token":              true,
+	"client_assertion":          true,
+	"client_secret":             true,
+	"code":                      true,
+	"code_verifier":             true,
+	"id_token":                  true,
+	"password":                  true,
+	"refresh_token":             true,
+	"registration_access_token": true,
+}
+
+// redactor - redacts the values of the fields it holds, see `newRedactor`
+type redactor map[string]bool
+
+// newRedactor - a redactor of the secret fields and of secrets, e.g.: the fields a headless
+// script marks secret
+func newRedactor(secrets ...string) redactor {
+	fields := redactor{}
+	for name := range secretFields {
+		fields[name] = true
+	}
+	for _, name := range secrets {
+		fields[name] = true
+	}
+	return fields
+}
+
+// redactHeader - redacts secret header values, keeping the authorization scheme, e.g.: "Bearer [REDACTED]"
+// Secrets in the query or fragment of redirect locations are redacted too
+func (r redactor) redactHeader(name, value string) string {
+	if strings.EqualFold(name, "Location") {
+		return r.redactURL(value)
+	}
+	if !secretHeaders[strings.ToLower(name)] {
+		return value
+	}
+	if split := strings.SplitN(value, " ", 2); len(split) == 2 && strings.Contains(strings.ToLower(name), "authorization") {
+		return split[0] + " " + Redacted
+	}
+	return Redacted
+}
+
+// redactURL - redacts secret fields in the query and fragment of a URL,
+// authorisation responses return codes and tokens in either
+func (r redactor) redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	if query := u.Query(); r.containsSecret(query) {
+		u.RawQuery = r.redactValues(query).Encode()
+	}
+	if fragment, err := url.ParseQuery(u.Fragment); err == nil && r.containsSecret(fragment) {
+		u.Fragment = r.redactValues(fragment).Encode()
+	}
+	return u.String()
+}
+
+// redactValues - copy of form or query parameters with secret fields redacted
+func (r redactor) redactValues(values url.Values) url.Values {
+	redacted := url.Values{}
+	for name, fieldValues := range values {
+		for _, value := range fieldValues {
+			if r[name] {
+				value = Redacted
+			}
+			redacted.Add(name, value)
+		}
+	}
+	return redacted
+}
+
+// redactBody - redacts secret fields of JSON and form bodies, other bodies are kept
+func (r redactor) redactBody(body string) string {
+	trimmed := strings.TrimSpace(body)
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var data interface{}
+		if err := json.Unmarshal([]byte(trimmed), &data); err != nil {
+			return body
+		}
+		if !r.redactJSON(data) {
+			return body
+		}
+		content, err := json.Marshal(data)
+		if err != nil {
+			return body
+		}
+		return string(content)
+	}
+
+	if values, err := url.ParseQuery(trimmed); err == nil && r.containsSecret(values) {
+		return r.redactValues(values).Encode()
+	}
+	return body
+}
+
+// redactJSON - redacts secret fields of a decoded JSON value in place, returns true if any was found
+func (r redactor) redactJSON(data interface{}) bool {
+	found := false
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if r[key] {
+				value[key] = Redacted
+				found = true
+				continue
+			}
+			if r.redactJSON(field) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if r.redactJSON(item) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+func (r redactor) containsSecret(values url.Values) bool {
+	for name := range values {
+		if r[name] {
+			return true
+		}
+	}
+	return false
+}
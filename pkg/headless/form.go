@@ -0,0 +1,201 @@
+package headless
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// form - an HTML form of a page, with the values a browser would submit
+type form struct {
+	action  *url.URL
+	method  string
+	values  url.Values
+	options map[string][]string // values offered by checkboxes, radios and selects, by field name
+	buttons map[string]string   // submit buttons, by name
+}
+
+// parseForm - the form of body selected by selector: "#id", a name or an id, the first
+// form when empty. Actions are resolved against page
+func parseForm(body string, page *url.URL, selector string) (*form, error) {
+	doc, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing page")
+	}
+
+	node := findForm(doc, strings.TrimPrefix(selector, "#"))
+	if node == nil {
+		if selector == "" {
+			return nil, errors.Errorf("no form on page %s", page)
+		}
+		return nil, errors.Errorf("no form %q on page %s", selector, page)
+	}
+
+	action, err := page.Parse(attr(node, "action"))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid form action %q", attr(node, "action"))
+	}
+	f := &form{
+		action:  action,
+		method:  strings.ToUpper(attr(node, "method")),
+		values:  url.Values{},
+		options: map[string][]string{},
+		buttons: map[string]string{},
+	}
+	if f.method != "POST" {
+		f.method = "GET"
+	}
+	f.addFields(node)
+	return f, nil
+}
+
+func findForm(node *html.Node, selector string) *html.Node {
+	if isElement(node, "form") && (selector == "" || attr(node, "id") == selector || attr(node, "name") == selector) {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := findForm(child, selector); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (f *form) addFields(node *html.Node) {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		name := attr(child, "name")
+		switch {
+		case name == "" || hasAttr(child, "disabled"):
+		case isElement(child, "input"):
+			f.addInput(child, name)
+		case isElement(child, "button"):
+			if t := strings.ToLower(attr(child, "type")); t == "" || t == "submit" {
+				f.buttons[name] = attr(child, "value")
+			}
+		case isElement(child, "select"):
+			f.addSelect(child, name)
+			continue
+		case isElement(child, "textarea"):
+			f.values.Add(name, text(child))
+			continue
+		}
+		f.addFields(child)
+	}
+}
+
+func (f *form) addInput(node *html.Node, name string) {
+	value := attr(node, "value")
+	switch strings.ToLower(attr(node, "type")) {
+	case "checkbox", "radio":
+		if value == "" {
+			value = "on"
+		}
+		f.options[name] = append(f.options[name], value)
+		if hasAttr(node, "checked") {
+			f.values.Add(name, value)
+		}
+	case "submit", "image":
+		f.buttons[name] = value
+	case "button", "reset", "file":
+	default:
+		f.values.Add(name, value)
+	}
+}
+
+func (f *form) addSelect(node *html.Node, name string) {
+	var options, selected []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if !isElement(child, "option") {
+				walk(child)
+				continue
+			}
+			value := text(child)
+			if hasAttr(child, "value") {
+				value = attr(child, "value")
+			}
+			options = append(options, value)
+			if hasAttr(child, "selected") {
+				selected = append(selected, value)
+			}
+		}
+	}
+	walk(node)
+
+	f.options[name] = append(f.options[name], options...)
+	if len(selected) == 0 && len(options) > 0 && !hasAttr(node, "multiple") {
+		selected = options[:1]
+	}
+	for _, value := range selected {
+		f.values.Add(name, value)
+	}
+}
+
+// selectOptions - selects the values of field in accounts, all values offered when empty
+func (f *form) selectOptions(field string, accounts []string) error {
+	offered := f.options[field]
+	if len(offered) == 0 {
+		return errors.Errorf("no accounts offered by field %q", field)
+	}
+	if len(accounts) == 0 {
+		f.values[field] = offered
+		return nil
+	}
+
+	selected := []string{}
+	for _, account := range accounts {
+		found := false
+		for _, value := range offered {
+			if value == account {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("account %q not offered by field %q", account, field)
+		}
+		selected = append(selected, account)
+	}
+	f.values[field] = selected
+	return nil
+}
+
+func isElement(node *html.Node, tag string) bool {
+	return node.Type == html.ElementNode && node.Data == tag
+}
+
+func hasAttr(node *html.Node, key string) bool {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func text(node *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type == html.TextNode {
+				b.WriteString(child.Data)
+			}
+			walk(child)
+		}
+	}
+	walk(node)
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,259 @@
+package headless
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/publicsuffix"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// maxRedirects - redirects followed by a request of a script
+const maxRedirects = 10
+
+// run - the state of a script run: the current page, its form and the redirect to the
+// redirect url
+type run struct {
+	script      Script
+	client      *resty.Client
+	ctx         *model.Context
+	recorder    *har.Recorder
+	logger      *logrus.Entry
+	redirectURL string
+	page        *url.URL
+	body        string
+	location    string
+	form        *form
+	code        string
+	secrets     []string
+}
+
+// Run - runs the script from consentURL and returns the authorisation code captured. Requests
+// are sent by a client of client, the HTTP client of the run, with a cookie jar of its own, and
+// recorded by recorder, which may be nil. `$` values are replaced by the values of ctx
+func (s Script) Run(ctx *model.Context, client *resty.Client, consentURL string, recorder *har.Recorder) (string, error) {
+	if client == nil {
+		return "", errors.Errorf("headless: script %q has no HTTP client", s.Name)
+	}
+	redirectURL, _ := ctx.GetString("redirect_url")
+	r := &run{
+		script:      s,
+		client:      newClient(client),
+		ctx:         ctx,
+		recorder:    recorder,
+		logger:      logrus.WithFields(logrus.Fields{"module": "headless", "script": s.Name}),
+		redirectURL: redirectURL,
+	}
+
+	if err := r.load(http.MethodGet, consentURL, nil); err != nil {
+		return "", errors.Wrapf(err, "headless: script %q loading consent url", s.Name)
+	}
+	for i, step := range s.Steps {
+		r.logger.WithField("action", step.Action).Debug("running step")
+		if err := r.step(step); err != nil {
+			return "", errors.Wrapf(err, "headless: script %q step %d (%s)", s.Name, i+1, step.Action)
+		}
+	}
+	if r.code == "" {
+		return "", errors.Errorf("headless: script %q didn't capture the code", s.Name)
+	}
+	return r.code, nil
+}
+
+// newClient - a client of the transport and timeout of base, keeping cookies and not following
+// redirects so the redirect url isn't requested
+func newClient(base *resty.Client) *resty.Client {
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List}) // never fails
+	httpClient := *base.GetClient()
+	httpClient.Jar = jar
+
+	client := resty.NewWithClient(&httpClient).
+		SetRedirectPolicy(resty.RedirectPolicyFunc(func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		})).
+		SetDebug(base.Debug)
+	client.Log = base.Log
+	for key, values := range base.Header {
+		client.Header[key] = values
+	}
+	return client
+}
+
+func (r *run) step(step Step) error {
+	switch step.Action {
+	case ActionFollow:
+		target, err := r.expand(step.URL)
+		if err != nil {
+			return err
+		}
+		return r.load(http.MethodGet, target, nil)
+
+	case ActionForm:
+		if r.page == nil {
+			return errors.New("no page loaded, redirected to the redirect url")
+		}
+		form, err := parseForm(r.body, r.page, step.Form)
+		if err != nil {
+			return err
+		}
+		r.form = form
+
+	case ActionFill:
+		if r.form == nil {
+			return errors.New("no form extracted")
+		}
+		for name, value := range step.Fields {
+			expanded, err := r.expand(value)
+			if err != nil {
+				return err
+			}
+			r.form.values.Set(name, expanded)
+		}
+		r.secrets = append(r.secrets, step.Secret...)
+
+	case ActionSelectAccounts:
+		if r.form == nil {
+			return errors.New("no form extracted")
+		}
+		accounts := make([]string, 0, len(step.Accounts))
+		for _, account := range step.Accounts {
+			expanded, err := r.expand(account)
+			if err != nil {
+				return err
+			}
+			accounts = append(accounts, expanded)
+		}
+		return r.form.selectOptions(step.Field, accounts)
+
+	case ActionSubmit:
+		if r.form == nil {
+			return errors.New("no form extracted")
+		}
+		values := url.Values{}
+		for name, value := range r.form.values {
+			values[name] = value
+		}
+		if step.Button != "" {
+			value, ok := r.form.buttons[step.Button]
+			if !ok {
+				return errors.Errorf("no submit button %q", step.Button)
+			}
+			values.Set(step.Button, value)
+		}
+		form := r.form
+		r.form = nil
+		return r.load(form.method, form.action.String(), values)
+
+	case ActionCaptureCode:
+		regex, err := step.codeRegex()
+		if err != nil {
+			return err
+		}
+		return r.capture(regex, step.From)
+
+	default:
+		return errors.Errorf("unknown action %q", step.Action)
+	}
+	return nil
+}
+
+// load - sends a request and follows its redirects, stopping at the redirect url
+func (r *run) load(method, target string, values url.Values) error {
+	for redirects := 0; ; redirects++ {
+		if redirects > maxRedirects {
+			return errors.Errorf("more than %d redirects", maxRedirects)
+		}
+		req := r.client.R().SetHeader("Accept", "text/html,*/*")
+		if method == http.MethodPost {
+			req.SetMultiValueFormData(values)
+		} else if values != nil {
+			req.SetMultiValueQueryParams(values)
+		}
+		resp, err := req.Execute(method, target)
+		r.recorder.Record("HeadlessConsentScript "+r.script.Name, resp, r.secrets...)
+		if err != nil {
+			return errors.Wrapf(err, "%s %s", method, target)
+		}
+		page := resp.RawResponse.Request.URL
+
+		location := resp.Header().Get("Location")
+		if resp.StatusCode() < 300 || resp.StatusCode() >= 400 || location == "" {
+			if resp.StatusCode() >= 400 {
+				return errors.Errorf("%s %s: status %d", method, target, resp.StatusCode())
+			}
+			r.page, r.body, r.location = page, string(resp.Body()), ""
+			return nil
+		}
+
+		next, err := page.Parse(location)
+		if err != nil {
+			return errors.Wrapf(err, "invalid redirect %q", location)
+		}
+		if r.isRedirectURL(next) {
+			r.page, r.body, r.location = nil, "", next.String()
+			return nil
+		}
+		// redirects are followed with GET, as by browsers for 301, 302 and 303
+		method, target, values = http.MethodGet, next.String(), nil
+	}
+}
+
+// isRedirectURL - true when u is the redirect url of the run, ignoring its query and fragment
+func (r *run) isRedirectURL(u *url.URL) bool {
+	if r.redirectURL == "" {
+		return false
+	}
+	redirect, err := url.Parse(r.redirectURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, redirect.Scheme) && strings.EqualFold(u.Host, redirect.Host) &&
+		strings.TrimSuffix(u.Path, "/") == strings.TrimSuffix(redirect.Path, "/")
+}
+
+func (r *run) capture(regex *regexp.Regexp, from string) error {
+	source := r.location
+	if from == "body" {
+		source = r.body
+	} else if source == "" {
+		return errors.New("not redirected to the redirect url")
+	}
+	match := regex.FindStringSubmatch(source)
+	if len(match) < 2 || match[1] == "" {
+		return errors.Errorf("regex %q doesn't match", regex)
+	}
+	code, err := url.QueryUnescape(match[1])
+	if err != nil {
+		code = match[1]
+	}
+	r.code = code
+	r.logger.Debug("captured authorisation code")
+	return nil
+}
+
+var replacementRegex = regexp.MustCompile(`\$([\w\-]+)`)
+
+// expand - value with each `$name` replaced by the context value of name
+func (r *run) expand(value string) (string, error) {
+	var missing []string
+	expanded := replacementRegex.ReplaceAllStringFunc(value, func(field string) string {
+		replacement, err := r.ctx.GetString(field[1:])
+		if err != nil {
+			missing = append(missing, field)
+			return field
+		}
+		return replacement
+	})
+	if len(missing) > 0 {
+		return "", errors.Errorf("%s not in context", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
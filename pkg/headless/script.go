@@ -0,0 +1,185 @@
+// Package headless runs headless consent scripts: declarative descriptions of how an ASPSP's
+// authorisation pages are driven without a PSU, from the consent URL to the authorisation code
+// of the redirect.
+//
+// A script is a JSON file of steps run in order, after the consent URL is loaded:
+//
+//	{
+//	  "name": "sandbox-login",
+//	  "steps": [
+//	    {"action": "form", "form": "#login"},
+//	    {"action": "fill", "fields": {"username": "$psu_username", "password": "$psu_password"}, "secret": ["password"]},
+//	    {"action": "submit"},
+//	    {"action": "form"},
+//	    {"action": "select_accounts", "field": "accounts"},
+//	    {"action": "submit", "button": "approve"},
+//	    {"action": "capture_code"}
+//	  ]
+//	}
+//
+// Redirects are followed, except to the redirect url of the run, and cookies are kept in a
+// cookie jar of the script run. Values starting with `$` are replaced by the context value
+// of the name, e.g.: the `headless_parameters` of the configuration. The values of the `secret`
+// fields of a fill are redacted in the exchanges recorded, as are fields named like secrets,
+// e.g.: `password`.
+package headless
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+)
+
+// Step actions
+const (
+	// ActionFollow - loads the page of `url`, relative to the current page
+	ActionFollow = "follow"
+	// ActionForm - extracts the form of the current page selected by `form`, the first when empty
+	ActionForm = "form"
+	// ActionFill - sets the `fields` of the form extracted, the values of the `secret` fields
+	// redacted in the exchanges recorded
+	ActionFill = "fill"
+	// ActionSelectAccounts - checks the `accounts` offered by the checkbox or select `field` of
+	// the form extracted, all when empty
+	ActionSelectAccounts = "select_accounts"
+	// ActionSubmit - submits the form extracted, with the submit `button` when not empty
+	ActionSubmit = "submit"
+	// ActionCaptureCode - captures the authorisation code with `regex` from the redirect to the
+	// redirect url, or from the body of the current page when `from` is "body"
+	ActionCaptureCode = "capture_code"
+)
+
+// defaultCodeRegex - the `code` parameter of the query or fragment of the redirect url
+const defaultCodeRegex = `[?&#]code=([^&#]+)`
+
+// scriptDirectory - directory of the bundled scripts
+const scriptDirectory = "components/headless/"
+
+// Script - a headless consent script
+type Script struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Steps       []Step `json:"steps"`
+}
+
+// Step - a step of a script, the fields used depend on the action
+type Step struct {
+	Action   string            `json:"action"`
+	URL      string            `json:"url,omitempty"`      // follow
+	Form     string            `json:"form,omitempty"`     // form: "#id" or the name or id of the form
+	Fields   map[string]string `json:"fields,omitempty"`   // fill: field names and values
+	Secret   []string          `json:"secret,omitempty"`   // fill: names of the fields redacted in the exchanges recorded
+	Field    string            `json:"field,omitempty"`    // select_accounts: name of the field listing the accounts
+	Accounts []string          `json:"accounts,omitempty"` // select_accounts: values of the accounts
+	Button   string            `json:"button,omitempty"`   // submit: name of the submit button
+	From     string            `json:"from,omitempty"`     // capture_code: "location" (default) or "body"
+	Regex    string            `json:"regex,omitempty"`    // capture_code: first group is the code
+}
+
+var (
+	operatorScriptDir string
+	lock              = &sync.RWMutex{}
+)
+
+// SetScriptDir - the directory of the operator's scripts, loaded by name when not bundled, none
+// when empty
+func SetScriptDir(dir string) {
+	lock.Lock()
+	defer lock.Unlock()
+	operatorScriptDir = dir
+}
+
+// Load - loads the script filename, bundled in `components/headless` or else in the directory set
+// by `SetScriptDir`. filename is a file name, scripts of other paths aren't loaded
+func Load(filename string) (Script, error) {
+	if filename == "" || filename != filepath.Base(filename) || strings.ContainsAny(filename, `/\`) || strings.HasPrefix(filename, ".") {
+		return Script{}, errors.Errorf("headless.Load: script %q isn't a file name", filename)
+	}
+	content, err := assets.ReadFile(scriptDirectory + filename)
+	if err != nil {
+		lock.RLock()
+		dir := operatorScriptDir
+		lock.RUnlock()
+		if dir == "" {
+			return Script{}, errors.Wrapf(err, "headless.Load: script %q", filename)
+		}
+		content, err = ioutil.ReadFile(filepath.Join(dir, filename))
+		if err != nil {
+			return Script{}, errors.Wrapf(err, "headless.Load: script %q", filename)
+		}
+	}
+
+	script := Script{}
+	if err := json.Unmarshal(content, &script); err != nil {
+		return Script{}, errors.Wrapf(err, "headless.Load: script %q", filename)
+	}
+	if script.Name == "" {
+		script.Name = filename
+	}
+	return script, script.Validate()
+}
+
+// Validate - checks the steps of the script have the fields of their action, and the script
+// captures the code
+func (s Script) Validate() error {
+	captures := false
+	for i, step := range s.Steps {
+		var err error
+		switch step.Action {
+		case ActionFollow:
+			if step.URL == "" {
+				err = errors.New("missing url")
+			}
+		case ActionForm, ActionSubmit:
+		case ActionFill:
+			if len(step.Fields) == 0 {
+				err = errors.New("missing fields")
+			}
+		case ActionSelectAccounts:
+			if step.Field == "" {
+				err = errors.New("missing field")
+			}
+		case ActionCaptureCode:
+			captures = true
+			err = step.validateCapture()
+		default:
+			err = errors.Errorf("unknown action %q", step.Action)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "headless: script %q step %d", s.Name, i+1)
+		}
+	}
+	if !captures {
+		return errors.Errorf("headless: script %q doesn't capture the code", s.Name)
+	}
+	return nil
+}
+
+func (s Step) validateCapture() error {
+	if s.From != "" && s.From != "location" && s.From != "body" {
+		return errors.Errorf("unknown from %q", s.From)
+	}
+	regex, err := s.codeRegex()
+	if err != nil {
+		return err
+	}
+	if regex.NumSubexp() < 1 {
+		return errors.Errorf("regex %q has no group", regex)
+	}
+	return nil
+}
+
+func (s Step) codeRegex() (*regexp.Regexp, error) {
+	if s.Regex == "" {
+		return regexp.MustCompile(defaultCodeRegex), nil
+	}
+	regex, err := regexp.Compile(s.Regex)
+	return regex, errors.Wrapf(err, "invalid regex %q", s.Regex)
+}
@@ -0,0 +1,269 @@
+package headless
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+const redirectURL = "https://tpp.example.com/callback"
+
+// aspsp - the authorisation pages of an ASPSP: the consent url redirects to a login form,
+// keeping the session in a cookie, then to an account selection form redirecting to the
+// redirect url with the code. approved receives the accounts selected
+func aspsp(t *testing.T, approved *[]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+		http.Redirect(w, r, "/login?state="+r.URL.Query().Get("state"), http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprintf(w, `<html><body>
+				<form id="search" action="/search"><input name="q"></form>
+				<form id="login" method="post" action="login">
+					<input type="hidden" name="state" value="%s">
+					<input name="username"><input type="password" name="password">
+					<button type="submit" name="login" value="1">Log in</button>
+				</form></body></html>`, r.URL.Query().Get("state"))
+			return
+		}
+		require.NoError(t, r.ParseForm())
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "s1" || r.PostForm.Get("username") != "psu" || r.PostForm.Get("password") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, "/consent?state="+r.PostForm.Get("state"), http.StatusSeeOther)
+	})
+	mux.HandleFunc("/consent", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			fmt.Fprintf(w, `<form method="POST">
+				<input type="hidden" name="state" value="%s">
+				<label><input type="checkbox" name="accounts" value="500000000000000000000001"> Current</label>
+				<label><input type="checkbox" name="accounts" value="500000000000000000000002"> Savings</label>
+				<select name="sca"><option value="sms">SMS</option><option value="app" selected>App</option></select>
+				<input type="submit" name="approve" value="yes"><input type="submit" name="deny" value="no">
+				</form>`, r.URL.Query().Get("state"))
+			return
+		}
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("approve") != "yes" || r.PostForm.Get("deny") != "" || r.PostForm.Get("sca") != "app" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*approved = r.PostForm["accounts"]
+		http.Redirect(w, r, redirectURL+"#code=c%2B1&state="+r.PostForm.Get("state"), http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func loginScript(accounts ...string) Script {
+	return Script{
+		Name: "login",
+		Steps: []Step{
+			{Action: ActionForm, Form: "#login"},
+			{Action: ActionFill, Fields: map[string]string{"username": "$psu_username", "password": "$psu_password"}},
+			{Action: ActionSubmit},
+			{Action: ActionForm},
+			{Action: ActionSelectAccounts, Field: "accounts", Accounts: accounts},
+			{Action: ActionSubmit, Button: "approve"},
+			{Action: ActionCaptureCode},
+		},
+	}
+}
+
+func scriptContext() *model.Context {
+	ctx := &model.Context{
+		"redirect_url": redirectURL,
+		"psu_username": "psu",
+		"psu_password": "secret",
+	}
+	return ctx
+}
+
+func TestScript_Run(t *testing.T) {
+	approved := []string{}
+	server := aspsp(t, &approved)
+	recorder := har.NewRecorder()
+
+	code, err := loginScript().Run(scriptContext(), resty.New(), server.URL+"/authorize?state=token001", recorder)
+
+	require.NoError(t, err)
+	assert.Equal(t, "c+1", code)
+	assert.Equal(t, []string{"500000000000000000000001", "500000000000000000000002"}, approved, "all accounts by default")
+	assert.Equal(t, 5, recorder.Len(), "the redirect url isn't requested")
+}
+
+func TestScript_Run_RedactsSecretFields(t *testing.T) {
+	approved := []string{}
+	server := aspsp(t, &approved)
+	recorder := har.NewRecorder()
+	script := loginScript()
+	script.Steps[1].Secret = []string{"username"}
+
+	_, err := script.Run(scriptContext(), resty.New(), server.URL+"/authorize", recorder)
+
+	require.NoError(t, err)
+	login := recorder.HAR().Log.Entries[2].Request
+	require.Equal(t, http.MethodPost, login.Method)
+	require.NotNil(t, login.PostData)
+	form, err := url.ParseQuery(login.PostData.Text)
+	require.NoError(t, err)
+	assert.Equal(t, har.Redacted, form.Get("username"))
+	assert.Equal(t, har.Redacted, form.Get("password"))
+	assert.NotEqual(t, har.Redacted, form.Get("state"))
+}
+
+func TestScript_Run_SelectsAccounts(t *testing.T) {
+	approved := []string{}
+	server := aspsp(t, &approved)
+
+	_, err := loginScript("$account_id").Run(scriptContext(), resty.New(), server.URL+"/authorize", nil)
+	assert.EqualError(t, err, `headless: script "login" step 5 (select_accounts): $account_id not in context`)
+
+	ctx := scriptContext()
+	ctx.PutString("account_id", "500000000000000000000002")
+	_, err = loginScript("$account_id").Run(ctx, resty.New(), server.URL+"/authorize", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"500000000000000000000002"}, approved)
+
+	_, err = loginScript("500000000000000000000003").Run(ctx, resty.New(), server.URL+"/authorize", nil)
+	assert.EqualError(t, err, `headless: script "login" step 5 (select_accounts): account "500000000000000000000003" not offered by field "accounts"`)
+}
+
+func TestScript_Run_Failures(t *testing.T) {
+	approved := []string{}
+	server := aspsp(t, &approved)
+
+	ctx := scriptContext()
+	ctx.PutString("psu_password", "wrong")
+	_, err := loginScript().Run(ctx, resty.New(), server.URL+"/authorize", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `step 3 (submit): POST `+server.URL+`/login: status 401`)
+
+	script := loginScript()
+	script.Steps[0].Form = "#missing"
+	_, err = script.Run(scriptContext(), resty.New(), server.URL+"/authorize", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `step 1 (form): no form "#missing" on page`)
+}
+
+func TestScript_Run_RedirectsWithCode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, redirectURL+"?code=ozone-code&state=token001", http.StatusFound)
+	}))
+	defer server.Close()
+
+	script, err := Load("ozone.json")
+	require.NoError(t, err)
+
+	code, err := script.Run(scriptContext(), resty.New(), server.URL, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ozone-code", code)
+}
+
+func TestLoad_ScriptDir(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"steps": [{"action": "capture_code"}]}`
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "operator.json"), []byte(content), 0600))
+	outside := filepath.Join(t.TempDir(), "outside.json")
+	require.NoError(t, ioutil.WriteFile(outside, []byte(content), 0600))
+	defer SetScriptDir("")
+
+	_, err := Load("operator.json")
+	assert.Error(t, err, "no script directory")
+
+	SetScriptDir(dir)
+	script, err := Load("operator.json")
+	require.NoError(t, err)
+	assert.Equal(t, "operator.json", script.Name)
+	_, err = Load("ozone.json")
+	assert.NoError(t, err, "bundled scripts are still loaded")
+
+	for _, filename := range []string{outside, "../" + filepath.Base(dir) + "/operator.json", "..", ""} {
+		_, err = Load(filename)
+		assert.EqualError(t, err, fmt.Sprintf("headless.Load: script %q isn't a file name", filename))
+	}
+}
+
+func TestScript_Validate(t *testing.T) {
+	testCases := []struct {
+		steps []Step
+		err   string
+	}{
+		{
+			steps: []Step{{Action: ActionCaptureCode, From: "body", Regex: `code: (\w+)`}},
+		},
+		{
+			steps: []Step{{Action: ActionSubmit}},
+			err:   `headless: script "test" doesn't capture the code`,
+		},
+		{
+			steps: []Step{{Action: "click"}},
+			err:   `headless: script "test" step 1: unknown action "click"`,
+		},
+		{
+			steps: []Step{{Action: ActionFollow}},
+			err:   `headless: script "test" step 1: missing url`,
+		},
+		{
+			steps: []Step{{Action: ActionForm}, {Action: ActionFill}},
+			err:   `headless: script "test" step 2: missing fields`,
+		},
+		{
+			steps: []Step{{Action: ActionSelectAccounts}},
+			err:   `headless: script "test" step 1: missing field`,
+		},
+		{
+			steps: []Step{{Action: ActionCaptureCode, Regex: `code=\w+`}},
+			err:   `headless: script "test" step 1: regex "code=\\w+" has no group`,
+		},
+		{
+			steps: []Step{{Action: ActionCaptureCode, From: "header"}},
+			err:   `headless: script "test" step 1: unknown from "header"`,
+		},
+	}
+	for _, tc := range testCases {
+		err := Script{Name: "test", Steps: tc.steps}.Validate()
+		if tc.err == "" {
+			assert.NoError(t, err)
+			continue
+		}
+		assert.EqualError(t, err, tc.err)
+	}
+}
+
+func TestParseForm(t *testing.T) {
+	body := `<form name="consent" action="/approve?x=1">
+		<input name="a" value="1" disabled>
+		<input type="radio" name="r" value="one"><input type="radio" name="r" value="two" checked>
+		<input type="checkbox" name="c">
+		<textarea name="note"> hello </textarea>
+		<select name="m" multiple><option>x</option><option>y</option></select>
+	</form>`
+
+	page, err := url.Parse("https://aspsp.example.com/consent/page")
+	require.NoError(t, err)
+
+	f, err := parseForm(body, page, "consent")
+
+	require.NoError(t, err)
+	assert.Equal(t, "GET", f.method)
+	assert.Equal(t, "https://aspsp.example.com/approve?x=1", f.action.String())
+	assert.Equal(t, "note=hello&r=two", f.values.Encode())
+	assert.Equal(t, map[string][]string{"r": {"one", "two"}, "c": {"on"}, "m": {"x", "y"}}, f.options)
+}
@@ -0,0 +1,336 @@
+// Package lint applies cross-cutting Open Banking rules to every response,
+// on top of the swagger schema validation.
+//
+// Rules are declared per spec version in `rules/`, e.g. `rules/v3.1.json`
+// applies to all `v3.1.x` responses. Each rule has an ID, a severity and a
+// check, violations of error rules fail a test case, violations of warning
+// rules are only reported. The bundled rules are warnings, operators opt into
+// failing test cases on them with EnableErrorRules.
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Rule severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Rule checks
+const (
+	CheckPresent  = "present"   // Fields must be present in the body
+	CheckPattern  = "pattern"   // String values of Fields must match Pattern
+	CheckSelfLink = "self-link" // Links.Self must be an absolute URL matching the request
+)
+
+// RuleSet - rules that apply to responses of a spec version
+type RuleSet struct {
+	Version string `json:"version"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule - a declarative response rule
+// Fields are dotted JSON paths without array indexes, e.g.: "Data.Transaction.Amount.Amount"
+// For the pattern check a field matches any path ending with it and may
+// contain wildcards, e.g.: "*DateTime" matches "Data.Balance.DateTime"
+// StatusCodes restricts the rule to a class of status codes, e.g.: "2xx", empty applies to all
+type Rule struct {
+	ID          string   `json:"id"`
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"`
+	Check       string   `json:"check"`
+	Fields      []string `json:"fields,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	StatusCodes string   `json:"statusCodes,omitempty"`
+	Disabled    bool     `json:"disabled,omitempty"`
+
+	regexp *regexp.Regexp
+}
+
+// Response - the response a rule set is applied to
+type Response struct {
+	URL        string // URL of the request
+	StatusCode int
+	Body       string
+}
+
+// Violation - records a rule that was not respected by a response
+type Violation struct {
+	RuleID   string `json:"ruleId"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s (%s): %s", v.RuleID, v.Severity, v.Message)
+}
+
+// LoadRuleSet reads the rule set bundled for a spec version
+func LoadRuleSet(version string) (RuleSet, error) {
+	sver, err := semver.ParseTolerant(version)
+	if err != nil {
+		return RuleSet{}, errors.Wrapf(err, "lint: parsing rule set version %q", version)
+	}
+	filename := fmt.Sprintf("pkg/lint/rules/v%d.%d.json", sver.Major, sver.Minor)
+
+	content, err := assets.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return RuleSet{}, fmt.Errorf("lint: could not find rule set for version %s", version)
+	} else if err != nil {
+		return RuleSet{}, errors.Wrapf(err, "lint: reading rule set, filename=%q", filename)
+	}
+	return ParseRuleSet(content)
+}
+
+// ParseRuleSet parses and checks a JSON rule set
+func ParseRuleSet(content []byte) (RuleSet, error) {
+	ruleSet := RuleSet{}
+	if err := json.Unmarshal(content, &ruleSet); err != nil {
+		return RuleSet{}, errors.Wrap(err, "lint: parsing rule set")
+	}
+
+	for i, rule := range ruleSet.Rules {
+		if rule.Severity != SeverityError && rule.Severity != SeverityWarning {
+			return RuleSet{}, fmt.Errorf("lint: rule %s has unknown severity %q", rule.ID, rule.Severity)
+		}
+		switch rule.Check {
+		case CheckPresent, CheckSelfLink:
+		case CheckPattern:
+			exp, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return RuleSet{}, errors.Wrapf(err, "lint: rule %s has invalid pattern", rule.ID)
+			}
+			ruleSet.Rules[i].regexp = exp
+		default:
+			return RuleSet{}, fmt.Errorf("lint: rule %s has unknown check %q", rule.ID, rule.Check)
+		}
+	}
+
+	return ruleSet, nil
+}
+
+var (
+	ruleSets     = map[string]*RuleSet{}
+	ruleSetsLock = &sync.Mutex{}
+
+	// errorRules - IDs of rules reported as errors whatever their severity, "all" for every rule
+	errorRules     = map[string]bool{}
+	errorRulesLock = &sync.RWMutex{}
+)
+
+// AllRules - enables every rule as an error with EnableErrorRules
+const AllRules = "all"
+
+// EnableErrorRules - report violations of the rules of ids as errors, failing test cases,
+// AllRules for every rule. Rules are reported with their declared severity otherwise
+func EnableErrorRules(ids ...string) {
+	errorRulesLock.Lock()
+	defer errorRulesLock.Unlock()
+	errorRules = map[string]bool{}
+	for _, id := range ids {
+		errorRules[strings.TrimSpace(id)] = true
+	}
+}
+
+// severity - the reported severity of a rule
+func (r Rule) severity() string {
+	errorRulesLock.RLock()
+	defer errorRulesLock.RUnlock()
+	if errorRules[AllRules] || errorRules[r.ID] {
+		return SeverityError
+	}
+	return r.Severity
+}
+
+// RuleSetFor returns the rule set for a spec version, loading it the first
+// time it's needed, false if no rule set is available for the version
+func RuleSetFor(version string) (RuleSet, bool) {
+	if version == "" {
+		return RuleSet{}, false
+	}
+
+	ruleSetsLock.Lock()
+	defer ruleSetsLock.Unlock()
+
+	ruleSet, ok := ruleSets[version]
+	if !ok {
+		loaded, err := LoadRuleSet(version)
+		if err != nil {
+			logrus.WithError(err).WithField("version", version).Warn("response lint rules disabled")
+		} else {
+			ruleSet = &loaded
+		}
+		ruleSets[version] = ruleSet
+	}
+
+	if ruleSet == nil {
+		return RuleSet{}, false
+	}
+	return *ruleSet, true
+}
+
+// Lint applies all enabled rules to a response
+// Responses without a JSON object body are not checked
+func (s RuleSet) Lint(r Response) []Violation {
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(r.Body), &data); err != nil {
+		return nil
+	}
+
+	violations := []Violation{}
+	for _, rule := range s.Rules {
+		if rule.Disabled || !rule.appliesTo(r.StatusCode) {
+			continue
+		}
+		for _, message := range rule.apply(r, data) {
+			violations = append(violations, Violation{
+				RuleID:   rule.ID,
+				Severity: rule.severity(),
+				Message:  message,
+			})
+		}
+	}
+	return violations
+}
+
+func (r Rule) appliesTo(statusCode int) bool {
+	if r.StatusCodes == "" {
+		return true
+	}
+	return r.StatusCodes == fmt.Sprintf("%dxx", statusCode/100)
+}
+
+func (r Rule) apply(response Response, data map[string]interface{}) []string {
+	switch r.Check {
+	case CheckPresent:
+		return r.checkPresent(data)
+	case CheckPattern:
+		return r.checkPattern(data, nil)
+	case CheckSelfLink:
+		return r.checkSelfLink(response, data)
+	}
+	return nil
+}
+
+func (r Rule) checkPresent(data map[string]interface{}) []string {
+	messages := []string{}
+	for _, field := range r.Fields {
+		if _, ok := lookup(data, field); !ok {
+			messages = append(messages, fmt.Sprintf("%s is required: %s", field, r.Description))
+		}
+	}
+	return messages
+}
+
+// checkPattern walks data matching string values of fields against the rule pattern,
+// segments are the property names leading to data
+func (r Rule) checkPattern(data interface{}, segments []string) []string {
+	messages := []string{}
+	switch value := data.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			messages = append(messages, r.checkPattern(value[key], append(segments, key))...)
+		}
+	case []interface{}:
+		for _, item := range value {
+			messages = append(messages, r.checkPattern(item, segments)...)
+		}
+	case string:
+		if r.matchesField(segments) && !r.regexp.MatchString(value) {
+			messages = append(messages, fmt.Sprintf("%s value %q: %s", strings.Join(segments, "."), value, r.Description))
+		}
+	}
+	return messages
+}
+
+// matchesField checks if any of the rule fields matches the end of a path
+func (r Rule) matchesField(segments []string) bool {
+	for _, field := range r.Fields {
+		fieldSegments := strings.Split(field, ".")
+		if len(fieldSegments) > len(segments) {
+			continue
+		}
+		tail := segments[len(segments)-len(fieldSegments):]
+		matched, err := path.Match(strings.Join(fieldSegments, "/"), strings.Join(tail, "/"))
+		if err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) checkSelfLink(response Response, data map[string]interface{}) []string {
+	value, ok := lookup(data, "Links.Self")
+	if !ok {
+		return nil
+	}
+	self, _ := value.(string)
+	selfURL, err := url.Parse(self)
+	if err != nil || !selfURL.IsAbs() || selfURL.Host == "" {
+		return []string{fmt.Sprintf("Links.Self %q is not an absolute URL: %s", self, r.Description)}
+	}
+
+	requestURL, err := url.Parse(response.URL)
+	if err != nil {
+		return nil
+	}
+	if !strings.EqualFold(selfURL.Scheme, requestURL.Scheme) ||
+		!strings.EqualFold(selfURL.Hostname(), requestURL.Hostname()) ||
+		urlPort(selfURL) != urlPort(requestURL) ||
+		strings.TrimSuffix(selfURL.Path, "/") != strings.TrimSuffix(requestURL.Path, "/") {
+		request := url.URL{Scheme: requestURL.Scheme, Host: requestURL.Host, Path: requestURL.Path}
+		return []string{fmt.Sprintf("Links.Self %q does not match request %q: %s", self, request.String(), r.Description)}
+	}
+	return nil
+}
+
+// urlPort - the port of an URL, the default port of its scheme when it has none
+func urlPort(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	}
+	return ""
+}
+
+// lookup finds a value in data by its dotted path
+func lookup(data map[string]interface{}, field string) (interface{}, bool) {
+	var current interface{} = data
+	for _, segment := range strings.Split(field, ".") {
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = object[segment]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
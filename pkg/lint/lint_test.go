@@ -0,0 +1,241 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRuleSet(t *testing.T) {
+	ruleSet, err := LoadRuleSet("v3.1.5")
+
+	require.NoError(t, err)
+	assert.Equal(t, "v3.1", ruleSet.Version)
+	assert.NotEmpty(t, ruleSet.Rules)
+}
+
+func TestLoadRuleSet_UnknownVersion(t *testing.T) {
+	_, err := LoadRuleSet("v9.9.9")
+
+	assert.EqualError(t, err, "lint: could not find rule set for version v9.9.9")
+}
+
+func TestParseRuleSet_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "unknown severity",
+			content:  `{"rules": [{"id": "R1", "severity": "fatal", "check": "present"}]}`,
+			expected: `lint: rule R1 has unknown severity "fatal"`,
+		},
+		{
+			name:     "unknown check",
+			content:  `{"rules": [{"id": "R1", "severity": "error", "check": "absent"}]}`,
+			expected: `lint: rule R1 has unknown check "absent"`,
+		},
+		{
+			name:     "invalid pattern",
+			content:  `{"rules": [{"id": "R1", "severity": "error", "check": "pattern", "pattern": "("}]}`,
+			expected: "lint: rule R1 has invalid pattern: error parsing regexp: missing closing ): `(`",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := ParseRuleSet([]byte(testCase.content))
+			assert.EqualError(t, err, testCase.expected)
+		})
+	}
+}
+
+func TestRuleSetFor(t *testing.T) {
+	_, ok := RuleSetFor("")
+	assert.False(t, ok)
+
+	_, ok = RuleSetFor("v9.9.9")
+	assert.False(t, ok)
+
+	ruleSet, ok := RuleSetFor("v3.1.5")
+	assert.True(t, ok)
+	assert.Equal(t, "v3.1", ruleSet.Version)
+}
+
+func TestRuleSet_Lint(t *testing.T) {
+	ruleSet, err := LoadRuleSet("v3.1.5")
+	require.NoError(t, err)
+
+	violations := ruleSet.Lint(Response{
+		URL:        "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions",
+		StatusCode: 200,
+		Body:       getTransactionsResponse,
+	})
+
+	assert.Len(t, violations, 0)
+}
+
+func TestRuleSet_Lint_ReturnsViolations(t *testing.T) {
+	ruleSet, err := LoadRuleSet("v3.1.5")
+	require.NoError(t, err)
+
+	violations := ruleSet.Lint(Response{
+		URL:        "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions",
+		StatusCode: 200,
+		Body:       getTransactionsResponseViolations,
+	})
+
+	expected := []Violation{
+		{RuleID: "OBLINT001", Severity: SeverityWarning, Message: `Data.Transaction.BookingDateTime value "2017-04-05T10:43:07": DateTime values must carry a timezone offset`},
+		{RuleID: "OBLINT002", Severity: SeverityWarning, Message: `Links.Self "/open-banking/v3.1/aisp/accounts/22289/transactions" is not an absolute URL: Links.Self must be an absolute URL to the requested resource`},
+		{RuleID: "OBLINT003", Severity: SeverityWarning, Message: "Meta is required: successful responses must include Meta"},
+		{RuleID: "OBLINT004", Severity: SeverityWarning, Message: `Data.Transaction.Amount.Amount value "10.123456": amounts must have up to 13 digits and up to 5 decimals`},
+	}
+	assert.Equal(t, expected, violations)
+}
+
+func TestRuleSet_Lint_SelfLinkMismatch(t *testing.T) {
+	ruleSet, err := LoadRuleSet("v3.1.5")
+	require.NoError(t, err)
+
+	violations := ruleSet.Lint(Response{
+		URL:        "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions",
+		StatusCode: 200,
+		Body:       `{"Data": {}, "Links": {"Self": "https://ob.example.com/open-banking/v3.1/aisp/accounts"}, "Meta": {}}`,
+	})
+
+	expected := []Violation{
+		{RuleID: "OBLINT002", Severity: SeverityWarning, Message: `Links.Self "https://ob.example.com/open-banking/v3.1/aisp/accounts" does not match request "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions": Links.Self must be an absolute URL to the requested resource`},
+	}
+	assert.Equal(t, expected, violations)
+}
+
+func TestRuleSet_Lint_SelfLinkHostMismatch(t *testing.T) {
+	ruleSet, err := LoadRuleSet("v3.1.5")
+	require.NoError(t, err)
+
+	for self, valid := range map[string]bool{
+		"https://OB.Example.com:443/open-banking/v3.1/aisp/accounts/22289/transactions":   true,
+		"HTTPS://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions/":      true,
+		"https://attacker.example.com/open-banking/v3.1/aisp/accounts/22289/transactions": false,
+		"http://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions":        false,
+		"https://ob.example.com:8443/open-banking/v3.1/aisp/accounts/22289/transactions":  false,
+	} {
+		violations := ruleSet.Lint(Response{
+			URL:        "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions?fromBookingDateTime=2017-04-05T10:43:07",
+			StatusCode: 200,
+			Body:       `{"Data": {}, "Links": {"Self": "` + self + `"}, "Meta": {}}`,
+		})
+
+		if valid {
+			assert.Empty(t, violations, self)
+			continue
+		}
+		expected := []Violation{
+			{RuleID: "OBLINT002", Severity: SeverityWarning, Message: `Links.Self "` + self + `" does not match request "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions": Links.Self must be an absolute URL to the requested resource`},
+		}
+		assert.Equal(t, expected, violations, self)
+	}
+}
+
+func TestRuleSet_Lint_ErrorResponse(t *testing.T) {
+	ruleSet, err := LoadRuleSet("v3.1.5")
+	require.NoError(t, err)
+
+	violations := ruleSet.Lint(Response{
+		URL:        "https://ob.example.com/open-banking/v3.1/aisp/accounts/foo",
+		StatusCode: 400,
+		Body:       `{"Code": "400", "Message": "Bad request", "Errors": [{"ErrorCode": "UK.OBIE.Field.Invalid", "Message": "Invalid"}, {"ErrorCode": "BadRequest", "Message": "Invalid"}]}`,
+	})
+
+	expected := []Violation{
+		{RuleID: "OBLINT005", Severity: SeverityWarning, Message: `Errors.ErrorCode value "BadRequest": error codes must be in the UK.OBIE namespace`},
+	}
+	assert.Equal(t, expected, violations)
+}
+
+func TestRuleSet_Lint_EnableErrorRules(t *testing.T) {
+	ruleSet, err := LoadRuleSet("v3.1.5")
+	require.NoError(t, err)
+	response := Response{
+		URL:        "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions",
+		StatusCode: 200,
+		Body:       getTransactionsResponseViolations,
+	}
+	defer EnableErrorRules()
+
+	EnableErrorRules("OBLINT003")
+	severities := map[string]string{}
+	for _, violation := range ruleSet.Lint(response) {
+		severities[violation.RuleID] = violation.Severity
+	}
+	assert.Equal(t, map[string]string{
+		"OBLINT001": SeverityWarning,
+		"OBLINT002": SeverityWarning,
+		"OBLINT003": SeverityError,
+		"OBLINT004": SeverityWarning,
+	}, severities)
+
+	EnableErrorRules(AllRules)
+	for _, violation := range ruleSet.Lint(response) {
+		assert.Equal(t, SeverityError, violation.Severity)
+	}
+}
+
+func TestRuleSet_Lint_SkipsDisabledRulesAndNonJSON(t *testing.T) {
+	ruleSet, err := ParseRuleSet([]byte(`{"rules": [{"id": "R1", "severity": "error", "check": "present", "fields": ["Meta"], "disabled": true}]}`))
+	require.NoError(t, err)
+
+	assert.Len(t, ruleSet.Lint(Response{StatusCode: 200, Body: `{}`}), 0)
+	assert.Len(t, ruleSet.Lint(Response{StatusCode: 200, Body: `<html></html>`}), 0)
+}
+
+const getTransactionsResponse = `
+		{
+			"Data": {
+				"Transaction": [
+					{
+						"AccountId": "22289",
+						"Status": "Booked",
+						"CreditDebitIndicator": "Credit",
+						"BookingDateTime": "2017-04-05T10:43:07+00:00",
+						"ValueDateTime": "2017-04-05T10:45:22Z",
+						"Amount": {
+							"Amount": "10.00",
+							"Currency": "GBP"
+						}
+					}
+				]
+			},
+			"Links": {
+				"Self": "https://ob.example.com/open-banking/v3.1/aisp/accounts/22289/transactions/"
+			},
+			"Meta": {
+				"TotalPages": 1
+			}
+		}
+	`
+
+const getTransactionsResponseViolations = `
+		{
+			"Data": {
+				"Transaction": [
+					{
+						"AccountId": "22289",
+						"Status": "Booked",
+						"CreditDebitIndicator": "Credit",
+						"BookingDateTime": "2017-04-05T10:43:07",
+						"Amount": {
+							"Amount": "10.123456",
+							"Currency": "GBP"
+						}
+					}
+				]
+			},
+			"Links": {
+				"Self": "/open-banking/v3.1/aisp/accounts/22289/transactions"
+			}
+		}
+	`
@@ -4,10 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
-	"io/ioutil"
-	"os"
 	"strings"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
 )
 
@@ -18,10 +17,7 @@ func LoadScripts(filename string) (Scripts, error) {
 		return Scripts{}, errors.New("https:// manifest loading not implemented")
 	}
 	path := strings.TrimPrefix(filename, "file://")
-	plan, err := ioutil.ReadFile(path)
-	if err != nil && os.IsNotExist(err) {
-		plan, err = ioutil.ReadFile("../../" + path)
-	}
+	plan, err := assets.ReadFileOrPath(path)
 	if err != nil {
 		return Scripts{}, err
 	}
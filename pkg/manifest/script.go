@@ -3,12 +3,11 @@ package manifest
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"regexp"
 	"sort"
 	"strings"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
@@ -502,18 +501,11 @@ func getVersionSpecificScripts(spectype, version string, ctx *model.Context) (Sc
 func loadAssertions() (References, error) {
 	refs, err := loadReferences("manifests/assertions.json")
 	if err != nil {
-		refs, err = loadReferences("../../manifests/assertions.json")
-		if err != nil {
-			return References{}, err
-		}
+		return References{}, err
 	}
 	refs2, err := loadReferences("manifests/data.json")
-
 	if err != nil {
-		refs2, err = loadReferences("../../manifests/data.json")
-		if err != nil {
-			return References{}, err
-		}
+		return References{}, err
 	}
 	for k, v := range refs2.References { // read in data references with body payloads
 		body := jsonString(v.Body)
@@ -546,14 +538,9 @@ func loadScripts(filename string) (Scripts, error) {
 		return Scripts{}, errors.New("loadscripts: https:// and http:// download of scripts not implemented")
 	} else if strings.HasPrefix(strings.ToLower(filename), schemeFile) {
 		fp := strings.TrimPrefix(filename, schemeFile)
-		scriptBytes, err = ioutil.ReadFile(fp)
-		if err != nil && os.IsNotExist(err) {
-			scriptBytes, err = ioutil.ReadFile(fmt.Sprintf("../../%s", fp))
-			if err != nil {
-				return Scripts{}, errors.Wrap(err, "loadScripts ioutil.ReadFile()")
-			}
-		} else if err != nil {
-			return Scripts{}, errors.Wrap(err, "loadScripts ioutil.ReadFile()")
+		scriptBytes, err = assets.ReadFileOrPath(fp)
+		if err != nil {
+			return Scripts{}, errors.Wrap(err, "loadScripts assets.ReadFileOrPath()")
 		}
 
 	} else {
@@ -569,7 +556,7 @@ func loadScripts(filename string) (Scripts, error) {
 }
 
 func loadReferences(filename string) (References, error) {
-	plan, err := ioutil.ReadFile(filename)
+	plan, err := assets.ReadFileOrPath(filename)
 	if err != nil {
 		return References{}, err
 	}
@@ -0,0 +1,159 @@
+package mockaspsp
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+// Profile - faults deliberately injected by the mock ASPSP, to check the suite
+// detects each of them. The zero value is a conformant ASPSP
+type Profile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Status - replaces the status code of successful resource responses
+	Status int `json:"status,omitempty"`
+	// MissingFields - removes the mandatory fields of `Data` in successful
+	// resource responses
+	MissingFields bool `json:"missing_fields,omitempty"`
+	// InvalidSignature - the x-jws-signature of responses doesn't match their body
+	InvalidSignature bool `json:"invalid_signature,omitempty"`
+	// Delay - every response is delayed
+	Delay time.Duration `json:"delay,omitempty"`
+	// ExpiredCertificate - TLS uses a certificate of the signing key that has expired
+	ExpiredCertificate bool `json:"expired_certificate,omitempty"`
+	// MaxTLSVersion - the highest TLS version accepted, e.g.: tls.VersionTLS11
+	MaxTLSVersion uint16 `json:"max_tls_version,omitempty"`
+}
+
+// Profiles - the fault profiles of the mock ASPSP by name
+var Profiles = map[string]Profile{
+	"conformant": {
+		Name:        "conformant",
+		Description: "No faults",
+	},
+	"wrong-status": {
+		Name:        "wrong-status",
+		Description: "Successful resource responses have the status code 202 Accepted",
+		Status:      http.StatusAccepted,
+	},
+	"missing-fields": {
+		Name:          "missing-fields",
+		Description:   "Successful resource responses lack the mandatory fields of Data",
+		MissingFields: true,
+	},
+	"invalid-signature": {
+		Name:             "invalid-signature",
+		Description:      "Response signatures don't match the response bodies",
+		InvalidSignature: true,
+	},
+	"slow": {
+		Name:        "slow",
+		Description: "Every response is delayed by 5 seconds",
+		Delay:       5 * time.Second,
+	},
+	"expired-certificate": {
+		Name:               "expired-certificate",
+		Description:        "The TLS certificate has expired",
+		ExpiredCertificate: true,
+	},
+	"tls11": {
+		Name:          "tls11",
+		Description:   "Only TLS 1.1 and lower are accepted",
+		MaxTLSVersion: tls.VersionTLS11,
+	},
+}
+
+// ProfileNames - the names of the fault profiles, sorted
+func ProfileNames() []string {
+	names := []string{}
+	for name := range Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupProfile - a fault profile by name
+func LookupProfile(name string) (Profile, error) {
+	profile, ok := Profiles[name]
+	if !ok {
+		return Profile{}, errors.Errorf("mockaspsp: unknown fault profile %q, profiles=%v", name, ProfileNames())
+	}
+	return profile, nil
+}
+
+// delay - delays every response by the delay of the profile
+func delay(duration time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			time.Sleep(duration)
+			return next(c)
+		}
+	}
+}
+
+// successStatus - the status code of a successful response of an operation
+func (s *Server) successStatus(op operation) int {
+	if s.config.Profile.Status != 0 {
+		return s.config.Profile.Status
+	}
+	return op.status
+}
+
+// dropRequired - removes the required properties of the objects of a response
+// value, e.g.: the `AccountId` of the accounts of `Data.Account`
+func dropRequired(value interface{}, schema spec.Schema) {
+	schema = mergeAllOf(schema)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			delete(v, name)
+		}
+		for name, property := range v {
+			if propertySchema, ok := schema.Properties[name]; ok {
+				dropRequired(property, propertySchema)
+			}
+		}
+	case []interface{}:
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for _, item := range v {
+				dropRequired(item, *schema.Items.Schema)
+			}
+		}
+	}
+}
+
+// expiredCertificate - a self-signed certificate of the key of a certificate, which
+// expired yesterday
+func expiredCertificate(cert authentication.Certificate) (tls.Certificate, error) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "mock-aspsp"},
+		NotBefore:    time.Now().Add(-48 * time.Hour),
+		NotAfter:     time.Now().Add(-24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if leaf, err := x509.ParseCertificate(cert.TLSCert().Certificate[0]); err == nil {
+		template.Subject = leaf.Subject
+		template.DNSNames = leaf.DNSNames
+		template.IPAddresses = leaf.IPAddresses
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, cert.PublicKey(), cert.PrivateKey())
+	if err != nil {
+		return tls.Certificate{}, errors.Wrap(err, "mockaspsp: creating expired certificate")
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: cert.PrivateKey()}, nil
+}
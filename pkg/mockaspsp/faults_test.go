@@ -0,0 +1,186 @@
+package mockaspsp
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+	resty "gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+)
+
+const consentPath = "/open-banking/v3.1/pisp/domestic-payment-consents"
+
+// createConsent - a payment consent created with a client credentials token
+func createConsent(c testClient) (*http.Response, string) {
+	clientToken := gjson.Get(c.token(url.Values{"grant_type": {"client_credentials"}, "scope": {"payments"}}), "access_token").String()
+	return c.do(http.MethodPost, consentPath, clientToken,
+		`{"Data":{"Initiation":{"InstructionIdentification":"ACME412","EndToEndIdentification":"FRESCO.21302.GFX.20",`+
+			`"InstructedAmount":{"Amount":"165.88","Currency":"GBP"},`+
+			`"CreditorAccount":{"SchemeName":"UK.OBIE.SortCodeAccountNumber","Identification":"08080021325698","Name":"ACME Inc"}}},"Risk":{}}`)
+}
+
+func TestLookupProfile(t *testing.T) {
+	profile, err := LookupProfile("tls11")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS11), profile.MaxTLSVersion)
+
+	_, err = LookupProfile("unknown")
+	assert.EqualError(t, err, `mockaspsp: unknown fault profile "unknown", profiles=[conformant expired-certificate invalid-signature missing-fields slow tls11 wrong-status]`)
+}
+
+func TestProfile_WrongStatusFailsValidation(t *testing.T) {
+	for name, pass := range map[string]bool{"conformant": true, "wrong-status": false} {
+		server, client := newProfileServer(t, Profiles[name])
+		c := testClient{t: t, client: client, server: server}
+		_, body := createConsent(c)
+		path := consentPath + "/" + gjson.Get(body, "Data.ConsentId").String()
+		clientToken := gjson.Get(c.token(url.Values{"grant_type": {"client_credentials"}, "scope": {"payments"}}), "access_token").String()
+
+		response, err := resty.NewWithClient(client).R().SetAuthToken(clientToken).Get(server.URL + path)
+		server.Close()
+		require.NoError(t, err)
+		testCase := model.TestCase{
+			ID:     "#t1000",
+			Input:  model.Input{Method: http.MethodGet, Endpoint: path},
+			Expect: model.Expect{StatusCode: http.StatusOK},
+		}
+		valid, errs := testCase.Validate(response, &model.Context{})
+		assert.Equal(t, pass, valid, name)
+		if !pass {
+			require.Len(t, errs, 1)
+			assert.Contains(t, errs[0].Error(), "HTTP Status code does not match: expected 200 got 202")
+		}
+	}
+}
+
+func TestProfile_MissingFieldsFailSchemaValidation(t *testing.T) {
+	server, client := newProfileServer(t, Profiles["missing-fields"])
+	defer server.Close()
+
+	response, body := createConsent(testClient{t: t, client: client, server: server})
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.False(t, gjson.Get(body, "Data.ConsentId").Exists())
+
+	validator, err := schema.NewSwaggerOBSpecValidator("Payment Initiation API", DefaultVersion)
+	require.NoError(t, err)
+	failures, err := validator.Validate(schema.Response{
+		Method:     http.MethodPost,
+		Path:       consentPath,
+		Header:     response.Header,
+		Body:       bytes.NewReader([]byte(body)),
+		StatusCode: response.StatusCode,
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, failures)
+}
+
+func TestProfile_InvalidSignatureFailsValidation(t *testing.T) {
+	server, client := newProfileServer(t, Profiles["invalid-signature"])
+	defer server.Close()
+
+	response, body := createConsent(testClient{t: t, client: client, server: server})
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	valid, _ := authentication.ValidateSignature(response.Header.Get("x-jws-signature"), body, server.URL+"/jwks", true)
+	assert.False(t, valid)
+}
+
+func TestProfile_Delay(t *testing.T) {
+	server, client := newProfileServer(t, Profile{Delay: 100 * time.Millisecond})
+	defer server.Close()
+
+	start := time.Now()
+	response, _ := testClient{t: t, client: client, server: server}.do(http.MethodGet, "/jwks", "", "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+}
+
+func TestProfile_MissingFieldsKeepStoredResource(t *testing.T) {
+	config := Config{Profile: Profiles["missing-fields"]}
+	certificate := loadCertificate(t)
+	config.Certificate, config.ClientID, config.ClientSecret = certificate, "tpp", "secret"
+	seed, err := LoadSeed(DefaultSeedFilename)
+	require.NoError(t, err)
+	config.Seed = seed
+	mock, err := NewServer(config, test.NullLogger())
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(mock)
+	server.TLS = mock.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		Certificates:       []tls.Certificate{certificate.TLSCert()},
+		InsecureSkipVerify: true,
+	}}}
+	c := testClient{t: t, client: client, server: server}
+
+	response, body := createConsent(c)
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+	require.False(t, gjson.Get(body, "Data.ConsentId").Exists())
+	mock.lock.Lock()
+	require.Len(t, mock.resources, 1)
+	path := ""
+	for resource := range mock.resources {
+		path = resource
+	}
+	mock.lock.Unlock()
+
+	clientToken := gjson.Get(c.token(url.Values{"grant_type": {"client_credentials"}, "scope": {"payments"}}), "access_token").String()
+	response, body = c.do(http.MethodGet, path, clientToken, "")
+	require.Equal(t, http.StatusOK, response.StatusCode, body)
+	require.False(t, gjson.Get(body, "Data.ConsentId").Exists())
+
+	mock.config.Profile = Profiles["conformant"]
+	response, body = c.do(http.MethodGet, path, clientToken, "")
+	require.Equal(t, http.StatusOK, response.StatusCode, body)
+	assert.True(t, gjson.Get(body, "Data.ConsentId").Exists(), "the fault didn't remove the fields of the stored resource")
+	assert.Equal(t, "ACME412", gjson.Get(body, "Data.Initiation.InstructionIdentification").String())
+}
+
+func TestProfile_ExpiredCertificateFailsHTTPClient(t *testing.T) {
+	server, _ := newProfileServer(t, Profiles["expired-certificate"])
+	defer server.Close()
+
+	served := server.TLS.Certificates[0]
+	client, err := executors.NewHTTPClient(executors.RunDefinition{
+		TrustStore: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: served.Certificate[0]}),
+	})
+	require.NoError(t, err)
+
+	_, err = client.R().Get(server.URL + "/jwks")
+	require.Error(t, err)
+	invalid := x509.CertificateInvalidError{}
+	require.True(t, errors.As(err, &invalid), err.Error())
+	assert.Equal(t, x509.Expired, invalid.Reason)
+}
+
+func TestProfile_TLS11FailsTLSValidation(t *testing.T) {
+	validator := discovery.NewStdTLSValidator(tls.VersionTLS12)
+
+	conformant, _ := newProfileServer(t, Profiles["conformant"])
+	defer conformant.Close()
+	result, err := validator.ValidateTLSVersion(conformant.URL)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+
+	server, _ := newProfileServer(t, Profiles["tls11"])
+	defer server.Close()
+	result, err = validator.ValidateTLSVersion(server.URL)
+	assert.False(t, err == nil && result.Valid, "the TLS 1.1 listener is rejected")
+}
@@ -0,0 +1,233 @@
+package mockaspsp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/google/uuid"
+)
+
+// patternCandidates - values tried, in order, for string properties with a pattern
+// they cover the patterns of the bundled specs, e.g.: amounts, currencies and country codes
+var patternCandidates = []string{
+	"10.00",
+	"GBP",
+	"GB",
+	"EvryDay",
+	"1",
+	"+44-7700900000",
+	"Mon, 02 Jan 2006 15:04:05 GMT",
+}
+
+// generator - builds a value valid against a response schema
+// Values of properties are taken from values by property name when their type matches
+// the schema, other values are generated from the schema constraints
+type generator struct {
+	values map[string]interface{}
+	now    time.Time
+}
+
+// response - generates a response body, `Links` and `Meta` are generated even when
+// they are optional, as are the collections of `Data` with one item, e.g.: Data.Account
+func (g generator) response(schema spec.Schema) interface{} {
+	schema = mergeAllOf(schema)
+	body, ok := g.value("", schema).(map[string]interface{})
+	if !ok {
+		return g.value("", schema)
+	}
+	for _, name := range []string{"Links", "Meta"} {
+		if property, ok := schema.Properties[name]; ok {
+			body[name] = g.value(name, property)
+		}
+	}
+	if data, ok := schema.Properties["Data"]; ok {
+		body["Data"] = g.object(mergeAllOf(data), true)
+	}
+	return body
+}
+
+func (g generator) value(name string, schema spec.Schema) interface{} {
+	schema = mergeAllOf(schema)
+
+	if value, ok := g.values[name]; ok && matchesType(value, schema) {
+		return value
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+
+	switch {
+	case schema.Type.Contains("object") || len(schema.Properties) > 0:
+		return g.object(schema, false)
+	case schema.Type.Contains("array"):
+		return g.array(name, schema)
+	case schema.Type.Contains("integer"):
+		if schema.Minimum != nil {
+			return int64(*schema.Minimum)
+		}
+		return int64(1)
+	case schema.Type.Contains("number"):
+		if schema.Minimum != nil {
+			return *schema.Minimum
+		}
+		return float64(1)
+	case schema.Type.Contains("boolean"):
+		return true
+	}
+	return g.string(name, schema)
+}
+
+// object - the required properties, and the array properties when collections is set
+func (g generator) object(schema spec.Schema, collections bool) map[string]interface{} {
+	object := map[string]interface{}{}
+	for _, name := range sortedPropertyNames(schema) {
+		property := mergeAllOf(schema.Properties[name])
+		if contains(schema.Required, name) || (collections && property.Type.Contains("array")) {
+			object[name] = g.value(name, property)
+		}
+	}
+	return object
+}
+
+func (g generator) array(name string, schema spec.Schema) []interface{} {
+	items := spec.Schema{}
+	if schema.Items != nil && schema.Items.Schema != nil {
+		items = *schema.Items.Schema
+	} else if schema.Items != nil && len(schema.Items.Schemas) > 0 {
+		items = schema.Items.Schemas[0]
+	}
+
+	length := int64(1)
+	if schema.MinItems != nil && *schema.MinItems > length {
+		length = *schema.MinItems
+	}
+	array := []interface{}{}
+	for i := int64(0); i < length; i++ {
+		array = append(array, g.value(name, items))
+	}
+	return array
+}
+
+func (g generator) string(name string, schema spec.Schema) string {
+	switch schema.Format {
+	case "date-time":
+		return g.now.Format(time.RFC3339)
+	case "date":
+		return g.now.Format("2006-01-02")
+	case "uri":
+		if self, ok := g.values["Self"].(string); ok {
+			return self
+		}
+		return "https://aspsp.example.com"
+	}
+
+	value := name
+	if schema.Pattern != "" {
+		value = "Mock"
+		if pattern, err := regexp.Compile(schema.Pattern); err == nil {
+			for _, candidate := range patternCandidates {
+				if pattern.MatchString(candidate) {
+					value = candidate
+					break
+				}
+			}
+		}
+	} else if strings.HasSuffix(name, "Id") {
+		value = uuid.New().String()
+	}
+	if value == "" {
+		value = "Mock"
+	}
+
+	if schema.MinLength != nil {
+		for int64(len(value)) < *schema.MinLength {
+			value += "0"
+		}
+	}
+	if schema.MaxLength != nil && int64(len(value)) > *schema.MaxLength {
+		value = value[:*schema.MaxLength]
+	}
+	return value
+}
+
+// mergeAllOf - the schema with the properties of its `allOf` schemas
+func mergeAllOf(schema spec.Schema) spec.Schema {
+	if len(schema.AllOf) == 0 {
+		return schema
+	}
+	merged := schema
+	merged.AllOf = nil
+	merged.Properties = map[string]spec.Schema{}
+	for name, property := range schema.Properties {
+		merged.Properties[name] = property
+	}
+	for _, part := range schema.AllOf {
+		part = mergeAllOf(part)
+		for name, property := range part.Properties {
+			merged.Properties[name] = property
+		}
+		merged.Required = append(merged.Required, part.Required...)
+		if len(merged.Type) == 0 {
+			merged.Type = part.Type
+		}
+	}
+	return merged
+}
+
+// matchesType - whether a seed value can be used for a schema
+func matchesType(value interface{}, schema spec.Schema) bool {
+	switch value.(type) {
+	case string:
+		return schema.Type.Contains("string")
+	case float64, int, int64:
+		return schema.Type.Contains("number") || schema.Type.Contains("integer")
+	case bool:
+		return schema.Type.Contains("boolean")
+	case map[string]interface{}:
+		return schema.Type.Contains("object")
+	case []interface{}:
+		return schema.Type.Contains("array")
+	}
+	return false
+}
+
+func sortedPropertyNames(schema spec.Schema) []string {
+	names := []string{}
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// merge - src merged over dst, objects are merged by key other values are replaced
+func merge(dst, src interface{}) interface{} {
+	dstObject, ok := dst.(map[string]interface{})
+	if !ok {
+		return src
+	}
+	srcObject, ok := src.(map[string]interface{})
+	if !ok {
+		return src
+	}
+	for key, value := range srcObject {
+		if current, ok := dstObject[key]; ok {
+			dstObject[key] = merge(current, value)
+			continue
+		}
+		dstObject[key] = value
+	}
+	return dstObject
+}
@@ -0,0 +1,97 @@
+package mockaspsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
+)
+
+// TestGeneratedResponsesAreValid - the responses generated for every operation of the
+// bundled specs pass the schema validation of the suite
+func TestGeneratedResponsesAreValid(t *testing.T) {
+	seed, err := LoadSeed(DefaultSeedFilename)
+	require.NoError(t, err)
+
+	for _, version := range []string{"v3.0.0", "v3.1.5"} {
+		dirname := "pkg/schema/spec/" + version
+		filenames, err := assets.ReadDir(dirname)
+		require.NoError(t, err)
+
+		for _, filename := range filenames {
+			content, err := assets.ReadFile(dirname + "/" + filename)
+			require.NoError(t, err)
+			doc, err := loads.Analyzed(json.RawMessage(content), "")
+			require.NoError(t, err)
+			swagger := doc.Spec()
+			validator, err := schema.NewSwaggerOBSpecValidator(swagger.Info.Title, version)
+			require.NoError(t, err)
+
+			for _, op := range specOperations(swagger, version) {
+				if op.schema == nil {
+					continue
+				}
+				path := swagger.BasePath + regexp.MustCompile(`\{\w+\}`).ReplaceAllString(op.path, "1")
+				body := generator{values: seed.Values, now: time.Now()}.response(*op.schema)
+				if seeded, ok := seed.Responses[op.path]; ok {
+					body = merge(body, copyJSON(seeded))
+				}
+				content, err := json.Marshal(body)
+				require.NoError(t, err)
+
+				failures, err := validator.Validate(schema.Response{
+					Method:     op.method,
+					Path:       path,
+					Header:     http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+					Body:       bytes.NewReader(content),
+					StatusCode: op.status,
+				})
+				require.NoError(t, err)
+				assert.Empty(t, failures, "%s %s %s %s", version, op.method, op.path, content)
+			}
+		}
+	}
+}
+
+func TestGenerator_String(t *testing.T) {
+	g := generator{values: map[string]interface{}{"Currency": "EUR", "Amount": 10}, now: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}
+	maxLength := int64(3)
+
+	assert.Equal(t, "EUR", g.value("Currency", stringSchema("", "")))
+	assert.Equal(t, "10.00", g.value("Amount", stringSchema("", `^\d{1,13}\.\d{1,5}$`)), "seed values of another type are not used")
+	assert.Equal(t, "GB", g.value("Country", stringSchema("", `^[A-Z]{2,2}$`)))
+	assert.Equal(t, "2020-01-02T03:04:05Z", g.value("CreationDateTime", stringSchema("date-time", "")))
+	assert.Equal(t, "Mock", g.value("Name", stringSchema("", `^(?!\s)(.*)(\S)$`)), "patterns not supported by regexp")
+
+	name := stringSchema("", "")
+	name.MaxLength = &maxLength
+	assert.Equal(t, "Nam", g.value("Name", name))
+}
+
+func TestMerge(t *testing.T) {
+	dst := map[string]interface{}{"Data": map[string]interface{}{"Status": "Pending", "Account": []interface{}{"generated"}}}
+	src := map[string]interface{}{"Data": map[string]interface{}{"Account": []interface{}{"seeded"}, "Permissions": "ReadAccountsBasic"}}
+
+	assert.Equal(t, map[string]interface{}{"Data": map[string]interface{}{
+		"Status":      "Pending",
+		"Account":     []interface{}{"seeded"},
+		"Permissions": "ReadAccountsBasic",
+	}}, merge(dst, src))
+}
+
+func stringSchema(format, pattern string) spec.Schema {
+	s := spec.StringProperty()
+	s.Format = format
+	s.Pattern = pattern
+	return *s
+}
@@ -0,0 +1,340 @@
+package mockaspsp
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+// codeLifetime - lifetime of authorization codes
+const codeLifetime = 5 * time.Minute
+
+// openIDConfiguration - the discovery document of the mock
+type openIDConfiguration struct {
+	authentication.OpenIDConfiguration
+	GrantTypesSupported              []string `json:"grant_types_supported"`
+	ScopesSupported                  []string `json:"scopes_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+}
+
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+func (s *Server) openIDConfiguration(c echo.Context) error {
+	issuer := baseURL(c)
+	return c.JSON(http.StatusOK, openIDConfiguration{
+		OpenIDConfiguration: authentication.OpenIDConfiguration{
+			Issuer:                                 issuer,
+			AuthorizationEndpoint:                  issuer + "/authorize",
+			TokenEndpoint:                          issuer + "/token",
+			JwksURI:                                issuer + "/jwks",
+			PushedAuthorizationRequestEndpoint:     issuer + "/par",
+			RequirePushedAuthorizationRequests:     s.config.RequirePAR,
+			RegistrationEndpoint:                   issuer + "/register",
+			TokenEndpointAuthMethodsSupported:      []string{"tls_client_auth", "private_key_jwt", "client_secret_basic"},
+			RequestObjectSigningAlgValuesSupported: []string{"PS256", "ES256", "none"},
+			ResponseTypesSupported:                 []string{"code", "code id_token"},
+			AcrValuesSupported:                     []string{"urn:openbanking:psd2:sca", "urn:openbanking:psd2:ca"},
+		},
+		GrantTypesSupported:              []string{"authorization_code", "client_credentials", "refresh_token"},
+		ScopesSupported:                  []string{"openid", "accounts", "payments", "fundsconfirmations"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{s.signingAlg.Alg()},
+		CodeChallengeMethodsSupported:    []string{authentication.CodeChallengeMethodS256},
+	})
+}
+
+func (s *Server) jwksHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.jwks())
+}
+
+// authorize - authorises the consent of the request object without PSU interaction
+// and redirects to the redirect URI with an authorization code
+func (s *Server) authorize(c echo.Context) error {
+	query, err := s.authorizationRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request_uri", ErrorDescription: err.Error()})
+	}
+	claims := jwt.MapClaims{}
+	if request := query.Get("request"); request != "" {
+		if _, _, err := new(jwt.Parser).ParseUnverified(request, claims); err != nil {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request_object", ErrorDescription: err.Error()})
+		}
+	}
+	redirectURI := firstNonEmpty(query.Get("redirect_uri"), claimString(claims, "redirect_uri"))
+	if redirectURI == "" {
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", ErrorDescription: "redirect_uri missing"})
+	}
+	clientID := firstNonEmpty(query.Get("client_id"), claimString(claims, "iss"))
+	responseType := firstNonEmpty(query.Get("response_type"), claimString(claims, "response_type"))
+	state := firstNonEmpty(query.Get("state"), claimString(claims, "state"))
+	redirect := authorizationRedirect{uri: redirectURI, fragment: responseType != "code", state: state}
+
+	challenge, err := s.codeChallenge(query, claims)
+	if err != nil {
+		return c.Redirect(http.StatusFound, redirect.location("error", "invalid_request", "error_description", err.Error()))
+	}
+
+	consentID := intentID(claims)
+	if err := s.authoriseConsent(consentID, clientID); err != nil {
+		return c.Redirect(http.StatusFound, redirect.location("error", "access_denied", "error_description", err.Error()))
+	}
+
+	code := uuid.New().String()
+	authorised := grant{
+		clientID:    clientID,
+		scope:       firstNonEmpty(query.Get("scope"), claimString(claims, "scope")),
+		consentID:   consentID,
+		redirectURI: redirectURI,
+		nonce:       claimString(claims, "nonce"),
+		challenge:   challenge,
+		expires:     time.Now().Add(codeLifetime),
+	}
+	s.lock.Lock()
+	s.codes[code] = authorised
+	s.lock.Unlock()
+
+	if !strings.Contains(responseType, "id_token") {
+		return c.Redirect(http.StatusFound, redirect.location("code", code))
+	}
+	idToken, err := s.idToken(c, authorised, code, state)
+	if err != nil {
+		return err
+	}
+	return c.Redirect(http.StatusFound, redirect.location("id_token", idToken, "code", code))
+}
+
+// codeChallenge - the PKCE code challenge of an authorization request, only S256 is
+// supported as in FAPI 1.0 Advanced
+func (s *Server) codeChallenge(query url.Values, claims jwt.MapClaims) (string, error) {
+	challenge := firstNonEmpty(query.Get(authentication.CodeChallenge), claimString(claims, authentication.CodeChallenge))
+	if challenge == "" {
+		if s.config.RequirePKCE {
+			return "", errors.New("code_challenge missing")
+		}
+		return "", nil
+	}
+	method := firstNonEmpty(query.Get(authentication.CodeChallengeMethod), claimString(claims, authentication.CodeChallengeMethod))
+	if method != authentication.CodeChallengeMethodS256 {
+		return "", fmt.Errorf("code_challenge_method %q unsupported", method)
+	}
+	return challenge, nil
+}
+
+// authorizationRedirect - builds the redirect to a TPP, the state is always the last
+// parameter so the code is followed by `&`
+type authorizationRedirect struct {
+	uri      string
+	fragment bool
+	state    string
+}
+
+func (r authorizationRedirect) location(keyValues ...string) string {
+	params := []string{}
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		params = append(params, keyValues[i]+"="+url.QueryEscape(keyValues[i+1]))
+	}
+	params = append(params, "state="+url.QueryEscape(r.state))
+
+	separator := "#"
+	if !r.fragment {
+		separator = "?"
+		if strings.Contains(r.uri, "?") {
+			separator = "&"
+		}
+	}
+	return r.uri + separator + strings.Join(params, "&")
+}
+
+// authoriseConsent - sets an awaiting consent of a client to authorised
+func (s *Server) authoriseConsent(consentID, clientID string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	consent, ok := s.consents[consentID]
+	if !ok {
+		return fmt.Errorf("unknown consent %q", consentID)
+	}
+	if consent.clientID != "" && clientID != "" && consent.clientID != clientID {
+		return fmt.Errorf("consent %q was not created by client %q", consentID, clientID)
+	}
+	if status := s.consentStatus(consentID); status != "AwaitingAuthorisation" {
+		return fmt.Errorf("consent %q has status %s", consentID, status)
+	}
+	s.setConsentStatus(consentID, "Authorised")
+	return nil
+}
+
+// token - the token endpoint, supports the client credentials, authorization
+// code and refresh token grants
+func (s *Server) token(c echo.Context) error {
+	clientID, err := s.authenticateClient(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_client", ErrorDescription: err.Error()})
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var issued grant
+	switch grantType := c.FormValue("grant_type"); grantType {
+	case "client_credentials":
+		issued = grant{clientID: clientID, scope: c.FormValue("scope")}
+	case "authorization_code":
+		code := c.FormValue("code")
+		authorised, ok := s.codes[code]
+		delete(s.codes, code)
+		if !ok || (authorised.clientID != "" && authorised.clientID != clientID) || time.Now().After(authorised.expires) {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", ErrorDescription: "unknown or expired authorization code"})
+		}
+		if redirectURI := c.FormValue("redirect_uri"); redirectURI != "" && redirectURI != authorised.redirectURI {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", ErrorDescription: "redirect_uri does not match the authorization request"})
+		}
+		if authorised.challenge != "" && authentication.CodeChallengeS256(c.FormValue(authentication.CodeVerifier)) != authorised.challenge {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", ErrorDescription: "code_verifier does not match the code challenge"})
+		}
+		issued = authorised
+		issued.clientID = clientID
+	case "refresh_token":
+		refreshed, ok := s.refreshTokens[c.FormValue("refresh_token")]
+		if !ok || refreshed.clientID != clientID {
+			return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_grant", ErrorDescription: "unknown refresh token"})
+		}
+		issued = refreshed
+	default:
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "unsupported_grant_type", ErrorDescription: grantType})
+	}
+
+	accessToken := uuid.New().String()
+	issued.expires = time.Now().Add(s.config.TokenLifetime)
+	s.tokens[accessToken] = issued
+	response := tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.config.TokenLifetime.Seconds()),
+		Scope:       issued.scope,
+	}
+	if issued.consentID != "" {
+		response.RefreshToken = uuid.New().String()
+		s.refreshTokens[response.RefreshToken] = issued
+		if response.IDToken, err = s.idToken(c, issued, "", ""); err != nil {
+			return err
+		}
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// authenticateClient - the client ID of a token request authenticated with
+// client_secret_basic, private_key_jwt or tls_client_auth
+// Client assertions are not verified, clients don't register keys with the mock
+func (s *Server) authenticateClient(r *http.Request) (string, error) {
+	var clientID string
+	if id, secret, ok := r.BasicAuth(); ok {
+		if !s.validClientSecret(id, secret) {
+			return "", errors.New("client secret does not match")
+		}
+		clientID = id
+	} else if assertion := r.FormValue("client_assertion"); assertion != "" {
+		claims := jwt.MapClaims{}
+		if _, _, err := new(jwt.Parser).ParseUnverified(assertion, claims); err != nil {
+			return "", errors.Wrap(err, "invalid client assertion")
+		}
+		clientID = claimString(claims, "iss")
+	} else {
+		clientID = r.FormValue("client_id")
+	}
+
+	if clientID == "" {
+		return "", errors.New("client authentication missing")
+	}
+	if s.config.ClientID != "" && clientID != s.config.ClientID && !s.registered(clientID) {
+		return "", fmt.Errorf("unknown client %q", clientID)
+	}
+	return clientID, nil
+}
+
+// idToken - a signed id token of an authorised consent, with the hashes of the code
+// and state of an authorization response when they are not empty
+func (s *Server) idToken(c echo.Context, authorised grant, code, state string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                   baseURL(c),
+		"sub":                   authorised.consentID,
+		"aud":                   authorised.clientID,
+		"iat":                   now.Unix(),
+		"exp":                   now.Add(s.config.TokenLifetime).Unix(),
+		"acr":                   "urn:openbanking:psd2:sca",
+		"openbanking_intent_id": authorised.consentID,
+	}
+	if authorised.nonce != "" {
+		claims["nonce"] = authorised.nonce
+	}
+	for claim, value := range map[string]string{"c_hash": code, "s_hash": state} {
+		if value == "" {
+			continue
+		}
+		hash, err := authentication.CalculateCHash(s.signingAlg.Alg(), value)
+		if err != nil {
+			return "", err
+		}
+		claims[claim] = hash
+	}
+
+	token := jwt.NewWithClaims(s.signingAlg, claims)
+	token.Header["kid"] = s.kid
+	signed, err := token.SignedString(s.config.Certificate.PrivateKey())
+	if err != nil {
+		return "", errors.Wrap(err, "mockaspsp: signing id token")
+	}
+	return signed, nil
+}
+
+// intentID - the consent ID of the `openbanking_intent_id` claim of a request object
+func intentID(claims jwt.MapClaims) string {
+	value := map[string]interface{}(claims)
+	for _, name := range []string{"claims", "id_token", "openbanking_intent_id"} {
+		object, ok := value[name].(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value = object
+	}
+	id, _ := value["value"].(string)
+	return id
+}
+
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
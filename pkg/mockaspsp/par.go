@@ -0,0 +1,98 @@
+package mockaspsp
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+// requestURIPrefix - the prefix of the request URIs of pushed authorization requests
+const requestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+// clientAuthenticationParams - form parameters of the client authentication of a pushed
+// authorization request, not part of the authorization request
+var clientAuthenticationParams = []string{"client_secret", "client_assertion", "client_assertion_type"}
+
+// pushedRequest - an authorization request pushed by a client, used once before it expires
+type pushedRequest struct {
+	clientID string
+	params   url.Values
+	expires  time.Time
+}
+
+type pushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"`
+}
+
+// pushAuthorizationRequest - the pushed authorization request endpoint, keeps the authorization
+// request of an authenticated client and returns its request URI
+// https://tools.ietf.org/html/draft-ietf-oauth-par
+func (s *Server) pushAuthorizationRequest(c echo.Context) error {
+	clientID, err := s.authenticateClient(c.Request())
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_client", ErrorDescription: err.Error()})
+	}
+	params, err := c.FormParams()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", ErrorDescription: err.Error()})
+	}
+	if params.Get("request_uri") != "" {
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", ErrorDescription: "request_uri is not allowed in a pushed authorization request"})
+	}
+	if id := params.Get("client_id"); id != "" && id != clientID {
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_request", ErrorDescription: "client_id does not match the client authenticated"})
+	}
+	for _, param := range clientAuthenticationParams {
+		params.Del(param)
+	}
+
+	requestURI := requestURIPrefix + uuid.New().String()
+	s.lock.Lock()
+	s.pushed[requestURI] = pushedRequest{
+		clientID: clientID,
+		params:   params,
+		expires:  time.Now().Add(s.config.RequestURILifetime),
+	}
+	s.lock.Unlock()
+
+	return c.JSON(http.StatusCreated, pushedAuthorizationResponse{
+		RequestURI: requestURI,
+		ExpiresIn:  int(s.config.RequestURILifetime.Seconds()),
+	})
+}
+
+// authorizationRequest - the parameters of an authorization request: those pushed for its
+// `request_uri`, which is used once, or its query
+func (s *Server) authorizationRequest(c echo.Context) (url.Values, error) {
+	requestURI := c.QueryParam("request_uri")
+	if requestURI == "" {
+		if s.config.RequirePAR {
+			return nil, errors.New("authorization requests must be pushed, request_uri missing")
+		}
+		return c.QueryParams(), nil
+	}
+	if !strings.HasPrefix(requestURI, requestURIPrefix) {
+		return nil, errors.New("request_uri not issued by the pushed authorization request endpoint")
+	}
+
+	s.lock.Lock()
+	pushed, ok := s.pushed[requestURI]
+	delete(s.pushed, requestURI)
+	s.lock.Unlock()
+
+	switch {
+	case !ok:
+		return nil, errors.New("unknown or used request_uri")
+	case time.Now().After(pushed.expires):
+		return nil, errors.New("expired request_uri")
+	case c.QueryParam("client_id") != "" && c.QueryParam("client_id") != pushed.clientID:
+		return nil, errors.New("request_uri was not pushed by client_id")
+	}
+	return pushed.params, nil
+}
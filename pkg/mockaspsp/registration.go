@@ -0,0 +1,201 @@
+package mockaspsp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+// registrationAuthMethods - the token endpoint auth methods clients can register with
+var registrationAuthMethods = []string{authentication.TlsClientAuth, authentication.PrivateKeyJwt, authentication.ClientSecretBasic}
+
+// registrationRequestClaims - claims of a registration request that are not client metadata
+var registrationRequestClaims = []string{"iss", "aud", "iat", "exp", "nbf", "jti"}
+
+// registeredClient - a client registered by dynamic client registration
+type registeredClient struct {
+	metadata    map[string]interface{}
+	secret      string
+	accessToken string // registration access token of the registration management requests
+}
+
+// registerClient - the OB dynamic client registration endpoint, registers the client of a signed
+// registration request. Requests are not verified, the software statement is trusted as is
+// https://openbankinguk.github.io/dcr-docs-pub/v3.2/dynamic-client-registration.html
+func (s *Server) registerClient(c echo.Context) error {
+	metadata, err := registrationRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_client_metadata", ErrorDescription: err.Error()})
+	}
+	client := registeredClient{metadata: metadata, accessToken: uuid.New().String()}
+	clientID := uuid.New().String()
+	s.lock.Lock()
+	s.clients[clientID] = client
+	s.lock.Unlock()
+	return c.JSON(http.StatusCreated, s.registration(c, clientID, client))
+}
+
+// readClient - the registration of the client of a registration management request
+func (s *Server) readClient(c echo.Context) error {
+	clientID, client, err := s.managedClient(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_token", ErrorDescription: err.Error()})
+	}
+	return c.JSON(http.StatusOK, s.registration(c, clientID, client))
+}
+
+// updateClient - replaces the metadata of the client of a registration management request with
+// those of its signed registration request
+func (s *Server) updateClient(c echo.Context) error {
+	clientID, client, err := s.managedClient(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_token", ErrorDescription: err.Error()})
+	}
+	metadata, err := registrationRequest(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, oauthError{Error: "invalid_client_metadata", ErrorDescription: err.Error()})
+	}
+	client.metadata = metadata
+	s.lock.Lock()
+	s.clients[clientID] = client
+	s.lock.Unlock()
+	return c.JSON(http.StatusOK, s.registration(c, clientID, client))
+}
+
+// deleteClient - deletes the client of a registration management request
+func (s *Server) deleteClient(c echo.Context) error {
+	clientID, _, err := s.managedClient(c)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, oauthError{Error: "invalid_token", ErrorDescription: err.Error()})
+	}
+	s.lock.Lock()
+	delete(s.clients, clientID)
+	s.lock.Unlock()
+	return c.NoContent(http.StatusNoContent)
+}
+
+// registrationRequest - the client metadata of a signed registration request, of which
+// redirect URIs must be those of its software statement
+func registrationRequest(c echo.Context) (map[string]interface{}, error) {
+	body, err := ioutil.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading registration request")
+	}
+	claims := jwt.MapClaims{}
+	token, _, err := new(jwt.Parser).ParseUnverified(string(body), claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid registration request")
+	}
+	if token.Method == jwt.SigningMethodNone {
+		return nil, errors.New("registration request is not signed")
+	}
+	statement := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(claimString(claims, "software_statement"), statement); err != nil {
+		return nil, errors.Wrap(err, "invalid software_statement")
+	}
+	if authMethod := claimString(claims, "token_endpoint_auth_method"); !contains(registrationAuthMethods, authMethod) {
+		return nil, fmt.Errorf("token_endpoint_auth_method %q unsupported", authMethod)
+	}
+	redirectURIs := claimStrings(claims, "redirect_uris")
+	if len(redirectURIs) == 0 {
+		return nil, errors.New("redirect_uris missing")
+	}
+	softwareRedirectURIs := claimStrings(statement, "software_redirect_uris")
+	for _, redirectURI := range redirectURIs {
+		if !contains(softwareRedirectURIs, redirectURI) {
+			return nil, fmt.Errorf("redirect_uri %q is not a software_redirect_uri", redirectURI)
+		}
+	}
+
+	metadata := map[string]interface{}{}
+	for name, value := range claims {
+		metadata[name] = value
+	}
+	for _, name := range registrationRequestClaims {
+		delete(metadata, name)
+	}
+	metadata["software_id"] = firstNonEmpty(claimString(claims, "software_id"), claimString(statement, "software_id"))
+	return metadata, nil
+}
+
+// managedClient - the client of a registration management request, authorised with its
+// registration access token or an access token of its client credentials grant
+func (s *Server) managedClient(c echo.Context) (string, registeredClient, error) {
+	clientID := c.Param("clientId")
+	token := bearerToken(c.Request())
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	client, ok := s.clients[clientID]
+	if !ok {
+		return "", registeredClient{}, fmt.Errorf("unknown client %q", clientID)
+	}
+	if token == client.accessToken {
+		return clientID, client, nil
+	}
+	issued, ok := s.tokens[token]
+	if !ok || issued.clientID != clientID || issued.consentID != "" || time.Now().After(issued.expires) {
+		return "", registeredClient{}, errors.New("registration management token missing or invalid")
+	}
+	return clientID, client, nil
+}
+
+// registration - the registration response of a client, a secret is issued on the first
+// response of clients authenticating with client_secret_basic
+func (s *Server) registration(c echo.Context, clientID string, client registeredClient) map[string]interface{} {
+	response := map[string]interface{}{}
+	for name, value := range client.metadata {
+		response[name] = value
+	}
+	if response["token_endpoint_auth_method"] == authentication.ClientSecretBasic {
+		s.lock.Lock()
+		if registered, ok := s.clients[clientID]; ok && registered.secret == "" {
+			registered.secret = uuid.New().String()
+			s.clients[clientID] = registered
+		}
+		response["client_secret"] = s.clients[clientID].secret
+		s.lock.Unlock()
+	}
+	response["client_id"] = clientID
+	response["registration_access_token"] = client.accessToken
+	response["registration_client_uri"] = baseURL(c) + "/register/" + clientID
+	return response
+}
+
+// registered - whether clientID is a client registered by dynamic client registration
+func (s *Server) registered(clientID string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.clients[clientID]
+	return ok
+}
+
+// validClientSecret - whether secret is the client_secret_basic secret of clientID: the secret
+// issued when it registered, else the secret of the config when set
+func (s *Server) validClientSecret(clientID, secret string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if client, ok := s.clients[clientID]; ok {
+		return client.secret != "" && secret == client.secret
+	}
+	return s.config.ClientSecret == "" || secret == s.config.ClientSecret
+}
+
+// claimStrings - the string values of an array claim
+func claimStrings(claims jwt.MapClaims, name string) []string {
+	values, _ := claims[name].([]interface{})
+	strs := []string{}
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
+}
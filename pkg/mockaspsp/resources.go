@@ -0,0 +1,428 @@
+package mockaspsp
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+)
+
+// operation - an operation of a bundled spec served by the mock
+type operation struct {
+	version  string
+	method   string
+	basePath string
+	path     string       // spec path, e.g.: /accounts/{AccountId}
+	status   int          // success status code
+	schema   *spec.Schema // success response schema, nil for responses without body
+	file     bool         // the success response is a file
+	request  *spec.Schema // request body schema
+	invalid  *spec.Schema // bad request response schema
+	psu      bool         // requires the token of an authorised consent
+	idName   string       // POST creating resources: the ID property, e.g.: ConsentId
+	stored   bool         // GET and DELETE of resources created by POST
+}
+
+// loadOperations - the operations of the bundled specs of a version
+func loadOperations(version string) ([]operation, error) {
+	dirname := "pkg/schema/spec/" + version
+	filenames, err := assets.ReadDir(dirname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "mockaspsp: opening spec folder failed, dirname=%q", dirname)
+	}
+
+	operations := []operation{}
+	for _, name := range filenames {
+		filename := dirname + "/" + name
+		content, err := assets.ReadFile(filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "mockaspsp: reading spec file, filename=%q", filename)
+		}
+		doc, err := loads.Analyzed(json.RawMessage(content), "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "mockaspsp: opening spec file, filename=%q", filename)
+		}
+		operations = append(operations, specOperations(doc.Spec(), version)...)
+	}
+	return operations, nil
+}
+
+// parentPathRegex - matches the spec path of a resource created by POST on its parent path
+var parentPathRegex = regexp.MustCompile(`^(.*)/\{(\w+)\}$`)
+
+func specOperations(swagger *spec.Swagger, version string) []operation {
+	paths := []string{}
+	for path := range swagger.Paths.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	operations := []operation{}
+	for _, path := range paths {
+		item := swagger.Paths.Paths[path]
+		for method, op := range map[string]*spec.Operation{
+			http.MethodGet:    item.Get,
+			http.MethodPost:   item.Post,
+			http.MethodPut:    item.Put,
+			http.MethodDelete: item.Delete,
+			http.MethodPatch:  item.Patch,
+		} {
+			if op == nil || op.Responses == nil {
+				continue
+			}
+			served := operation{
+				version:  version,
+				method:   method,
+				basePath: swagger.BasePath,
+				path:     path,
+				psu:      hasSecurity(op, "PSUOAuth2Security"),
+			}
+			served.status, served.schema = successResponse(op.Responses)
+			if served.schema != nil && served.schema.Type.Contains("file") {
+				served.schema, served.file = nil, true
+			}
+			for _, param := range op.Parameters {
+				if param.In == "body" {
+					served.request = param.Schema
+				}
+			}
+			if invalid, ok := op.Responses.StatusCodeResponses[http.StatusBadRequest]; ok {
+				served.invalid = invalid.Schema
+			}
+			if method == http.MethodPost {
+				served.idName = createdIDName(swagger, path)
+			}
+			if matches := parentPathRegex.FindStringSubmatch(path); matches != nil && method != http.MethodPost {
+				parent, ok := swagger.Paths.Paths[matches[1]]
+				served.stored = ok && parent.Post != nil
+			}
+			operations = append(operations, served)
+		}
+	}
+	return operations
+}
+
+// createdIDName - the ID of the resources created by POST on a path, the parameter of
+// the path of the created resource, e.g.: DomesticPaymentId
+func createdIDName(swagger *spec.Swagger, path string) string {
+	for resourcePath, item := range swagger.Paths.Paths {
+		matches := parentPathRegex.FindStringSubmatch(resourcePath)
+		if matches != nil && matches[1] == path && item.Get != nil {
+			return matches[2]
+		}
+	}
+	return ""
+}
+
+// successResponse - the lowest 2xx status code of an operation and its schema
+func successResponse(responses *spec.Responses) (int, *spec.Schema) {
+	codes := []int{}
+	for code := range responses.StatusCodeResponses {
+		if code >= 200 && code < 300 {
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) == 0 {
+		return http.StatusOK, nil
+	}
+	sort.Ints(codes)
+	return codes[0], responses.StatusCodeResponses[codes[0]].Schema
+}
+
+func hasSecurity(op *spec.Operation, name string) bool {
+	for _, requirement := range op.Security {
+		if _, ok := requirement[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// echoPath - a spec path with echo route parameters, e.g.: /accounts/:AccountId
+func echoPath(path string) string {
+	return regexp.MustCompile(`\{(\w+)\}`).ReplaceAllString(path, ":$1")
+}
+
+// resourceHandler - serves an operation, responses are generated from the spec with
+// the seed data, resources created by POST are stored and served by GET
+func (s *Server) resourceHandler(op operation) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if status := s.authorise(c.Request(), op); status != 0 {
+			return s.respond(c, op, status, nil)
+		}
+		if accountID := c.Param("AccountId"); op.psu && accountID != "" && !contains(s.config.Seed.AccountIDs(), accountID) {
+			return s.respond(c, op, http.StatusBadRequest, s.badRequest(c, op, "UK.OBIE.Field.Invalid", "account not consented: "+accountID))
+		}
+		if op.stored {
+			return s.storedResource(c, op)
+		}
+
+		if op.file {
+			setInteractionID(c)
+			return c.Blob(s.successStatus(op), echo.MIMEOctetStream, []byte("mock file"))
+		}
+		request := map[string]interface{}{}
+		if op.request != nil {
+			if err := json.NewDecoder(c.Request().Body).Decode(&request); err != nil {
+				return s.respond(c, op, http.StatusBadRequest, s.badRequest(c, op, "UK.OBIE.Resource.InvalidFormat", "invalid request body: "+err.Error()))
+			}
+			if err := validate.AgainstSchema(op.request, request, strfmt.Default); err != nil {
+				return s.respond(c, op, http.StatusBadRequest, s.badRequest(c, op, "UK.OBIE.Field.Invalid", err.Error()))
+			}
+		}
+		body := s.generate(c, op)
+		if op.method == http.MethodPost && op.idName != "" {
+			s.create(c, op, body, request)
+		}
+		return s.respond(c, op, s.successStatus(op), body)
+	}
+}
+
+// authorise - the status code rejecting a request, 0 when the request is authorised
+// PSU operations need the token of an authorised consent with a permission of the
+// endpoint, other operations any token
+func (s *Server) authorise(r *http.Request, op operation) int {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return http.StatusUnauthorized
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	issued, ok := s.tokens[bearerToken(r)]
+	if !ok || time.Now().After(issued.expires) {
+		return http.StatusUnauthorized
+	}
+	if !op.psu {
+		return 0
+	}
+	if issued.consentID == "" || s.consentStatus(issued.consentID) != "Authorised" {
+		return http.StatusUnauthorized
+	}
+	if !s.permitted(issued.consentID, op.path) {
+		return http.StatusForbidden
+	}
+	return 0
+}
+
+// permitted - whether a consent has a permission of an endpoint, the accounts permissions
+// only give access to endpoints without a permission of their own, the lock must be held
+func (s *Server) permitted(consentID, path string) bool {
+	codes := model.EndpointPermissionCodes(path)
+	if len(codes) == 0 {
+		return true
+	}
+	required := []string{}
+	for _, code := range codes {
+		if !strings.HasPrefix(string(code), "ReadAccounts") {
+			required = append(required, string(code))
+		}
+	}
+	if len(required) == 0 {
+		for _, code := range codes {
+			required = append(required, string(code))
+		}
+	}
+
+	data, _ := s.resources[s.consents[consentID].resource]["Data"].(map[string]interface{})
+	permissions, _ := data["Permissions"].([]interface{})
+	for _, permission := range permissions {
+		if code, ok := permission.(string); ok && contains(required, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// generate - a response generated from the spec with the seed data, nil for operations
+// without response body
+func (s *Server) generate(c echo.Context, op operation) map[string]interface{} {
+	if op.schema == nil {
+		return nil
+	}
+	values := map[string]interface{}{}
+	for name, value := range s.config.Seed.Values {
+		values[name] = value
+	}
+	for i, name := range c.ParamNames() {
+		values[name] = c.ParamValues()[i]
+	}
+	values["Self"] = baseURL(c) + c.Request().URL.RequestURI()
+
+	body, ok := generator{values: values, now: time.Now()}.response(*op.schema).(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if seeded, ok := s.config.Seed.Responses[op.path]; ok {
+		merge(body, copyJSON(seeded))
+	}
+	return body
+}
+
+// create - stores the resource created by a POST, the request `Data` and `Risk` are
+// part of the created resource
+func (s *Server) create(c echo.Context, op operation, body, request map[string]interface{}) {
+	for _, key := range []string{"Data", "Risk"} {
+		if value, ok := request[key]; ok {
+			if _, ok := body[key]; ok {
+				body[key] = merge(body[key], value)
+			}
+		}
+	}
+	data, ok := body["Data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	id := uuid.New().String()
+	data[op.idName] = id
+	resource := c.Request().URL.Path + "/" + id
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if op.idName == "ConsentId" {
+		data["Status"] = "AwaitingAuthorisation"
+		s.consents[id] = consent{resource: resource, clientID: s.tokens[bearerToken(c.Request())].clientID}
+	}
+	s.resources[resource] = copyJSON(body).(map[string]interface{})
+}
+
+// storedResource - GET or DELETE of a resource created by POST
+func (s *Server) storedResource(c echo.Context, op operation) error {
+	s.lock.Lock()
+	resource, ok := s.resources[c.Request().URL.Path]
+	if ok && op.method == http.MethodDelete {
+		delete(s.resources, c.Request().URL.Path)
+		for id, consent := range s.consents {
+			if consent.resource == c.Request().URL.Path {
+				delete(s.consents, id)
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	if !ok {
+		return s.respond(c, op, http.StatusBadRequest, s.badRequest(c, op, "UK.OBIE.Resource.NotFound", "resource not found"))
+	}
+	if op.method == http.MethodDelete {
+		return s.respond(c, op, s.successStatus(op), nil)
+	}
+
+	s.lock.Lock()
+	body := copyJSON(resource).(map[string]interface{})
+	s.lock.Unlock()
+	if links, ok := body["Links"].(map[string]interface{}); ok {
+		links["Self"] = baseURL(c) + c.Request().URL.RequestURI()
+	}
+	return s.respond(c, op, s.successStatus(op), body)
+}
+
+// badRequest - an error response of the operation spec
+func (s *Server) badRequest(c echo.Context, op operation, errorCode, message string) map[string]interface{} {
+	if op.invalid == nil {
+		return nil
+	}
+	values := map[string]interface{}{
+		"ErrorCode": errorCode,
+		"Message":   message,
+		"Code":      http.StatusText(http.StatusBadRequest),
+		"Id":        uuid.New().String(),
+	}
+	body, _ := generator{values: values, now: time.Now()}.response(*op.invalid).(map[string]interface{})
+	return body
+}
+
+// respond - writes a response with a detached x-jws-signature of the body
+func (s *Server) respond(c echo.Context, op operation, status int, body map[string]interface{}) error {
+	setInteractionID(c)
+	if body == nil {
+		return c.NoContent(status)
+	}
+	if s.config.Profile.MissingFields && status < http.StatusBadRequest && op.schema != nil {
+		// a copy, body may be a stored resource served again
+		body = copyJSON(body).(map[string]interface{})
+		dropRequired(body["Data"], mergeAllOf(*op.schema).Properties["Data"])
+	}
+
+	content, err := json.Marshal(body)
+	if err != nil {
+		return errors.Wrap(err, "mockaspsp: encoding response")
+	}
+	signed := content
+	if s.config.Profile.InvalidSignature {
+		signed = append(append([]byte{}, content...), ' ')
+	}
+	signature, err := s.sign(signed, op.version)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set("x-jws-signature", signature)
+	return c.JSONBlob(status, content)
+}
+
+// setInteractionID - responses have the interaction ID of the request, or a new one
+func setInteractionID(c echo.Context) {
+	interactionID := c.Request().Header.Get("x-fapi-interaction-id")
+	if interactionID == "" {
+		interactionID = uuid.New().String()
+	}
+	c.Response().Header().Set("x-fapi-interaction-id", interactionID)
+}
+
+// consentStatus - the status of a stored consent, the lock must be held
+func (s *Server) consentStatus(consentID string) string {
+	consent, ok := s.consents[consentID]
+	if !ok {
+		return ""
+	}
+	data, _ := s.resources[consent.resource]["Data"].(map[string]interface{})
+	status, _ := data["Status"].(string)
+	return status
+}
+
+// setConsentStatus - updates the status of a stored consent, the lock must be held
+func (s *Server) setConsentStatus(consentID, status string) {
+	consent, ok := s.consents[consentID]
+	if !ok {
+		return
+	}
+	if data, ok := s.resources[consent.resource]["Data"].(map[string]interface{}); ok {
+		data["Status"] = status
+		data["StatusUpdateDateTime"] = time.Now().Format(time.RFC3339)
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// copyJSON - a deep copy of a decoded JSON value
+func copyJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = copyJSON(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = copyJSON(item)
+		}
+		return copied
+	}
+	return value
+}
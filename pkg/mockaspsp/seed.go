@@ -0,0 +1,56 @@
+package mockaspsp
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+)
+
+// DefaultSeedFilename - the seed data bundled with the suite
+const DefaultSeedFilename = "config/mock-aspsp-seed.json"
+
+// Seed - data served by the mock ASPSP in the responses generated from the specs
+type Seed struct {
+	// Values - values of response properties by name, e.g.: "AccountId", used
+	// wherever a property of that name and type is generated
+	Values map[string]interface{} `json:"values"`
+	// Responses - response bodies by spec path, e.g.: "/accounts", merged over
+	// the responses generated for the path
+	Responses map[string]interface{} `json:"responses"`
+}
+
+// LoadSeed - reads seed data from a file path or a bundled asset
+func LoadSeed(filename string) (Seed, error) {
+	content, err := assets.ReadFileOrPath(filename)
+	if err != nil {
+		return Seed{}, errors.Wrapf(err, "mockaspsp: reading seed data, filename=%q", filename)
+	}
+
+	seed := Seed{}
+	if err := json.Unmarshal(content, &seed); err != nil {
+		return Seed{}, errors.Wrapf(err, "mockaspsp: parsing seed data, filename=%q", filename)
+	}
+	return seed, nil
+}
+
+// AccountIDs - the accounts of the PSU, the accounts of the seeded `/accounts`
+// response or else the seeded AccountId
+func (s Seed) AccountIDs() []string {
+	ids := []string{}
+	if response, ok := s.Responses["/accounts"].(map[string]interface{}); ok {
+		data, _ := response["Data"].(map[string]interface{})
+		accounts, _ := data["Account"].([]interface{})
+		for _, account := range accounts {
+			account, _ := account.(map[string]interface{})
+			if id, ok := account["AccountId"].(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if id, ok := s.Values["AccountId"].(string); ok && len(ids) == 0 {
+		ids = append(ids, id)
+	}
+	return ids
+}
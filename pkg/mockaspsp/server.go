@@ -0,0 +1,217 @@
+// Package mockaspsp is a local ASPSP serving the Open Banking APIs of the bundled
+// swagger specs, so the suite can run journeys end to end without a sandbox.
+//
+// Responses are generated from the response schemas of the specs with the values
+// of a Seed, and signed with a detached x-jws-signature. Consents are authorised
+// by the authorization endpoint without PSU interaction, as in a headless flow.
+package mockaspsp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo"
+	"github.com/labstack/echo/middleware"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+// DefaultVersion - the spec version served when none is configured
+const DefaultVersion = "v3.1.5"
+
+// Config - configuration of the mock ASPSP
+type Config struct {
+	// Version - version of the bundled specs served, e.g.: "v3.1.5"
+	Version string
+	// Certificate - TLS server certificate, also signs responses and id tokens
+	Certificate authentication.Certificate
+	// ClientCAs - client certificates are verified against them when set
+	ClientCAs *x509.CertPool
+	// ClientID - the client allowed to call the ASPSP, any client when empty
+	ClientID string
+	// ClientSecret - the secret of client_secret_basic, any secret when empty
+	ClientSecret string
+	// OrgID - the `http://openbanking.org.uk/iss` of response signatures
+	OrgID string
+	// TokenLifetime - lifetime of access tokens, one hour when zero
+	TokenLifetime time.Duration
+	Seed          Seed
+	// Profile - faults injected in the responses, none when zero
+	Profile Profile
+	// RequirePKCE - authorization requests without an S256 code challenge are rejected
+	RequirePKCE bool
+	// RequirePAR - authorization requests not pushed to the pushed authorization request
+	// endpoint are rejected
+	RequirePAR bool
+	// RequestURILifetime - lifetime of the request URIs of pushed authorization requests,
+	// one minute when zero
+	RequestURILifetime time.Duration
+}
+
+// Server - the mock ASPSP, wraps *echo.Echo
+type Server struct {
+	*echo.Echo
+	config  Config
+	logger  *logrus.Entry
+	kid     string
+	tlsCert tls.Certificate
+
+	lock          *sync.Mutex
+	resources     map[string]map[string]interface{} // created resources by URL path
+	consents      map[string]consent
+	codes         map[string]grant
+	tokens        map[string]grant
+	refreshTokens map[string]grant
+	pushed        map[string]pushedRequest // pushed authorization requests by request URI
+	// clients - clients registered by dynamic client registration, by client ID
+	clients map[string]registeredClient
+	// signingAlg - responses and id tokens are signed with the default alg of the certificate key
+	signingAlg jwt.SigningMethod
+}
+
+// consent - a consent created by a TPP, its status is kept in the stored resource
+type consent struct {
+	resource string
+	clientID string
+}
+
+// grant - what an authorization code or token was issued for
+type grant struct {
+	clientID    string
+	scope       string
+	consentID   string // empty for client credentials
+	redirectURI string
+	nonce       string
+	challenge   string // PKCE S256 code challenge of an authorization code
+	expires     time.Time
+}
+
+// NewServer - creates a mock ASPSP serving the bundled specs of config.Version
+func NewServer(config Config, logger *logrus.Entry) (*Server, error) {
+	if config.Certificate == nil {
+		return nil, errors.New("mockaspsp: a certificate is required")
+	}
+	if config.Version == "" {
+		config.Version = DefaultVersion
+	}
+	if config.TokenLifetime == 0 {
+		config.TokenLifetime = time.Hour
+	}
+	if config.RequestURILifetime == 0 {
+		config.RequestURILifetime = time.Minute
+	}
+	if config.OrgID == "" {
+		orgID, err := config.Certificate.SignatureIssuer(false)
+		if err != nil || orgID == "" {
+			orgID = "mock-aspsp"
+		}
+		config.OrgID = orgID
+	}
+	kid, err := keyID(config.Certificate)
+	if err != nil {
+		return nil, errors.Wrap(err, "mockaspsp: calculating signing key id")
+	}
+	operations, err := loadOperations(config.Version)
+	if err != nil {
+		return nil, err
+	}
+	tlsCert := config.Certificate.TLSCert()
+	if config.Profile.ExpiredCertificate {
+		if tlsCert, err = expiredCertificate(config.Certificate); err != nil {
+			return nil, err
+		}
+	}
+
+	server := &Server{
+		Echo:          echo.New(),
+		config:        config,
+		logger:        logger,
+		kid:           kid,
+		tlsCert:       tlsCert,
+		lock:          &sync.Mutex{},
+		resources:     map[string]map[string]interface{}{},
+		consents:      map[string]consent{},
+		codes:         map[string]grant{},
+		tokens:        map[string]grant{},
+		refreshTokens: map[string]grant{},
+		pushed:        map[string]pushedRequest{},
+		clients:       map[string]registeredClient{},
+		signingAlg:    authentication.SigningAlgForKey(config.Certificate.PublicKey()),
+	}
+	server.HideBanner = true
+	server.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
+		Output: logger.Writer(),
+	}))
+	server.Use(middleware.Recover())
+	if config.Profile.Delay > 0 {
+		server.Use(delay(config.Profile.Delay))
+	}
+
+	server.GET("/.well-known/openid-configuration", server.openIDConfiguration)
+	server.GET("/jwks", server.jwksHandler)
+	server.GET("/authorize", server.authorize)
+	server.POST("/token", server.token, requireClientCertificate)
+	server.POST("/par", server.pushAuthorizationRequest, requireClientCertificate)
+	server.POST("/register", server.registerClient, requireClientCertificate)
+	server.GET("/register/:clientId", server.readClient, requireClientCertificate)
+	server.PUT("/register/:clientId", server.updateClient, requireClientCertificate)
+	server.DELETE("/register/:clientId", server.deleteClient, requireClientCertificate)
+	for _, op := range operations {
+		server.Match([]string{op.method}, op.basePath+echoPath(op.path), server.resourceHandler(op), requireClientCertificate)
+	}
+	logger.WithFields(logrus.Fields{
+		"operations": len(operations),
+		"profile":    config.Profile.Name,
+	}).Infof("serving %s specs", config.Version)
+
+	return server, nil
+}
+
+// TLSConfig - TLS of the mock, the client certificates of the mutual TLS of token and
+// resource endpoints are requested here and required by those endpoints
+func (s *Server) TLSConfig() *tls.Config {
+	config := &tls.Config{
+		Certificates: []tls.Certificate{s.tlsCert},
+		ClientAuth:   tls.RequestClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if s.config.Profile.MaxTLSVersion != 0 {
+		config.MinVersion = tls.VersionTLS10
+		config.MaxVersion = s.config.Profile.MaxTLSVersion
+	}
+	if s.config.ClientCAs != nil {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		config.ClientCAs = s.config.ClientCAs
+	}
+	return config
+}
+
+// ListenAndServeTLS - serves the mock ASPSP on address
+func (s *Server) ListenAndServeTLS(address string) error {
+	return s.StartServer(&http.Server{Addr: address, TLSConfig: s.TLSConfig()})
+}
+
+// requireClientCertificate - rejects requests without a client certificate
+func requireClientCertificate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		state := c.Request().TLS
+		if state == nil || len(state.PeerCertificates) == 0 {
+			return c.JSON(http.StatusUnauthorized, oauthError{
+				Error:            "invalid_client",
+				ErrorDescription: "mutual TLS client certificate required",
+			})
+		}
+		return next(c)
+	}
+}
+
+// baseURL - the URL the ASPSP was called at, used as issuer and in links
+func baseURL(c echo.Context) string {
+	return "https://" + c.Request().Host
+}
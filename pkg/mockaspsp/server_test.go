@@ -0,0 +1,486 @@
+package mockaspsp
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+)
+
+const (
+	certFile = "../../certs/conformancesuite_cert.pem"
+	keyFile  = "../../certs/conformancesuite_key.pem"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *http.Client) {
+	return newProfileServer(t, Profile{})
+}
+
+func newProfileServer(t *testing.T, profile Profile) (*httptest.Server, *http.Client) {
+	return newConfigServer(t, Config{Profile: profile})
+}
+
+// newConfigServer - a mock ASPSP of config with the test certificate, client and seed
+func newConfigServer(t *testing.T, config Config) (*httptest.Server, *http.Client) {
+	certificate := loadCertificate(t)
+	seed, err := LoadSeed(DefaultSeedFilename)
+	require.NoError(t, err)
+	config.Certificate, config.ClientID, config.ClientSecret, config.Seed = certificate, "tpp", "secret", seed
+	mock, err := NewServer(config, test.NullLogger())
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(mock)
+	server.TLS = mock.TLSConfig()
+	server.StartTLS()
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			Certificates:       []tls.Certificate{certificate.TLSCert()},
+			InsecureSkipVerify: true,
+		}},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	return server, client
+}
+
+func loadCertificate(t *testing.T) authentication.Certificate {
+	cert, err := ioutil.ReadFile(certFile)
+	require.NoError(t, err)
+	key, err := ioutil.ReadFile(keyFile)
+	require.NoError(t, err)
+	certificate, err := authentication.NewCertificate(string(cert), string(key))
+	require.NoError(t, err)
+	return certificate
+}
+
+type testClient struct {
+	t      *testing.T
+	client *http.Client
+	server *httptest.Server
+}
+
+func (c testClient) do(method, path, token, body string) (*http.Response, string) {
+	request, err := http.NewRequest(method, c.server.URL+path, strings.NewReader(body))
+	require.NoError(c.t, err)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("x-fapi-interaction-id", "93bac548-d2de-4546-b106-880a5018460d")
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response, err := c.client.Do(request)
+	require.NoError(c.t, err)
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(response.Body)
+	require.NoError(c.t, err)
+	return response, string(content)
+}
+
+func (c testClient) token(form url.Values) string {
+	request, err := http.NewRequest(http.MethodPost, c.server.URL+"/token", strings.NewReader(form.Encode()))
+	require.NoError(c.t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth("tpp", "secret")
+	response, err := c.client.Do(request)
+	require.NoError(c.t, err)
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(response.Body)
+	require.NoError(c.t, err)
+	require.Equal(c.t, http.StatusOK, response.StatusCode, string(content))
+	return string(content)
+}
+
+func (c testClient) validSignature(response *http.Response, body string, b64 bool) {
+	valid, err := authentication.ValidateSignature(response.Header.Get("x-jws-signature"), body, c.server.URL+"/jwks", b64)
+	require.NoError(c.t, err)
+	assert.True(c.t, valid)
+}
+
+func TestServer_AccountsHeadlessJourney(t *testing.T) {
+	server, client := newTestServer(t)
+	defer server.Close()
+	c := testClient{t: t, client: client, server: server}
+
+	response, body := c.do(http.MethodGet, "/.well-known/openid-configuration", "", "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, server.URL+"/token", gjson.Get(body, "token_endpoint").String())
+
+	clientToken := gjson.Get(c.token(url.Values{"grant_type": {"client_credentials"}, "scope": {"accounts"}}), "access_token").String()
+	response, body = c.do(http.MethodPost, "/open-banking/v3.1/aisp/account-access-consents", clientToken,
+		`{"Data":{"Permissions":["ReadAccountsBasic","ReadBalances"]},"Risk":{}}`)
+	require.Equal(t, http.StatusCreated, response.StatusCode, body)
+	assert.Equal(t, "AwaitingAuthorisation", gjson.Get(body, "Data.Status").String())
+	assert.Equal(t, "ReadBalances", gjson.Get(body, "Data.Permissions.1").String())
+	assert.Equal(t, "93bac548-d2de-4546-b106-880a5018460d", response.Header.Get("x-fapi-interaction-id"))
+	c.validSignature(response, body, true)
+	consentID := gjson.Get(body, "Data.ConsentId").String()
+
+	response, _ = c.do(http.MethodGet, "/open-banking/v3.1/aisp/accounts", clientToken, "")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode, "client credentials token")
+
+	consentURL, err := authentication.PSUURLGenerate(authentication.PSUConsentClaims{
+		AuthorizationEndpoint: server.URL + "/authorize",
+		Iss:                   "tpp",
+		ResponseType:          "code id_token",
+		Scope:                 "openid accounts",
+		RedirectURI:           "https://127.0.0.1:8443/conformancesuite/callback",
+		ConsentId:             consentID,
+		State:                 "Token001",
+	})
+	require.NoError(t, err)
+	response, _ = c.do(http.MethodGet, strings.TrimPrefix(consentURL.String(), server.URL), "", "")
+	require.Equal(t, http.StatusFound, response.StatusCode)
+	location := response.Header.Get("Location")
+	assert.True(t, strings.HasPrefix(location, "https://127.0.0.1:8443/conformancesuite/callback#id_token="), location)
+	assert.True(t, strings.HasSuffix(location, "&state=Token001"), location)
+	code := regexp.MustCompile("code=(.*)&").FindStringSubmatch(location)[1]
+
+	_, body = c.do(http.MethodGet, "/open-banking/v3.1/aisp/account-access-consents/"+consentID, clientToken, "")
+	assert.Equal(t, "Authorised", gjson.Get(body, "Data.Status").String())
+
+	tokens := c.token(url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"https://127.0.0.1:8443/conformancesuite/callback"}})
+	accessToken := gjson.Get(tokens, "access_token").String()
+	assert.NotEmpty(t, gjson.Get(tokens, "id_token").String())
+	assert.NotEmpty(t, gjson.Get(tokens, "refresh_token").String())
+
+	response, body = c.do(http.MethodGet, "/open-banking/v3.1/aisp/accounts", accessToken, "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "700004000000000000000001", gjson.Get(body, "Data.Account.0.AccountId").String())
+	assert.Equal(t, 2, len(gjson.Get(body, "Data.Account").Array()))
+	c.validSignature(response, body, true)
+
+	response, body = c.do(http.MethodGet, "/open-banking/v3.1/aisp/accounts/700004000000000000000002/balances", accessToken, "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "700004000000000000000002", gjson.Get(body, "Data.Balance.0.AccountId").String())
+	assert.Equal(t, server.URL+"/open-banking/v3.1/aisp/accounts/700004000000000000000002/balances", gjson.Get(body, "Links.Self").String())
+
+	response, _ = c.do(http.MethodGet, "/open-banking/v3.1/aisp/accounts/700004000000000000000002/beneficiaries", accessToken, "")
+	assert.Equal(t, http.StatusForbidden, response.StatusCode, "permission not consented")
+	response, body = c.do(http.MethodGet, "/open-banking/v3.1/aisp/accounts/foobar/balances", accessToken, "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "account not consented")
+	assert.Equal(t, "UK.OBIE.Field.Invalid", gjson.Get(body, "Errors.0.ErrorCode").String())
+
+	refreshed := c.token(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {gjson.Get(tokens, "refresh_token").String()}})
+	response, _ = c.do(http.MethodGet, "/open-banking/v3.1/aisp/accounts", gjson.Get(refreshed, "access_token").String(), "")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, _ = c.do(http.MethodDelete, "/open-banking/v3.1/aisp/account-access-consents/"+consentID, clientToken, "")
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	response, _ = c.do(http.MethodGet, "/open-banking/v3.1/aisp/accounts", accessToken, "")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode, "deleted consent")
+}
+
+func TestServer_PaymentSubmission(t *testing.T) {
+	server, client := newTestServer(t)
+	defer server.Close()
+	c := testClient{t: t, client: client, server: server}
+
+	clientToken := gjson.Get(c.token(url.Values{"grant_type": {"client_credentials"}, "scope": {"payments"}}), "access_token").String()
+	initiation := `{"InstructionIdentification":"ACME412","EndToEndIdentification":"FRESCO.21302.GFX.20","InstructedAmount":{"Amount":"165.88","Currency":"GBP"},"CreditorAccount":{"SchemeName":"UK.OBIE.SortCodeAccountNumber","Identification":"08080021325698","Name":"ACME Inc"}}`
+	response, body := c.do(http.MethodPost, "/open-banking/v3.1/pisp/domestic-payment-consents", clientToken,
+		`{"Data":{"Initiation":`+initiation+`},"Risk":{"PaymentContextCode":"EcommerceGoods"}}`)
+	require.Equal(t, http.StatusCreated, response.StatusCode, body)
+	assert.JSONEq(t, initiation, gjson.Get(body, "Data.Initiation").Raw)
+	assert.Equal(t, "EcommerceGoods", gjson.Get(body, "Risk.PaymentContextCode").String())
+	consentID := gjson.Get(body, "Data.ConsentId").String()
+
+	response, body = c.do(http.MethodPost, "/open-banking/v3.1/pisp/domestic-payment-consents", clientToken,
+		`{"Data":{"Initiation":{"InstructedAmount":{"Amount":"165.88","Currency":"GBP"}}},"Risk":{}}`)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "request not valid against the spec")
+	assert.Equal(t, "UK.OBIE.Field.Invalid", gjson.Get(body, "Errors.0.ErrorCode").String())
+
+	response, _ = c.do(http.MethodGet, "/authorize?response_type=code&client_id=tpp&state=Token002&redirect_uri="+url.QueryEscape("https://tpp.example.com/callback")+
+		"&request="+unsignedRequest(t, consentID), "", "")
+	require.Equal(t, http.StatusFound, response.StatusCode)
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "Token002", location.Query().Get("state"))
+	paymentToken := gjson.Get(c.token(url.Values{"grant_type": {"authorization_code"}, "code": {location.Query().Get("code")}}), "access_token").String()
+
+	response, body = c.do(http.MethodPost, "/open-banking/v3.1/pisp/domestic-payments", paymentToken,
+		`{"Data":{"ConsentId":"`+consentID+`","Initiation":`+initiation+`},"Risk":{}}`)
+	require.Equal(t, http.StatusCreated, response.StatusCode, body)
+	paymentID := gjson.Get(body, "Data.DomesticPaymentId").String()
+	assert.NotEmpty(t, paymentID)
+	assert.Equal(t, consentID, gjson.Get(body, "Data.ConsentId").String())
+
+	response, stored := c.do(http.MethodGet, "/open-banking/v3.1/pisp/domestic-payments/"+paymentID, clientToken, "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, gjson.Get(body, "Data").Raw, gjson.Get(stored, "Data").Raw)
+
+	response, body = c.do(http.MethodGet, "/open-banking/v3.1/pisp/domestic-payments/unknown", clientToken, "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	assert.Equal(t, "UK.OBIE.Resource.NotFound", gjson.Get(body, "Errors.0.ErrorCode").String())
+}
+
+func TestServer_PKCE(t *testing.T) {
+	server, client := newTestServer(t)
+	defer server.Close()
+	c := testClient{t: t, client: client, server: server}
+	clientToken := gjson.Get(c.token(url.Values{"grant_type": {"client_credentials"}, "scope": {"accounts"}}), "access_token").String()
+
+	authorize := func(verifier string) string {
+		_, body := c.do(http.MethodPost, "/open-banking/v3.1/aisp/account-access-consents", clientToken, `{"Data":{"Permissions":["ReadAccountsBasic"]},"Risk":{}}`)
+		consentURL, err := authentication.PSUURLGenerate(authentication.PSUConsentClaims{
+			AuthorizationEndpoint: server.URL + "/authorize",
+			Iss:                   "tpp",
+			ResponseType:          "code",
+			RedirectURI:           "https://tpp.example.com/callback",
+			ConsentId:             gjson.Get(body, "Data.ConsentId").String(),
+			State:                 "Token001",
+			CodeVerifier:          verifier,
+		})
+		require.NoError(t, err)
+		response, _ := c.do(http.MethodGet, strings.TrimPrefix(consentURL.String(), server.URL), "", "")
+		require.Equal(t, http.StatusFound, response.StatusCode)
+		location, err := url.Parse(response.Header.Get("Location"))
+		require.NoError(t, err)
+		return location.Query().Get("code")
+	}
+	exchange := func(code, verifier string) (int, string) {
+		form := url.Values{"grant_type": {"authorization_code"}, "code": {code}, "client_id": {"tpp"}}
+		if verifier != "" {
+			form.Set("code_verifier", verifier)
+		}
+		response, err := client.PostForm(server.URL+"/token", form)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		content, err := ioutil.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, string(content)
+	}
+
+	verifier, err := authentication.NewCodeVerifier()
+	require.NoError(t, err)
+	wrong, err := authentication.NewCodeVerifier()
+	require.NoError(t, err)
+
+	status, body := exchange(authorize(verifier), wrong)
+	assert.Equal(t, http.StatusBadRequest, status, "wrong verifier")
+	assert.Equal(t, "code_verifier does not match the code challenge", gjson.Get(body, "error_description").String())
+
+	status, _ = exchange(authorize(verifier), "")
+	assert.Equal(t, http.StatusBadRequest, status, "missing verifier")
+
+	status, body = exchange(authorize(verifier), verifier)
+	assert.Equal(t, http.StatusOK, status, body)
+	assert.NotEmpty(t, gjson.Get(body, "access_token").String())
+}
+
+func TestServer_RequirePKCE(t *testing.T) {
+	certificate := loadCertificate(t)
+	mock, err := NewServer(Config{Certificate: certificate, Seed: Seed{}, RequirePKCE: true}, test.NullLogger())
+	require.NoError(t, err)
+	server := httptest.NewServer(mock)
+	defer server.Close()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	response, err := client.Get(server.URL + "/authorize?response_type=code&state=Token001&redirect_uri=" + url.QueryEscape("https://tpp.example.com/callback"))
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusFound, response.StatusCode)
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "invalid_request", location.Query().Get("error"))
+	assert.Equal(t, "code_challenge missing", location.Query().Get("error_description"))
+}
+
+func (c testClient) push(form url.Values, authenticated bool) (int, string) {
+	request, err := http.NewRequest(http.MethodPost, c.server.URL+"/par", strings.NewReader(form.Encode()))
+	require.NoError(c.t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if authenticated {
+		request.SetBasicAuth("tpp", "secret")
+	}
+	response, err := c.client.Do(request)
+	require.NoError(c.t, err)
+	defer response.Body.Close()
+	content, err := ioutil.ReadAll(response.Body)
+	require.NoError(c.t, err)
+	return response.StatusCode, string(content)
+}
+
+func TestServer_PushedAuthorizationRequest(t *testing.T) {
+	server, client := newConfigServer(t, Config{RequirePAR: true})
+	defer server.Close()
+	c := testClient{t: t, client: client, server: server}
+	clientToken := gjson.Get(c.token(url.Values{"grant_type": {"client_credentials"}, "scope": {"accounts"}}), "access_token").String()
+	_, body := c.do(http.MethodPost, "/open-banking/v3.1/aisp/account-access-consents", clientToken, `{"Data":{"Permissions":["ReadAccountsBasic"]},"Risk":{}}`)
+	consentURL, err := authentication.PSUURLGenerate(authentication.PSUConsentClaims{
+		AuthorizationEndpoint: server.URL + "/authorize",
+		Iss:                   "tpp",
+		ResponseType:          "code",
+		RedirectURI:           "https://tpp.example.com/callback",
+		ConsentId:             gjson.Get(body, "Data.ConsentId").String(),
+		State:                 "Token001",
+	})
+	require.NoError(t, err)
+
+	_, openid := c.do(http.MethodGet, "/.well-known/openid-configuration", "", "")
+	assert.Equal(t, "https://"+strings.TrimPrefix(server.URL, "https://")+"/par", gjson.Get(openid, "pushed_authorization_request_endpoint").String())
+	assert.True(t, gjson.Get(openid, "require_pushed_authorization_requests").Bool())
+
+	response, _ := c.do(http.MethodGet, strings.TrimPrefix(consentURL.String(), server.URL), "", "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "not pushed")
+
+	unauthenticated := consentURL.Query()
+	unauthenticated.Del("client_id")
+	status, _ := c.push(unauthenticated, false)
+	assert.Equal(t, http.StatusUnauthorized, status, "no client authentication")
+
+	withRequestURI := consentURL.Query()
+	withRequestURI.Set("request_uri", requestURIPrefix+"foo")
+	status, _ = c.push(withRequestURI, true)
+	assert.Equal(t, http.StatusBadRequest, status, "request_uri pushed")
+
+	status, body = c.push(consentURL.Query(), true)
+	require.Equal(t, http.StatusCreated, status, body)
+	requestURI := gjson.Get(body, "request_uri").String()
+	assert.True(t, strings.HasPrefix(requestURI, requestURIPrefix))
+	assert.Equal(t, int64(60), gjson.Get(body, "expires_in").Int())
+
+	authorize := "/authorize?client_id=tpp&request_uri=" + url.QueryEscape(requestURI)
+	response, _ = c.do(http.MethodGet, authorize, "", "")
+	require.Equal(t, http.StatusFound, response.StatusCode)
+	location, err := url.Parse(response.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, location.Query().Get("code"))
+	assert.Equal(t, "Token001", location.Query().Get("state"))
+
+	response, body = c.do(http.MethodGet, authorize, "", "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "request_uri used")
+	assert.Equal(t, "invalid_request_uri", gjson.Get(body, "error").String())
+}
+
+func TestServer_PushedAuthorizationRequestExpires(t *testing.T) {
+	server, client := newConfigServer(t, Config{RequestURILifetime: time.Nanosecond})
+	defer server.Close()
+	c := testClient{t: t, client: client, server: server}
+
+	status, body := c.push(url.Values{"response_type": {"code"}, "client_id": {"tpp"}}, true)
+	require.Equal(t, http.StatusCreated, status, body)
+
+	response, _ := c.do(http.MethodGet, "/authorize?request_uri="+url.QueryEscape(gjson.Get(body, "request_uri").String()), "", "")
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func TestServer_RequiresClientCertificate(t *testing.T) {
+	server, _ := newTestServer(t)
+	defer server.Close()
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	response, err := client.PostForm(server.URL+"/token", url.Values{"grant_type": {"client_credentials"}, "client_id": {"tpp"}})
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	response, err = client.Get(server.URL + "/jwks")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	jwks := authentication.JWKS{}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&jwks))
+	require.Len(t, jwks.Keys, 1)
+	assert.Len(t, jwks.Keys[0].X5c, 1)
+}
+
+func TestServer_UnknownClient(t *testing.T) {
+	server, client := newTestServer(t)
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/token", strings.NewReader("grant_type=client_credentials"))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth("tpp", "wrong")
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+}
+
+// registrationRequest - a registration request of the software redirecting to redirectURI, signed
+// with alg
+func (c testClient) registrationRequest(alg jwt.SigningMethod, redirectURI string) string {
+	ssa, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"software_id":            "software",
+		"software_redirect_uris": []string{"https://tpp.example.com/callback"},
+	}).SignedString([]byte("directory"))
+	require.NoError(c.t, err)
+	var key interface{} = loadCertificate(c.t).PrivateKey()
+	if alg == jwt.SigningMethodNone {
+		key = jwt.UnsafeAllowNoneSignatureType
+	}
+	request, err := jwt.NewWithClaims(alg, jwt.MapClaims{
+		"iss":                        "software",
+		"redirect_uris":              []string{redirectURI},
+		"token_endpoint_auth_method": "client_secret_basic",
+		"grant_types":                []string{"client_credentials"},
+		"software_statement":         ssa,
+	}).SignedString(key)
+	require.NoError(c.t, err)
+	return request
+}
+
+func TestServer_DynamicClientRegistration(t *testing.T) {
+	server, client := newTestServer(t)
+	defer server.Close()
+	c := testClient{t: t, client: client, server: server}
+
+	_, openid := c.do(http.MethodGet, "/.well-known/openid-configuration", "", "")
+	assert.Equal(t, server.URL+"/register", gjson.Get(openid, "registration_endpoint").String())
+
+	response, _ := c.do(http.MethodPost, "/register", "", c.registrationRequest(jwt.SigningMethodNone, "https://tpp.example.com/callback"))
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "unsigned")
+	response, body := c.do(http.MethodPost, "/register", "", c.registrationRequest(jwt.SigningMethodRS256, "https://attacker.example.com"))
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode, "redirect URI not in the software statement")
+	assert.Equal(t, "invalid_client_metadata", gjson.Get(body, "error").String())
+
+	response, body = c.do(http.MethodPost, "/register", "", c.registrationRequest(jwt.SigningMethodRS256, "https://tpp.example.com/callback"))
+	require.Equal(t, http.StatusCreated, response.StatusCode, body)
+	clientID, secret := gjson.Get(body, "client_id").String(), gjson.Get(body, "client_secret").String()
+	assert.NotEmpty(t, secret)
+	assert.Equal(t, "software", gjson.Get(body, "software_id").String())
+	assert.False(t, gjson.Get(body, "iss").Exists())
+	assert.Equal(t, server.URL+"/register/"+clientID, gjson.Get(body, "registration_client_uri").String())
+
+	request, err := http.NewRequest(http.MethodPost, server.URL+"/token", strings.NewReader("grant_type=client_credentials"))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(clientID, secret)
+	tokenResponse, err := client.Do(request)
+	require.NoError(t, err)
+	defer tokenResponse.Body.Close()
+	assert.Equal(t, http.StatusOK, tokenResponse.StatusCode, "registered client authenticates")
+
+	response, _ = c.do(http.MethodGet, "/register/"+clientID, "wrong", "")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+	registrationToken := gjson.Get(body, "registration_access_token").String()
+	response, body = c.do(http.MethodGet, "/register/"+clientID, registrationToken, "")
+	require.Equal(t, http.StatusOK, response.StatusCode, body)
+	assert.Equal(t, secret, gjson.Get(body, "client_secret").String())
+
+	response, _ = c.do(http.MethodDelete, "/register/"+clientID, registrationToken, "")
+	assert.Equal(t, http.StatusNoContent, response.StatusCode)
+	response, _ = c.do(http.MethodGet, "/register/"+clientID, registrationToken, "")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode, "deleted")
+}
+
+// unsignedRequest - a request object with the intent ID of a consent, as the suite sends in headless flows
+func unsignedRequest(t *testing.T, consentID string) string {
+	consentURL, err := authentication.PSUURLGenerate(authentication.PSUConsentClaims{AuthorizationEndpoint: "https://aspsp.example.com", ConsentId: consentID})
+	require.NoError(t, err)
+	return url.QueryEscape(consentURL.Query().Get("request"))
+}
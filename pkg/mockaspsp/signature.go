@@ -0,0 +1,74 @@
+package mockaspsp
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+)
+
+// trustAnchor - the `http://openbanking.org.uk/tan` of response signatures
+const trustAnchor = "openbanking.org.uk"
+
+// keyID - the `kid` of the signing key, calculated as the Open Banking directory does
+func keyID(cert authentication.Certificate) (string, error) {
+	return authentication.KeyID(cert.PublicKey())
+}
+
+// sign - the detached x-jws-signature of a response body for a spec version
+// The payload is base64 encoded from v3.1.4, v3.0 signatures don't have a trust anchor
+func (s *Server) sign(body []byte, version string) (string, error) {
+	var token jwt.Token
+	b64 := version >= "v3.1.4"
+	switch {
+	case b64:
+		token = authentication.GetSignatureToken314Plus(s.kid, s.config.OrgID, trustAnchor, s.signingAlg)
+	case version < "v3.1":
+		token = authentication.GetSignatureToken30(s.kid, s.config.OrgID, trustAnchor, s.signingAlg)
+	default:
+		token = authentication.GetSignatureToken313Minus(s.kid, s.config.OrgID, trustAnchor, s.signingAlg)
+	}
+
+	signed, err := authentication.CreateSignature(&token, s.config.Certificate.PrivateKey(), string(body), b64)
+	if err != nil {
+		return "", errors.Wrap(err, "mockaspsp: signing response")
+	}
+	return authentication.SplitJWSWithBody(signed), nil
+}
+
+// jwks - the key set with the signing key of the ASPSP
+func (s *Server) jwks() authentication.JWKS {
+	jwk := authentication.JWK{
+		Alg: s.signingAlg.Alg(),
+		Kid: s.kid,
+		Use: "sig",
+	}
+	switch publicKey := s.config.Certificate.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(exponentBytes(publicKey.E))
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = publicKey.Params().Name
+		jwk.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, 32)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, 32)))
+	}
+	for _, der := range s.config.Certificate.TLSCert().Certificate {
+		jwk.X5c = append(jwk.X5c, base64.StdEncoding.EncodeToString(der))
+	}
+	return authentication.JWKS{Keys: []authentication.JWK{jwk}}
+}
+
+// exponentBytes - big-endian bytes of an RSA public exponent
+func exponentBytes(e int) []byte {
+	bytes := []byte{}
+	for ; e > 0; e >>= 8 {
+		bytes = append([]byte{byte(e)}, bytes...)
+	}
+	return bytes
+}
@@ -1,10 +1,12 @@
 package model
 
 import (
-	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
 )
 
 // Component - a reusable test case building block
@@ -26,26 +28,22 @@ func MakeComponent(name string) Component {
 }
 
 const (
-	productionComponentDirectory = "components/"
-	relativeComponentDirectory   = "../../components/"
-	testComponentDirectory       = "../model/component/testdata/"
+	componentDirectory     = "components/"
+	testComponentDirectory = "../model/component/testdata/"
 )
 
 // LoadComponent - Utility to load Manifest Data Model containing all Rules, Tests and Conditions
 func LoadComponent(filename string) (Component, error) {
 	var c Component
 
-	// handle varying location of component directory depending on test/prod
-	fileContents, err := ioutil.ReadFile(productionComponentDirectory + filename)
+	// bundled components first, then test components or a component file path
+	fileContents, err := assets.ReadFile(componentDirectory + filename)
 	if err != nil {
-		fileContents, err = ioutil.ReadFile(relativeComponentDirectory + filename)
+		fileContents, err = ioutil.ReadFile(testComponentDirectory + filename)
 		if err != nil {
-			fileContents, err = ioutil.ReadFile(testComponentDirectory + filename)
+			fileContents, err = ioutil.ReadFile(filename)
 			if err != nil {
-				fileContents, err = ioutil.ReadFile(filename)
-				if err != nil {
-					return c, err
-				}
+				return c, err
 			}
 		}
 	}
@@ -140,7 +140,7 @@ func (c *Context) DumpContext(text ...string) {
 		}
 	} else {
 		for k, v := range *c {
-			if k == "client_secret" || k == "basic_authentication" || k == "signingPublic" || k == "signingPrivate" { // skip potentially sensitive fields - likely need to be more robust
+			if k == "client_secret" || k == "basic_authentication" || k == "signingPublic" || k == "signingPrivate" || k == "signingSigner" { // skip potentially sensitive fields - likely need to be more robust
 				continue
 			}
 			logrus.StandardLogger().Tracef("[Context] %s:%v", k, v)
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -44,8 +45,8 @@ var disableJws = false // defaults to JWS disabled in line with waiver 007
 var b64Status bool     // store b64 value for report
 
 // CreateRequest is the main Input work horse which examines the various Input parameters and generates an
-// http.Request object which represents the request
-func (i *Input) CreateRequest(tc *TestCase, ctx *Context) (*resty.Request, error) {
+// http.Request object which represents the request, sent by client
+func (i *Input) CreateRequest(tc *TestCase, ctx *Context, client *resty.Client) (*resty.Request, error) {
 	var err error
 
 	if tc == nil {
@@ -56,11 +57,15 @@ func (i *Input) CreateRequest(tc *TestCase, ctx *Context) (*resty.Request, error
 		return nil, i.AppErr(fmt.Sprintf("error CreateRequest - Context is nil"))
 	}
 
+	if client == nil {
+		return nil, i.AppErr("error CreateRequest - HTTP client is nil")
+	}
+
 	if i.Endpoint == "" || i.Method == "" { // we don't have a value input object
 		return nil, i.AppErr(fmt.Sprintf("error empty Endpoint(%s) or Method(%s)", i.Endpoint, i.Method))
 	}
 
-	req := resty.R() // create basic request that will be sent to endpoint
+	req := client.R() // create basic request that will be sent to endpoint
 
 	tc.Input.Endpoint, err = replaceContextField(tc.Input.Endpoint, ctx)
 	if err != nil {
@@ -132,6 +137,9 @@ func (i *Input) setClaims(tc *TestCase, ctx *Context) error {
 			fallthrough
 		case "consenturl":
 			i.AppMsg("==> executing consenturl strategy")
+			if err := i.setCodeChallenge(ctx); err != nil {
+				return i.AppErr(fmt.Sprintf("error creating code verifier %s", err.Error()))
+			}
 			token, err := i.GenerateRequestToken(ctx)
 			if err != nil {
 				return i.AppErr(fmt.Sprintf("error creating request token %s", err.Error()))
@@ -151,7 +159,7 @@ func (i *Input) setClaims(tc *TestCase, ctx *Context) error {
 			}
 		case "jwt-bearer":
 			i.AppMsg("==> executing jwt-bearer strategy")
-			token, err := i.GenerateSignedJWT(ctx, jwt.SigningMethodRS256)
+			token, err := i.GenerateSignedJWT(ctx, clientAssertionAlg(ctx))
 			if err != nil {
 				return i.AppErr(fmt.Sprintf("error creating AlgRS256JWT %s", err.Error()))
 			}
@@ -163,6 +171,16 @@ func (i *Input) setClaims(tc *TestCase, ctx *Context) error {
 	return nil
 }
 
+// clientAssertionAlg - the alg of jwt-bearer tokens: RS256, or ES256 for EC signing keys
+func clientAssertionAlg(ctx *Context) jwt.SigningMethod {
+	if cert, err := signingCertFromContext(ctx); err == nil {
+		if _, ok := cert.PublicKey().(*ecdsa.PublicKey); ok {
+			return authentication.SigningMethodES256
+		}
+	}
+	return authentication.SigningMethodRS256
+}
+
 func (i *Input) GenerateRequestToken(ctx *Context) (string, error) {
 	alg, err := ctx.GetString("requestObjectSigningAlg")
 	if err != nil && err != ErrNotFound {
@@ -175,6 +193,34 @@ func (i *Input) GenerateRequestToken(ctx *Context) (string, error) {
 	return i.GenerateSignedJWT(ctx, signingMethod)
 }
 
+// CodeVerifierKey - the context key of the PKCE code verifier of the consent url of state,
+// sent on the exchange of the code of the consent
+func CodeVerifierKey(state string) string {
+	return authentication.CodeVerifier + "_" + state
+}
+
+// setCodeChallenge - generates the PKCE code verifier of a consent url, put in ctx as
+// `code_verifier` and by the state of the consent, and sets its S256 code challenge in claims
+func (i *Input) setCodeChallenge(ctx *Context) error {
+	verifier, err := authentication.NewCodeVerifier()
+	if err != nil {
+		return err
+	}
+	ctx.PutString(authentication.CodeVerifier, verifier)
+	ctx.PutString(CodeVerifierKey(i.Claims["state"]), verifier)
+	i.Claims[authentication.CodeChallenge] = authentication.CodeChallengeS256(verifier)
+	i.Claims[authentication.CodeChallengeMethod] = authentication.CodeChallengeMethodS256
+	return nil
+}
+
+// setCodeChallengeClaims - the PKCE code challenge claims of a request object
+func setCodeChallengeClaims(jwtClaims jwt.MapClaims, inputClaims map[string]string) {
+	if challenge, ok := inputClaims[authentication.CodeChallenge]; ok {
+		jwtClaims[authentication.CodeChallenge] = challenge
+		jwtClaims[authentication.CodeChallengeMethod] = inputClaims[authentication.CodeChallengeMethod]
+	}
+}
+
 func consentURL(authEndpoint string, claims map[string]string, token string) string {
 	queryString := url.Values{}
 	queryString.Set("client_id", claims["iss"])
@@ -183,6 +229,10 @@ func consentURL(authEndpoint string, claims map[string]string, token string) str
 	queryString.Set("request", token)
 	queryString.Set("state", claims["state"])
 	queryString.Set("redirect_uri", claims["redirect_url"])
+	if challenge, ok := claims[authentication.CodeChallenge]; ok {
+		queryString.Set(authentication.CodeChallenge, challenge)
+		queryString.Set(authentication.CodeChallengeMethod, claims[authentication.CodeChallengeMethod])
+	}
 
 	consentURL := fmt.Sprintf("%s?%s", authEndpoint, queryString.Encode())
 
@@ -390,17 +440,9 @@ func (i *Input) Clone() Input {
 }
 
 func signingCertFromContext(ctx *Context) (authentication.Certificate, error) {
-	privKey, err := ctx.GetString("signingPrivate")
-	if err != nil {
-		return nil, errors.New("input, couldn't find `SigningPrivate` in context")
-	}
-	pubKey, err := ctx.GetString("signingPublic")
-	if err != nil {
-		return nil, errors.New("input, couldn't find `SigningPublic` in context")
-	}
-	cert, err := authentication.NewCertificate(pubKey, privKey)
+	cert, err := authentication.SigningCertFromContext(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "input, couldn't create `certificate` from pub/priv keys")
+		return nil, errors.Wrap(err, "input, couldn't create signing `certificate`")
 	}
 	return cert, nil
 }
@@ -447,6 +489,7 @@ func (i *Input) GenerateSignedJWT(ctx *Context, alg jwt.SigningMethod) (string,
 	if responseType, ok := i.Claims["responseType"]; ok {
 		claims["response_type"] = responseType
 	}
+	setCodeChallengeClaims(claims, i.Claims)
 
 	logrus.WithFields(logrus.Fields{
 		"claims":   claims,
@@ -460,7 +503,7 @@ func (i *Input) GenerateSignedJWT(ctx *Context, alg jwt.SigningMethod) (string,
 	if err != nil {
 		return "", i.AppErr(errors.Wrap(err, "Create certificate from context").Error())
 	}
-	kid, err := authentication.GetKID(ctx, cert.PublicKey().N.Bytes())
+	kid, err := authentication.GetKID(ctx, cert.PublicKey())
 	if err != nil {
 		return "", errors.Wrap(err, "model.Input.generateJWSSignature failure: unable to get KID")
 	}
@@ -588,6 +631,7 @@ func (i *Input) generateUnsignedJWT(ctx *Context) (string, error) {
 	claims["aud"] = i.Claims["aud"]
 	claims["redirect_uri"] = i.Claims["redirect_url"]
 	SetAdditonalClaims(claims, i.Claims)
+	setCodeChallengeClaims(claims, i.Claims)
 
 	consentClaim := consentClaims{Essential: true, Value: i.Claims["consentId"]}
 	myIdent := obIDToken{IntentID: consentClaim}
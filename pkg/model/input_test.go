@@ -25,17 +25,17 @@ import (
 
 func TestCreateRequestEmptyEndpointOrMethod(t *testing.T) {
 	i := &Input{}
-	req, err := i.CreateRequest(emptyTestCase, emptyContext)
+	req, err := i.CreateRequest(emptyTestCase, emptyContext, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 
 	i = &Input{Endpoint: "http://google.com"}
-	req, err = i.CreateRequest(emptyTestCase, emptyContext)
+	req, err = i.CreateRequest(emptyTestCase, emptyContext, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 
 	i = &Input{Method: "GET"}
-	req, err = i.CreateRequest(emptyTestCase, emptyContext)
+	req, err = i.CreateRequest(emptyTestCase, emptyContext, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
@@ -51,24 +51,31 @@ func TestCreateRequestEmptyEndpointOrMethod(t *testing.T) {
 
 func TestCreateRequestionNilContext(t *testing.T) {
 	i := &Input{Method: "GET", Endpoint: "http://google.com"}
-	req, err := i.CreateRequest(emptyTestCase, nil)
+	req, err := i.CreateRequest(emptyTestCase, nil, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
 
 func TestCreateRequestionNilTestcase(t *testing.T) {
 	i := &Input{Method: "GET", Endpoint: "http://google.com"}
-	req, err := i.CreateRequest(nil, emptyContext)
+	req, err := i.CreateRequest(nil, emptyContext, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
 
+func TestCreateRequestionNilClient(t *testing.T) {
+	i := &Input{Method: "GET", Endpoint: "http://google.com"}
+	req, err := i.CreateRequest(emptyTestCase, emptyContext, nil)
+	assert.EqualError(t, err, "error CreateRequest - HTTP client is nil")
+	assert.Nil(t, req)
+}
+
 func TestCreateRequestNilHeaderContext(t *testing.T) {
 	headers := map[string]string{
 		"Myheader": "myValue",
 	}
 	i := &Input{Method: "GET", Endpoint: "http://google.com", Headers: headers}
-	req, err := i.CreateRequest(emptyTestCase, emptyContext)
+	req, err := i.CreateRequest(emptyTestCase, emptyContext, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	for k, v := range req.Header {
@@ -86,7 +93,7 @@ func TestCreateRequestHeaderContext(t *testing.T) {
 		"authorisation_endpoint": "https://example.com/authorisation",
 	}
 	i := &Input{Method: "GET", Endpoint: "http://google.com", Headers: headers}
-	req, err := i.CreateRequest(emptyTestCase, &ctx)
+	req, err := i.CreateRequest(emptyTestCase, &ctx, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	for k, v := range req.Header {
@@ -104,7 +111,7 @@ func TestSetBearerAuthTokenFromContext(t *testing.T) {
 		"authorisation_endpoint": "https://example.com/authorisation",
 	}
 	i := &Input{Method: "GET", Endpoint: "http://google.com", Headers: headers}
-	req, err := i.CreateRequest(emptyTestCase, &ctx)
+	req, err := i.CreateRequest(emptyTestCase, &ctx, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	for k, v := range req.Header {
@@ -151,7 +158,7 @@ func TestFormData(t *testing.T) {
 		"scope":      "accounts openid"}}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(emptyContext, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	assert.Equal(t, 2, len(req.FormData))
@@ -164,7 +171,7 @@ func TestFormDataMissingContextVariable(t *testing.T) {
 		"scope":      "accounts openid"}}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(&ctx1)
+	req, err := tc.Prepare(&ctx1, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
@@ -173,7 +180,7 @@ func TestInputBody(t *testing.T) {
 	i := Input{Endpoint: "/accounts", Method: "POST", RequestBody: "The Rain in Spain Falls Mainly on the Plain"}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(emptyContext, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 	assert.Equal(t, "The Rain in Spain Falls Mainly on the Plain", req.Body.(string))
@@ -192,13 +199,14 @@ func TestInputClaims(t *testing.T) {
 		}}
 	ctx := Context{"baseurl": "http://mybaseurl", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	runCtx := Context{}
+	req, err := tc.Prepare(&runCtx, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 
 	m, err := url.ParseQuery(req.URL)
 	require.NoError(t, err)
-	assert.Equal(t, m["request"][0], "eyJhbGciOiJub25lIn0.eyJhdWQiOiIiLCJjbGFpbXMiOnsiaWRfdG9rZW4iOnsib3BlbmJhbmtpbmdfaW50ZW50X2lkIjp7ImVzc2VudGlhbCI6dHJ1ZSwidmFsdWUiOiIifX19LCJpc3MiOiI4NjcyMzg0ZS05YTMzLTQzOWYtODkyNC02N2JiMTQzNDBkNzEiLCJyZWRpcmVjdF91cmkiOiJodHRwczovL3Rlc3QuZXhhbXBsZS5jby51ay9yZWRpciIsInNjb3BlIjoib3BlbmlkIGFjY291bnRzIn0.")
+	assertRequestObjectWithCodeChallenge(t, runCtx, m, "eyJhbGciOiJub25lIn0.eyJhdWQiOiIiLCJjbGFpbXMiOnsiaWRfdG9rZW4iOnsib3BlbmJhbmtpbmdfaW50ZW50X2lkIjp7ImVzc2VudGlhbCI6dHJ1ZSwidmFsdWUiOiIifX19LCJpc3MiOiI4NjcyMzg0ZS05YTMzLTQzOWYtODkyNC02N2JiMTQzNDBkNzEiLCJyZWRpcmVjdF91cmkiOiJodHRwczovL3Rlc3QuZXhhbXBsZS5jby51ay9yZWRpciIsInNjb3BlIjoib3BlbmlkIGFjY291bnRzIn0.")
 }
 
 func TestInputClaimsWithContextReplacementParameters(t *testing.T) {
@@ -216,14 +224,61 @@ func TestInputClaimsWithContextReplacementParameters(t *testing.T) {
 		}}
 	ctx := Context{"baseurl": "http://mybaseurl", "consent_id": "myconsentid", "authorisation_endpoint": "https://example.com/authorisation"}
 	tc := TestCase{Input: i, Context: ctx}
-	req, err := tc.Prepare(emptyContext)
+	runCtx := Context{}
+	req, err := tc.Prepare(&runCtx, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 
 	m, err := url.ParseQuery(req.URL)
 	require.NoError(t, err)
-	assert.Equal(t, m["request"][0], "eyJhbGciOiJub25lIn0.eyJhdWQiOiJodHRwOi8vbXliYXNldXJsIiwiY2xhaW1zIjp7ImlkX3Rva2VuIjp7Im9wZW5iYW5raW5nX2ludGVudF9pZCI6eyJlc3NlbnRpYWwiOnRydWUsInZhbHVlIjoibXljb25zZW50aWQifX19LCJpc3MiOiI4NjcyMzg0ZS05YTMzLTQzOWYtODkyNC02N2JiMTQzNDBkNzEiLCJyZWRpcmVjdF91cmkiOiJodHRwczovL3Rlc3QuZXhhbXBsZS5jby51ay9yZWRpciIsInNjb3BlIjoib3BlbmlkIGFjY291bnRzIn0.")
+	assertRequestObjectWithCodeChallenge(t, runCtx, m, "eyJhbGciOiJub25lIn0.eyJhdWQiOiJodHRwOi8vbXliYXNldXJsIiwiY2xhaW1zIjp7ImlkX3Rva2VuIjp7Im9wZW5iYW5raW5nX2ludGVudF9pZCI6eyJlc3NlbnRpYWwiOnRydWUsInZhbHVlIjoibXljb25zZW50aWQifX19LCJpc3MiOiI4NjcyMzg0ZS05YTMzLTQzOWYtODkyNC02N2JiMTQzNDBkNzEiLCJyZWRpcmVjdF91cmkiOiJodHRwczovL3Rlc3QuZXhhbXBsZS5jby51ay9yZWRpciIsInNjb3BlIjoib3BlbmlkIGFjY291bnRzIn0.")
+
+}
+
+// assertRequestObjectWithCodeChallenge - asserts the request object of query has the claims of
+// expected and the code challenge of the code verifier of ctx, as the query
+func assertRequestObjectWithCodeChallenge(t *testing.T, ctx Context, query url.Values, expected string) {
+	verifier, err := ctx.GetString("code_verifier")
+	require.NoError(t, err)
+	challenge := authentication.CodeChallengeS256(verifier)
+	assert.Equal(t, challenge, query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+
+	claims := jwt.MapClaims{}
+	_, _, err = new(jwt.Parser).ParseUnverified(query.Get("request"), claims)
+	require.NoError(t, err)
+	assert.Equal(t, challenge, claims["code_challenge"])
+	assert.Equal(t, "S256", claims["code_challenge_method"])
+	delete(claims, "code_challenge")
+	delete(claims, "code_challenge_method")
+
+	expectedClaims := jwt.MapClaims{}
+	_, _, err = new(jwt.Parser).ParseUnverified(expected, expectedClaims)
+	require.NoError(t, err)
+	assert.Equal(t, expectedClaims, claims)
+}
 
+func TestInputClaimsCodeVerifierPerConsent(t *testing.T) {
+	runCtx := Context{"authorisation_endpoint": "https://example.com/authorisation"}
+	challenges := map[string]string{}
+	for _, state := range []string{"Token001", "Token002"} {
+		tc := TestCase{Input: Input{Endpoint: "/accounts", Method: "GET",
+			Generation: map[string]string{"strategy": "consenturl"},
+			Claims:     map[string]string{"iss": "client", "responseType": "code", "state": state},
+		}}
+		req, err := tc.Prepare(&runCtx, testClient)
+		require.NoError(t, err)
+		consentURL, err := url.Parse(req.URL)
+		require.NoError(t, err)
+		challenges[state] = consentURL.Query().Get("code_challenge")
+	}
+
+	for state, challenge := range challenges {
+		verifier, err := runCtx.GetString(CodeVerifierKey(state))
+		require.NoError(t, err)
+		assert.Equal(t, authentication.CodeChallengeS256(verifier), challenge, state)
+	}
+	assert.NotEqual(t, challenges["Token001"], challenges["Token002"])
 }
 
 func TestInputClaimsConsentId(t *testing.T) {
@@ -241,7 +296,7 @@ func TestInputClaimsConsentId(t *testing.T) {
 			"responseType": "code",
 		}}
 	tc := TestCase{Input: i, Context: ctx}
-	res, err := i.CreateRequest(&tc, &ctx)
+	res, err := i.CreateRequest(&tc, &ctx, testClient)
 	assert.NoError(t, err, "create request should succeed")
 	assert.NotNil(t, res)
 }
@@ -280,7 +335,7 @@ func TestClaimsJWTBearer(t *testing.T) {
 		},
 	}
 	tc := TestCase{Input: i, Context: ctx}
-	res, err := i.CreateRequest(&tc, &ctx)
+	res, err := i.CreateRequest(&tc, &ctx, testClient)
 	require.NoError(t, err, "create request should succeed")
 	assert.NotNil(t, res)
 	jwtbearer, exists := ctx.Get("jwtbearer")
@@ -318,7 +373,7 @@ func TestBodyLiteral(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "This is my literal body"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.Nil(t, err)
 	assert.Equal(t, "This is my literal body", req.Body)
 }
@@ -331,7 +386,7 @@ func TestBodyReplacement(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "$replacebody"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.Nil(t, err)
 	assert.Equal(t, "this is my body", req.Body)
 }
@@ -345,7 +400,7 @@ func TestBodyTwoReplacements(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "$replacebody $replace2"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.Nil(t, err)
 	assert.Equal(t, "this is my body and this is my heart", req.Body)
 }
@@ -360,7 +415,7 @@ func TestPaymentBodyReplace(t *testing.T) {
 
 	i := Input{Method: "POST", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.Nil(t, err)
 	assert.Equal(t, "{\"Data\": {\"ConsentId\": \"sdp-1-b5bbdb18-eeb1-4c11-919d-9a237c8f1c7d\",\"Initiation\":{\"InstructionIdentification\":\"SIDP01\",\"EndToEndIdentification\":\"FRESCO.21302.GFX.20\",\"InstructedAmount\":{\"Amount\":\"15.00\",\"Currency\":\"GBP\"},\"CreditorAccount\":{\"SchemeName\":\"SortCodeAccountNumber\",\"Identification\":\"20000319470104\",\"Name\":\"Messers Simplex & Co\"}} },\"Risk\":{}}", req.Body)
 }
@@ -381,7 +436,7 @@ func TestPaymentBodyReplaceTestCase100300(t *testing.T) {
 	logrus.SetOutput(os.Stdout)
 	logrus.SetLevel(logrus.TraceLevel)
 	ctx.DumpContext("Testcase")
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 
 	assert.Nil(t, err)
 	_ = req
@@ -398,7 +453,7 @@ func TestJWSSignaturNotPOST(t *testing.T) {
 
 	i := Input{JwsSig: true, Method: "GET", Endpoint: "https://google.com", RequestBody: ""}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.EqualError(t, err, "createRequest: cannot apply jws signature to method that isn't POST")
 	assert.Nil(t, req)
 }
@@ -413,7 +468,7 @@ func TestJWSSignatureEmptyBody(t *testing.T) {
 
 	i := Input{JwsSig: true, Method: "POST", Endpoint: "https://google.com", RequestBody: ""}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.EqualError(t, err, "createRequest: cannot create x-jws-signature, as request body is empty")
 	assert.Nil(t, req)
 }
@@ -428,7 +483,7 @@ func TestJWSDetachedSignatureGET(t *testing.T) {
 
 	i := Input{JwsSig: true, Method: "GET", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
@@ -10,6 +10,7 @@ import (
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/resty.v1"
 )
 
 const simplejson = `{"name":{"first":"Janet","last":"Prichard"},"age":47}`
@@ -20,6 +21,7 @@ const simplejson = `{"name":{"first":"Janet","last":"Prichard"},"age":47}`
 // send response to match object
 // have match object parse response and extract json field into context parameter
 var emptyContext = &Context{}
+var testClient = resty.New()
 var emptyTestCase = &TestCase{}
 
 func TestContextPutFromMatch(t *testing.T) {
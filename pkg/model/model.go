@@ -4,14 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/lint"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
-	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
 
 	"github.com/sirupsen/logrus"
 
@@ -80,6 +80,8 @@ type TestCase struct {
 	Validator         schema.Validator `json:"-"` // Swagger schema validator
 	ValidateSignature bool             `json:"validateSignature,omitempty"`
 	StatusCode        string           `json:"statusCode,omitempty"`
+	Warnings          []string         `json:"-"` // Validation findings that don't fail the testcase
+	RuleViolations    []lint.Violation `json:"-"` // Response lint rules not respected
 }
 
 // MakeTestCase builds an empty testcase
@@ -97,9 +99,10 @@ func MakeTestCase() TestCase {
 // Prepare a Testcase for execution at and endpoint,
 // results in a standard http request that encapsulates the testcase request
 // as defined in the test case object with any context inputs/replacements etc applied
-func (t *TestCase) Prepare(ctx *Context) (*resty.Request, error) {
+// The request is sent by client, the HTTP client of the run
+func (t *TestCase) Prepare(ctx *Context, client *resty.Client) (*resty.Request, error) {
 	t.ApplyContext(ctx)
-	return t.ApplyInput(ctx)
+	return t.ApplyInput(ctx, client)
 }
 
 // Validate takes the http response that results as a consequence of sending the testcase http
@@ -122,6 +125,8 @@ func (t *TestCase) Validate(resp *resty.Response, ctx *Context) (bool, []error)
 		logrus.WithField("testcase", t.String()).Debug("Validate: resty.body is empty")
 	}
 	t.Header = resp.Header()
+	t.Warnings = nil
+	t.RuleViolations = nil
 	pass, errs := t.ApplyExpects(resp, ctx)
 
 	var failures []schema.Failure
@@ -142,6 +147,10 @@ func (t *TestCase) Validate(resp *resty.Response, ctx *Context) (bool, []error)
 			return false, []error{t.AppErr("Validate: " + err.Error())}
 		}
 		for _, failure := range failures {
+			if failure.Warning {
+				t.Warnings = append(t.Warnings, failure.Message)
+				continue
+			}
 			errs = append(errs, errors.New(failure.Message))
 		}
 	} else {
@@ -164,9 +173,23 @@ func (t *TestCase) Validate(resp *resty.Response, ctx *Context) (bool, []error)
 		}
 	}
 
-	// Gather fields within json response - for reporting
-	collector := schemaprops.GetPropertyCollector()
-	collector.CollectProperties(t.Input.Method, t.Input.Endpoint, t.Body, resp.StatusCode())
+	// Apply response lint rules for the spec version
+	if ruleSet, ok := lint.RuleSetFor(t.APIVersion); ok {
+		var requestURL string
+		if resp.Request != nil {
+			requestURL = resp.Request.URL
+		}
+		t.RuleViolations = ruleSet.Lint(lint.Response{
+			URL:        requestURL,
+			StatusCode: resp.StatusCode(),
+			Body:       t.Body,
+		})
+		for _, violation := range t.RuleViolations {
+			if violation.Severity == lint.SeverityError {
+				errs = append(errs, errors.New(violation.String()))
+			}
+		}
+	}
 
 	return pass, errs
 }
@@ -278,11 +301,11 @@ type Expect struct {
 //     Rule - receives http response from endpoint and provides it back to testcase
 //     Testcase evaluates the http response object using its 'Expects' clause
 //     Testcase passes or fails depending on the 'Expects' outcome
-func (t *TestCase) ApplyInput(rulectx *Context) (*resty.Request, error) {
+func (t *TestCase) ApplyInput(rulectx *Context, client *resty.Client) (*resty.Request, error) {
 	if t.Input.Method == "" {
 		return nil, t.AppErr("error: TestCase input cannot have empty input.Method")
 	}
-	req, err := t.Input.CreateRequest(t, rulectx)
+	req, err := t.Input.CreateRequest(t, rulectx, client)
 	if err != nil {
 		return nil, t.AppErr("createRequest: " + err.Error())
 	}
@@ -605,7 +628,7 @@ func (e *Expect) Clone() Expect {
 
 // LoadTestCaseFromJSONFile a single testcase from a json file
 func LoadTestCaseFromJSONFile(filename string) (TestCase, error) {
-	bytes, err := ioutil.ReadFile(filename)
+	bytes, err := assets.ReadFileOrPath(filename)
 	if err != nil {
 		return TestCase{}, err
 	}
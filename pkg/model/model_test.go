@@ -138,7 +138,7 @@ func TestChainedTestCases(t *testing.T) {
 	rulectx := Context{}      // create a context to hold the passed parameters
 	tc01 := rule.Tests[0][0]  // get the first testcase of the first rule
 	tc01.Validator = schema.NewNullValidator()
-	req, err := tc01.Prepare(&rulectx) // Prepare calls ApplyInput and ApplyContext on testcase
+	req, err := tc01.Prepare(&rulectx, testClient) // Prepare calls ApplyInput and ApplyContext on testcase
 	require.NoError(t, err)
 	resp, err := executor.ExecuteTestCase(req, &tc01, &rulectx) // send the request to be executed resulting in a response
 	assert.NoError(t, err)
@@ -148,7 +148,7 @@ func TestChainedTestCases(t *testing.T) {
 
 	tc02 := rule.Tests[0][1] // get the second testcase of the first rule
 	tc02.Validator = schema.NewNullValidator()
-	req, err = tc02.Prepare(&rulectx) // Prepare
+	req, err = tc02.Prepare(&rulectx, testClient) // Prepare
 	assert.NoError(t, err)
 	resp, err = executor.ExecuteTestCase(req, &tc02, &rulectx) // Execute
 	assert.NoError(t, err)
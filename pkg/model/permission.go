@@ -73,6 +73,16 @@ func (sp standardPermissions) permissionsForEndpoint(endpoint string) []permissi
 	return endpointPermissions
 }
 
+// EndpointPermissionCodes returns the standard permission codes giving access
+// to an endpoint, e.g.: `/accounts/{AccountId}/balances`
+func EndpointPermissionCodes(endpoint string) []Code {
+	codes := []Code{}
+	for _, p := range newStandardPermissions().permissionsForEndpoint(endpoint) {
+		codes = append(codes, p.Code)
+	}
+	return codes
+}
+
 // staticApiPermission is the standard for OB permission
 // accesses to account endpoints
 var staticApiPermissions = []permission{
@@ -173,6 +173,11 @@ func TestPermissionsForEndpointMultipleEndpointsURL(t *testing.T) {
 	assert.Equal(t, Code("b"), search[1].Code)
 }
 
+func TestEndpointPermissionCodes(t *testing.T) {
+	assert.Equal(t, []Code{"ReadAccountsBasic", "ReadBalances"}, EndpointPermissionCodes("/accounts/{AccountId}/balances"))
+	assert.Empty(t, EndpointPermissionCodes("/domestic-payments"))
+}
+
 func TestStaticPermissionsHaveNotChanged(t *testing.T) {
 	expected, err := json.MarshalIndent(staticApiPermissions, "", "    ")
 	require.NoError(t, err)
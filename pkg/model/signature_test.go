@@ -52,7 +52,7 @@ func TestSimpleb64falseSignature(t *testing.T) {
 	_ = pubKey
 	i := Input{JwsSig: true, Method: "POST", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.Nil(t, err)
 	sig := req.Header.Get("x-jws-signature")
 	assert.NotEmpty(t, sig)
@@ -69,7 +69,7 @@ func TestSimpleb64trueSignature(t *testing.T) {
 	_ = pubKey
 	i := Input{JwsSig: true, Method: "POST", Endpoint: "https://google.com", RequestBody: "$domestic_payment_template"}
 	tc := TestCase{Input: i}
-	req, err := tc.Prepare(&ctx)
+	req, err := tc.Prepare(&ctx, testClient)
 	assert.Nil(t, err)
 	sig := req.Header.Get("x-jws-signature")
 	assert.NotEmpty(t, sig)
@@ -138,7 +138,7 @@ func GetPem(url string) (*x509.Certificate, error) {
 
 }
 
-func validateSignatureTest(token, body string, signingMethod jwt.SigningMethod, pubKey *rsa.PublicKey) (bool, error) {
+func validateSignatureTest(token, body string, signingMethod jwt.SigningMethod, pubKey crypto.PublicKey) (bool, error) {
 	segments := strings.Split(token, ".")
 	segments[1] = body
 	err := signingMethod.Verify(strings.Join(segments[:2], "."), segments[2], pubKey)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"testing"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/lint"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
@@ -201,7 +202,7 @@ func TestMockedTestCase(t *testing.T) {
 	err := json.Unmarshal(basicTestCase, &testcase)
 	assert.NoError(t, err)
 
-	req, err := testcase.Prepare(&Context{})
+	req, err := testcase.Prepare(&Context{}, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 
@@ -218,7 +219,7 @@ func TestMockedTestCaseExpectOneOfSucceeds(t *testing.T) {
 	err := json.Unmarshal(expectOneOfTestCase, &testcase)
 	assert.NoError(t, err)
 
-	req, err := testcase.Prepare(&Context{})
+	req, err := testcase.Prepare(&Context{}, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 
@@ -235,7 +236,7 @@ func TestMockedTestCaseExpectOneOfFails(t *testing.T) {
 	err := json.Unmarshal(expectOneOfTestCase, &testcase)
 	assert.NoError(t, err)
 
-	req, err := testcase.Prepare(&Context{})
+	req, err := testcase.Prepare(&Context{}, testClient)
 	assert.Nil(t, err)
 	assert.NotNil(t, req)
 
@@ -278,7 +279,31 @@ func TestJsonExpectMatch(t *testing.T) {
 
 func TestApplyInputNoGetMethod(t *testing.T) {
 	tc := TestCase{}
-	req, err := tc.Prepare(emptyContext)
+	req, err := tc.Prepare(emptyContext, testClient)
 	assert.NotNil(t, err)
 	assert.Nil(t, req)
 }
+
+// Check that response lint rules for the testcase api version are applied
+func TestValidateAppliesLintRules(t *testing.T) {
+	var testcase TestCase // get the testcase
+	err := json.Unmarshal(jsonTestCase, &testcase)
+	assert.NoError(t, err)
+	testcase.Validator = schema.NewNullValidator()
+	testcase.APIVersion = "v3.1.5"
+
+	res := test.CreateHTTPResponse(200, "OK", `{"Data": {"Account": [{"AccountId": "500000000000000000000001"}]}, "Links": {"Self": "/accounts"}}`)
+
+	result, errs := testcase.Validate(res, emptyContext)
+	assert.Equal(t, result, true)
+	assert.Len(t, errs, 0)
+	assert.Len(t, testcase.RuleViolations, 2)
+	assert.Equal(t, "OBLINT002", testcase.RuleViolations[0].RuleID)
+
+	lint.EnableErrorRules("OBLINT003")
+	defer lint.EnableErrorRules()
+	result, errs = testcase.Validate(res, emptyContext)
+	assert.Equal(t, result, true)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, "OBLINT003 (error): Meta is required: successful responses must include Meta", errs[0].Error())
+}
@@ -0,0 +1,185 @@
+package report
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
+)
+
+// Coverage - response fields returned by the ASPSP during a run, per
+// endpoint, joined with the fields declared in the swagger schema.
+type Coverage struct {
+	APIs []APICoverage `json:"apis"`
+}
+
+// APICoverage - coverage of the endpoints of an API specification.
+type APICoverage struct {
+	Name      string             `json:"name"`
+	Version   string             `json:"version"`
+	Endpoints []EndpointCoverage `json:"endpoints"`
+}
+
+// EndpointCoverage - coverage of an endpoint response.
+// Code is empty for endpoints with conditional properties that were never called.
+type EndpointCoverage struct {
+	Method             string   `json:"method"`
+	Path               string   `json:"path"`
+	Code               string   `json:"code"`
+	MandatorySeen      []string `json:"mandatorySeen"`      // Mandatory schema fields returned
+	OptionalSeen       []string `json:"optionalSeen"`       // Optional schema fields returned
+	OptionalNotSeen    []string `json:"optionalNotSeen"`    // Optional schema fields never returned
+	ConditionalNotSeen []string `json:"conditionalNotSeen"` // Conditional properties from the discovery model never returned
+}
+
+// responseFields - format of the JSON output of `schemaprops.PropertyCollector`.
+type responseFields struct {
+	ResponseFields []schemaprops.PropertyOutput `json:"responseFields"`
+}
+
+// NewCoverage - create `Coverage` from the JSON output of the property collector, see
+// `schemaprops.PropertyCollector.OutputJSON`, and the conditional properties of `disco`.
+func NewCoverage(responseFieldsJSON string, disco discovery.Model) (Coverage, error) {
+	coverage := Coverage{APIs: []APICoverage{}}
+
+	fields := responseFields{}
+	if strings.TrimSpace(responseFieldsJSON) != "" {
+		if err := json.Unmarshal([]byte(responseFieldsJSON), &fields); err != nil {
+			return Coverage{}, errors.Wrap(err, "report.NewCoverage: json.Unmarshal failed, could not parse response fields")
+		}
+	}
+
+	for _, api := range fields.ResponseFields {
+		apiCoverage := APICoverage{
+			Name:      api.Api,
+			Version:   api.Version,
+			Endpoints: []EndpointCoverage{},
+		}
+		conditional := conditionalProperties(disco, api.Api, api.Version)
+
+		for _, endpoint := range api.Endpoints {
+			key := endpoint.Method + " " + endpoint.Path
+			for _, response := range endpoint.Responses {
+				endpointCoverage, err := newEndpointCoverage(api, endpoint, response, conditional[key])
+				if err != nil {
+					logrus.WithError(err).WithFields(logrus.Fields{
+						"api":      api.Api,
+						"endpoint": key,
+						"code":     response.Code,
+					}).Trace("report.NewCoverage: no schema for response")
+					continue
+				}
+				apiCoverage.Endpoints = append(apiCoverage.Endpoints, endpointCoverage)
+			}
+			delete(conditional, key)
+		}
+
+		// conditional properties of endpoints that were never called were never returned
+		for _, key := range sortedKeys(conditional) {
+			method, path := splitEndpoint(key)
+			apiCoverage.Endpoints = append(apiCoverage.Endpoints, EndpointCoverage{
+				Method:             method,
+				Path:               path,
+				MandatorySeen:      []string{},
+				OptionalSeen:       []string{},
+				OptionalNotSeen:    []string{},
+				ConditionalNotSeen: conditional[key],
+			})
+		}
+
+		coverage.APIs = append(coverage.APIs, apiCoverage)
+	}
+
+	return coverage, nil
+}
+
+func newEndpointCoverage(api schemaprops.PropertyOutput, endpoint schemaprops.Endpoint, response schemaprops.Response, conditional []string) (EndpointCoverage, error) {
+	statusCode, err := strconv.Atoi(response.Code)
+	if err != nil {
+		return EndpointCoverage{}, err
+	}
+	schemaFields, err := schema.ResponseFields(api.Api, api.Version, endpoint.Method, endpoint.Path, statusCode)
+	if err != nil {
+		return EndpointCoverage{}, err
+	}
+
+	seen := map[string]bool{}
+	for _, field := range response.Fields {
+		seen[field] = true
+	}
+
+	coverage := EndpointCoverage{
+		Method:             endpoint.Method,
+		Path:               endpoint.Path,
+		Code:               response.Code,
+		MandatorySeen:      []string{},
+		OptionalSeen:       []string{},
+		OptionalNotSeen:    []string{},
+		ConditionalNotSeen: []string{},
+	}
+	for _, field := range schemaFields {
+		switch {
+		case field.Mandatory && seen[field.Path]:
+			coverage.MandatorySeen = append(coverage.MandatorySeen, field.Path)
+		case !field.Mandatory && seen[field.Path]:
+			coverage.OptionalSeen = append(coverage.OptionalSeen, field.Path)
+		case !field.Mandatory:
+			coverage.OptionalNotSeen = append(coverage.OptionalNotSeen, field.Path)
+		}
+	}
+	for _, field := range conditional {
+		if !seen[field] {
+			coverage.ConditionalNotSeen = append(coverage.ConditionalNotSeen, field)
+		}
+	}
+
+	return coverage, nil
+}
+
+// conditionalProperties - paths of the conditional properties of an API in
+// the discovery model, keyed by "METHOD /path", e.g.: "GET /accounts"
+// Array wildcards are removed to match collected fields, e.g.:
+// "Data.Transaction.*.Balance" is "Data.Transaction.Balance"
+func conditionalProperties(disco discovery.Model, name, version string) map[string][]string {
+	properties := map[string][]string{}
+	for _, item := range disco.DiscoveryModel.DiscoveryItems {
+		if item.APISpecification.Name != name || item.APISpecification.Version != version {
+			continue
+		}
+		for _, endpoint := range item.Endpoints {
+			for _, property := range endpoint.ConditionalProperties {
+				if property.Request {
+					continue
+				}
+				key := endpoint.Method + " " + endpoint.Path
+				path := strings.Replace(property.Path, ".*", "", -1)
+				properties[key] = append(properties[key], path)
+			}
+		}
+	}
+	return properties
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func splitEndpoint(key string) (string, string) {
+	split := strings.SplitN(key, " ", 2)
+	if len(split) != 2 {
+		return "", key
+	}
+	return split[0], split[1]
+}
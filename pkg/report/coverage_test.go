@@ -0,0 +1,126 @@
+package report
+
+import (
+	"testing"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+)
+
+func TestNewCoverage(t *testing.T) {
+	require := test.NewRequire(t)
+
+	disco := discovery.Model{
+		DiscoveryModel: discovery.ModelDiscovery{
+			DiscoveryItems: []discovery.ModelDiscoveryItem{
+				{
+					APISpecification: discovery.ModelAPISpecification{
+						Name:    "Account and Transaction API Specification",
+						Version: "v3.1.5",
+					},
+					Endpoints: []discovery.ModelEndpoint{
+						{
+							Method: "GET",
+							Path:   "/accounts/{AccountId}/balances",
+							ConditionalProperties: []discovery.ConditionalProperty{
+								{Schema: "OBReadBalance1", Name: "CreditLine", Path: "Data.Balance.*.CreditLine"},
+								{Schema: "OBReadBalance1", Name: "Links", Path: "Links"},
+							},
+						},
+						{
+							Method: "GET",
+							Path:   "/accounts/{AccountId}/transactions",
+							ConditionalProperties: []discovery.ConditionalProperty{
+								{Schema: "OBTransaction6", Name: "Balance", Path: "Data.Transaction.*.Balance"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	coverage, err := NewCoverage(balancesResponseFields, disco)
+	require.NoError(err)
+
+	require.Len(coverage.APIs, 1)
+	api := coverage.APIs[0]
+	require.Equal("Account and Transaction API Specification", api.Name)
+	require.Equal("v3.1.5", api.Version)
+	require.Len(api.Endpoints, 2)
+
+	balances := api.Endpoints[0]
+	require.Equal("GET", balances.Method)
+	require.Equal("/accounts/{AccountId}/balances", balances.Path)
+	require.Equal("200", balances.Code)
+	require.Equal([]string{
+		"Data",
+		"Data.Balance",
+		"Data.Balance.AccountId",
+		"Data.Balance.Amount",
+		"Data.Balance.Amount.Amount",
+		"Data.Balance.Amount.Currency",
+		"Data.Balance.CreditDebitIndicator",
+		"Data.Balance.DateTime",
+		"Data.Balance.Type",
+	}, balances.MandatorySeen)
+	require.Equal([]string{"Links", "Links.Self"}, balances.OptionalSeen)
+	require.Contains(balances.OptionalNotSeen, "Data.Balance.CreditLine")
+	require.Contains(balances.OptionalNotSeen, "Meta")
+	require.NotContains(balances.OptionalNotSeen, "Links.Self")
+	require.Equal([]string{"Data.Balance.CreditLine"}, balances.ConditionalNotSeen)
+
+	// endpoints with conditional properties that were not called
+	require.Equal(EndpointCoverage{
+		Method:             "GET",
+		Path:               "/accounts/{AccountId}/transactions",
+		MandatorySeen:      []string{},
+		OptionalSeen:       []string{},
+		OptionalNotSeen:    []string{},
+		ConditionalNotSeen: []string{"Data.Transaction.Balance"},
+	}, api.Endpoints[1])
+}
+
+func TestNewCoverage_Empty(t *testing.T) {
+	require := test.NewRequire(t)
+
+	coverage, err := NewCoverage("", discovery.Model{})
+	require.NoError(err)
+	require.Equal(Coverage{APIs: []APICoverage{}}, coverage)
+
+	_, err = NewCoverage("{", discovery.Model{})
+	require.EqualError(err, "report.NewCoverage: json.Unmarshal failed, could not parse response fields: unexpected end of JSON input")
+}
+
+const balancesResponseFields = `{
+	"responseFields": [
+		{
+			"api": "Account and Transaction API Specification",
+			"version": "v3.1.5",
+			"endpoints": [
+				{
+					"method": "GET",
+					"path": "/accounts/{AccountId}/balances",
+					"responses": [
+						{
+							"code": "200",
+							"fields": [
+								"Data",
+								"Data.Balance",
+								"Data.Balance.AccountId",
+								"Data.Balance.Amount",
+								"Data.Balance.Amount.Amount",
+								"Data.Balance.Amount.Currency",
+								"Data.Balance.CreditDebitIndicator",
+								"Data.Balance.DateTime",
+								"Data.Balance.Type",
+								"Links",
+								"Links.Self"
+							]
+						}
+					]
+				}
+			]
+		}
+	]
+}`
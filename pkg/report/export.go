@@ -4,11 +4,11 @@ import (
 	"archive/zip"
 	"encoding/json"
 	"io"
-	"io/ioutil"
-	"os"
 	"path/filepath"
 	"strings"
 
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+
 	"github.com/pkg/errors"
 )
 
@@ -18,6 +18,8 @@ const (
 	reportFilename         = "report.json"
 	discoveryFilename      = "discovery.json"
 	responseFieldsFilename = "responseFields.json"
+	coverageFilename       = "coverage.json"
+	exchangesFilename      = "exchanges.har"
 )
 
 // Exporter - allows the exporting of a `Report`.
@@ -98,6 +100,34 @@ func (e *zipExporter) create(zipWriter *zip.Writer, reportJSON, discoveryJSON []
 		return errors.Wrapf(err, "zipExporter.Export: zip.Writer.Write failed, could write to %q, responseFields=%+v", responseFieldsFile, e.report.ResponseFields)
 	}
 
+	coverageJSON, err := json.MarshalIndent(e.report.Coverage, marshalIndentPrefix, marshalIndent)
+	if err != nil {
+		return errors.Wrapf(err, "zipExporter.Export: json.MarshalIndent failed, coverage=%+v", e.report.Coverage)
+	}
+
+	coverageFile, err := zipWriter.Create(coverageFilename)
+	if err != nil {
+		return errors.Wrapf(err, "zipExporter.Export: zip.Writer.Create failed, could not create file %q", coverageFilename)
+	}
+
+	if _, err := coverageFile.Write(coverageJSON); err != nil {
+		return errors.Wrapf(err, "zipExporter.Export: zip.Writer.Write failed, could write to %q, coverageJSON=%+v", coverageFilename, string(coverageJSON))
+	}
+
+	exchangesJSON, err := json.MarshalIndent(e.report.Exchanges, marshalIndentPrefix, marshalIndent)
+	if err != nil {
+		return errors.Wrap(err, "zipExporter.Export: json.MarshalIndent failed, could not marshal exchanges")
+	}
+
+	exchangesFile, err := zipWriter.Create(exchangesFilename)
+	if err != nil {
+		return errors.Wrapf(err, "zipExporter.Export: zip.Writer.Create failed, could not create file %q", exchangesFilename)
+	}
+
+	if _, err := exchangesFile.Write(exchangesJSON); err != nil {
+		return errors.Wrapf(err, "zipExporter.Export: zip.Writer.Write failed, could write to %q", exchangesFilename)
+	}
+
 	for _, manifest := range e.report.Discovery.DiscoveryModel.DiscoveryItems {
 		_, filename := filepath.Split(manifest.APISpecification.Manifest)
 
@@ -108,13 +138,8 @@ func (e *zipExporter) create(zipWriter *zip.Writer, reportJSON, discoveryJSON []
 		}
 
 		path := strings.TrimPrefix(manifest.APISpecification.Manifest, "file://")
-		fileContents, err := ioutil.ReadFile(path)
-		if err != nil && os.IsNotExist(err) {
-			fileContents, err = ioutil.ReadFile("../../" + path)
-			if err != nil {
-				return errors.Wrapf(err, "zipExporter.Export: zip.Writer.Write failed, could open manifest file %s", filename)
-			}
-		} else if err != nil {
+		fileContents, err := assets.ReadFileOrPath(path)
+		if err != nil {
 			return errors.Wrapf(err, "zipExporter.Export: zip.Writer.Write failed, could open manifest file %s", filename)
 		}
 
@@ -8,6 +8,7 @@ import (
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/server/models"
 	internal_time "bitbucket.org/openbankingteam/conformance-suite/pkg/time"
 	validation "github.com/go-ozzo/ozzo-validation"
@@ -27,16 +28,20 @@ type Report struct {
 	Created          string             `json:"created"`                  // Date and time when the report was created, formatted accorrding to RFC3339 (https://tools.ietf.org/html/rfc3339). Note RFC3339 is derived from ISO 8601 (https://en.wikipedia.org/wiki/ISO_8601).
 	Expiration       *string            `json:"expiration,omitempty"`     // Date and time when the report should not longer be accepted, formatted accorrding to RFC3339 (https://tools.ietf.org/html/rfc3339). Note RFC3339 is derived from ISO 8601 (https://en.wikipedia.org/wiki/ISO_8601).
 	Fails            int                `json:"fails"`                    // Calculates *total* failures across the whole report, accumulated for each specification.
+	Warnings         int                `json:"warnings"`                 // Calculates *total* warnings across the whole report, e.g., undeclared properties found in strict schema mode.
 	Version          string             `json:"version"`                  // The current version of the report model used.
 	Status           Status             `json:"status"`                   // A status describing overall condition of the report.
 	CertifiedBy      CertifiedBy        `json:"certifiedBy"`              // The certifier of the report.
 	SignatureChain   *[]SignatureChain  `json:"signatureChain,omitempty"` // When Add digital signature is set this contains the signature chain.
 	Discovery        discovery.Model    `json:"-"`                        // Original used discovery model
 	ResponseFields   string             `json:"-"`                        // ResponseFields - already in JSON format
+	Coverage         Coverage           `json:"-"`                        // Coverage - response fields seen joined with the swagger schema
+	Exchanges        har.HAR            `json:"-"`                        // Exchanges - HTTP requests and responses of the run, secrets redacted
 	APISpecification []APISpecification `json:"apiSpecification"`         // API and version tested, along with test cases
 	FCSVersion       string             `json:"fcsVersion"`               // Version of FCS running the tests
 	Products         []string           `json:"products"`                 // Products tested, e.g., "Business, Personal, Cards"
 	JWSStatus        string             `json:"jwsStatus"`                // Signature status
+	AuthMethod       string             `json:"tokenEndpointAuthMethod"`  // Client authentication at the token endpoint, e.g., "private_key_jwt"
 }
 
 type APISpecification struct {
@@ -81,7 +86,18 @@ func NewReport(exportResults models.ExportResults, environment string) (Report,
 	}
 	signatureChain := []SignatureChain{}
 
+	coverage, err := NewCoverage(exportResults.ResponseFields, exportResults.DiscoveryModel)
+	if err != nil {
+		return Report{}, err
+	}
+
+	exchanges := exportResults.Exchanges
+	if exchanges.Log.Entries == nil {
+		exchanges = har.HAR{Log: har.NewLog()}
+	}
+
 	fails := GetFails(exportResults.Results)
+	warnings := GetWarnings(exportResults.Results)
 	apiSpecs := []APISpecification{}
 	for k, results := range exportResults.Results {
 		tlsVersionResult := exportResults.TLSVersionResult[strings.ReplaceAll(k.APIName, " ", "-")]
@@ -103,16 +119,20 @@ func NewReport(exportResults models.ExportResults, environment string) (Report,
 		Created:          created,
 		Expiration:       &expiration,
 		Fails:            fails,
+		Warnings:         warnings,
 		Version:          Version,
 		Status:           StatusComplete,
 		CertifiedBy:      certifiedBy,
 		SignatureChain:   &signatureChain,
 		Discovery:        exportResults.DiscoveryModel,
 		ResponseFields:   exportResults.ResponseFields,
+		Coverage:         coverage,
+		Exchanges:        exchanges,
 		APISpecification: apiSpecs,
 		FCSVersion:       version.FullVersion,
 		Products:         exportResults.ExportRequest.Products,
 		JWSStatus:        exportResults.JWSStatus,
+		AuthMethod:       exportResults.AuthMethod,
 	}, nil
 }
 
@@ -128,3 +148,14 @@ func GetFails(specs map[results.ResultKey][]results.TestCase) int {
 	}
 	return fails
 }
+
+// GetWarnings - warnings is the total number of warnings reported across all specification tests.
+func GetWarnings(specs map[results.ResultKey][]results.TestCase) int {
+	var warnings int
+	for _, results := range specs {
+		for _, result := range results {
+			warnings += len(result.Warnings)
+		}
+	}
+	return warnings
+}
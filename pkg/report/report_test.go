@@ -265,6 +265,22 @@ func TestReport_GetFails_Fails_Four(t *testing.T) {
 	require.Equal(expected, actual)
 }
 
+func TestReport_GetWarnings(t *testing.T) {
+	require := test.NewRequire(t)
+
+	specs := stubResults(true, true, true)
+	spec1 := results.ResultKey{
+		APIVersion: "APIVersion1",
+		APIName:    "APIName1",
+	}
+	specs[spec1][0].Warnings = []string{".Data.Extra in body is not declared in the schema"}
+	specs[spec1][1].Warnings = []string{".Data.One in body is not declared in the schema", ".Data.Two in body is not declared in the schema"}
+	expected := 3
+	actual := GetWarnings(specs)
+
+	require.Equal(expected, actual)
+}
+
 func TestNewReport(t *testing.T) {
 	t.Parallel()
 	// TODO: add test cases once functionality is read. Intentionally skipping test for now.
@@ -301,6 +317,16 @@ func TestNewReport(t *testing.T) {
 	}
 }
 
+func TestNewReport_AuthMethod(t *testing.T) {
+	exportResults := stubExportResults()
+	exportResults.AuthMethod = "client_secret_jwt"
+
+	report, err := NewReport(exportResults, "Testing")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "client_secret_jwt", report.AuthMethod)
+}
+
 func stubResults(pass1, pass2, pass3 bool) map[results.ResultKey][]results.TestCase {
 	specs := map[results.ResultKey][]results.TestCase{}
 
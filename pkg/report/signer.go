@@ -1,11 +1,13 @@
 package report
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/sha256"
 	"encoding/hex"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 )
 
 /*
@@ -47,8 +49,9 @@ func (sc reportClaims) Valid() error {
 // - ReportDigest
 // - DiscoveryDigest
 // - ManifestDigest
-func sign(claims reportClaims, meta map[string]string, privateKey *rsa.PrivateKey) (string, error) {
-	t := jwt.NewWithClaims(jwt.SigningMethodPS256, claims)
+// Reports are signed with PS256 by RSA keys, ES256 by EC P-256 keys, in memory or external
+func sign(claims reportClaims, meta map[string]string, privateKey crypto.Signer) (string, error) {
+	t := jwt.NewWithClaims(authentication.SigningAlgForKey(privateKey.Public()), claims)
 
 	for k, v := range meta {
 		t.Header[k] = v
@@ -62,7 +65,7 @@ func sign(claims reportClaims, meta map[string]string, privateKey *rsa.PrivateKe
 	return signed, nil
 }
 
-func verifySignature(rawJwt string, publicKey *rsa.PublicKey, claims reportClaims) error {
+func verifySignature(rawJwt string, publicKey crypto.PublicKey, claims reportClaims) error {
 	keyFunc := func(*jwt.Token) (interface{}, error) {
 		return publicKey, nil
 	}
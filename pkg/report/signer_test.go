@@ -1,6 +1,9 @@
 package report
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -173,6 +176,25 @@ func TestReportVerificationFailure(t *testing.T) {
 	}
 }
 
+func TestReportSignatureES256(t *testing.T) {
+	require := test.NewRequire(t)
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err, "generate EC P-256 key")
+	claims := reportClaims{
+		ReportDigest:    "report-hash-sum",
+		DiscoveryDigest: "discovery-hash-sum",
+		ManifestDigest:  "manifest-hash-sum",
+	}
+
+	signed, err := sign(claims, map[string]string{}, privateKey)
+	require.NoError(err, "Sign error")
+	tk, _, err := new(jwt.Parser).ParseUnverified(signed, &reportClaims{})
+	require.NoError(err, "jwt.ParseUnverified")
+	require.Equal("ES256", tk.Header["alg"])
+	require.NoError(verifySignature(signed, &privateKey.PublicKey, claims), "verify error")
+}
+
 func TestDigestCalculation(t *testing.T) {
 	input := "foo-bar"
 	hexSHA256 := "7d89c4f517e3bd4b5e8e76687937005b602ea00c5cba3e25ef1fc6575a55103e"
@@ -5,15 +5,20 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 
+	"github.com/go-openapi/spec"
 	"github.com/go-openapi/strfmt"
 	"github.com/go-openapi/validate"
 )
 
 // bodyValidator implements a schema body validator
 // validates body schema using swagger spec document
+// In strict mode properties not declared in the schema are also reported,
+// as warnings, when the schema doesn't explicitly allow additional properties
 type bodyValidator struct {
 	finder finder
+	strict bool
 }
 
 func newBodyValidator(finder finder) Validator {
@@ -22,6 +27,13 @@ func newBodyValidator(finder finder) Validator {
 	}
 }
 
+func newStrictBodyValidator(finder finder) Validator {
+	return bodyValidator{
+		finder: finder,
+		strict: true,
+	}
+}
+
 func (v bodyValidator) Validate(r Response) ([]Failure, error) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
@@ -57,11 +69,77 @@ func (v bodyValidator) validate(r Response, body []byte) ([]Failure, error) {
 	// swagger API call
 	val := validate.NewSchemaValidator(response.Schema, v.finder.doc, "", strfmt.Default)
 	result := val.Validate(data)
+
+	var failures []Failure
 	if result.HasErrors() {
-		return mapToFailures(result), nil
+		failures = mapToFailures(result)
+	}
+
+	if v.strict && response.Schema != nil {
+		for _, property := range undeclaredProperties(response.Schema, data, "") {
+			message := fmt.Sprintf("%s in body is not declared in the schema", property)
+			failures = append(failures, newWarning(message))
+		}
 	}
 
-	return nil, nil
+	return failures, nil
+}
+
+// undeclaredProperties walks data alongside its schema and returns the path
+// of every property that is not declared by the schema, ignoring objects
+// that explicitly state whether additional properties are allowed
+func undeclaredProperties(sc *spec.Schema, data interface{}, path string) []string {
+	undeclared := []string{}
+	switch value := data.(type) {
+	case map[string]interface{}:
+		properties := declaredProperties(sc)
+		implicit := sc.AdditionalProperties == nil
+		for _, subSchema := range sc.AllOf {
+			implicit = implicit && subSchema.AdditionalProperties == nil
+		}
+
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			propertyPath := path + "." + key
+			propertySchema, ok := properties[key]
+			if !ok {
+				if implicit {
+					undeclared = append(undeclared, propertyPath)
+				}
+				continue
+			}
+			undeclared = append(undeclared, undeclaredProperties(&propertySchema, value[key], propertyPath)...)
+		}
+	case []interface{}:
+		if sc.Items == nil || sc.Items.Schema == nil {
+			return undeclared
+		}
+		for i, item := range value {
+			itemPath := fmt.Sprintf("%s.%d", path, i)
+			undeclared = append(undeclared, undeclaredProperties(sc.Items.Schema, item, itemPath)...)
+		}
+	}
+	return undeclared
+}
+
+// declaredProperties returns the properties of a schema including those
+// composed with allOf
+func declaredProperties(sc *spec.Schema) map[string]spec.Schema {
+	properties := map[string]spec.Schema{}
+	for key, property := range sc.Properties {
+		properties[key] = property
+	}
+	for _, subSchema := range sc.AllOf {
+		for key, property := range declaredProperties(&subSchema) {
+			properties[key] = property
+		}
+	}
+	return properties
 }
 
 // mapToFailures maps between swagger error and this package Failure object
@@ -82,3 +82,74 @@ func TestBodyValidator_Validate_ReturnsFailures(t *testing.T) {
 	}
 	assert.Equal(t, expected, failures)
 }
+
+func TestBodyValidator_Validate_StrictReportsUndeclaredProperties(t *testing.T) {
+	doc, err := loads.Spec("spec/v3.1.5/account-info-swagger-flattened.json")
+	require.NoError(t, err)
+	f := newFinder(doc)
+	validator := newStrictBodyValidator(f)
+	body := strings.NewReader(getAccountsResponseUndeclared)
+	r := Response{
+		Method:     "GET",
+		Path:       "/accounts",
+		StatusCode: http.StatusOK,
+		Body:       body,
+	}
+
+	failures, err := validator.Validate(r)
+
+	require.NoError(t, err)
+	expected := []Failure{
+		{Message: ".Data.Account.0.Account.0.SchemaName in body is not declared in the schema", Warning: true},
+		{Message: ".Data.Extra in body is not declared in the schema", Warning: true},
+	}
+	assert.Equal(t, expected, failures)
+}
+
+func TestBodyValidator_Validate_NonStrictIgnoresUndeclaredProperties(t *testing.T) {
+	doc, err := loads.Spec("spec/v3.1.5/account-info-swagger-flattened.json")
+	require.NoError(t, err)
+	f := newFinder(doc)
+	validator := newBodyValidator(f)
+	body := strings.NewReader(getAccountsResponseUndeclared)
+	r := Response{
+		Method:     "GET",
+		Path:       "/accounts",
+		StatusCode: http.StatusOK,
+		Body:       body,
+	}
+
+	failures, err := validator.Validate(r)
+
+	require.NoError(t, err)
+	assert.Len(t, failures, 0)
+}
+
+const getAccountsResponseUndeclared = `
+		{
+			"Data": {
+				"Account": [
+					{
+						"AccountId": "500000000000000000000001",
+						"Currency": "GBP",
+						"AccountType": "Personal",
+						"AccountSubType": "CurrentAccount",
+						"Account": [
+						{
+							"SchemeName": "UK.OBIE.SortCodeAccountNumber",
+							"SchemaName": "UK.OBIE.SortCodeAccountNumber",
+							"Identification": "10000119820101"
+						}
+						]
+					}
+				],
+				"Extra": "not in the spec"
+			},
+			"Links": {
+				"Self": "http://modelobank2018.o3bank.co.uk/open-banking/v3.1/aisp/accounts"
+			},
+			"Meta": {
+				"TotalPages": 1
+			}
+		}
+	`
@@ -0,0 +1,144 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+)
+
+// namespaceOBIE is the namespace reserved for codes published by Open Banking,
+// codes from any other namespace are proprietary to the ASPSP
+const namespaceOBIE = "UK.OBIE."
+
+// CodeLists holds the OB external code lists bundled for a spec version
+// The code lists are maintained from the OB code-list spreadsheets, as
+// they are not part of the swagger enums
+type CodeLists struct {
+	Version   string     `json:"version"`
+	CodeLists []CodeList `json:"codeLists"`
+}
+
+// CodeList is an external code list, e.g.: OBExternalAccountIdentification4Code
+// Fields are the property names the list applies to, qualified by the name
+// of their parent object, e.g.: "DebtorAccount.SchemeName"
+// Namespaced code lists accept any value outside the UK.OBIE namespace
+type CodeList struct {
+	Name       string   `json:"name"`
+	Fields     []string `json:"fields"`
+	Namespaced bool     `json:"namespaced,omitempty"`
+	Codes      []string `json:"codes"`
+}
+
+// contains checks if code is allowed by this code list
+func (c CodeList) contains(code string) bool {
+	if c.Namespaced && !strings.HasPrefix(code, namespaceOBIE) {
+		return true
+	}
+	for _, allowed := range c.Codes {
+		if allowed == code {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadCodeLists reads the bundled code lists for a spec version,
+// code lists are shared by all patch versions, e.g.: v3.1.5 uses v3.1.json
+func LoadCodeLists(version string) (CodeLists, error) {
+	sver, err := semver.ParseTolerant(version)
+	if err != nil {
+		return CodeLists{}, errors.Wrapf(err, "schema: parsing code lists version %q", version)
+	}
+	filename := fmt.Sprintf("pkg/schema/codelists/v%d.%d.json", sver.Major, sver.Minor)
+
+	content, err := assets.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return CodeLists{}, fmt.Errorf("schema: could not find code lists for version %s", version)
+	} else if err != nil {
+		return CodeLists{}, errors.Wrapf(err, "schema: reading code lists, filename=%q", filename)
+	}
+
+	codeLists := CodeLists{}
+	if err := json.Unmarshal(content, &codeLists); err != nil {
+		return CodeLists{}, errors.Wrapf(err, "schema: parsing code lists, filename=%q", filename)
+	}
+	return codeLists, nil
+}
+
+// codeListValidator implements a validator that checks fields holding
+// external codes against the bundled code lists
+type codeListValidator struct {
+	fields map[string]CodeList
+}
+
+func newCodeListValidator(codeLists CodeLists) Validator {
+	fields := map[string]CodeList{}
+	for _, codeList := range codeLists.CodeLists {
+		for _, field := range codeList.Fields {
+			fields[field] = codeList
+		}
+	}
+	return codeListValidator{
+		fields: fields,
+	}
+}
+
+func (v codeListValidator) Validate(r Response) ([]Failure, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		// unparseable bodies are reported by the body validator
+		return nil, nil
+	}
+
+	return v.validate(data, "", ""), nil
+}
+
+// validate walks data reporting codes not found in the code list of their field,
+// parent is the name of the object property data was found in
+func (v codeListValidator) validate(data interface{}, path, parent string) []Failure {
+	failures := []Failure{}
+	switch value := data.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			propertyPath := path + "." + key
+			codeList, ok := v.fields[parent+"."+key]
+			code, isString := value[key].(string)
+			if ok && isString {
+				if !codeList.contains(code) {
+					message := fmt.Sprintf("%s in body should be one of %s code list, found %q", propertyPath, codeList.Name, code)
+					failures = append(failures, newFailure(message))
+				}
+				continue
+			}
+			failures = append(failures, v.validate(value[key], propertyPath, key)...)
+		}
+	case []interface{}:
+		for i, item := range value {
+			failures = append(failures, v.validate(item, fmt.Sprintf("%s.%d", path, i), parent)...)
+		}
+	}
+	return failures
+}
+
+func (v codeListValidator) IsRequestProperty(method, path, propertpath string) (bool, string, error) {
+	return false, "", nil
+}
@@ -0,0 +1,100 @@
+package schema
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCodeLists(t *testing.T) {
+	codeLists, err := LoadCodeLists("v3.1.5")
+
+	require.NoError(t, err)
+	assert.Equal(t, "v3.1", codeLists.Version)
+	assert.NotEmpty(t, codeLists.CodeLists)
+}
+
+func TestLoadCodeLists_UnknownVersion(t *testing.T) {
+	_, err := LoadCodeLists("v9.9.9")
+
+	assert.EqualError(t, err, "schema: could not find code lists for version v9.9.9")
+}
+
+func TestCodeList_Contains(t *testing.T) {
+	codeList := CodeList{
+		Name:       "OBExternalAccountIdentification4Code",
+		Namespaced: true,
+		Codes:      []string{"UK.OBIE.SortCodeAccountNumber"},
+	}
+
+	assert.True(t, codeList.contains("UK.OBIE.SortCodeAccountNumber"))
+	assert.True(t, codeList.contains("UK.AlphaBank.AccountNumber"))
+	assert.False(t, codeList.contains("UK.OBIE.SortCode"))
+
+	codeList.Namespaced = false
+	assert.False(t, codeList.contains("UK.AlphaBank.AccountNumber"))
+}
+
+func TestCodeListValidator_Validate(t *testing.T) {
+	codeLists, err := LoadCodeLists("v3.1.5")
+	require.NoError(t, err)
+	validator := newCodeListValidator(codeLists)
+	r := Response{
+		Method:     "GET",
+		Path:       "/accounts",
+		StatusCode: http.StatusOK,
+		Body:       strings.NewReader(getAccountsResponse),
+	}
+
+	failures, err := validator.Validate(r)
+
+	require.NoError(t, err)
+	assert.Len(t, failures, 0)
+}
+
+func TestCodeListValidator_Validate_ReturnsFailures(t *testing.T) {
+	codeLists, err := LoadCodeLists("v3.1.5")
+	require.NoError(t, err)
+	validator := newCodeListValidator(codeLists)
+	r := Response{
+		Method:     "GET",
+		Path:       "/accounts/22289/balances",
+		StatusCode: http.StatusOK,
+		Body:       strings.NewReader(getBalancesResponseInvalidCodes),
+	}
+
+	failures, err := validator.Validate(r)
+
+	require.NoError(t, err)
+	expected := []Failure{
+		{Message: `.Data.Balance.0.Type in body should be one of OBBalanceType1Code code list, found "Closing"`},
+		{Message: `.Data.Balance.1.Servicer.SchemeName in body should be one of OBExternalFinancialInstitutionIdentification4Code code list, found "UK.OBIE.BIC"`},
+	}
+	assert.Equal(t, expected, failures)
+}
+
+const getBalancesResponseInvalidCodes = `
+		{
+			"Data": {
+				"Balance": [
+					{
+						"AccountId": "22289",
+						"Type": "Closing",
+						"CreditDebitIndicator": "Credit"
+					},
+					{
+						"AccountId": "22289",
+						"Type": "InterimAvailable",
+						"CreditDebitIndicator": "Credit",
+						"Servicer": {
+							"SchemeName": "UK.OBIE.BIC",
+							"Identification": "80200110203345"
+						}
+					}
+				]
+			}
+		}
+	`
@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/go-openapi/spec"
+)
+
+// Field is a response property declared by a schema
+// Path is dotted without array indexes, e.g.: "Data.Account.AccountId", the
+// same format used by schemaprops to collect the fields of a response
+// Mandatory is only true when the property and all its parents are required
+type Field struct {
+	Path      string
+	Mandatory bool
+}
+
+// ResponseFields returns the properties declared for a response of a bundled OB spec
+// path is the swagger path of the endpoint, e.g.: "/accounts/{AccountId}"
+func ResponseFields(specName, version, method, path string, statusCode int) ([]Field, error) {
+	validator, err := NewSwaggerOBSpecValidator(specName, version)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := validator.(validators)
+	if !ok {
+		return nil, errors.New("schema: response fields not supported by validator")
+	}
+
+	response, err := newFinder(v.document).Response(method, path, statusCode)
+	if err != nil {
+		return nil, err
+	}
+	if response.Schema == nil {
+		return []Field{}, nil
+	}
+
+	return schemaFields(response.Schema, "", true), nil
+}
+
+// schemaFields walks a schema returning its properties sorted by path
+func schemaFields(sc *spec.Schema, path string, mandatory bool) []Field {
+	if sc.Items != nil && sc.Items.Schema != nil {
+		return schemaFields(sc.Items.Schema, path, mandatory)
+	}
+
+	required := map[string]bool{}
+	for _, name := range requiredProperties(sc) {
+		required[name] = true
+	}
+
+	properties := declaredProperties(sc)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fields := []Field{}
+	for _, name := range names {
+		property := properties[name]
+		propertyPath := name
+		if path != "" {
+			propertyPath = path + "." + name
+		}
+		propertyMandatory := mandatory && required[name]
+		fields = append(fields, Field{Path: propertyPath, Mandatory: propertyMandatory})
+		fields = append(fields, schemaFields(&property, propertyPath, propertyMandatory)...)
+	}
+	return fields
+}
+
+// requiredProperties returns the required properties of a schema including
+// those composed with allOf
+func requiredProperties(sc *spec.Schema) []string {
+	required := append([]string{}, sc.Required...)
+	for _, subSchema := range sc.AllOf {
+		required = append(required, requiredProperties(&subSchema)...)
+	}
+	return required
+}
@@ -0,0 +1,25 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFields(t *testing.T) {
+	fields, err := ResponseFields("Account and Transaction API Specification", "v3.1.5", "GET", "/accounts/{AccountId}/balances", 200)
+	require.NoError(t, err)
+
+	assert.Contains(t, fields, Field{Path: "Data.Balance.Amount.Amount", Mandatory: true})
+	assert.Contains(t, fields, Field{Path: "Data.Balance.CreditLine", Mandatory: false})
+	// required by an optional parent
+	assert.Contains(t, fields, Field{Path: "Data.Balance.CreditLine.Included", Mandatory: false})
+	assert.Contains(t, fields, Field{Path: "Meta.TotalPages", Mandatory: false})
+}
+
+func TestResponseFields_NotFound(t *testing.T) {
+	_, err := ResponseFields("Account and Transaction API Specification", "v3.1.5", "GET", "/accounts/{AccountId}/balances", 418)
+
+	assert.Equal(t, ErrNotFound, err)
+}
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"regexp"
+	"sync"
 )
 
 // Matcher exposes a path comparison interface
@@ -11,23 +12,32 @@ type Matcher interface {
 	Match(pathWithParams, path2 string) bool
 }
 
-type paramMatcher struct{}
+// paramMatcher compiles each path with params once, paths are
+// matched against the same few spec paths on every response
+type paramMatcher struct {
+	compiled *sync.Map
+}
 
 func NewMatcher() Matcher {
-	return paramMatcher{}
+	return paramMatcher{
+		compiled: &sync.Map{},
+	}
 }
 
 // matches a param in a URL in format `{AccountId}`
 var r = regexp.MustCompile("{[a-zA-Z0-9_]+}")
 
 func (m paramMatcher) Match(pathWithParams, path string) bool {
+	rr, ok := m.compiled.Load(pathWithParams)
+	if !ok {
+		// paths that don't compile are stored as nil and never match
+		compiled, _ := regexp.Compile(r.ReplaceAllString(pathWithParams, `[^/]+`) + `$`)
+		rr, _ = m.compiled.LoadOrStore(pathWithParams, compiled)
+	}
 
-	pathWithParams = r.ReplaceAllString(pathWithParams, `[^/]+`) + `$`
-
-	rr, err := regexp.Compile(pathWithParams)
-	if err != nil {
+	compiled := rr.(*regexp.Regexp)
+	if compiled == nil {
 		return false
 	}
-
-	return rr.MatchString(path)
+	return compiled.MatchString(path)
 }
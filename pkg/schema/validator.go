@@ -1,11 +1,15 @@
 package schema
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
-	"os"
+	"sync"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/assets"
 
 	"github.com/go-openapi/loads"
 	"github.com/go-openapi/spec"
@@ -25,6 +29,7 @@ type Response struct {
 // Failure represents a validation failure
 type Failure struct {
 	Message string
+	Warning bool // Warnings are reported but do not fail a test case
 }
 
 func newFailure(message string) Failure {
@@ -33,53 +38,76 @@ func newFailure(message string) Failure {
 	}
 }
 
+func newWarning(message string) Failure {
+	return Failure{
+		Message: message,
+		Warning: true,
+	}
+}
+
+var strictMode = false
+
+// EnableStrictMode - report response properties not declared in the
+// schema as warnings, for validators created after the call
+func EnableStrictMode() {
+	strictMode = true
+}
+
 // Validator validates a HTTP response object against a schema
 type Validator interface {
 	Validate(Response) ([]Failure, error)
 	IsRequestProperty(method, path, propertpath string) (bool, string, error)
 }
 
+var (
+	obSpecValidators     = map[string]Validator{}
+	obSpecValidatorsLock = &sync.Mutex{}
+)
+
+// NewSwaggerOBSpecValidator returns a swagger validator for a bundled OB spec
+// Validators are cached for the life of the process, the spec files are only
+// read and parsed the first time a spec name and version is requested
 func NewSwaggerOBSpecValidator(specName, version string) (Validator, error) {
-	var err error
-
-	prodDir := "pkg/schema/spec/" + version
-	testDir := "../../pkg/schema/spec/" + version
-
-	dirnameIndex := 0
-	dirnames := []string{prodDir, testDir}
-	files := []os.FileInfo{}
-	for index, dirname := range dirnames {
-		filesReadDir, errReadDir := ioutil.ReadDir(dirname)
-		if errReadDir != nil {
-			wd, errGetwd := os.Getwd()
-			if errGetwd != nil {
-				err = errors.Wrapf(errGetwd, "schema: opening spec folder failed in os.Getwd, dirname=%q", dirname)
-			} else {
-				err = errors.Wrapf(errReadDir, "schema: opening spec folder failed, dirname=%q, wd=%q", dirname, wd)
-			}
-		} else {
-			err = nil
-			dirnameIndex = index
-			files = filesReadDir
-			break
-		}
+	key := fmt.Sprintf("%s %s strict=%t", specName, version, strictMode)
+
+	obSpecValidatorsLock.Lock()
+	defer obSpecValidatorsLock.Unlock()
+
+	if validator, ok := obSpecValidators[key]; ok {
+		return validator, nil
 	}
 
+	validator, err := loadOBSpecValidator(specName, version)
 	if err != nil {
 		return nil, err
 	}
+	obSpecValidators[key] = validator
+	return validator, nil
+}
+
+// loadOBSpecValidator reads the bundled specs for a version until it finds
+// the one for specName and returns a validator for it
+func loadOBSpecValidator(specName, version string) (Validator, error) {
+	dirname := "pkg/schema/spec/" + version
+	filenames, err := assets.ReadDir(dirname)
+	if err != nil {
+		return nil, errors.Wrapf(err, "schema: opening spec folder failed, dirname=%q", dirname)
+	}
 
-	dirname := dirnames[dirnameIndex]
-	for _, f := range files {
-		filename := dirname + "/" + f.Name()
-		doc, err := loads.Spec(filename)
+	for _, name := range filenames {
+		filename := dirname + "/" + name
+		content, err := assets.ReadFile(filename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "schema: reading spec file, filename=%q", filename)
+		}
+		doc, err := loads.Analyzed(json.RawMessage(content), "")
 		if err != nil {
 			return nil, errors.Wrapf(err, "schema: opening spec file, filename=%q", filename)
 		}
 
 		if doc.Spec().Info.Version == version && doc.Spec().Info.Title == specName {
 			logrus.Traceln("Returning swagger validator filename: " + filename)
-			return NewSwaggerValidator(filename)
+			return newValidator(doc)
 		}
 	}
 
@@ -123,11 +151,34 @@ func newValidator(doc *loads.Document) (Validator, error) {
 	case "v3.1.4":
 		fallthrough
 	case "v3.1.5":
+		bodyValidator := newBodyValidator(f)
+		if strictMode {
+			bodyValidator = newStrictBodyValidator(f)
+		}
+		codeLists, err := LoadCodeLists(specVersion)
+		if err != nil {
+			return nil, err
+		}
 		return validators{
 			validators: []Validator{
 				newContentTypeValidator(f),
 				newStatusCodeValidator(f),
-				newBodyValidator(f),
+				bodyValidator,
+				newCodeListValidator(codeLists),
+			},
+			document: doc,
+		}, nil
+	case "v3.2":
+		// the dynamic client registration spec, which has no external code lists
+		bodyValidator := newBodyValidator(f)
+		if strictMode {
+			bodyValidator = newStrictBodyValidator(f)
+		}
+		return validators{
+			validators: []Validator{
+				newContentTypeValidator(f),
+				newStatusCodeValidator(f),
+				bodyValidator,
 			},
 			document: doc,
 		}, nil
@@ -137,8 +188,19 @@ func newValidator(doc *loads.Document) (Validator, error) {
 }
 
 func (v validators) Validate(r Response) ([]Failure, error) {
+	// each validator consumes the body so keep a copy for all of them
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	allFailures := []Failure{}
 	for _, validator := range v.validators {
+		r.Body = bytes.NewReader(body)
 		failures, err := validator.Validate(r)
 		if err != nil {
 			return nil, err
@@ -79,7 +79,7 @@ func TestValidators_Validate_FailureEmptyOptionalProperty(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Len(t, failures, 1)
-	assert.Equal(t, []Failure{{"Data.Transaction.TransactionReference in body should be at least 1 chars long"}}, failures)
+	assert.Equal(t, []Failure{{Message: "Data.Transaction.TransactionReference in body should be at least 1 chars long"}}, failures)
 }
 
 const getTransactionsResponseEmptyTransactionReference = `
@@ -363,3 +363,39 @@ var getStandingOrdersWithFreeformFields = []string{`{
   }
 }`,
 }
+
+func TestNewSwaggerOBSpecValidator_Cached(t *testing.T) {
+	validator, err := NewSwaggerOBSpecValidator("Account and Transaction API Specification", "v3.1.5")
+	require.NoError(t, err)
+
+	cached, err := NewSwaggerOBSpecValidator("Account and Transaction API Specification", "v3.1.5")
+	require.NoError(t, err)
+
+	assert.Same(t, validator.(validators).document, cached.(validators).document)
+	assert.Contains(t, obSpecValidators, "Account and Transaction API Specification v3.1.5 strict=false")
+}
+
+func TestNewSwaggerOBSpecValidator_NotFound(t *testing.T) {
+	_, err := NewSwaggerOBSpecValidator("Unknown API Specification", "v3.1.5")
+
+	assert.EqualError(t, err, "schema: could not find spec file for spec Unknown API Specification version v3.1.5")
+	assert.NotContains(t, obSpecValidators, "Unknown API Specification v3.1.5 strict=false")
+}
+
+func BenchmarkNewSwaggerOBSpecValidator(b *testing.B) {
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := NewSwaggerOBSpecValidator("Payment Initiation API", "v3.1.5"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := loadOBSpecValidator("Payment Initiation API", "v3.1.5"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
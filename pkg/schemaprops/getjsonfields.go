@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
@@ -18,13 +19,16 @@ type PropertyCollector interface {
 	GetProperties() map[string]map[string]int
 	SetCollectorAPIDetails(api, version string)
 	OutputJSON() string
+	CopyConsentGathering() PropertyCollector
 }
 
+// Collector gathers the fields of the responses of a run, it is safe for concurrent use
 type Collector struct {
 	level      int
 	currentApi int
 	path       []string
 	Apis       []PropertyOutput
+	lock       *sync.Mutex
 }
 
 type PropertyOutput struct {
@@ -56,25 +60,49 @@ var ConsentGathering = "ConsentGathering"
 
 var subPathx = "[a-zA-Z0-9_{}-]+" // url sub path regex
 
-var (
-	collector PropertyCollector
-)
-
-func GetPropertyCollector() PropertyCollector {
-	if collector == nil {
-		collector = MakeCollector()
-	}
-	return collector
-}
-
+// MakeCollector - returns a new collector, collectors are scoped to a run
+// so the fields of a run are never merged with those of another one
 func MakeCollector() PropertyCollector {
 	c := &Collector{}
 	c.path = make([]string, 20)
 	c.Apis = []PropertyOutput{}
+	c.lock = &sync.Mutex{}
 	return c
 }
 
+// CopyConsentGathering - returns a new collector holding a copy of the fields
+// collected while acquiring consents, used to start each test run
+func (c *Collector) CopyConsentGathering() PropertyCollector {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	copied := MakeCollector().(*Collector)
+	for _, api := range c.Apis {
+		if api.Api != ConsentGathering {
+			continue
+		}
+		p := PropertyOutput{Api: api.Api, Version: api.Version}
+		p.endpoints = make(map[string]map[string]int, len(api.endpoints))
+		for endpoint, fields := range api.endpoints {
+			p.endpoints[endpoint] = make(map[string]int, len(fields))
+			for field, count := range fields {
+				p.endpoints[endpoint][field] = count
+			}
+		}
+		copied.Apis = append(copied.Apis, p)
+		copied.currentApi = len(copied.Apis) - 1
+	}
+	return copied
+}
+
 func (c *Collector) SetCollectorAPIDetails(api, version string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.setCollectorAPIDetails(api, version)
+}
+
+func (c *Collector) setCollectorAPIDetails(api, version string) {
 	p := PropertyOutput{Api: api, Version: version}
 	p.endpoints = make(map[string]map[string]int, 0)
 	c.Apis = append(c.Apis, p)
@@ -82,6 +110,9 @@ func (c *Collector) SetCollectorAPIDetails(api, version string) {
 }
 
 func (c Collector) GetProperties() map[string]map[string]int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	return c.Apis[c.currentApi].endpoints
 }
 
@@ -118,9 +149,12 @@ func sortPathStrings(m map[string]string) []string {
 }
 
 func (c *Collector) CollectProperties(method, endpoint, body string, code int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	if len(c.Apis) == 0 {
 		logrus.Warnln("Warning no API defined yet")
-		c.SetCollectorAPIDetails("undefined", "0.0")
+		c.setCollectorAPIDetails("undefined", "0.0")
 	}
 
 	requestPaths := make(map[string]int, 20)
@@ -198,6 +232,9 @@ func (c *Collector) expand(i interface{}, m map[string]int) {
 }
 
 func (c Collector) OutputJSON() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
 	apis := c.Apis
 	var err error
 
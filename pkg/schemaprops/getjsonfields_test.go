@@ -12,7 +12,7 @@ import (
 
 func TestCollectReturnedJSONFields(t *testing.T) {
 	logrus.SetLevel(logrus.TraceLevel)
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("myapi", "v3.1.0")
 	c.CollectProperties("GET", "/accounts", string(tdata1), 200)
 	c.OutputJSON()
@@ -20,7 +20,7 @@ func TestCollectReturnedJSONFields(t *testing.T) {
 
 func TestTransactionsJSONFields(t *testing.T) {
 	logrus.SetLevel(logrus.TraceLevel)
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("myapi", "v3.1.0")
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions", string(atransaction), 200)
 	c.SetCollectorAPIDetails("yourapi", "v3.1.1")
@@ -31,7 +31,7 @@ func TestTransactionsJSONFields(t *testing.T) {
 
 func TestAccountsJSONFields(t *testing.T) {
 	logrus.SetLevel(logrus.TraceLevel)
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("myapi", "v3.1.0")
 	c.CollectProperties("GET", "/open-banking/3.1/aisp/accounts", string(accounts), 200)
 	result := c.OutputJSON()
@@ -39,7 +39,7 @@ func TestAccountsJSONFields(t *testing.T) {
 }
 
 func TestAddEmptyAPI(t *testing.T) {
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions", string(atransaction), 200)
 	apitype, err := FindApi("https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions")
 	assert.Equal(t, "accounts", apitype)
@@ -59,7 +59,7 @@ func TestAddEmptyAPI(t *testing.T) {
 }
 
 func TestAddUnnamedApiThenMerge(t *testing.T) {
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails(ConsentGathering, "1")
 
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accounts), 200)
@@ -76,7 +76,7 @@ func TestAddUnnamedApiThenMerge(t *testing.T) {
 
 func TestMergeUnnamedApiThenMerge(t *testing.T) {
 
-	c := GetPropertyCollector()
+	c := MakeCollector()
 	c.SetCollectorAPIDetails("ConsentGathering", "")
 	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accountsmerge), 200)
 
@@ -5381,3 +5381,32 @@ var (
 	}
  }`)
 )
+
+func TestCollectorsAreScopedToARun(t *testing.T) {
+	first := MakeCollector()
+	first.SetCollectorAPIDetails("Accounts and Trasactions", "v3.1.0")
+	first.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accounts), 200)
+
+	second := MakeCollector()
+	second.SetCollectorAPIDetails("Accounts and Trasactions", "v3.1.0")
+	second.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions", string(atransaction), 200)
+
+	assert.NotContains(t, first.OutputJSON(), "Data.Transaction")
+	assert.NotContains(t, second.OutputJSON(), "Data.Account.AccountId")
+}
+
+func TestCopyConsentGathering(t *testing.T) {
+	c := MakeCollector()
+	c.SetCollectorAPIDetails(ConsentGathering, "")
+	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accountsmerge), 200)
+	c.SetCollectorAPIDetails("Accounts and Trasactions", "v3.1.0")
+	c.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts/1234567853/transactions", string(atransaction), 200)
+
+	run := c.CopyConsentGathering()
+	run.SetCollectorAPIDetails("Accounts and Trasactions", "v3.1.0")
+	run.CollectProperties("GET", "https://myserver/open-banking/3.1/aisp/accounts", string(accounts), 200)
+
+	result := run.OutputJSON()
+	assert.Contains(t, result, "MyFatMergeField")
+	assert.NotContains(t, result, "Data.Transaction")
+}
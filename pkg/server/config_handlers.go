@@ -10,9 +10,9 @@ import (
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/server/models"
-	"gopkg.in/resty.v1"
 
 	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -34,9 +34,18 @@ func responseTypesSupported() [3]ResponseType {
 	}
 }
 
+// tokenEndpointAuthMethodsSupported - the `token_endpoint_auth_method` values implemented
+func tokenEndpointAuthMethodsSupported() []interface{} {
+	methods := []interface{}{}
+	for _, method := range authentication.SuiteSupportedAuthMethodsMostSecureFirst() {
+		methods = append(methods, method)
+	}
+	return methods
+}
+
 type configHandlers struct {
-	logger  *logrus.Entry
-	journey Journey
+	logger   *logrus.Entry
+	sessions *Sessions
 }
 
 // Needs to be a interface{} slice, see the official test for an example
@@ -44,7 +53,7 @@ type configHandlers struct {
 type SupportedRequestSignAlg interface{}
 
 func SupportedRequestSignAlgValues() []interface{} {
-	return []interface{}{"PS256", "RS256", "NONE"}
+	return []interface{}{"PS256", "RS256", "ES256", "NONE"}
 }
 
 // SupportedAcrValues returns a slice of supported acr values to be used in the request object
@@ -55,8 +64,9 @@ func SupportedAcrValues() []string {
 }
 
 type GlobalConfiguration struct {
-	SigningPrivate                string                               `json:"signing_private" validate:"not_empty"`
+	SigningPrivate                string                               `json:"signing_private"` // Not set when signing_signer is
 	SigningPublic                 string                               `json:"signing_public" validate:"not_empty"`
+	SigningSigner                 string                               `json:"signing_signer,omitempty"` // URI of the external signer of the signing key, e.g.: pkcs11:token=fcs;object=signing, see package signer
 	TransportPrivate              string                               `json:"transport_private" validate:"not_empty"`
 	TransportPublic               string                               `json:"transport_public" validate:"not_empty"`
 	ClientID                      string                               `json:"client_id" validate:"not_empty"`
@@ -65,6 +75,7 @@ type GlobalConfiguration struct {
 	ResponseType                  string                               `json:"response_type" validate:"not_empty"`
 	TokenEndpointAuthMethod       string                               `json:"token_endpoint_auth_method" validate:"not_empty"`
 	AuthorizationEndpoint         string                               `json:"authorization_endpoint" validate:"valid_url"`
+	PushedAuthorizationEndpoint   string                               `json:"pushed_authorization_request_endpoint,omitempty"`
 	ResourceBaseURL               string                               `json:"resource_base_url" validate:"valid_url"`
 	XFAPIFinancialID              string                               `json:"x_fapi_financial_id" validate:"not_empty"`
 	XFAPICustomerIPAddress        string                               `json:"x_fapi_customer_ip_address,omitempty"`
@@ -87,6 +98,11 @@ type GlobalConfiguration struct {
 	AcrValuesSupported            []string                             `json:"acr_values_supported,omitempty"`
 	ConditionalProperties         []discovery.ConditionalAPIProperties `json:"conditional_properties,omitempty"`
 	CBPIIDebtorAccount            discovery.CBPIIDebtorAccount         `json:"cbpii_debtor_account"`
+	TrustStore                    string                               `json:"trust_store,omitempty"`  // PEM CA certificates trusted on top of the system and OB roots
+	HTTPTimeout                   string                               `json:"http_timeout,omitempty"` // e.g.: 30s, no timeout when empty
+	HTTPProxy                     string                               `json:"http_proxy,omitempty"`
+	TokenVault                    string                               `json:"token_vault,omitempty"`         // Encrypted token vault file of the "store" token acquisition, opened with the passphrase of the server
+	HeadlessParameters            map[string]string                    `json:"headless_parameters,omitempty"` // Context values of the headless consent script, e.g.: psu_username
 }
 
 // Validate - used by https://github.com/go-ozzo/ozzo-validation to validate struct.
@@ -96,6 +112,8 @@ func (c GlobalConfiguration) Validate() error {
 		validation.Field(&c.CreditorAccount, validation.Required),
 		validation.Field(&c.InternationalCreditorAccount, validation.Required),
 		validation.Field(&c.ResponseType, validation.Required, validation.In(values[:]...)),
+		validation.Field(&c.TokenEndpointAuthMethod, validation.In(tokenEndpointAuthMethodsSupported()...)),
+		validation.Field(&c.PushedAuthorizationEndpoint, is.URL),
 		validation.Field(&c.InstructedAmount),
 		validation.Field(&c.CurrencyOfTransfer, validation.Match(regexp.MustCompile("^[A-Z]{3,3}$"))),
 		validation.Field(&c.AcrValuesSupported, validation.By(acrValuesValidator)),
@@ -150,16 +168,16 @@ func strSliceContains(slice []string, str string) bool {
 	return false
 }
 
-func newConfigHandlers(journey Journey, logger *logrus.Entry) configHandlers {
+func newConfigHandlers(sessions *Sessions, logger *logrus.Entry) configHandlers {
 	return configHandlers{
-		journey: journey,
-		logger:  logger.WithField("module", "configHandlers"),
+		sessions: sessions,
+		logger:   logger.WithField("module", "configHandlers"),
 	}
 }
 
 // GET /api/config/conditional-property
 func (h configHandlers) configConditionalPropertyHandler(c echo.Context) error {
-	conditionalProperties := h.journey.ConditionalProperties()
+	conditionalProperties := h.sessions.journeyOf(c).ConditionalProperties()
 	filteredProps := make([]discovery.ConditionalAPIProperties, 0, len(conditionalProperties))
 	for _, v := range conditionalProperties {
 		if len(v.Endpoints) > 0 {
@@ -185,10 +203,7 @@ func (h configHandlers) configGlobalPostHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
 
-	// Use the transport keys for MATLS as some endpoints require this
-	resty.SetCertificates(journeyConfig.certificateTransport.TLSCert())
-
-	err = h.journey.SetConfig(journeyConfig)
+	err = h.sessions.journeyOf(c).SetConfig(journeyConfig)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
@@ -202,7 +217,7 @@ func MakeJourneyConfig(config *GlobalConfiguration) (JourneyConfig, error) {
 		return JourneyConfig{}, errors.New(message)
 	}
 
-	certificateSigning, err := authentication.NewCertificate(config.SigningPublic, config.SigningPrivate)
+	certificateSigning, err := signingCertificate(config)
 	if err != nil {
 		return JourneyConfig{}, errors.Wrap(err, "error with signing certificate")
 	}
@@ -212,6 +227,19 @@ func MakeJourneyConfig(config *GlobalConfiguration) (JourneyConfig, error) {
 		return JourneyConfig{}, errors.Wrap(err, "error with transport certificate")
 	}
 
+	var httpTimeout time.Duration
+	if config.HTTPTimeout != "" {
+		httpTimeout, err = time.ParseDuration(config.HTTPTimeout)
+		if err != nil {
+			return JourneyConfig{}, errors.Wrap(err, "error with http_timeout")
+		}
+	}
+	if config.HTTPProxy != "" {
+		if _, err := url.Parse(config.HTTPProxy); err != nil {
+			return JourneyConfig{}, errors.Wrap(err, "error with http_proxy")
+		}
+	}
+
 	return JourneyConfig{
 		certificateSigning:            certificateSigning,
 		certificateTransport:          certificateTransport,
@@ -221,6 +249,7 @@ func MakeJourneyConfig(config *GlobalConfiguration) (JourneyConfig, error) {
 		ResponseType:                  config.ResponseType,
 		tokenEndpointAuthMethod:       config.TokenEndpointAuthMethod,
 		authorizationEndpoint:         config.AuthorizationEndpoint,
+		pushedAuthorizationEndpoint:   config.PushedAuthorizationEndpoint,
 		resourceBaseURL:               config.ResourceBaseURL,
 		xXFAPIFinancialID:             config.XFAPIFinancialID,
 		xXFAPICustomerIPAddress:       config.XFAPICustomerIPAddress,
@@ -239,16 +268,34 @@ func MakeJourneyConfig(config *GlobalConfiguration) (JourneyConfig, error) {
 		requestObjectSigningAlgorithm: config.RequestObjectSigningAlgorithm,
 		signingPublic:                 config.SigningPublic,
 		signingPrivate:                config.SigningPrivate,
+		signingSigner:                 config.SigningSigner,
 		useNonOBDirectory:             config.UseNonOBDirectory,
 		signingKid:                    config.SigningKid,
 		signatureTrustAnchor:          config.SignatureTrustAnchor,
 		AcrValuesSupported:            config.AcrValuesSupported,
 		conditionalProperties:         config.ConditionalProperties,
 		cbpiiDebtorAccount:            config.CBPIIDebtorAccount,
+		trustStore:                    []byte(config.TrustStore),
+		httpTimeout:                   httpTimeout,
+		httpProxy:                     config.HTTPProxy,
+		headlessParameters:            config.HeadlessParameters,
+		source:                        config,
 	}, nil
 }
 
+// signingCertificate - the signing certificate of config, of which the private key is signing_private
+// or is held by the external signer of signing_signer
+func signingCertificate(config *GlobalConfiguration) (authentication.Certificate, error) {
+	if config.SigningSigner != "" {
+		return authentication.NewExternalCertificate(config.SigningPublic, config.SigningSigner)
+	}
+	return authentication.NewCertificate(config.SigningPublic, config.SigningPrivate)
+}
+
 func validateConfig(config *GlobalConfiguration) (bool, string) {
+	if config.SigningPrivate == "" && config.SigningSigner == "" {
+		return false, "signing_private is empty"
+	}
 	rules := parseRules(config)
 	for _, rule := range rules {
 		ok, message := rule.validateFunc(rule.property, rule.value)
@@ -12,6 +12,7 @@ import (
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/stretchr/testify/assert"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/server/models"
@@ -102,6 +103,16 @@ func TestValidateConfig(t *testing.T) {
 	assert.Empty(t, msg)
 }
 
+func TestValidateConfigSigningSigner(t *testing.T) {
+	config := configStubMissing("SigningPrivate")
+	config.SigningSigner = "daemon:signing"
+
+	ok, msg := validateConfig(&config)
+
+	assert.True(t, ok)
+	assert.Empty(t, msg)
+}
+
 func configStubMissing(missingField string) GlobalConfiguration {
 	creditorAccount := models.Payment{
 		SchemeName:     "UK.OBIE.SortCodeAccountNumber",
@@ -227,6 +238,20 @@ func TestValidateConfigTestsEmpty(t *testing.T) {
 	}
 }
 
+func TestGlobalConfigurationValidateTokenEndpointAuthMethod(t *testing.T) {
+	config := configStubMissing("SigningPrivate")
+	for _, authMethod := range []string{"client_secret_basic", "client_secret_post", "client_secret_jwt", "private_key_jwt", "tls_client_auth"} {
+		config.TokenEndpointAuthMethod = authMethod
+		errs, _ := config.Validate().(validation.Errors)
+		assert.NotContains(t, errs, "token_endpoint_auth_method", authMethod)
+	}
+
+	config.TokenEndpointAuthMethod = "client_secret_foo"
+	errs, ok := config.Validate().(validation.Errors)
+	assert.True(t, ok)
+	assert.EqualError(t, errs["token_endpoint_auth_method"], "must be a valid value")
+}
+
 // TestServerConfigGlobalPostValid - tests /api/config/global
 func TestServerConfigGlobalPostValid(t *testing.T) {
 	require := test.NewRequire(t)
@@ -0,0 +1,56 @@
+package server
+
+import (
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+)
+
+type dcrHandlers struct {
+	sessions *Sessions
+	logger   *logrus.Entry
+}
+
+// dynamicClientRegistrationResponse - the results of the dynamic client registration conformance tests
+type dynamicClientRegistrationResponse struct {
+	Results []results.TestCase `json:"results"`
+}
+
+func newDCRHandlers(sessions *Sessions, logger *logrus.Entry) dcrHandlers {
+	return dcrHandlers{
+		sessions: sessions,
+		logger:   logger.WithField("module", "dcrHandlers"),
+	}
+}
+
+// Validate - used by https://github.com/go-ozzo/ozzo-validation to validate struct.
+func (r DynamicClientRegistration) Validate() error {
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.SoftwareStatement, validation.Required),
+		validation.Field(&r.RegistrationEndpoint, validation.Required, is.URL),
+	)
+}
+
+// POST /api/dcr
+func (h dcrHandlers) postDCRHandler(c echo.Context) error {
+	registration := new(DynamicClientRegistration)
+	if err := c.Bind(registration); err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.Wrap(err, "error with Bind")))
+	}
+	if err := registration.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+	}
+
+	testResults, err := h.sessions.journeyOf(c).RegisterClient(*registration)
+	if err != nil {
+		h.logger.WithError(err).Error("dynamic client registration")
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
+	}
+	return c.JSON(http.StatusOK, dynamicClientRegistrationResponse{Results: testResults})
+}
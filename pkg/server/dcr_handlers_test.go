@@ -0,0 +1,179 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/resty.v1"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	dmocks "bitbucket.org/openbankingteam/conformance-suite/pkg/discovery/mocks"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	gmocks "bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/mockaspsp"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/version/mocks"
+)
+
+func TestDCRHandlers(t *testing.T) {
+	journey := &MockJourney{}
+	registration := DynamicClientRegistration{SoftwareStatement: "ssa", RegistrationEndpoint: "https://aspsp.example.com/register", UseCredentials: true}
+	journey.On("RegisterClient", registration).Return([]results.TestCase{{Id: "#dcr001", Pass: true}}, nil).Once()
+	journey.On("RegisterClient", registration).Return(nil, errConfigNotSet).Once()
+	server := NewServer(journey, nullLogger(), &mocks.Version{})
+	defer func() {
+		require.NoError(t, server.Shutdown(context.TODO()))
+	}()
+
+	body := `{"software_statement":"ssa","registration_endpoint":"https://aspsp.example.com/register","use_credentials":true}`
+	code, response, _ := request(http.MethodPost, "/api/dcr", strings.NewReader(body), server)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, response.String(), `"id":"#dcr001"`)
+
+	code, response, _ = request(http.MethodPost, "/api/dcr", strings.NewReader(body), server)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.JSONEq(t, `{"error":"error journey config not set"}`, response.String())
+
+	code, _, _ = request(http.MethodPost, "/api/dcr", strings.NewReader(`{"software_statement":"ssa"}`), server)
+	assert.Equal(t, http.StatusBadRequest, code, "registration_endpoint missing")
+	journey.AssertExpectations(t)
+}
+
+func TestJourneyRegisterClient(t *testing.T) {
+	cert, err := ioutil.ReadFile("../../certs/conformancesuite_cert.pem")
+	require.NoError(t, err)
+	key, err := ioutil.ReadFile("../../certs/conformancesuite_key.pem")
+	require.NoError(t, err)
+	certificate, err := authentication.NewCertificate(string(cert), string(key))
+	require.NoError(t, err)
+	mockASPSP, err := mockaspsp.NewServer(mockaspsp.Config{Certificate: certificate, ClientID: "tpp"}, test.NullLogger())
+	require.NoError(t, err)
+	aspsp := httptest.NewUnstartedServer(mockASPSP)
+	aspsp.TLS = mockASPSP.TLSConfig()
+	aspsp.StartTLS()
+	defer aspsp.Close()
+
+	journey := NewJourney(nullLogger(), &gmocks.MockGenerator{}, &dmocks.Validator{}, discovery.NewNullTLSValidator(), false)
+	_, err = journey.RegisterClient(DynamicClientRegistration{})
+	assert.Equal(t, errConfigNotSet, err)
+
+	redirectURL := "https://127.0.0.1:8443/conformancesuite/callback"
+	require.NoError(t, journey.SetConfig(JourneyConfig{
+		certificateSigning:      certificate,
+		certificateTransport:    certificate,
+		clientID:                "tpp",
+		clientSecret:            "secret",
+		tokenEndpoint:           aspsp.URL + "/token",
+		tokenEndpointAuthMethod: authentication.ClientSecretBasic,
+		issuer:                  aspsp.URL,
+		redirectURL:             redirectURL,
+		resourceIDs: model.ResourceIDs{
+			AccountIDs:   []model.ResourceAccountID{{AccountID: "account-id"}},
+			StatementIDs: []model.ResourceStatementID{{StatementID: "statement-id"}},
+		},
+		source: &GlobalConfiguration{ClientID: "tpp", ClientSecret: "secret"},
+	}))
+	journey.httpClient = resty.New().SetTLSClientConfig(&tls.Config{
+		Certificates:       []tls.Certificate{certificate.TLSCert()},
+		InsecureSkipVerify: true,
+	})
+	ssa, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"software_id":            "software",
+		"software_redirect_uris": []string{redirectURL},
+	}).SignedString([]byte("directory"))
+	require.NoError(t, err)
+
+	testResults, err := journey.RegisterClient(DynamicClientRegistration{
+		SoftwareStatement:    ssa,
+		RegistrationEndpoint: aspsp.URL + "/register",
+		UseCredentials:       true,
+	})
+	require.NoError(t, err)
+	for _, result := range testResults {
+		assert.True(t, result.Pass, "%s %v", result.Id, result.Fail)
+	}
+	assert.NotEqual(t, "tpp", journey.config.clientID)
+	assert.NotEmpty(t, journey.config.clientSecret)
+	assert.Equal(t, journey.config.clientID, journey.config.source.ClientID)
+	basicAuth, err := journey.context.GetString(CtxConstBasicAuthentication)
+	require.NoError(t, err)
+	expected, err := authentication.CalculateClientSecretBasicToken(journey.config.clientID, journey.config.clientSecret)
+	require.NoError(t, err)
+	assert.Equal(t, expected, basicAuth)
+
+	_, err = journey.RegisterClient(DynamicClientRegistration{SoftwareStatement: "invalid", RegistrationEndpoint: aspsp.URL + "/register", UseCredentials: true})
+	assert.EqualError(t, err, "journey.RegisterClient: no client registered, the journey config is unchanged")
+	basicAuth, err = journey.context.GetString(CtxConstBasicAuthentication)
+	require.NoError(t, err)
+	assert.Equal(t, expected, basicAuth)
+}
+
+func TestJourneyRegisterClient_UnlockedDuringRegistration(t *testing.T) {
+	cert, err := ioutil.ReadFile("../../certs/conformancesuite_cert.pem")
+	require.NoError(t, err)
+	key, err := ioutil.ReadFile("../../certs/conformancesuite_key.pem")
+	require.NoError(t, err)
+	certificate, err := authentication.NewCertificate(string(cert), string(key))
+	require.NoError(t, err)
+
+	journey := NewJourney(nullLogger(), &gmocks.MockGenerator{}, &dmocks.Validator{}, discovery.NewNullTLSValidator(), false)
+	locked := make(chan bool, 1)
+	aspsp := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		unlocked := make(chan struct{})
+		go func() {
+			journey.journeyLock.Lock()
+			journey.journeyLock.Unlock()
+			close(unlocked)
+		}()
+		wasLocked := false
+		select {
+		case <-unlocked:
+		case <-time.After(time.Second):
+			wasLocked = true
+		}
+		select {
+		case locked <- wasLocked: // the first request
+		default:
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer aspsp.Close()
+	require.NoError(t, journey.SetConfig(JourneyConfig{
+		certificateSigning:      certificate,
+		certificateTransport:    certificate,
+		clientID:                "tpp",
+		clientSecret:            "secret",
+		tokenEndpoint:           aspsp.URL + "/token",
+		tokenEndpointAuthMethod: authentication.ClientSecretBasic,
+		issuer:                  aspsp.URL,
+		redirectURL:             "https://127.0.0.1:8443/conformancesuite/callback",
+		resourceIDs: model.ResourceIDs{
+			AccountIDs:   []model.ResourceAccountID{{AccountID: "account-id"}},
+			StatementIDs: []model.ResourceStatementID{{StatementID: "statement-id"}},
+		},
+	}))
+	journey.httpClient = resty.New().SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	ssa, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"software_id": "software"}).SignedString([]byte("directory"))
+	require.NoError(t, err)
+
+	_, err = journey.RegisterClient(DynamicClientRegistration{
+		SoftwareStatement:    ssa,
+		RegistrationEndpoint: aspsp.URL + "/register",
+		UseCredentials:       true,
+	})
+
+	assert.EqualError(t, err, "journey.RegisterClient: no client registered, the journey config is unchanged")
+	require.NotEmpty(t, locked)
+	assert.False(t, <-locked, "the journey is locked while registering")
+}
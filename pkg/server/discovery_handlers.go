@@ -25,6 +25,7 @@ type PostDiscoveryModelResponse struct {
 	RequestObjectSigningAlgValuesSupported        map[string][]string `json:"request_object_signing_alg_values_supported"`
 	DefaultRequestObjectSigningAlgValuesSupported map[string]string   `json:"default_request_object_signing_alg_values_supported"`
 	AuthorizationEndpoints                        map[string]string   `json:"authorization_endpoints"`
+	PushedAuthorizationEndpoints                  map[string]string   `json:"pushed_authorization_request_endpoints"`
 	Issuers                                       map[string]string   `json:"issuers"`
 	DefaultTxnFromDateTime                        string              `json:"default_transaction_from_date"`
 	DefaultTxnToDateTime                          string              `json:"default_transaction_to_date"`
@@ -37,12 +38,12 @@ type validationFailuresResponse struct {
 }
 
 type discoveryHandlers struct {
-	webJourney Journey
-	logger     *logrus.Entry
+	sessions *Sessions
+	logger   *logrus.Entry
 }
 
-func newDiscoveryHandlers(webJourney Journey, logger *logrus.Entry) discoveryHandlers {
-	return discoveryHandlers{webJourney, logger.WithField("handler", "discoveryHandlers")}
+func newDiscoveryHandlers(sessions *Sessions, logger *logrus.Entry) discoveryHandlers {
+	return discoveryHandlers{sessions, logger.WithField("handler", "discoveryHandlers")}
 }
 
 func (d discoveryHandlers) setDiscoveryModelHandler(c echo.Context) error {
@@ -55,7 +56,7 @@ func (d discoveryHandlers) setDiscoveryModelHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
 
-	failures, err := d.webJourney.SetDiscoveryModel(discoveryModel)
+	failures, err := d.sessions.journeyOf(c).SetDiscoveryModel(discoveryModel)
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
@@ -72,6 +73,7 @@ func (d discoveryHandlers) setDiscoveryModelHandler(c echo.Context) error {
 		RequestObjectSigningAlgValuesSupported:        map[string][]string{},
 		DefaultRequestObjectSigningAlgValuesSupported: map[string]string{},
 		AuthorizationEndpoints:                        map[string]string{},
+		PushedAuthorizationEndpoints:                  map[string]string{},
 		Issuers:                                       map[string]string{},
 		ResponseTypesSupported:                        []string{},
 		AcrValuesSupported:                            []string{},
@@ -91,7 +93,7 @@ func (d discoveryHandlers) setDiscoveryModelHandler(c echo.Context) error {
 			}).Error("Error on /.well-known/openid-configuration")
 			failures = append(failures, newOpenidConfigurationURIFailure(discoveryItemIndex, e))
 		} else {
-			var SupportedRequestSignAlgValues = []string{"PS256", "RS256", "NONE"}
+			var SupportedRequestSignAlgValues = []string{"PS256", "RS256", "ES256", "NONE"}
 			requestObjectSigningAlgValuesSupported := sets.InsensitiveIntersection(config.RequestObjectSigningAlgValuesSupported, SupportedRequestSignAlgValues)
 			if len(requestObjectSigningAlgValuesSupported) == 0 {
 				return errors.New("no supported request object signing alg found")
@@ -99,6 +101,9 @@ func (d discoveryHandlers) setDiscoveryModelHandler(c echo.Context) error {
 
 			response.TokenEndpoints[key] = config.TokenEndpoint
 			response.AuthorizationEndpoints[key] = config.AuthorizationEndpoint
+			if config.PushedAuthorizationRequestEndpoint != "" {
+				response.PushedAuthorizationEndpoints[key] = config.PushedAuthorizationRequestEndpoint
+			}
 			response.Issuers[key] = config.Issuer
 			response.TokenEndpointAuthMethods[key] = authentication.SuiteSupportedAuthMethodsMostSecureFirst()
 			response.DefaultTokenEndpointAuthMethod[key] = authentication.DefaultAuthMethod(config.TokenEndpointAuthMethodsSupported, d.logger)
@@ -20,14 +20,14 @@ const (
 )
 
 type exportHandlers struct {
-	journey Journey
-	logger  *logrus.Entry
+	sessions *Sessions
+	logger   *logrus.Entry
 }
 
-func newExportHandlers(journey Journey, logger *logrus.Entry) exportHandlers {
+func newExportHandlers(sessions *Sessions, logger *logrus.Entry) exportHandlers {
 	return exportHandlers{
-		journey: journey,
-		logger:  logger.WithField("handler", "exportHandlers"),
+		sessions: sessions,
+		logger:   logger.WithField("handler", "exportHandlers"),
 	}
 }
 
@@ -45,10 +45,12 @@ func (h exportHandlers) postExport(c echo.Context) error {
 
 	logger.WithField("request", request).Info("Exporting ...")
 
-	results := h.journey.Results().AllResultsGrouped()
-	responseFields := h.journey.Results().ResponseFieldsJSON()
-	tokens := h.journey.Events().AllAcquiredAccessToken()
-	discovery, err := h.journey.DiscoveryModel()
+	journey := h.sessions.journeyOf(c)
+	results := journey.Results().AllResultsGrouped()
+	responseFields := journey.Results().ResponseFieldsJSON()
+	exchanges := journey.Results().Exchanges()
+	tokens := journey.Events().AllAcquiredAccessToken()
+	discovery, err := journey.DiscoveryModel()
 
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.Wrap(err, "exporting report-get journey discovery model")))
@@ -60,9 +62,11 @@ func (h exportHandlers) postExport(c echo.Context) error {
 		Results:          results,
 		Tokens:           tokens,
 		DiscoveryModel:   discovery,
-		TLSVersionResult: h.journey.TLSVersionResult(),
+		TLSVersionResult: journey.TLSVersionResult(),
 		ResponseFields:   responseFields,
+		Exchanges:        exchanges,
 		JWSStatus:        model.JWSStatus(),
+		AuthMethod:       journey.TokenEndpointAuthMethod(),
 	}
 
 	r, err := report.NewReport(exportResults, request.Environment)
@@ -83,3 +87,19 @@ func (h exportHandlers) postExport(c echo.Context) error {
 	// c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="report.zip"`)
 	return c.Blob(http.StatusOK, MIMEApplicationZIP, buff.Bytes())
 }
+
+// getCoverage - returns the response fields coverage of the last run
+func (h exportHandlers) getCoverage(c echo.Context) error {
+	journey := h.sessions.journeyOf(c)
+	discovery, err := journey.DiscoveryModel()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, NewErrorResponse(errors.Wrap(err, "coverage-get journey discovery model")))
+	}
+
+	coverage, err := report.NewCoverage(journey.Results().ResponseFieldsJSON(), discovery)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, NewErrorResponse(err))
+	}
+
+	return c.JSON(http.StatusOK, coverage)
+}
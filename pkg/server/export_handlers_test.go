@@ -222,3 +222,26 @@ func TestServerPostExport_InvalidRequest(t *testing.T) {
 		}, headers, body.String())
 	}
 }
+
+func TestServerGetCoverage(t *testing.T) {
+	require := test.NewRequire(t)
+
+	discoveryModel := &discovery.Model{}
+	validator := &discovery_mocks.Validator{}
+	validator.On("Validate", discoveryModel).Return(discovery.NoValidationFailures(), nil)
+	generator := &gmocks.MockGenerator{}
+	journey := NewJourney(nullLogger(), generator, validator, discovery.NewNullTLSValidator(), false)
+
+	_, err := journey.SetDiscoveryModel(discoveryModel)
+	require.NoError(err)
+
+	server := NewServer(journey, nullLogger(), &version_mocks.Version{})
+	defer func() {
+		require.NoError(server.Shutdown(context.TODO()))
+	}()
+
+	code, body, _ := request(http.MethodGet, "/api/export/coverage", nil, server)
+
+	require.Equal(http.StatusOK, code, body.String())
+	require.JSONEq(`{"apis": []}`, body.String())
+}
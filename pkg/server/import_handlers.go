@@ -15,14 +15,14 @@ import (
 )
 
 type importHandlers struct {
-	journey Journey
-	logger  *logrus.Entry
+	sessions *Sessions
+	logger   *logrus.Entry
 }
 
-func newImportHandlers(journey Journey, logger *logrus.Entry) importHandlers {
+func newImportHandlers(sessions *Sessions, logger *logrus.Entry) importHandlers {
 	return importHandlers{
-		journey: journey,
-		logger:  logger.WithField("handler", "importHandlers"),
+		sessions: sessions,
+		logger:   logger.WithField("handler", "importHandlers"),
 	}
 }
 
@@ -4,22 +4,30 @@ package server
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"gopkg.in/resty.v1"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/dcr"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/events"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/server/models"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/vault"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/version"
 )
 
 var (
@@ -30,6 +38,7 @@ var (
 	errConsentIDAcquisitionFailed      = errors.New("ConsentId acquistion failed")
 	errDynamicResourceAllocationFailed = errors.New("Dynamic Resource allocation failed")
 	errNoTestCases                     = errors.New("No testcases were generated - please select a wider set of endpoints to test")
+	errConfigNotSet                    = errors.New("error journey config not set")
 )
 
 // Journey represents all possible steps for a user test conformance journey
@@ -54,9 +63,11 @@ type Journey interface {
 	NewDaemonController()
 	Results() executors.DaemonController
 	SetConfig(config JourneyConfig) error
+	RegisterClient(registration DynamicClientRegistration) ([]results.TestCase, error)
 	ConditionalProperties() []discovery.ConditionalAPIProperties
 	Events() events.Events
 	TLSVersionResult() map[string]*discovery.TLSValidationResult
+	TokenEndpointAuthMethod() string
 }
 
 type journey struct {
@@ -67,6 +78,14 @@ type journey struct {
 	specRun               generation.SpecRun
 	testCasesRunGenerated bool
 	collector             executors.TokenCollector
+	tokens                *executors.TokenStore
+	propertyCollector     schemaprops.PropertyCollector
+	recorder              *har.Recorder
+	httpClient            *resty.Client        // Sends the requests of the journey, made of its config by SetConfig
+	runOptions            executors.RunOptions // Options of the runs set by the operator, see `SetRunOptions`
+	tokenVaultPassphrase  string               // Opens the token vault of the config, see `SetTokenVaultPassphrase`
+	vaultTokens           []string             // Names of the tokens used from the token vault, see `useVaultTokens`
+	pushedAuthorization   *executors.PushedAuthorization
 	allCollected          bool
 	validDiscoveryModel   *discovery.Model
 	context               model.Context
@@ -79,6 +98,10 @@ type journey struct {
 	tlsValidator          discovery.TLSValidator
 	conditionalProperties []discovery.ConditionalAPIProperties
 	dynamicResourceIDs    bool
+	store                 *Store             // Saves the journey state when persisted, see `Persist`
+	sessionID             string             // Session of the journey in store
+	resumeResults         []results.TestCase // Results restored from store, the next run skips their test cases
+	restored              bool               // Test cases were restored from store instead of generated
 }
 
 // NewJourney creates an instance for a user journey
@@ -93,6 +116,8 @@ func NewJourney(logger *logrus.Entry, generator generation.Generator, validator
 		context:               model.Context{},
 		log:                   logger.WithField("module", "journey"),
 		events:                events.NewEvents(),
+		tokens:                executors.NewTokenStore(logger, nil),
+		runOptions:            executors.NewRunOptions(),
 		permissions:           make(map[string][]manifest.RequiredTokens),
 		manifests:             make([]manifest.Scripts, 0),
 		tlsValidator:          tlsValidator,
@@ -109,8 +134,8 @@ func (wj *journey) NewDaemonController() {
 
 	wj.journeyLock.Lock()
 	defer wj.journeyLock.Unlock()
-	wj.daemonController = executors.NewBufferedDaemonController()
 	wj.events = events.NewEvents()
+	wj.daemonController = wj.newDaemonController()
 }
 
 func (wj *journey) SetDiscoveryModel(discoveryModel *discovery.Model) (discovery.ValidationFailures, error) {
@@ -128,6 +153,9 @@ func (wj *journey) SetDiscoveryModel(discoveryModel *discovery.Model) (discovery
 	wj.validDiscoveryModel = discoveryModel
 	wj.testCasesRunGenerated = false
 	wj.allCollected = false
+	wj.restored = false
+	wj.resumeResults = nil
+	wj.tokens = executors.NewTokenStore(wj.log, nil)
 
 	if discoveryModel.DiscoveryModel.DiscoveryVersion == "v0.4.0" { // Conditional properties requires 0.4.0
 		//TODO: remove this constraint once support for v0.3.0 discovery model is dropped
@@ -143,6 +171,7 @@ func (wj *journey) SetDiscoveryModel(discoveryModel *discovery.Model) (discovery
 		}
 	}
 
+	wj.save()
 	return discovery.NoValidationFailures(), nil
 }
 
@@ -157,6 +186,13 @@ func (wj *journey) DiscoveryModel() (discovery.Model, error) {
 	return *discoveryModel, nil
 }
 
+// TokenEndpointAuthMethod - the client authentication at the token endpoint of the configuration
+func (wj *journey) TokenEndpointAuthMethod() string {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	return wj.config.tokenEndpointAuthMethod
+}
+
 func (wj *journey) TLSVersionResult() map[string]*discovery.TLSValidationResult {
 	logger := wj.log.WithFields(logrus.Fields{
 		"package":  "server",
@@ -211,6 +247,10 @@ func (wj *journey) TestCases() (generation.SpecRun, error) {
 		return generation.SpecRun{}, errDiscoveryModelNotSet
 	}
 
+	if wj.testCasesRunGenerated && wj.restored { // generated before a restart
+		return wj.specRun, nil
+	}
+
 	if wj.testCasesRunGenerated {
 		logger.WithFields(logrus.Fields{
 			"err":                      errTestCasesGenerated,
@@ -226,7 +266,7 @@ func (wj *journey) TestCases() (generation.SpecRun, error) {
 		logrus.Warn("JWKS URI is empty")
 	}
 
-	if tlsCheck {
+	if tlsCheck && wj.runOptions.Replay == nil { // replayed runs don't connect to the ASPSP
 		for k, discoveryItem := range wj.validDiscoveryModel.DiscoveryModel.DiscoveryItems {
 			tlsValidationResult, err := wj.tlsValidator.ValidateTLSVersion(discoveryItem.ResourceBaseURI)
 			if err != nil {
@@ -252,6 +292,7 @@ func (wj *journey) TestCases() (generation.SpecRun, error) {
 			if len(apiversions) > 0 {
 				wj.context.PutStringSlice("apiversions", apiversions)
 			}
+			wj.context.PutStringSlice(CtxPushedAuthorizationSpecTypes, PushedAuthorizationSpecTypes(discovery.DiscoveryItems))
 			// version string gets replaced in URLS like  "endpoint": "/open-banking/$api-version/aisp/account-access-consents",
 			version, err := semver.ParseTolerant(discovery.DiscoveryItems[0].APISpecification.Version)
 			if err != nil {
@@ -285,10 +326,15 @@ func (wj *journey) TestCases() (generation.SpecRun, error) {
 			}
 		}
 
-		collector := schemaprops.GetPropertyCollector()
-		collector.SetCollectorAPIDetails(schemaprops.ConsentGathering, "")
+		wj.propertyCollector = schemaprops.MakeCollector()
+		wj.propertyCollector.SetCollectorAPIDetails(schemaprops.ConsentGathering, "")
+		wj.recorder = har.NewRecorder()
+		wj.pushedAuthorization = executors.NewPushedAuthorization()
 
-		if discovery.TokenAcquisition == "psu" { // Handle  PSU Consent
+		vaultSatisfied := discovery.TokenAcquisition == "store" && wj.useVaultTokens(logger)
+		if vaultSatisfied {
+			logger.Info("token vault satisfies all required tokens, skipping consent acquisition")
+		} else if discovery.TokenAcquisition == "psu" || discovery.TokenAcquisition == "store" { // Handle  PSU Consent, store acquires the tokens the vault misses
 			logger.WithFields(logrus.Fields{
 				"discovery.TokenAcquisition": discovery.TokenAcquisition,
 			}).Debug("AcquirePSUTokens ...")
@@ -380,6 +426,7 @@ func (wj *journey) TestCases() (generation.SpecRun, error) {
 			wj.allCollected = true
 		}
 		wj.testCasesRunGenerated = true
+		wj.save()
 	}
 
 	logger.Tracef("SpecRun.SpecConsentRequirements: %#v", wj.specRun.SpecConsentRequirements)
@@ -416,7 +463,7 @@ func (wj *journey) CollectToken(code, state, scope string) error {
 		return errTestCasesNotGenerated
 	}
 
-	accessToken, err := executors.ExchangeCodeForAccessToken(state, code, &wj.context)
+	accessToken, err := executors.ExchangeCodeForAccessToken(state, code, &wj.context, wj.makeRunDefinition())
 	if err != nil {
 		logger.WithFields(logrus.Fields{
 			"err":         err,
@@ -441,7 +488,7 @@ func (wj *journey) CollectToken(code, state, scope string) error {
 	}
 
 	if wj.config.useDynamicResourceID {
-		err := executors.GetDynamicResourceIds(state, accessToken, &wj.context, wj.permissions["accounts"])
+		err := executors.GetDynamicResourceIds(state, accessToken, &wj.context, wj.permissions["accounts"], wj.httpClient, wj.recorder)
 		if err != nil {
 			logger.WithFields(logrus.Fields{
 				"err": err,
@@ -454,7 +501,9 @@ func (wj *journey) CollectToken(code, state, scope string) error {
 		logger.Tracef("journey perms: %#v", v)
 	}
 
-	return wj.collector.Collect(state, accessToken)
+	err = wj.collector.Collect(state, accessToken)
+	wj.save()
+	return err
 }
 
 func (wj *journey) AllTokenCollected() bool {
@@ -521,6 +570,30 @@ func (wj *journey) RunTests() error {
 	}
 
 	runDefinition := wj.makeRunDefinition()
+	if len(wj.resumeResults) > 0 { // resuming after a restart, the results restored are reported already
+		var kept []results.TestCase
+		runDefinition.SpecRun, kept = pendingSpecRun(wj.specRun, wj.resumeResults)
+		logger.WithFields(logrus.Fields{
+			"completedTestResults": len(wj.resumeResults),
+			"keptTestResults":      len(kept),
+		}).Info("resuming run")
+		wj.resumeResults = nil
+		if wj.store != nil {
+			if err := wj.store.ReplaceResults(wj.sessionID, kept); err != nil {
+				logger.WithError(err).Error("replacing results of test cases run again")
+			}
+		}
+	} else if wj.store != nil {
+		if err := wj.store.ClearResults(wj.sessionID); err != nil {
+			logger.WithError(err).Error("clearing results of the previous run")
+		}
+	}
+	wj.journeyLock.Lock()
+	wj.save()
+	wj.journeyLock.Unlock()
+	// each run only reports its own response fields and those of the consents it uses
+	runDefinition.Collector = wj.propertyCollector.CopyConsentGathering()
+	runDefinition.Recorder = wj.recorder.Copy()
 	runner := executors.NewTestCaseRunner(wj.log, runDefinition, wj.daemonController)
 	wj.context.PutString(CtxPhase, "run")
 	err := runner.RunTestCases(&wj.context)
@@ -554,6 +627,95 @@ func (wj *journey) createTokenCollector(consentIds executors.TokenConsentIDs) {
 	}).Debug("TokenCollector status ...")
 }
 
+// useVaultTokens - puts the access tokens and consent IDs of the token vault of the config in the
+// context and maps them to the test cases, when the vault satisfies all required tokens. False
+// when there's no vault or it misses a required token, the consents must be acquired then.
+// Test cases refer to the tokens by name, so the tokens are only held by the context and token
+// store, and never saved, see `save`
+func (wj *journey) useVaultTokens(logger *logrus.Entry) bool {
+	wj.vaultTokens = nil
+	if wj.config.tokenVault == nil {
+		logger.Warn("store token acquisition without a token_vault in the config")
+		return false
+	}
+
+	tokenVault := wj.config.tokenVault
+	required := map[string][]manifest.RequiredTokens{}
+	stored := map[string]vault.Token{}
+	missing := []string{}
+	for specType, specRequired := range wj.permissions {
+		for _, rt := range specRequired {
+			token, ok := tokenVault.Match(rt.Name, rt.Perms)
+			if !ok {
+				missing = append(missing, rt.Name)
+				continue
+			}
+			rt.ConsentID = token.ConsentID
+			required[specType] = append(required[specType], rt)
+			stored[rt.Name] = token
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		logger.WithFields(logrus.Fields{
+			"missing": missing,
+			"vault":   tokenVault.Names(),
+		}).Warn("token vault misses required tokens")
+		return false
+	}
+
+	for specType, specRequired := range required {
+		for _, rt := range specRequired {
+			if rt.ConsentParam != "" && rt.ConsentID != "" {
+				wj.context.PutString(rt.ConsentParam, rt.ConsentID)
+			}
+		}
+		for _, spec := range wj.specRun.SpecTestCases {
+			switch specType {
+			case "payments":
+				manifest.MapTokensToPaymentTestCases(specRequired, spec.TestCases, &wj.context)
+			case "cbpii":
+				manifest.MapTokensToCBPIITestCases(specRequired, spec.TestCases, &wj.context)
+			default:
+				manifest.MapTokensToTestCases(specRequired, spec.TestCases)
+			}
+		}
+	}
+	for name, token := range stored {
+		wj.context.PutString(name, token.AccessToken)
+		if name == "Token001" {
+			wj.context.PutString("access_token", token.AccessToken)
+		}
+		wj.tokens.Set(name, executors.StoredToken{
+			AccessToken:  token.AccessToken,
+			RefreshToken: token.RefreshToken,
+			Expiry:       token.ExpiresAt,
+		})
+		wj.vaultTokens = append(wj.vaultTokens, name)
+	}
+	sort.Strings(wj.vaultTokens)
+
+	wj.allCollected = true
+	return true
+}
+
+// openTokenVault - decrypts the token vault of the config with the passphrase of the server
+func (wj *journey) openTokenVault() error {
+	wj.config.tokenVault = nil
+	if wj.config.source == nil || wj.config.source.TokenVault == "" {
+		return nil
+	}
+	if wj.tokenVaultPassphrase == "" {
+		return errors.New("error with token_vault: the server has no token vault passphrase")
+	}
+	decrypted, err := vault.Decrypt([]byte(wj.config.source.TokenVault), wj.tokenVaultPassphrase)
+	if err != nil {
+		return errors.Wrap(err, "error with token_vault")
+	}
+	wj.config.tokenVault = &decrypted
+	return nil
+}
+
 func (wj *journey) makeGeneratorConfig() generation.GeneratorConfig {
 	return generation.GeneratorConfig{
 		ClientID:              wj.config.clientID,
@@ -572,7 +734,48 @@ func (wj *journey) makeRunDefinition() executors.RunDefinition {
 		SpecRun:       wj.specRun,
 		SigningCert:   wj.config.certificateSigning,
 		TransportCert: wj.config.certificateTransport,
+		Collector:     wj.propertyCollector,
+		Recorder:      wj.recorder,
+		TrustStore:    wj.config.trustStore,
+		Timeout:       wj.config.httpTimeout,
+		Proxy:         wj.config.httpProxy,
+		UserAgent:     userAgent(wj.config.certificateTransport),
+		HTTPClient:    wj.httpClient,
+		Tokens:        wj.tokens,
+		Events:        wj.events,
+
+		PushedAuthorization: wj.pushedAuthorization,
+		RunOptions:          wj.runOptions,
+	}
+}
+
+// SetTokenVaultPassphrase - sets the passphrase opening the token vault of the config, before
+// the config is set or restored. It's set by the operator, never by the config
+func (wj *journey) SetTokenVaultPassphrase(passphrase string) {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	wj.tokenVaultPassphrase = passphrase
+}
+
+// SetRunOptions - sets the options of the runs of the journey, before its config is set or restored
+func (wj *journey) SetRunOptions(options executors.RunOptions) {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	wj.runOptions = options
+}
+
+// userAgent - the User-Agent of the requests of the suite, identifying the
+// organisation of the transport certificate when it has one
+func userAgent(certificateTransport authentication.Certificate) string {
+	agent := "OpenBankingFCS/" + version.NewBitBucket("").GetHumanVersion()
+	if certificateTransport == nil {
+		return agent
+	}
+	_, ou, cn, err := certificateTransport.DN()
+	if err == nil && cn != "" && ou != "" {
+		return agent + "/" + ou + "/" + cn
 	}
+	return agent
 }
 
 // JourneyConfig main configuration variables
@@ -585,6 +788,7 @@ type JourneyConfig struct {
 	ResponseType                  string
 	tokenEndpointAuthMethod       string
 	authorizationEndpoint         string
+	pushedAuthorizationEndpoint   string
 	resourceBaseURL               string
 	xXFAPIFinancialID             string
 	xXFAPICustomerIPAddress       string
@@ -604,6 +808,7 @@ type JourneyConfig struct {
 	requestObjectSigningAlgorithm string
 	signingPrivate                string
 	signingPublic                 string
+	signingSigner                 string // URI of the external signer of the signing key, see package signer
 	useNonOBDirectory             bool
 	signingKid                    string
 	signatureTrustAnchor          string
@@ -611,6 +816,12 @@ type JourneyConfig struct {
 	AcrValuesSupported            []string
 	conditionalProperties         []discovery.ConditionalAPIProperties
 	cbpiiDebtorAccount            discovery.CBPIIDebtorAccount
+	trustStore                    []byte
+	httpTimeout                   time.Duration
+	httpProxy                     string
+	tokenVault                    *vault.Vault         // Tokens of the "store" token acquisition, see `journey.openTokenVault`
+	headlessParameters            map[string]string    // Context values of the headless consent script
+	source                        *GlobalConfiguration // Configuration the journey config was made of, saved by `Persist`
 }
 
 func (wj *journey) SetConfig(config JourneyConfig) error {
@@ -619,15 +830,94 @@ func (wj *journey) SetConfig(config JourneyConfig) error {
 
 	wj.config = config
 	wj.config.useDynamicResourceID = wj.dynamicResourceIDs // fed from environment variable 'dynres'=true/false
+	if err := wj.openTokenVault(); err != nil {
+		return err
+	}
 	err := PutParametersToJourneyContext(wj.config, wj.context)
 	if err != nil {
 		return err
 	}
 
+	// Every request of the journey is sent by a client of its own certificates
+	httpClient, err := executors.NewHTTPClient(wj.makeRunDefinition())
+	if err != nil {
+		return err
+	}
+	wj.httpClient = httpClient
+
 	wj.customTestParametersToJourneyContext()
+	wj.save()
 	return nil
 }
 
+// DynamicClientRegistration - the software the dynamic client registration conformance tests
+// register with the ASPSP of the journey config
+type DynamicClientRegistration struct {
+	SoftwareStatement    string   `json:"software_statement"`
+	RegistrationEndpoint string   `json:"registration_endpoint"`
+	RedirectURIs         []string `json:"redirect_uris,omitempty"`
+	// UseCredentials - the client registered is kept and becomes the client of the journey
+	UseCredentials bool `json:"use_credentials"`
+}
+
+// RegisterClient - runs the dynamic client registration conformance tests with the signing and
+// transport certificates of the journey config. The credentials of the client registered replace
+// those of the config when registration.UseCredentials is set
+func (wj *journey) RegisterClient(registration DynamicClientRegistration) ([]results.TestCase, error) {
+	wj.journeyLock.Lock()
+	if wj.config.certificateSigning == nil || wj.config.certificateTransport == nil {
+		wj.journeyLock.Unlock()
+		return nil, errConfigNotSet
+	}
+	transportDN, _, _, err := wj.config.certificateTransport.DN()
+	if err != nil {
+		wj.journeyLock.Unlock()
+		return nil, errors.Wrap(err, "journey.RegisterClient")
+	}
+	redirectURIs := registration.RedirectURIs
+	if len(redirectURIs) == 0 {
+		redirectURIs = []string{wj.config.redirectURL}
+	}
+	config := dcr.Config{
+		SSA:                     registration.SoftwareStatement,
+		Kid:                     wj.config.signingKid,
+		SigningCert:             wj.config.certificateSigning,
+		Issuer:                  wj.config.issuer,
+		RegistrationEndpoint:    registration.RegistrationEndpoint,
+		TokenEndpoint:           wj.config.tokenEndpoint,
+		TokenEndpointAuthMethod: wj.config.tokenEndpointAuthMethod,
+		RedirectURIs:            redirectURIs,
+		TransportSubjectDN:      transportDN,
+		KeepRegistration:        registration.UseCredentials,
+	}
+	httpClient, recorder := wj.httpClient, wj.recorder
+	wj.journeyLock.Unlock()
+
+	// the registration requests are sent without holding the lock, so the journey stays responsive
+	testResults, registered := dcr.Run(config, httpClient, recorder)
+	if !registration.UseCredentials {
+		return testResults, nil
+	}
+	if registered == nil {
+		return testResults, errors.New("journey.RegisterClient: no client registered, the journey config is unchanged")
+	}
+
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+	wj.config.clientID = registered.ClientID
+	wj.config.clientSecret = registered.ClientSecret
+	if wj.config.source != nil {
+		wj.config.source.ClientID = registered.ClientID
+		wj.config.source.ClientSecret = registered.ClientSecret
+	}
+	if err := PutParametersToJourneyContext(wj.config, wj.context); err != nil {
+		return testResults, err
+	}
+	wj.log.WithField("client_id", registered.ClientID).Info("journey config uses the client registered")
+	wj.save()
+	return testResults, nil
+}
+
 // ConditionalProperties retrieve conditional properties right after
 // they have been set from the discovery model to the webJourney.ConditionalProperties
 func (wj *journey) ConditionalProperties() []discovery.ConditionalAPIProperties {
@@ -698,6 +988,24 @@ func DetermineAPIVersions(apis []discovery.ModelDiscoveryItem) []string {
 	return apiversions
 }
 
+// PushedAuthorizationSpecTypes - the spec types of the discovery items of which the authorization
+// requests are pushed to the `pushed_authorization_request_endpoint`
+func PushedAuthorizationSpecTypes(apis []discovery.ModelDiscoveryItem) []string {
+	specTypes := []string{}
+	for _, v := range apis {
+		if !v.PushedAuthRequests {
+			continue
+		}
+		specType, err := manifest.GetSpecType(v.APISpecification.SchemaVersion)
+		if err != nil {
+			logrus.WithError(err).Warnf("no spec type of %s, its authorization requests aren't pushed", v.APISpecification.Name)
+			continue
+		}
+		specTypes = append(specTypes, specType)
+	}
+	return specTypes
+}
+
 var tlsCheck = true
 
 func EnableTLSCheck(state bool) {
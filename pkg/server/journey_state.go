@@ -0,0 +1,310 @@
+package server
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schema"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/schemaprops"
+)
+
+// journeyState - the state of a journey saved in a `Store` after each step, so a restarted
+// server resumes the journey without acquiring the consents again
+type journeyState struct {
+	DiscoveryModel        *discovery.Model                     `json:"discovery_model,omitempty"`
+	ConditionalProperties []discovery.ConditionalAPIProperties `json:"conditional_properties,omitempty"`
+	Config                *GlobalConfiguration                 `json:"config,omitempty"`
+	APIVersion            string                               `json:"api_version,omitempty"`
+	Context               model.Context                        `json:"context"`
+	SpecRun               generation.SpecRun                   `json:"spec_run"`
+	Permissions           map[string][]manifest.RequiredTokens `json:"permissions,omitempty"`
+	ConsentIDs            executors.TokenConsentIDs            `json:"consent_ids,omitempty"`
+	Tokens                map[string]executors.StoredToken     `json:"tokens,omitempty"`
+	TestCasesRunGenerated bool                                 `json:"test_cases_generated"`
+	AllCollected          bool                                 `json:"all_collected"`
+	VaultTokens           bool                                 `json:"vault_tokens,omitempty"` // Tokens of the token vault are used again on restore
+}
+
+// Persist - saves the state of the journey of a session in store after each step, and the results
+// of its runs. The state and results stored of the session are restored first, the next run
+// resumes after the restored results
+func (wj *journey) Persist(store *Store, sessionID string) error {
+	wj.journeyLock.Lock()
+	defer wj.journeyLock.Unlock()
+
+	wj.store = store
+	wj.sessionID = sessionID
+	defer func() {
+		wj.daemonController = wj.newDaemonController()
+	}()
+
+	state, err := store.LoadState(sessionID)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	restored, err := store.Results(sessionID)
+	if err != nil {
+		return err
+	}
+	return wj.restore(state, restored)
+}
+
+// restore - sets the state of the journey to a stored state
+func (wj *journey) restore(state journeyState, restored []results.TestCase) error {
+	if state.Config != nil {
+		config, err := MakeJourneyConfig(state.Config)
+		if err != nil {
+			return errors.Wrap(err, "journey.restore: config")
+		}
+		config.useDynamicResourceID = wj.dynamicResourceIDs
+		config.apiVersion = state.APIVersion
+		wj.config = config
+		if err := wj.openTokenVault(); err != nil {
+			return errors.Wrap(err, "journey.restore: config")
+		}
+	}
+
+	wj.validDiscoveryModel = state.DiscoveryModel
+	wj.conditionalProperties = state.ConditionalProperties
+	wj.context = model.Context{}
+	wj.context.PutContext(&state.Context)
+	if state.Config != nil {
+		httpClient, err := executors.NewHTTPClient(wj.makeRunDefinition())
+		if err != nil {
+			return errors.Wrap(err, "journey.restore: HTTP client")
+		}
+		wj.httpClient = httpClient
+	}
+
+	wj.specRun = restoreSpecRun(wj.log, state.SpecRun)
+	if wj.validDiscoveryModel != nil {
+		items := wj.validDiscoveryModel.DiscoveryModel.DiscoveryItems
+		for k := range items {
+			items[k].APISpecification.SpecType, _ = manifest.GetSpecType(items[k].APISpecification.SchemaVersion)
+		}
+	}
+	wj.permissions = state.Permissions
+	if wj.permissions == nil {
+		wj.permissions = make(map[string][]manifest.RequiredTokens)
+	}
+	wj.testCasesRunGenerated = state.TestCasesRunGenerated
+	wj.allCollected = state.AllCollected
+	wj.restored = state.TestCasesRunGenerated
+
+	if wj.testCasesRunGenerated {
+		// response fields and HTTP exchanges of the consents acquired before the restart are lost
+		wj.propertyCollector = schemaprops.MakeCollector()
+		wj.propertyCollector.SetCollectorAPIDetails(schemaprops.ConsentGathering, "")
+		wj.recorder = har.NewRecorder()
+	}
+	if len(state.ConsentIDs) > 0 {
+		wj.collector = executors.NewTokenCollector(wj.log, state.ConsentIDs, wj.doneCollectionCallback, wj.events)
+	}
+	wj.tokens = executors.NewTokenStore(wj.log, state.Tokens)
+	if state.VaultTokens && !wj.useVaultTokens(wj.log) {
+		return errors.New("journey.restore: the token vault misses tokens used before the restart")
+	}
+	wj.resumeResults = restored
+
+	wj.log.WithFields(logrus.Fields{
+		"session":              wj.sessionID,
+		"testCasesGenerated":   wj.testCasesRunGenerated,
+		"allCollected":         wj.allCollected,
+		"completedTestResults": len(restored),
+	}).Info("journey restored")
+	return nil
+}
+
+// save - saves the state of the journey when persisted, see `Persist`. The journey lock must be held
+func (wj *journey) save() {
+	if wj.store == nil {
+		return
+	}
+
+	state := journeyState{
+		DiscoveryModel:        wj.validDiscoveryModel,
+		ConditionalProperties: wj.conditionalProperties,
+		Config:                wj.config.source,
+		APIVersion:            wj.config.apiVersion,
+		Context:               persistentContext(wj.context),
+		SpecRun:               persistentSpecRun(wj.specRun),
+		Permissions:           wj.permissions,
+		TestCasesRunGenerated: wj.testCasesRunGenerated,
+		AllCollected:          wj.allCollected,
+		Tokens:                wj.tokens.Tokens(),
+		VaultTokens:           len(wj.vaultTokens) > 0,
+	}
+	// tokens of the token vault are never saved, they're taken from the vault again on restore
+	for _, name := range wj.vaultTokens {
+		delete(state.Context, name)
+		delete(state.Tokens, name)
+		if name == "Token001" {
+			delete(state.Context, "access_token")
+		}
+	}
+	if wj.collector != nil {
+		state.ConsentIDs = wj.collector.Tokens()
+	}
+	if err := wj.store.SaveState(wj.sessionID, state); err != nil {
+		wj.log.WithError(err).WithField("session", wj.sessionID).Error("saving journey state")
+	}
+}
+
+// newDaemonController - a daemon controller holding the results restored for the next run,
+// saving the results of the run when the journey is persisted
+func (wj *journey) newDaemonController() executors.DaemonController {
+	controller := executors.NewRestoredDaemonController(wj.resumeResults)
+	if wj.store == nil {
+		return controller
+	}
+	return persistentDaemonController{
+		DaemonController: controller,
+		store:            wj.store,
+		sessionID:        wj.sessionID,
+		log:              wj.log,
+	}
+}
+
+// pendingSpecRun - the test cases of a spec run to run again to resume it, those without a result yet
+// and those of their chains, as the context values put by completed test cases aren't saved.
+// Also returns the completed results kept, those of test cases not run again
+func pendingSpecRun(specRun generation.SpecRun, completed []results.TestCase) (generation.SpecRun, []results.TestCase) {
+	done := map[results.ResultKey]map[string]bool{}
+	for _, result := range completed {
+		key := results.ResultKey{APIName: result.API, APIVersion: result.APIVersion}
+		if done[key] == nil {
+			done[key] = map[string]bool{}
+		}
+		done[key][result.Id] = true
+	}
+	isDone := func(testCase model.TestCase) bool {
+		return done[results.ResultKey{APIName: testCase.APIName, APIVersion: testCase.APIVersion}][testCase.ID]
+	}
+
+	// specifications run one after the other sharing the run context, chains span them
+	all := []model.TestCase{}
+	for _, spec := range specRun.SpecTestCases {
+		all = append(all, spec.TestCases...)
+	}
+	resumed := executors.ResumedTestCases(all, isDone)
+
+	pending := generation.SpecRun{SpecConsentRequirements: specRun.SpecConsentRequirements}
+	rerun := map[results.ResultKey]map[string]bool{}
+	i := 0
+	for _, spec := range specRun.SpecTestCases {
+		testCases := []model.TestCase{}
+		for _, testCase := range spec.TestCases {
+			if resumed[i] {
+				testCases = append(testCases, testCase)
+				key := results.ResultKey{APIName: testCase.APIName, APIVersion: testCase.APIVersion}
+				if rerun[key] == nil {
+					rerun[key] = map[string]bool{}
+				}
+				rerun[key][testCase.ID] = true
+			}
+			i++
+		}
+		pending.SpecTestCases = append(pending.SpecTestCases, generation.SpecificationTestCases{
+			Specification: spec.Specification,
+			TestCases:     testCases,
+		})
+	}
+
+	kept := []results.TestCase{}
+	for _, result := range completed {
+		if !rerun[results.ResultKey{APIName: result.API, APIVersion: result.APIVersion}][result.Id] {
+			kept = append(kept, result)
+		}
+	}
+	return pending, kept
+}
+
+// persistentDaemonController - a daemon controller saving each result to a store
+type persistentDaemonController struct {
+	executors.DaemonController
+	store     *Store
+	sessionID string
+	log       *logrus.Entry
+}
+
+// AddResult - saves the result before sending it, so a restart never sends it again
+func (c persistentDaemonController) AddResult(result results.TestCase) {
+	if err := c.store.AppendResult(c.sessionID, result); err != nil {
+		c.log.WithError(err).WithField("session", c.sessionID).Error("saving test case result")
+	}
+	c.DaemonController.AddResult(result)
+}
+
+// persistentContext - the values of a context that can be saved and restored, e.g.: strings,
+// numbers and string slices
+func persistentContext(ctx model.Context) model.Context {
+	persistent := model.Context{}
+	for key, value := range ctx {
+		switch v := value.(type) {
+		case string, bool, int, float64:
+			persistent[key] = v
+		case []interface{}:
+			if isStringSlice(v) {
+				persistent[key] = v
+			}
+		}
+	}
+	return persistent
+}
+
+func isStringSlice(values []interface{}) bool {
+	for _, value := range values {
+		if _, ok := value.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// persistentSpecRun - a copy of a spec run whose test case contexts can be saved and restored
+func persistentSpecRun(specRun generation.SpecRun) generation.SpecRun {
+	persistent := generation.SpecRun{SpecConsentRequirements: specRun.SpecConsentRequirements}
+	for _, spec := range specRun.SpecTestCases {
+		testCases := make([]model.TestCase, len(spec.TestCases))
+		for i, testCase := range spec.TestCases {
+			testCase.Context = persistentContext(testCase.Context)
+			testCases[i] = testCase
+		}
+		persistent.SpecTestCases = append(persistent.SpecTestCases, generation.SpecificationTestCases{
+			Specification: spec.Specification,
+			TestCases:     testCases,
+		})
+	}
+	return persistent
+}
+
+// restoreSpecRun - sets what isn't saved of a spec run: spec types and swagger validators
+func restoreSpecRun(logger *logrus.Entry, specRun generation.SpecRun) generation.SpecRun {
+	for i, spec := range specRun.SpecTestCases {
+		apiSpec := spec.Specification
+		apiSpec.SpecType, _ = manifest.GetSpecType(apiSpec.SchemaVersion)
+		specRun.SpecTestCases[i].Specification = apiSpec
+
+		validator, err := schema.NewSwaggerOBSpecValidator(apiSpec.Name, apiSpec.Version)
+		if err != nil {
+			logger.WithError(err).Warnf("restoring swagger validator of %s", apiSpec.Name)
+			validator = schema.NewNullValidator()
+		}
+		for j := range spec.TestCases {
+			spec.TestCases[j].Validator = validator
+		}
+	}
+	return specRun
+}
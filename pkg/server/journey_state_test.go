@@ -0,0 +1,204 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/vault"
+)
+
+const testAPIName = "Account and Transaction API Specification"
+
+func testSpecRun() generation.SpecRun {
+	return generation.SpecRun{
+		SpecTestCases: []generation.SpecificationTestCases{
+			{
+				Specification: discovery.ModelAPISpecification{Name: testAPIName, Version: "v3.1"},
+				TestCases: []model.TestCase{
+					{ID: "#t1001", APIName: testAPIName, APIVersion: "v3.1"},
+					{ID: "#t1002", APIName: testAPIName, APIVersion: "v3.1"},
+					{ID: "#t1003", APIName: testAPIName, APIVersion: "v3.1"},
+				},
+			},
+		},
+	}
+}
+
+func TestPendingSpecRun(t *testing.T) {
+	completed := []results.TestCase{
+		{Id: "#t1001", API: testAPIName, APIVersion: "v3.1"},
+		{Id: "#t1003", API: testAPIName, APIVersion: "v3.0"},
+	}
+
+	pending, kept := pendingSpecRun(testSpecRun(), completed)
+
+	require.Len(t, pending.SpecTestCases, 1)
+	assert.Equal(t, []string{"#t1002", "#t1003"}, testCaseIDs(pending.SpecTestCases[0].TestCases), "results of another API version don't complete a test case")
+	assert.Equal(t, completed, kept)
+}
+
+// chainSpecRun - a spec run of a producer putting `PaymentId` into the context, its consumer and an
+// independent test case
+func chainSpecRun() generation.SpecRun {
+	specRun := testSpecRun()
+	testCases := specRun.SpecTestCases[0].TestCases
+	testCases[0].Input = model.Input{Method: "POST", Endpoint: "/domestic-payments"}
+	testCases[0].Expect = model.Expect{ContextPut: model.ContextAccessor{
+		Matches: []model.Match{{ContextName: "PaymentId", JSON: "Data.DomesticPaymentId"}},
+	}}
+	testCases[1].Input = model.Input{Method: "GET", Endpoint: "/domestic-payments/$PaymentId"}
+	testCases[2].Input = model.Input{Method: "GET", Endpoint: "/accounts"}
+	return specRun
+}
+
+func testCaseIDs(testCases []model.TestCase) []string {
+	ids := []string{}
+	for _, testCase := range testCases {
+		ids = append(ids, testCase.ID)
+	}
+	return ids
+}
+
+func TestPendingSpecRun_RunsChainAgain(t *testing.T) {
+	producer := results.TestCase{Id: "#t1001", Pass: true, API: testAPIName, APIVersion: "v3.1"}
+	independent := results.TestCase{Id: "#t1003", Pass: true, API: testAPIName, APIVersion: "v3.1"}
+	other := results.TestCase{Id: "#par001", Pass: true, API: "Pushed Authorization Requests", APIVersion: "v1.0"}
+
+	pending, kept := pendingSpecRun(chainSpecRun(), []results.TestCase{producer, independent, other})
+
+	require.Len(t, pending.SpecTestCases, 1)
+	assert.Equal(t, []string{"#t1001", "#t1002"}, testCaseIDs(pending.SpecTestCases[0].TestCases), "the producer runs again to put the value its consumer uses")
+	assert.Equal(t, []results.TestCase{independent, other}, kept)
+}
+
+func TestJourneyPersist_RestoresAfterRestart(t *testing.T) {
+	store := newTestStore(t)
+
+	before := testJourney().(*journey)
+	require.NoError(t, before.Persist(store, "alice"))
+	before.validDiscoveryModel = &discovery.Model{}
+	before.specRun = testSpecRun()
+	before.testCasesRunGenerated = true
+	before.allCollected = true
+	before.context.PutString("client_id", "abc")
+	before.save()
+	result := results.TestCase{Id: "#t1001", Pass: true, API: testAPIName, APIVersion: "v3.1"}
+	before.daemonController.AddResult(result)
+	assert.Equal(t, result, <-before.daemonController.Results())
+
+	after := testJourney().(*journey)
+	require.NoError(t, after.Persist(store, "alice"))
+
+	assert.True(t, after.testCasesRunGenerated)
+	assert.True(t, after.allCollected)
+	clientID, err := after.context.GetString("client_id")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", clientID)
+	specRun, err := after.TestCases()
+	require.NoError(t, err, "test cases generated before the restart")
+	require.Len(t, specRun.SpecTestCases, 1)
+	assert.Len(t, specRun.SpecTestCases[0].TestCases, 3)
+	assert.NotNil(t, specRun.SpecTestCases[0].TestCases[0].Validator)
+
+	require.Len(t, after.daemonController.AllResults(), 1)
+	assert.Equal(t, "#t1001", after.daemonController.AllResults()[0].Id)
+	select {
+	case <-after.daemonController.Results():
+		assert.Fail(t, "restored result sent again")
+	case <-time.After(time.Millisecond):
+	}
+	pending, _ := pendingSpecRun(after.specRun, after.resumeResults)
+	assert.Len(t, pending.SpecTestCases[0].TestCases, 2)
+}
+
+func TestJourneyPersist_ResumesChainCutBetweenProducerAndConsumer(t *testing.T) {
+	store := newTestStore(t)
+
+	before := testJourney().(*journey)
+	require.NoError(t, before.Persist(store, "alice"))
+	before.validDiscoveryModel = &discovery.Model{}
+	before.specRun = chainSpecRun()
+	before.testCasesRunGenerated = true
+	before.allCollected = true
+	before.save()
+	// the producer completes, the restart comes before its consumer runs
+	before.daemonController.AddResult(results.TestCase{Id: "#t1001", Pass: false, API: testAPIName, APIVersion: "v3.1"})
+	before.daemonController.AddResult(results.TestCase{Id: "#t1003", Pass: true, API: testAPIName, APIVersion: "v3.1"})
+
+	after := testJourney().(*journey)
+	require.NoError(t, after.Persist(store, "alice"))
+	specRun, err := after.TestCases()
+	require.NoError(t, err)
+	pending, kept := pendingSpecRun(specRun, after.resumeResults)
+	assert.Equal(t, []string{"#t1001", "#t1002"}, testCaseIDs(pending.SpecTestCases[0].TestCases))
+	require.NoError(t, store.ReplaceResults("alice", kept))
+
+	// the chain runs again, the producer's result replaces the restored one
+	after.daemonController.AddResult(results.TestCase{Id: "#t1001", Pass: true, API: testAPIName, APIVersion: "v3.1"})
+	after.daemonController.AddResult(results.TestCase{Id: "#t1002", Pass: true, API: testAPIName, APIVersion: "v3.1"})
+	all := after.daemonController.AllResults()
+	require.Len(t, all, 3)
+	assert.Equal(t, "#t1001", all[0].Id)
+	assert.True(t, all[0].Pass)
+	grouped := after.daemonController.AllResultsGrouped()[results.ResultKey{APIName: testAPIName, APIVersion: "v3.1"}]
+	assert.Len(t, grouped, 3)
+
+	stored, err := store.Results("alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"#t1003", "#t1001", "#t1002"}, resultIDs(stored))
+}
+
+func resultIDs(testCases []results.TestCase) []string {
+	ids := []string{}
+	for _, testCase := range testCases {
+		ids = append(ids, testCase.Id)
+	}
+	return ids
+}
+
+func TestJourneyPersist_VaultTokensNotSaved(t *testing.T) {
+	store := newTestStore(t)
+	tokenVault := &vault.Vault{Tokens: []vault.Token{
+		{Name: "accountToken0001", AccessToken: "access-1", RefreshToken: "refresh-1"},
+		{Name: "accountToken0002", AccessToken: "access-2"},
+	}}
+	before := vaultJourney(tokenVault)
+	require.NoError(t, before.Persist(store, "alice"))
+	require.True(t, before.useVaultTokens(nullLogger()))
+	before.context.PutString("client_id", "abc")
+	before.save()
+
+	state, err := store.LoadState("alice")
+	require.NoError(t, err)
+	assert.True(t, state.VaultTokens)
+	assert.Equal(t, "abc", state.Context["client_id"])
+	assert.NotContains(t, state.Context, "accountToken0001")
+	assert.Empty(t, state.Tokens)
+
+	after := vaultJourney(tokenVault)
+	require.NoError(t, after.restore(state, nil))
+	accessToken, err := after.context.GetString("accountToken0001")
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", accessToken, "taken from the vault again")
+	assert.Equal(t, "refresh-1", after.tokens.Tokens()["accountToken0001"].RefreshToken)
+
+	withoutVault := vaultJourney(nil)
+	assert.EqualError(t, withoutVault.restore(state, nil), "journey.restore: the token vault misses tokens used before the restart")
+}
+
+func TestJourneyPersist_NothingStored(t *testing.T) {
+	store := newTestStore(t)
+	journey := testJourney().(*journey)
+
+	require.NoError(t, journey.Persist(store, "alice"))
+
+	assert.False(t, journey.testCasesRunGenerated)
+	assert.Empty(t, journey.daemonController.AllResults())
+}
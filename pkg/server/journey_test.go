@@ -3,14 +3,17 @@ package server
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery/mocks"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/server/models"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/vault"
 
 	gmocks "bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
 	"github.com/pkg/errors"
@@ -161,3 +164,88 @@ func TestJourneySetConfig(t *testing.T) {
 	require.NoError(journey.SetConfig(config))
 	require.Equal(config, journey.config)
 }
+
+// vaultJourney - a journey of two account test cases requiring the tokens of tokenVault
+func vaultJourney(tokenVault *vault.Vault) *journey {
+	journey := NewJourney(nullLogger(), &gmocks.MockGenerator{}, &mocks.Validator{}, discovery.NewNullTLSValidator(), false)
+	journey.config.tokenVault = tokenVault
+	journey.permissions = map[string][]manifest.RequiredTokens{
+		"accounts": {
+			{Name: "accountToken0001", IDs: []string{"#t1001"}, Perms: []string{"ReadAccountsBasic"}},
+			{Name: "accountToken0002", IDs: []string{"#t1002"}, Perms: []string{"ReadTransactionsDetail", "ReadTransactionsCredits"}},
+		},
+	}
+	journey.specRun = generation.SpecRun{
+		SpecTestCases: []generation.SpecificationTestCases{
+			{TestCases: []model.TestCase{{ID: "#t1001"}, {ID: "#t1002"}}},
+		},
+	}
+	return journey
+}
+
+func TestJourneyUseVaultTokens(t *testing.T) {
+	require := test.NewRequire(t)
+	expiry := time.Now().Add(time.Hour)
+	journey := vaultJourney(&vault.Vault{Tokens: []vault.Token{
+		{Name: "accountToken0001", AccessToken: "access-1", RefreshToken: "refresh-1", ExpiresAt: expiry},
+		{Permissions: []string{"ReadTransactionsCredits", "ReadTransactionsDetail", "ReadTransactionsDebits"}, AccessToken: "access-2"},
+	}})
+
+	require.True(journey.useVaultTokens(nullLogger()))
+
+	require.True(journey.allCollected)
+	for name, accessToken := range map[string]string{"accountToken0001": "access-1", "accountToken0002": "access-2"} {
+		value, err := journey.context.GetString(name)
+		require.NoError(err)
+		require.Equal(accessToken, value)
+	}
+	testCases := journey.specRun.SpecTestCases[0].TestCases
+	require.Equal("Bearer $accountToken0001", testCases[0].Input.Headers["Authorization"])
+	require.Equal("Bearer $accountToken0002", testCases[1].Input.Headers["Authorization"])
+	stored := journey.tokens.Tokens()["accountToken0001"]
+	require.Equal("refresh-1", stored.RefreshToken)
+	require.Equal(expiry, stored.Expiry)
+}
+
+func TestJourneyUseVaultTokensMissingToken(t *testing.T) {
+	require := test.NewRequire(t)
+	journey := vaultJourney(&vault.Vault{Tokens: []vault.Token{
+		{Name: "accountToken0001", AccessToken: "access-1"},
+	}})
+
+	require.False(journey.useVaultTokens(nullLogger()))
+	require.False(journey.allCollected)
+	_, err := journey.context.GetString("accountToken0001")
+	require.Error(err, "no token is used unless the vault satisfies all")
+
+	require.False(vaultJourney(nil).useVaultTokens(nullLogger()))
+}
+
+func TestJourneyOpenTokenVault(t *testing.T) {
+	require := test.NewRequire(t)
+	sealed, err := vault.Encrypt(vault.Vault{Tokens: []vault.Token{{Name: "accountToken0001", AccessToken: "access-1"}}}, "correct horse")
+	require.NoError(err)
+	journey := vaultJourney(nil)
+	journey.config.source = &GlobalConfiguration{TokenVault: string(sealed)}
+
+	require.EqualError(journey.openTokenVault(), "error with token_vault: the server has no token vault passphrase")
+	journey.SetTokenVaultPassphrase("wrong")
+	require.EqualError(journey.openTokenVault(), "error with token_vault: "+vault.ErrWrongPassphrase.Error())
+	require.Nil(journey.config.tokenVault)
+
+	journey.SetTokenVaultPassphrase("correct horse")
+	require.NoError(journey.openTokenVault())
+	require.Equal([]string{"accountToken0001"}, journey.config.tokenVault.Names())
+}
+
+func TestPushedAuthorizationSpecTypes(t *testing.T) {
+	require := require.New(t)
+
+	items := []discovery.ModelDiscoveryItem{
+		{APISpecification: discovery.ModelAPISpecification{Name: "Account and Transaction API Specification", SchemaVersion: "https://raw.githubusercontent.com/OpenBankingUK/read-write-api-specs/v3.1.0/dist/account-info-swagger.json"}, PushedAuthRequests: true},
+		{APISpecification: discovery.ModelAPISpecification{Name: "Payment Initiation API", SchemaVersion: "https://raw.githubusercontent.com/OpenBankingUK/read-write-api-specs/v3.1.0/dist/payment-initiation-swagger.json"}},
+		{APISpecification: discovery.ModelAPISpecification{Name: "Unknown API", SchemaVersion: "https://example.com/unknown-swagger.json"}, PushedAuthRequests: true},
+	}
+	require.Equal([]string{"accounts"}, PushedAuthorizationSpecTypes(items))
+	require.Empty(PushedAuthorizationSpecTypes(items[1:2]))
+}
@@ -8,6 +8,7 @@ import executors "bitbucket.org/openbankingteam/conformance-suite/pkg/executors"
 import generation "bitbucket.org/openbankingteam/conformance-suite/pkg/generation"
 import manifest "bitbucket.org/openbankingteam/conformance-suite/pkg/manifest"
 import mock "github.com/stretchr/testify/mock"
+import results "bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
 
 // MockJourney is an autogenerated mock type for the Journey type
 type MockJourney struct {
@@ -121,6 +122,29 @@ func (_m *MockJourney) NewDaemonController() {
 	_m.Called()
 }
 
+// RegisterClient provides a mock function with given fields: registration
+func (_m *MockJourney) RegisterClient(registration DynamicClientRegistration) ([]results.TestCase, error) {
+	ret := _m.Called(registration)
+
+	var r0 []results.TestCase
+	if rf, ok := ret.Get(0).(func(DynamicClientRegistration) []results.TestCase); ok {
+		r0 = rf(registration)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]results.TestCase)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(DynamicClientRegistration) error); ok {
+		r1 = rf(registration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Results provides a mock function with given fields:
 func (_m *MockJourney) Results() executors.DaemonController {
 	ret := _m.Called()
@@ -214,6 +238,20 @@ func (_m *MockJourney) TLSVersionResult() map[string]*discovery.TLSValidationRes
 	return r0
 }
 
+// TokenEndpointAuthMethod provides a mock function with given fields:
+func (_m *MockJourney) TokenEndpointAuthMethod() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // TestCases provides a mock function with given fields:
 func (_m *MockJourney) TestCases() (generation.SpecRun, error) {
 	ret := _m.Called()
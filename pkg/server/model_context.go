@@ -3,9 +3,7 @@ package server
 import (
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/authentication"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/model"
-	"bitbucket.org/openbankingteam/conformance-suite/pkg/version"
 	"github.com/sirupsen/logrus"
-	"gopkg.in/resty.v1"
 )
 
 const (
@@ -18,6 +16,8 @@ const (
 	CtxConstFapiCustomerIPAddress          = "x-fapi-customer-ip-address"
 	CtxConstRedirectURL                    = "redirect_url"
 	CtxConstAuthorisationEndpoint          = "authorisation_endpoint"
+	CtxPushedAuthorizationEndpoint         = "pushed_authorization_request_endpoint"
+	CtxPushedAuthorizationSpecTypes        = "pushedAuthorizationSpecTypes" // Spec types of which the authorization requests are pushed
 	CtxConstBasicAuthentication            = "basic_authentication"
 	CtxConstResourceBaseURL                = "resource_server"
 	CtxConstIssuer                         = "issuer"
@@ -44,6 +44,7 @@ const (
 	CtxRequestObjectSigningAlg             = "requestObjectSigningAlg"
 	CtxSigningPrivate                      = "signingPrivate"
 	CtxSigningPublic                       = "signingPublic"
+	CtxSigningSigner                       = "signingSigner"
 	CtxPhase                               = "phase"
 	CtxNonOBDirectory                      = "nonOBDirectory"
 	CtxSigningKid                          = "signingKid"
@@ -56,6 +57,11 @@ const (
 func PutParametersToJourneyContext(config JourneyConfig, context model.Context) error {
 	config.apiVersion = "v3.1"
 
+	// the headless consent script values don't override the configuration values put below
+	for key, value := range config.headlessParameters {
+		context.PutString(key, value)
+	}
+
 	context.PutString(CtxConstClientID, config.clientID)
 	context.PutString(CtxConstClientSecret, config.clientSecret)
 	context.PutString(CtxConstTokenEndpoint, config.tokenEndpoint)
@@ -65,6 +71,7 @@ func PutParametersToJourneyContext(config JourneyConfig, context model.Context)
 	context.PutString(CtxConstFapiCustomerIPAddress, config.xXFAPICustomerIPAddress)
 	context.PutString(CtxConstRedirectURL, config.redirectURL)
 	context.PutString(CtxConstAuthorisationEndpoint, config.authorizationEndpoint)
+	context.PutString(CtxPushedAuthorizationEndpoint, config.pushedAuthorizationEndpoint)
 	context.PutString(CtxConstResourceBaseURL, config.resourceBaseURL)
 	context.PutString(CtxAPIVersion, config.apiVersion)
 	context.PutString(CtxConsentedAccountID, config.resourceIDs.AccountIDs[0].AccountID)
@@ -87,6 +94,7 @@ func PutParametersToJourneyContext(config JourneyConfig, context model.Context)
 	context.PutString(CtxRequestObjectSigningAlg, config.requestObjectSigningAlgorithm)
 	context.PutString(CtxSigningPrivate, config.signingPrivate)
 	context.PutString(CtxSigningPublic, config.signingPublic)
+	context.PutString(CtxSigningSigner, config.signingSigner)
 	context.PutString(CtxTransactionFromDate, config.transactionFromDate)
 	context.PutString(CtxTransactionToDate, config.transactionToDate)
 	context.Put(CtxNonOBDirectory, config.useNonOBDirectory)
@@ -108,13 +116,6 @@ func PutParametersToJourneyContext(config JourneyConfig, context model.Context)
 		context.Delete(CtxStatementID)
 	}
 
-	_, ou, cn, err := config.certificateTransport.DN()
-	if err == nil && cn != "" && ou != "" {
-		resty.SetHeader("User-Agent", "OpenBankingFCS/"+version.NewBitBucket("").GetHumanVersion()+"/"+ou+"/"+cn)
-	} else {
-		resty.SetHeader("User-Agent", "OpenBankingFCS/"+version.NewBitBucket("").GetHumanVersion())
-	}
-
 	logrus.Tracef("TokenEndpoint auth method %s", config.tokenEndpointAuthMethod)
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/discovery"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/events"
 	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/har"
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
@@ -76,6 +77,8 @@ type ExportResults struct {
 	Tokens           []events.AcquiredAccessToken              `json:"tokens"`
 	DiscoveryModel   discovery.Model                           `json:"discovery_model"`
 	ResponseFields   string                                    `json:"-"`
+	Exchanges        har.HAR                                   `json:"-"`
 	TLSVersionResult map[string]*discovery.TLSValidationResult `json:"-"`
 	JWSStatus        string                                    `json:"jws_status"`
+	AuthMethod       string                                    `json:"token_endpoint_auth_method"`
 }
@@ -48,14 +48,14 @@ type AuthClaim struct {
 }
 
 type redirectHandlers struct {
-	journey Journey
-	logger  *logrus.Entry
+	sessions *Sessions
+	logger   *logrus.Entry
 }
 
-func newRedirectHandlers(journey Journey, logger *logrus.Entry) redirectHandlers {
+func newRedirectHandlers(sessions *Sessions, logger *logrus.Entry) redirectHandlers {
 	return redirectHandlers{
-		journey: journey,
-		logger:  logger.WithField("module", "redirectHandlers"),
+		sessions: sessions,
+		logger:   logger.WithField("module", "redirectHandlers"),
 	}
 }
 
@@ -75,7 +75,7 @@ func (h redirectHandlers) postFragmentOKHandler(c echo.Context) error {
 	// (Nothing to validate)
 	if fragment.IDToken == "" {
 		if fragment.Code != "" {
-			err := h.handleCodeExchange(fragment.Code, fragment.State, fragment.Scope)
+			err := h.handleCodeExchange(c, fragment.Code, fragment.State, fragment.Scope)
 			if err != nil {
 				resp := NewErrorResponse(errors.Wrap(err, "unable to handle redirect"))
 				return c.JSON(http.StatusBadRequest, resp)
@@ -85,7 +85,7 @@ func (h redirectHandlers) postFragmentOKHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, errors.New("code not set"))
 	}
 
-	err := h.handleCodeExchange(fragment.Code, fragment.State, fragment.Scope)
+	err := h.handleCodeExchange(c, fragment.Code, fragment.State, fragment.Scope)
 	if err != nil {
 		resp := NewErrorResponse(errors.Wrap(err, "unable to handle redirect"))
 		return c.JSON(http.StatusBadRequest, resp)
@@ -135,7 +135,7 @@ func (h redirectHandlers) postQueryOKHandler(c echo.Context) error {
 	// (Nothing to validate)
 	if query.IDToken == "" {
 		if query.Code != "" {
-			err := h.handleCodeExchange(query.Code, query.State, query.Scope)
+			err := h.handleCodeExchange(c, query.Code, query.State, query.Scope)
 			if err != nil {
 				resp := NewErrorResponse(errors.Wrap(err, "unable to handle redirect"))
 				return c.JSON(http.StatusBadRequest, resp)
@@ -145,7 +145,7 @@ func (h redirectHandlers) postQueryOKHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, errors.New("code not set"))
 	}
 
-	err := h.handleCodeExchange(query.Code, query.State, query.Scope)
+	err := h.handleCodeExchange(c, query.Code, query.State, query.Scope)
 	if err != nil {
 		resp := NewErrorResponse(errors.Wrap(err, "unable to handle redirect"))
 		return c.JSON(http.StatusBadRequest, resp)
@@ -179,14 +179,14 @@ func (h redirectHandlers) postQueryOKHandler(c echo.Context) error {
 	// return c.JSON(http.StatusBadRequest, resp)
 }
 
-func (h redirectHandlers) handleCodeExchange(code string, state string, scope string) error {
+func (h redirectHandlers) handleCodeExchange(c echo.Context, code string, state string, scope string) error {
 	h.logger.WithFields(logrus.Fields{
 		"function": "handleCodeExchange",
 		"code":     code,
 		"state":    state,
 		"scope":    scope,
-	}).Info("h.journey.CollectToken ...")
-	return h.journey.CollectToken(code, state, scope)
+	}).Info("journey.CollectToken ...")
+	return h.sessions.journeyOf(c).CollectToken(code, state, scope)
 }
 
 // postErrorHandler - POST /api/redirect/error
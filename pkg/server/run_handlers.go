@@ -24,14 +24,14 @@ const (
 )
 
 type runHandlers struct {
-	journey  Journey
+	sessions *Sessions
 	upgrader *websocket.Upgrader
 	logger   *logrus.Entry
 }
 
-func newRunHandlers(journey Journey, upgrader *websocket.Upgrader, logger *logrus.Entry) runHandlers {
+func newRunHandlers(sessions *Sessions, upgrader *websocket.Upgrader, logger *logrus.Entry) runHandlers {
 	return runHandlers{
-		journey:  journey,
+		sessions: sessions,
 		upgrader: upgrader,
 		logger:   logger,
 	}
@@ -39,7 +39,7 @@ func newRunHandlers(journey Journey, upgrader *websocket.Upgrader, logger *logru
 
 // runStartPostHandler creates a new test run
 func (h runHandlers) runStartPostHandler(c echo.Context) error {
-	err := h.journey.RunTests()
+	err := h.sessions.journeyOf(c).RunTests()
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
@@ -70,8 +70,9 @@ func (h runHandlers) listenResultWebSocket(c echo.Context) error {
 	logger.Debug("client connected")
 
 	pingTicker := time.NewTicker(pingFrequency)
-	daemon := h.journey.Results()
-	events := h.journey.Events()
+	journey := h.sessions.journeyOf(c)
+	daemon := journey.Results()
+	events := journey.Events()
 	for {
 		if h.shouldStop(daemon, ws, logger) {
 			break
@@ -98,6 +99,10 @@ func (h runHandlers) listenResultWebSocket(c echo.Context) error {
 			if err := h.processAcquiredAllAccessTokensEvent(ws, logger, event, ok); err != nil {
 				break
 			}
+		case event, ok := <-events.RefreshedTokensChannel():
+			if err := h.processRefreshedAccessTokenEvent(ws, logger, event, ok); err != nil {
+				break
+			}
 		}
 	}
 
@@ -140,7 +145,7 @@ func (h runHandlers) doSendPingMessage(ws *websocket.Conn, logger *logrus.Entry)
 
 // stopHandler sends signal to stop running test
 func (h runHandlers) stopRunHandler(c echo.Context) error {
-	h.journey.StopTestRun()
+	h.sessions.journeyOf(c).StopTestRun()
 	return nil
 }
 
@@ -225,6 +230,27 @@ func (h runHandlers) processAcquiredAllAccessTokensEvent(ws *websocket.Conn, log
 	return nil
 }
 
+func (h runHandlers) processRefreshedAccessTokenEvent(ws *websocket.Conn, logger *logrus.Entry, event events.RefreshedAccessToken, ok bool) error {
+	if !ok {
+		err := errors.New("error reading from events.RefreshedTokens channel")
+		logger.Error(err)
+		return err
+	}
+
+	wsEvent := newRefreshedAccessTokenWebSocketEvent(event)
+	logger.WithFields(logrus.Fields{
+		"wsEvent.Type":    wsEvent.Type,
+		"event.TokenName": event.TokenName,
+		"event.Error":     event.Error,
+	}).Info("sending event")
+	if err := ws.WriteJSON(wsEvent); err != nil {
+		logger.WithError(err).Error("[processRefreshedAccessTokenEvent] writing json to websocket")
+		return err
+	}
+
+	return nil
+}
+
 type StoppedEvent struct {
 	Stopped bool `json:"stopped"`
 }
@@ -282,3 +308,15 @@ func newAcquiredAllAccessTokensWebSocketEvent(event events.AcquiredAllAccessToke
 		Value: event,
 	}
 }
+
+type RefreshedAccessTokenWebSocketEvent struct {
+	Type  string                      `json:"type"`
+	Value events.RefreshedAccessToken `json:"value"`
+}
+
+func newRefreshedAccessTokenWebSocketEvent(event events.RefreshedAccessToken) RefreshedAccessTokenWebSocketEvent {
+	return RefreshedAccessTokenWebSocketEvent{
+		Type:  "ResultType_RefreshedAccessToken",
+		Value: event,
+	}
+}
@@ -23,8 +23,19 @@ type Server struct {
 	version    version.Checker
 }
 
-// NewServer returns new echo.Echo server.
+// NewServer returns new echo.Echo server, every request shares the journey.
 func NewServer(journey Journey, logger *logrus.Entry, version version.Checker) *Server {
+	sessions := NewSessions(nil, 0)
+	sessions.add("", journey)
+	return newServer(sessions, logger, version)
+}
+
+// NewSessionServer returns new echo.Echo server, requests use the journey of their session, see `Sessions.middleware`.
+func NewSessionServer(sessions *Sessions, logger *logrus.Entry, version version.Checker) *Server {
+	return newServer(sessions, logger, version, sessions.middleware)
+}
+
+func newServer(sessions *Sessions, logger *logrus.Entry, version version.Checker, apiMiddleware ...echo.MiddlewareFunc) *Server {
 	server := &Server{
 		Echo:    echo.New(),
 		logger:  logger,
@@ -59,58 +70,70 @@ func NewServer(journey Journey, logger *logrus.Entry, version version.Checker) *
 		Browse:  false,
 	}))
 
-	registerRoutes(journey, server, logger, version)
+	registerRoutes(sessions, server, logger, version, apiMiddleware...)
 
 	return server
 }
 
-func registerRoutes(journey Journey, server *Server, logger *logrus.Entry, version version.Checker) {
+func registerRoutes(sessions *Sessions, server *Server, logger *logrus.Entry, version version.Checker, apiMiddleware ...echo.MiddlewareFunc) {
 	// swagger ui endpoints
 	for path, handler := range swaggerHandlers(logger) {
 		server.GET(path, handler)
 	}
 
 	// anything prefixed with api
-	api := server.Group("/api")
+	api := server.Group("/api", apiMiddleware...)
 
 	api.GET("/ping", func(c echo.Context) error { return nil })
 
-	importHandlers := newImportHandlers(journey, logger)
+	importHandlers := newImportHandlers(sessions, logger)
 	api.POST("/import/review", importHandlers.postImportReview)
 	api.POST("/import/rerun", importHandlers.postImportRerun)
 
-	configHandlers := newConfigHandlers(journey, logger)
+	configHandlers := newConfigHandlers(sessions, logger)
 	// endpoint to post global configuration
 	api.POST("/config/global", configHandlers.configGlobalPostHandler)
 	api.GET("/config/conditional-property", configHandlers.configConditionalPropertyHandler)
 
+	// endpoint to register the client of the configuration by dynamic client registration
+	dcrHandlers := newDCRHandlers(sessions, logger)
+	api.POST("/dcr", dcrHandlers.postDCRHandler)
+
 	// endpoints for discovery model
-	discoveryHandlers := newDiscoveryHandlers(journey, logger)
+	discoveryHandlers := newDiscoveryHandlers(sessions, logger)
 	api.POST("/discovery-model", discoveryHandlers.setDiscoveryModelHandler)
 
 	// endpoints for test cases
-	testCaseHandlers := newTestCaseHandlers(journey, NewWebSocketUpgrader(), logger)
+	testCaseHandlers := newTestCaseHandlers(sessions, NewWebSocketUpgrader(), logger)
 	api.GET("/test-cases", testCaseHandlers.testCasesHandler)
 
 	// endpoints for test runner
-	runHandlers := newRunHandlers(journey, NewWebSocketUpgrader(), logger)
+	runHandlers := newRunHandlers(sessions, NewWebSocketUpgrader(), logger)
 	api.POST("/run", runHandlers.runStartPostHandler)
 	api.GET("/run/ws", runHandlers.listenResultWebSocket)
 	api.DELETE("/run", runHandlers.stopRunHandler)
 
 	// endpoints for validating and storing the token retrieved in `/conformancesuite/callback`
 	// `pkg/server/assets/main.js` calls into this endpoint.
-	redirectHandlers := newRedirectHandlers(journey, logger)
+	redirectHandlers := newRedirectHandlers(sessions, logger)
 	api.POST("/redirect/fragment/ok", redirectHandlers.postFragmentOKHandler)
 	api.POST("/redirect/query/ok", redirectHandlers.postQueryOKHandler)
 	api.POST("/redirect/error", redirectHandlers.postErrorHandler)
 
-	exportHandlers := newExportHandlers(journey, logger)
+	exportHandlers := newExportHandlers(sessions, logger)
 	api.POST("/export", exportHandlers.postExport)
+	api.GET("/export/coverage", exportHandlers.getCoverage)
 
 	// endpoints for utility function such as version/update checking.
 	utilityEndpoints := newUtilityEndpoints(version)
 	api.GET("/version", utilityEndpoints.versionCheck)
+
+	// endpoints for the session of a user sharing the server
+	sessionHandlers := newSessionHandlers(sessions)
+	api.GET("/session", sessionHandlers.getSessionHandler)
+	api.DELETE("/session", sessionHandlers.deleteSessionHandler)
+	// listing the sessions is for the operator of the server, it doesn't start a session
+	server.GET("/api/sessions", sessionHandlers.listSessionsHandler)
 }
 
 // skipperSwagger - ensures that all requests not prefixed with any string in `pathsToSkip` is skipped.
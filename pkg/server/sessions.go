@@ -0,0 +1,320 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// SessionHeader - header of the session ID of a request, takes precedence over `SessionCookie`
+	SessionHeader = "X-Fcs-Session-Id"
+	// SessionCookie - cookie of the session ID of a request, set on the first request of a session
+	SessionCookie = "fcs_session"
+	// DefaultSessionIdleTimeout - time after the last request of a session after which it expires
+	DefaultSessionIdleTimeout = 8 * time.Hour
+	// sessionKey - key of the session in the echo context of a request
+	sessionKey = "session"
+)
+
+var (
+	errSessionNotFound  = errors.New("session not found")
+	errNotOperator      = errors.New("listing sessions requires the operator token of the server")
+	errInvalidSessionID = errors.New("invalid session ID, expected 1 to 64 letters, digits, '-' or '_'")
+
+	sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// Session - a user journey of the server, see `Sessions`
+type Session struct {
+	ID       string    `json:"id"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+	journey  Journey
+}
+
+// Sessions - the journeys of the users of a server keyed by session ID, so users
+// sharing a server don't clobber each other's discovery model, config, tokens and results.
+// Session IDs are issued by the server, and sessions expire once idle for the idle timeout
+type Sessions struct {
+	newJourney    func(id string) Journey
+	idleTimeout   time.Duration
+	operatorToken string
+	store         *Store
+	lock          *sync.Mutex
+	sessions      map[string]*Session
+}
+
+// NewSessions - sessions whose journeys are created by newJourney when a session starts or is
+// restored, and expiring after idleTimeout without requests, never when zero
+func NewSessions(newJourney func(id string) Journey, idleTimeout time.Duration) *Sessions {
+	return &Sessions{
+		newJourney:  newJourney,
+		idleTimeout: idleTimeout,
+		lock:        &sync.Mutex{},
+		sessions:    map[string]*Session{},
+	}
+}
+
+// SetOperatorToken - the bearer token of the operator of the server listing its sessions,
+// see `sessionHandlers.listSessionsHandler`. Listing is disabled when empty
+func (s *Sessions) SetOperatorToken(token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.operatorToken = token
+}
+
+// SessionActivity - the ID and last activity of a session, nothing of its journey
+type SessionActivity struct {
+	ID       string    `json:"id"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// Activity - the activity of the sessions, ordered by ID
+func (s *Sessions) Activity() []SessionActivity {
+	s.expire()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	activity := make([]SessionActivity, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		activity = append(activity, SessionActivity{ID: session.ID, LastUsed: session.LastUsed})
+	}
+	sort.Slice(activity, func(i, j int) bool { return activity[i].ID < activity[j].ID })
+	return activity
+}
+
+// isOperator - whether authorization is the bearer operator token, never when there's none
+func (s *Sessions) isOperator(authorization string) bool {
+	s.lock.Lock()
+	token := s.operatorToken
+	s.lock.Unlock()
+
+	bearer := strings.TrimPrefix(authorization, "Bearer ")
+	if token == "" || bearer == authorization {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1
+}
+
+// Create - starts a session of a new ID and journey
+func (s *Sessions) Create() Session {
+	session := s.create()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return *session
+}
+
+// create - starts a session of a new ID, its journey created without holding the lock
+func (s *Sessions) create() *Session {
+	id := uuid.New().String()
+	journey := s.newJourney(id)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.add(id, journey)
+}
+
+// add - adds the session id of journey, the lock held
+func (s *Sessions) add(id string, journey Journey) *Session {
+	now := time.Now()
+	session := &Session{ID: id, Created: now, LastUsed: now, journey: journey}
+	s.sessions[id] = session
+	return session
+}
+
+// Journey - the journey of the session id, false when there's no such session
+func (s *Sessions) Journey(id string) (Journey, bool) {
+	s.expire()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	session.LastUsed = time.Now()
+	return session.journey, true
+}
+
+// Restore - restores the sessions with a journey in store, see `journey.Persist`.
+// Sessions deleted or expired are removed from store
+func (s *Sessions) Restore(store *Store) error {
+	ids, err := store.SessionIDs()
+	if err != nil {
+		return errors.Wrap(err, "server.Sessions.Restore")
+	}
+	journeys := make([]Journey, 0, len(ids))
+	for _, id := range ids {
+		journeys = append(journeys, s.newJourney(id))
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.store = store
+	for i, id := range ids {
+		s.add(id, journeys[i])
+	}
+	return nil
+}
+
+// Delete - stops the test run of a session and removes it, along with what's stored of it
+func (s *Sessions) Delete(id string) error {
+	s.lock.Lock()
+	session, ok := s.sessions[id]
+	delete(s.sessions, id)
+	store := s.store
+	s.lock.Unlock()
+
+	if !ok {
+		return errSessionNotFound
+	}
+	return s.remove(session, store)
+}
+
+// expire - deletes the sessions idle for longer than the idle timeout
+func (s *Sessions) expire() {
+	if s.idleTimeout <= 0 {
+		return
+	}
+	s.lock.Lock()
+	expired := []*Session{}
+	for id, session := range s.sessions {
+		if time.Since(session.LastUsed) > s.idleTimeout {
+			expired = append(expired, session)
+			delete(s.sessions, id)
+		}
+	}
+	store := s.store
+	s.lock.Unlock()
+
+	for _, session := range expired {
+		if err := s.remove(session, store); err != nil {
+			logrus.WithError(err).WithField("session", session.ID).Warn("removing expired session")
+		}
+	}
+}
+
+// remove - stops the test run of a session removed and deletes what's stored of it
+func (s *Sessions) remove(session *Session, store *Store) error {
+	session.journey.StopTestRun()
+	if store != nil {
+		return store.Delete(session.ID)
+	}
+	return nil
+}
+
+// session - the session of id, started when id is empty or, unless it's of a header, unknown,
+// e.g.: the session of a cookie expired. The bool is true when the session is started
+func (s *Sessions) session(id string, header bool) (*Session, bool, error) {
+	s.expire()
+	s.lock.Lock()
+	session, ok := s.sessions[id]
+	if ok {
+		session.LastUsed = time.Now()
+	}
+	s.lock.Unlock()
+
+	if ok {
+		return session, false, nil
+	}
+	if header {
+		return nil, false, errSessionNotFound
+	}
+	return s.create(), true, nil
+}
+
+// journeyOf - the journey of the session of a request, see `middleware`
+func (s *Sessions) journeyOf(c echo.Context) Journey {
+	if session, ok := c.Get(sessionKey).(*Session); ok {
+		return session.journey
+	}
+	journey, _ := s.Journey("")
+	return journey
+}
+
+// middleware - resolves the session of a request from `SessionHeader` or `SessionCookie`,
+// starting a session in a new cookie when the request has neither or the session of its cookie
+// expired. Session IDs are only issued by the server, a header of an unknown session is refused
+func (s *Sessions) middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := c.Request().Header.Get(SessionHeader)
+		header := id != ""
+		if !header {
+			if cookie, err := c.Cookie(SessionCookie); err == nil {
+				id = cookie.Value
+			}
+		}
+		if id != "" && !sessionIDPattern.MatchString(id) {
+			return c.JSON(http.StatusBadRequest, NewErrorResponse(errInvalidSessionID))
+		}
+		session, started, err := s.session(id, header)
+		if err != nil {
+			return c.JSON(http.StatusUnauthorized, NewErrorResponse(err))
+		}
+		if started {
+			c.SetCookie(&http.Cookie{
+				Name:     SessionCookie,
+				Value:    session.ID,
+				Path:     "/",
+				Secure:   true,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		c.Set(sessionKey, session)
+		return next(c)
+	}
+}
+
+// sessionHandlers - endpoints of the session of a request
+type sessionHandlers struct {
+	sessions *Sessions
+}
+
+func newSessionHandlers(sessions *Sessions) sessionHandlers {
+	return sessionHandlers{
+		sessions: sessions,
+	}
+}
+
+// GET /api/session
+func (h sessionHandlers) getSessionHandler(c echo.Context) error {
+	session, ok := c.Get(sessionKey).(*Session)
+	if !ok {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(errSessionNotFound))
+	}
+	h.sessions.lock.Lock()
+	response := *session
+	h.sessions.lock.Unlock()
+	return c.JSON(http.StatusOK, response)
+}
+
+// GET /api/sessions
+// Operator only, outside of the sessions of users, see `Sessions.SetOperatorToken`
+func (h sessionHandlers) listSessionsHandler(c echo.Context) error {
+	if !h.sessions.isOperator(c.Request().Header.Get(echo.HeaderAuthorization)) {
+		return c.JSON(http.StatusForbidden, NewErrorResponse(errNotOperator))
+	}
+	return c.JSON(http.StatusOK, h.sessions.Activity())
+}
+
+// DELETE /api/session
+func (h sessionHandlers) deleteSessionHandler(c echo.Context) error {
+	session, ok := c.Get(sessionKey).(*Session)
+	if !ok {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(errSessionNotFound))
+	}
+	if err := h.sessions.Delete(session.ID); err != nil {
+		return c.JSON(http.StatusNotFound, NewErrorResponse(err))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
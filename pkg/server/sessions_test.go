@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+	versionmock "bitbucket.org/openbankingteam/conformance-suite/pkg/version/mocks"
+)
+
+// sessionRequest - makes a test request of a session, by header when header is true or else by cookie
+func sessionRequest(method, path, session string, header bool, server *Server) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if header {
+		req.Header.Set(SessionHeader, session)
+	} else {
+		req.AddCookie(&http.Cookie{Name: SessionCookie, Value: session})
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+func newTestSessionServer(t *testing.T) (*Server, *Sessions, *int) {
+	created := 0
+	sessions := NewSessions(func(string) Journey {
+		created++
+		return testJourney()
+	}, time.Hour)
+	server := NewSessionServer(sessions, nullLogger(), &versionmock.Version{})
+	t.Cleanup(func() {
+		require.NoError(t, server.Shutdown(context.TODO()))
+	})
+	return server, sessions, &created
+}
+
+func TestSessionServer_JourneyPerSession(t *testing.T) {
+	server, sessions, created := newTestSessionServer(t)
+	alice, bob := sessions.Create(), sessions.Create()
+
+	for _, session := range []string{alice.ID, bob.ID, alice.ID} {
+		rec := sessionRequest(http.MethodGet, "/api/config/conditional-property", session, true, server)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	rec := sessionRequest(http.MethodGet, "/api/config/conditional-property", bob.ID, false, server)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Result().Cookies(), "existing session isn't restarted")
+
+	assert.Equal(t, 2, *created)
+	aliceJourney, _ := sessions.Journey(alice.ID)
+	bobJourney, _ := sessions.Journey(bob.ID)
+	assert.NotSame(t, aliceJourney, bobJourney)
+}
+
+func TestSessionServer_StartsSessionInCookie(t *testing.T) {
+	server, sessions, _ := newTestSessionServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/session", nil)
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, SessionCookie, cookies[0].Name)
+	assert.True(t, cookies[0].HttpOnly)
+	session := Session{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &session))
+	assert.Equal(t, cookies[0].Value, session.ID)
+	_, ok := sessions.Journey(session.ID)
+	assert.True(t, ok)
+}
+
+func TestSessionServer_UnknownSession(t *testing.T) {
+	server, sessions, created := newTestSessionServer(t)
+
+	rec := sessionRequest(http.MethodGet, "/api/config/conditional-property", "alice", true, server)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.JSONEq(t, `{"error": "session not found"}`, rec.Body.String())
+	assert.Equal(t, 0, *created, "session IDs are issued by the server")
+
+	rec = sessionRequest(http.MethodGet, "/api/config/conditional-property", "alice", false, server)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1, "unknown cookie session is restarted")
+	assert.NotEqual(t, "alice", cookies[0].Value)
+	_, ok := sessions.Journey("alice")
+	assert.False(t, ok)
+}
+
+func TestSessionServer_InvalidSessionID(t *testing.T) {
+	server, _, created := newTestSessionServer(t)
+
+	rec := sessionRequest(http.MethodGet, "/api/config/conditional-property", "../etc/passwd", true, server)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error": "invalid session ID, expected 1 to 64 letters, digits, '-' or '_'"}`, rec.Body.String())
+	assert.Equal(t, 0, *created)
+}
+
+func TestSessionServer_DeleteSession(t *testing.T) {
+	require := test.NewRequire(t)
+	server, sessions, _ := newTestSessionServer(t)
+	alice, bob := sessions.Create(), sessions.Create()
+
+	rec := sessionRequest(http.MethodGet, "/api/session", bob.ID, true, server)
+	require.Equal(http.StatusOK, rec.Code)
+	session := Session{}
+	require.NoError(json.Unmarshal(rec.Body.Bytes(), &session))
+	require.Equal(bob.ID, session.ID, "only the session of the request")
+
+	rec = sessionRequest(http.MethodDelete, "/api/session", bob.ID, true, server)
+	require.Equal(http.StatusNoContent, rec.Code)
+	_, ok := sessions.Journey(bob.ID)
+	require.False(ok)
+	_, ok = sessions.Journey(alice.ID)
+	require.True(ok)
+
+	rec = sessionRequest(http.MethodDelete, "/api/session", bob.ID, true, server)
+	require.Equal(http.StatusUnauthorized, rec.Code)
+	require.JSONEq(`{"error": "session not found"}`, rec.Body.String())
+}
+
+func TestSessionServer_ListSessionsOperatorOnly(t *testing.T) {
+	server, sessions, created := newTestSessionServer(t)
+	alice, bob := sessions.Create(), sessions.Create()
+	list := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/sessions", nil)
+		if authorization != "" {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := list("Bearer operator")
+	assert.Equal(t, http.StatusForbidden, rec.Code, "listing is disabled without an operator token")
+	assert.JSONEq(t, `{"error": "listing sessions requires the operator token of the server"}`, rec.Body.String())
+
+	sessions.SetOperatorToken("operator")
+	for _, authorization := range []string{"", "operator", "Bearer other"} {
+		rec = list(authorization)
+		assert.Equal(t, http.StatusForbidden, rec.Code, authorization)
+	}
+
+	rec = list("Bearer operator")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Result().Cookies(), "the operator doesn't start a session")
+	assert.Equal(t, 2, *created)
+	listed := []map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &listed))
+	require.Len(t, listed, 2)
+	ids := []string{}
+	for _, session := range listed {
+		assert.Len(t, session, 2, "only the ID and last activity of a session")
+		assert.Contains(t, session, "last_used")
+		ids = append(ids, session["id"].(string))
+	}
+	assert.ElementsMatch(t, []string{alice.ID, bob.ID}, ids)
+}
+
+func TestSessions_ExpireIdleSessions(t *testing.T) {
+	sessions := NewSessions(func(string) Journey { return testJourney() }, time.Minute)
+	idle, active := sessions.Create(), sessions.Create()
+	sessions.lock.Lock()
+	sessions.sessions[idle.ID].LastUsed = time.Now().Add(-2 * time.Minute)
+	sessions.lock.Unlock()
+
+	_, ok := sessions.Journey(active.ID)
+	assert.True(t, ok)
+	_, ok = sessions.Journey(idle.ID)
+	assert.False(t, ok)
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/vault"
+)
+
+const (
+	stateFilename   = "journey.json"
+	resultsFilename = "results.jsonl"
+)
+
+// Store - persists the journeys of sessions as files of a directory, so a restarted server
+// resumes them. Each session has a directory holding its journey state and the results of its
+// run, one per line. The state holds private keys and access tokens, it's encrypted with the
+// key of the store, as a token vault file, and only readable by its owner
+type Store struct {
+	dir  string
+	key  string
+	lock *sync.Mutex
+}
+
+// NewStore - a store of the directory dir, created when missing, encrypting states with the
+// passphrase key
+func NewStore(dir, key string) (*Store, error) {
+	if key == "" {
+		return nil, errors.New("server.NewStore: empty state key")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.Wrap(err, "server.NewStore: creating directory")
+	}
+	return &Store{
+		dir:  dir,
+		key:  key,
+		lock: &sync.Mutex{},
+	}, nil
+}
+
+// storedResult - a result of a run, with the API it's grouped by in reports
+type storedResult struct {
+	results.TestCase
+	API        string `json:"api"`
+	APIVersion string `json:"api_version"`
+}
+
+// SessionIDs - the sessions with a stored journey, sorted
+func (s *Store) SessionIDs() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "server.Store.SessionIDs")
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !sessionIDPattern.MatchString(entry.Name()) {
+			continue
+		}
+		if _, err := os.Stat(s.path(entry.Name(), stateFilename)); err == nil {
+			ids = append(ids, entry.Name())
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// SaveState - replaces the stored journey state of a session
+func (s *Store) SaveState(id string, state journeyState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(err, "server.Store.SaveState: marshalling state")
+	}
+	data, err = vault.Seal(data, s.key)
+	if err != nil {
+		return errors.Wrap(err, "server.Store.SaveState: encrypting state")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := os.MkdirAll(filepath.Join(s.dir, id), 0700); err != nil {
+		return errors.Wrap(err, "server.Store.SaveState: creating session directory")
+	}
+	// written aside and renamed, a crash while saving keeps the previous state
+	tmp := s.path(id, stateFilename+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "server.Store.SaveState: writing state")
+	}
+	return errors.Wrap(os.Rename(tmp, s.path(id, stateFilename)), "server.Store.SaveState: renaming state")
+}
+
+// LoadState - the stored journey state of a session, an `os.IsNotExist` error when none
+func (s *Store) LoadState(id string) (journeyState, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	state := journeyState{}
+	data, err := ioutil.ReadFile(s.path(id, stateFilename))
+	if err != nil {
+		return state, err
+	}
+	if data, err = vault.Open(data, s.key); err != nil {
+		return state, errors.Wrap(err, "server.Store.LoadState: decrypting state")
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, errors.Wrap(err, "server.Store.LoadState: unmarshalling state")
+	}
+	return state, nil
+}
+
+// AppendResult - stores a result of the run of a session
+func (s *Store) AppendResult(id string, result results.TestCase) error {
+	data, err := json.Marshal(storedResult{TestCase: result, API: result.API, APIVersion: result.APIVersion})
+	if err != nil {
+		return errors.Wrap(err, "server.Store.AppendResult: marshalling result")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := os.MkdirAll(filepath.Join(s.dir, id), 0700); err != nil {
+		return errors.Wrap(err, "server.Store.AppendResult: creating session directory")
+	}
+	file, err := os.OpenFile(s.path(id, resultsFilename), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return errors.Wrap(err, "server.Store.AppendResult: opening results")
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		return errors.Wrap(err, "server.Store.AppendResult: writing result")
+	}
+	return errors.Wrap(file.Close(), "server.Store.AppendResult: closing results")
+}
+
+// Results - the stored results of the run of a session, in the order they were appended.
+// A line truncated by a crash is ignored
+func (s *Store) Results(id string) ([]results.TestCase, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	stored := []results.TestCase{}
+	file, err := os.Open(s.path(id, resultsFilename))
+	if os.IsNotExist(err) {
+		return stored, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "server.Store.Results: opening results")
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		result := storedResult{}
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			continue
+		}
+		result.TestCase.API = result.API
+		result.TestCase.APIVersion = result.APIVersion
+		stored = append(stored, result.TestCase)
+	}
+	return stored, errors.Wrap(scanner.Err(), "server.Store.Results: reading results")
+}
+
+// ReplaceResults - replaces the stored results of the run of a session, e.g.: with those kept
+// when resuming a run
+func (s *Store) ReplaceResults(id string, replaced []results.TestCase) error {
+	data := []byte{}
+	for _, result := range replaced {
+		line, err := json.Marshal(storedResult{TestCase: result, API: result.API, APIVersion: result.APIVersion})
+		if err != nil {
+			return errors.Wrap(err, "server.Store.ReplaceResults: marshalling result")
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := os.MkdirAll(filepath.Join(s.dir, id), 0700); err != nil {
+		return errors.Wrap(err, "server.Store.ReplaceResults: creating session directory")
+	}
+	tmp := s.path(id, resultsFilename+".tmp")
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.Wrap(err, "server.Store.ReplaceResults: writing results")
+	}
+	return errors.Wrap(os.Rename(tmp, s.path(id, resultsFilename)), "server.Store.ReplaceResults: replacing results")
+}
+
+// ClearResults - removes the stored results of the run of a session, before a new run
+func (s *Store) ClearResults(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := os.Remove(s.path(id, resultsFilename))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "server.Store.ClearResults")
+	}
+	return nil
+}
+
+// Delete - removes everything stored of a session
+func (s *Store) Delete(id string) error {
+	if !sessionIDPattern.MatchString(id) {
+		return errInvalidSessionID
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return errors.Wrap(os.RemoveAll(filepath.Join(s.dir, id)), "server.Store.Delete")
+}
+
+func (s *Store) path(id, filename string) string {
+	return filepath.Join(s.dir, id, filename)
+}
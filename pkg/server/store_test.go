@@ -0,0 +1,104 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/executors/results"
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/vault"
+)
+
+func newTestStore(t *testing.T) *Store {
+	store, err := NewStore(filepath.Join(t.TempDir(), "state"), "state key")
+	require.NoError(t, err)
+	return store
+}
+
+func TestStore_SaveAndLoadState(t *testing.T) {
+	store := newTestStore(t)
+
+	_, err := store.LoadState("alice")
+	assert.True(t, os.IsNotExist(err))
+
+	state := journeyState{APIVersion: "v3.1", TestCasesRunGenerated: true, Context: map[string]interface{}{"client_id": "abc"}}
+	require.NoError(t, store.SaveState("alice", state))
+	loaded, err := store.LoadState("alice")
+	require.NoError(t, err)
+	assert.Equal(t, state, loaded)
+
+	info, err := os.Stat(filepath.Join(store.dir, "alice", stateFilename))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	data, err := ioutil.ReadFile(filepath.Join(store.dir, "alice", stateFilename))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "client_id", "the state is encrypted")
+
+	other, err := NewStore(store.dir, "other key")
+	require.NoError(t, err)
+	_, err = other.LoadState("alice")
+	assert.EqualError(t, err, "server.Store.LoadState: decrypting state: "+vault.ErrWrongPassphrase.Error())
+
+	_, err = NewStore(store.dir, "")
+	assert.EqualError(t, err, "server.NewStore: empty state key")
+}
+
+func TestStore_Results(t *testing.T) {
+	store := newTestStore(t)
+
+	stored, err := store.Results("alice")
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+
+	first := results.TestCase{Id: "#t1001", Pass: true, API: "Account and Transaction API Specification", APIVersion: "v3.1"}
+	second := results.TestCase{Id: "#t2001", Fail: []string{"status code"}, API: "Payment Initiation API", APIVersion: "v3.1"}
+	require.NoError(t, store.AppendResult("alice", first))
+	require.NoError(t, store.AppendResult("alice", second))
+
+	// a line truncated by a crash
+	file, err := os.OpenFile(filepath.Join(store.dir, "alice", resultsFilename), os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"id": "#t30`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	stored, err = store.Results("alice")
+	require.NoError(t, err)
+	require.Len(t, stored, 2)
+	assert.Equal(t, first.Id, stored[0].Id)
+	assert.Equal(t, first.API, stored[0].API)
+	assert.Equal(t, first.APIVersion, stored[0].APIVersion)
+	assert.Equal(t, second.Fail, stored[1].Fail)
+	assert.Equal(t, second.API, stored[1].API)
+
+	require.NoError(t, store.ClearResults("alice"))
+	require.NoError(t, store.ClearResults("alice"), "clearing twice")
+	stored, err = store.Results("alice")
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
+
+func TestStore_SessionIDsAndDelete(t *testing.T) {
+	store := newTestStore(t)
+	require.NoError(t, store.SaveState("bob", journeyState{}))
+	require.NoError(t, store.SaveState("alice", journeyState{}))
+	require.NoError(t, store.AppendResult("carol", results.TestCase{Id: "#t1001"}))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(store.dir, "not-a-session"), nil, 0600))
+
+	ids, err := store.SessionIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, ids, "sessions without a state aren't listed")
+
+	require.NoError(t, store.Delete("alice"))
+	ids, err = store.SessionIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, ids)
+
+	assert.Equal(t, errInvalidSessionID, store.Delete(".."))
+	_, err = os.Stat(store.dir)
+	assert.NoError(t, err)
+}
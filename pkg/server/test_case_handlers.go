@@ -9,22 +9,23 @@ import (
 )
 
 type testCaseHandlers struct {
-	journey  Journey
+	sessions *Sessions
 	upgrader *websocket.Upgrader
 	logger   *logrus.Entry
 }
 
-func newTestCaseHandlers(journey Journey, upgrader *websocket.Upgrader, logger *logrus.Entry) testCaseHandlers {
+func newTestCaseHandlers(sessions *Sessions, upgrader *websocket.Upgrader, logger *logrus.Entry) testCaseHandlers {
 	return testCaseHandlers{
-		journey:  journey,
+		sessions: sessions,
 		upgrader: upgrader,
 		logger:   logger,
 	}
 }
 
 func (d testCaseHandlers) testCasesHandler(c echo.Context) error {
-	d.journey.NewDaemonController() // fix for not sending events to correct websocket after a websocket reconnect
-	testCases, err := d.journey.TestCases()
+	journey := d.sessions.journeyOf(c)
+	journey.NewDaemonController() // fix for not sending events to correct websocket after a websocket reconnect
+	testCases, err := journey.TestCases()
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, NewErrorResponse(err))
 	}
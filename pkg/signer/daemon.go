@@ -0,0 +1,161 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The signing daemon protocol: for each signature the suite connects to the Unix socket of the
+// daemon, writes a JSON daemonRequest and reads a JSON daemonResponse, then closes the connection.
+// Digests are signed as crypto.Signer signs them: RSA keys sign with PKCS1v15 or PSS padding, EC
+// keys return ASN.1 DER signatures.
+
+// daemonTimeout - timeout of a signature of a signing daemon
+const daemonTimeout = 10 * time.Second
+
+const (
+	paddingPKCS1v15 = "PKCS1v15"
+	paddingPSS      = "PSS"
+)
+
+// daemonRequest - a signing request of the signing daemon protocol
+type daemonRequest struct {
+	Key        string `json:"key"`
+	Digest     []byte `json:"digest"`                // base64 encoded
+	Hash       string `json:"hash"`                  // hash of the digest, e.g.: SHA-256
+	Padding    string `json:"padding,omitempty"`     // RSA keys: PKCS1v15 or PSS
+	SaltLength int    `json:"salt_length,omitempty"` // PSS padding
+}
+
+// daemonResponse - the signature of a daemonRequest, or why it isn't signed
+type daemonResponse struct {
+	Signature []byte `json:"signature,omitempty"` // base64 encoded
+	Error     string `json:"error,omitempty"`
+}
+
+// daemonSigner - a signer of a key of a signing daemon listening on a Unix socket
+type daemonSigner struct {
+	socket    string
+	key       string
+	publicKey crypto.PublicKey
+}
+
+// newDaemonSigner - the signer of a daemon:<key name> uri, of the daemon listening on socket
+func newDaemonSigner(socket string, uri *url.URL, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	if uri.Opaque == "" {
+		return nil, errors.New("signing daemon key name missing")
+	}
+	return daemonSigner{socket: socket, key: uri.Opaque, publicKey: publicKey}, nil
+}
+
+func (s daemonSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign - digest signed by the signing daemon
+func (s daemonSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	request := daemonRequest{Key: s.key, Digest: digest, Hash: opts.HashFunc().String()}
+	if _, ok := s.publicKey.(*rsa.PublicKey); ok {
+		request.Padding = paddingPKCS1v15
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			request.Padding = paddingPSS
+			request.SaltLength = saltLength(pss)
+		}
+	}
+
+	conn, err := net.DialTimeout("unix", s.socket, daemonTimeout)
+	if err != nil {
+		return nil, errors.Wrap(err, "signer: connecting to signing daemon")
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(daemonTimeout)); err != nil {
+		return nil, errors.Wrap(err, "signer: signing daemon")
+	}
+	if err := json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, errors.Wrap(err, "signer: writing signing daemon request")
+	}
+	response := daemonResponse{}
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, errors.Wrap(err, "signer: reading signing daemon response")
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("signer: signing daemon: %s", response.Error)
+	}
+	return response.Signature, nil
+}
+
+// saltLength - the PSS salt length of opts, the length of the hash unless set
+func saltLength(opts *rsa.PSSOptions) int {
+	if opts.SaltLength > 0 {
+		return opts.SaltLength
+	}
+	return opts.HashFunc().Size()
+}
+
+// Serve - serves the signing daemon protocol on listener with keys by name, until listener fails or
+// is closed. It is the reference implementation of the protocol, used by the `signing-daemon` command
+func Serve(listener net.Listener, keys map[string]crypto.Signer) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go serveConn(conn, keys)
+	}
+}
+
+// serveConn - signs the request of a connection
+func serveConn(conn net.Conn, keys map[string]crypto.Signer) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(daemonTimeout))
+	response := daemonResponse{}
+	request := daemonRequest{}
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		response.Error = "invalid request: " + err.Error()
+	} else if signature, err := request.sign(keys); err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Signature = signature
+	}
+	_ = json.NewEncoder(conn).Encode(response)
+}
+
+// sign - the signature of the request by its key
+func (r daemonRequest) sign(keys map[string]crypto.Signer) ([]byte, error) {
+	key, ok := keys[r.Key]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", r.Key)
+	}
+	hash, ok := hashes[r.Hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported hash %q", r.Hash)
+	}
+	if len(r.Digest) != hash.Size() {
+		return nil, fmt.Errorf("invalid %s digest length %d", r.Hash, len(r.Digest))
+	}
+	var opts crypto.SignerOpts = hash
+	switch r.Padding {
+	case paddingPSS:
+		opts = &rsa.PSSOptions{SaltLength: r.SaltLength, Hash: hash}
+	case "", paddingPKCS1v15:
+	default:
+		return nil, fmt.Errorf("unsupported padding %q", r.Padding)
+	}
+	return key.Sign(rand.Reader, r.Digest, opts)
+}
+
+// hashes - the hashes of signed digests by name
+var hashes = map[string]crypto.Hash{
+	crypto.SHA256.String(): crypto.SHA256,
+	crypto.SHA384.String(): crypto.SHA384,
+	crypto.SHA512.String(): crypto.SHA512,
+}
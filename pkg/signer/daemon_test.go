@@ -0,0 +1,85 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"net"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+)
+
+// serveDaemon - a signing daemon of keys on a socket of a temporary directory, closed when t ends
+func serveDaemon(t *testing.T, keys map[string]crypto.Signer) string {
+	socket := filepath.Join(t.TempDir(), "signing.sock")
+	listener, err := net.Listen("unix", socket)
+	test.NewRequire(t).NoError(err)
+	t.Cleanup(func() { listener.Close() })
+	go Serve(listener, keys) // nolint:errcheck
+	return socket
+}
+
+func daemonSignerOf(t *testing.T, socket, key string, publicKey crypto.PublicKey) crypto.Signer {
+	s, err := newDaemonSigner(socket, &url.URL{Scheme: schemeDaemon, Opaque: key}, publicKey)
+	test.NewRequire(t).NoError(err)
+	return s
+}
+
+func TestDaemonSignerSigns(t *testing.T) {
+	require := test.NewRequire(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	socket := serveDaemon(t, map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey})
+	digest := sha256.Sum256([]byte("signed"))
+
+	rsaSigner := daemonSignerOf(t, socket, "rsa", &rsaKey.PublicKey)
+	pss := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	signature, err := rsaSigner.Sign(rand.Reader, digest[:], pss)
+	require.NoError(err)
+	require.NoError(rsa.VerifyPSS(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature, pss))
+
+	signature, err = rsaSigner.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(err)
+	require.NoError(rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, digest[:], signature))
+
+	ecSigner := daemonSignerOf(t, socket, "ec", &ecKey.PublicKey)
+	signature, err = ecSigner.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(err)
+	require.True(ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], signature))
+	require.Equal(&ecKey.PublicKey, ecSigner.Public())
+}
+
+func TestDaemonSignerUnknownKey(t *testing.T) {
+	require := test.NewRequire(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	socket := serveDaemon(t, map[string]crypto.Signer{"ec": ecKey})
+	digest := sha256.Sum256([]byte("signed"))
+
+	_, err = daemonSignerOf(t, socket, "other", &ecKey.PublicKey).Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.EqualError(err, `signer: signing daemon: unknown key "other"`)
+
+	_, err = daemonSignerOf(t, socket, "ec", &ecKey.PublicKey).Sign(rand.Reader, digest[:4], crypto.SHA256)
+	require.EqualError(err, "signer: signing daemon: invalid SHA-256 digest length 4")
+}
+
+func TestDaemonSignerDaemonUnavailable(t *testing.T) {
+	require := test.NewRequire(t)
+
+	socket := filepath.Join(t.TempDir(), "missing.sock")
+	digest := sha256.Sum256([]byte("signed"))
+
+	_, err := daemonSignerOf(t, socket, "ec", nil).Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.Error(err)
+	require.Contains(err.Error(), "signer: connecting to signing daemon")
+}
@@ -0,0 +1,365 @@
+//go:build cgo
+// +build cgo
+
+package signer
+
+/*
+#cgo linux LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+#include <string.h>
+
+typedef unsigned long CK_ULONG;
+typedef CK_ULONG CK_RV;
+
+typedef struct { unsigned char major; unsigned char minor; } CK_VERSION;
+typedef struct { CK_ULONG type; void *pValue; CK_ULONG ulValueLen; } CK_ATTRIBUTE;
+typedef struct { CK_ULONG mechanism; void *pParameter; CK_ULONG ulParameterLen; } CK_MECHANISM;
+typedef struct { CK_ULONG hashAlg; CK_ULONG mgf; CK_ULONG sLen; } CK_RSA_PKCS_PSS_PARAMS;
+
+typedef struct {
+	void *CreateMutex;
+	void *DestroyMutex;
+	void *LockMutex;
+	void *UnlockMutex;
+	CK_ULONG flags;
+	void *pReserved;
+} CK_C_INITIALIZE_ARGS;
+
+typedef struct {
+	unsigned char label[32];
+	unsigned char manufacturerID[32];
+	unsigned char model[16];
+	unsigned char serialNumber[16];
+	CK_ULONG flags;
+	CK_ULONG counts[10];
+	CK_VERSION hardwareVersion;
+	CK_VERSION firmwareVersion;
+	unsigned char utcTime[16];
+} CK_TOKEN_INFO;
+
+// CK_FUNCTION_LIST - the function list of PKCS#11 v2.x modules, up to C_Sign as the rest isn't used
+typedef struct {
+	CK_VERSION version;
+	CK_RV (*C_Initialize)(void *);
+	CK_RV (*C_Finalize)(void *);
+	void *C_GetInfo;
+	void *C_GetFunctionList;
+	CK_RV (*C_GetSlotList)(unsigned char, CK_ULONG *, CK_ULONG *);
+	void *C_GetSlotInfo;
+	CK_RV (*C_GetTokenInfo)(CK_ULONG, CK_TOKEN_INFO *);
+	void *C_GetMechanismList;
+	void *C_GetMechanismInfo;
+	void *C_InitToken;
+	void *C_InitPIN;
+	void *C_SetPIN;
+	CK_RV (*C_OpenSession)(CK_ULONG, CK_ULONG, void *, void *, CK_ULONG *);
+	CK_RV (*C_CloseSession)(CK_ULONG);
+	void *C_CloseAllSessions;
+	void *C_GetSessionInfo;
+	void *C_GetOperationState;
+	void *C_SetOperationState;
+	CK_RV (*C_Login)(CK_ULONG, CK_ULONG, unsigned char *, CK_ULONG);
+	void *C_Logout;
+	void *C_CreateObject;
+	void *C_CopyObject;
+	void *C_DestroyObject;
+	void *C_GetObjectSize;
+	void *C_GetAttributeValue;
+	void *C_SetAttributeValue;
+	CK_RV (*C_FindObjectsInit)(CK_ULONG, CK_ATTRIBUTE *, CK_ULONG);
+	CK_RV (*C_FindObjects)(CK_ULONG, CK_ULONG *, CK_ULONG, CK_ULONG *);
+	CK_RV (*C_FindObjectsFinal)(CK_ULONG);
+	void *C_EncryptInit;
+	void *C_Encrypt;
+	void *C_EncryptUpdate;
+	void *C_EncryptFinal;
+	void *C_DecryptInit;
+	void *C_Decrypt;
+	void *C_DecryptUpdate;
+	void *C_DecryptFinal;
+	void *C_DigestInit;
+	void *C_Digest;
+	void *C_DigestUpdate;
+	void *C_DigestKey;
+	void *C_DigestFinal;
+	CK_RV (*C_SignInit)(CK_ULONG, CK_MECHANISM *, CK_ULONG);
+	CK_RV (*C_Sign)(CK_ULONG, unsigned char *, CK_ULONG, unsigned char *, CK_ULONG *);
+} CK_FUNCTION_LIST;
+
+typedef CK_RV (*CK_C_GetFunctionList)(CK_FUNCTION_LIST **);
+
+#define CKR_OK 0UL
+#define CKR_USER_ALREADY_LOGGED_IN 0x100UL
+#define CKR_CRYPTOKI_ALREADY_INITIALIZED 0x191UL
+#define CKF_OS_LOCKING_OK 0x2UL
+#define CKF_SERIAL_SESSION 0x4UL
+#define CKU_USER 1UL
+#define CKO_PRIVATE_KEY 3UL
+#define CKA_CLASS 0x0UL
+#define CKA_LABEL 0x3UL
+#define CKA_ID 0x102UL
+
+// pkcs11_load - loads the module of path and its function list, the error when it fails
+static const char *pkcs11_load(const char *path, CK_FUNCTION_LIST **functions) {
+	void *module = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (module == NULL) {
+		return dlerror();
+	}
+	CK_C_GetFunctionList getFunctionList = (CK_C_GetFunctionList) dlsym(module, "C_GetFunctionList");
+	if (getFunctionList == NULL) {
+		return "C_GetFunctionList not found";
+	}
+	if (getFunctionList(functions) != CKR_OK) {
+		return "C_GetFunctionList failed";
+	}
+	return NULL;
+}
+
+static CK_RV pkcs11_initialize(CK_FUNCTION_LIST *f) {
+	CK_C_INITIALIZE_ARGS args;
+	memset(&args, 0, sizeof(args));
+	args.flags = CKF_OS_LOCKING_OK;
+	CK_RV rv = f->C_Initialize(&args);
+	return rv == CKR_CRYPTOKI_ALREADY_INITIALIZED ? CKR_OK : rv;
+}
+
+static CK_RV pkcs11_slots(CK_FUNCTION_LIST *f, CK_ULONG *slots, CK_ULONG *count) {
+	return f->C_GetSlotList(1, slots, count);
+}
+
+static CK_RV pkcs11_token_label(CK_FUNCTION_LIST *f, CK_ULONG slot, unsigned char *label) {
+	CK_TOKEN_INFO info;
+	CK_RV rv = f->C_GetTokenInfo(slot, &info);
+	if (rv == CKR_OK) {
+		memcpy(label, info.label, sizeof(info.label));
+	}
+	return rv;
+}
+
+static CK_RV pkcs11_open_session(CK_FUNCTION_LIST *f, CK_ULONG slot, CK_ULONG *session) {
+	return f->C_OpenSession(slot, CKF_SERIAL_SESSION, NULL, NULL, session);
+}
+
+static CK_RV pkcs11_close_session(CK_FUNCTION_LIST *f, CK_ULONG session) {
+	return f->C_CloseSession(session);
+}
+
+static CK_RV pkcs11_login(CK_FUNCTION_LIST *f, CK_ULONG session, unsigned char *pin, CK_ULONG pinLen) {
+	CK_RV rv = f->C_Login(session, CKU_USER, pin, pinLen);
+	return rv == CKR_USER_ALREADY_LOGGED_IN ? CKR_OK : rv;
+}
+
+// pkcs11_find_key - the first private key with the label and id which aren't empty, found is 0 when none
+static CK_RV pkcs11_find_key(CK_FUNCTION_LIST *f, CK_ULONG session, unsigned char *label, CK_ULONG labelLen,
+		unsigned char *id, CK_ULONG idLen, CK_ULONG *key, CK_ULONG *found) {
+	CK_ULONG class = CKO_PRIVATE_KEY;
+	CK_ATTRIBUTE template[3];
+	CK_ULONG count = 0;
+	template[count].type = CKA_CLASS;
+	template[count].pValue = &class;
+	template[count++].ulValueLen = sizeof(class);
+	if (labelLen > 0) {
+		template[count].type = CKA_LABEL;
+		template[count].pValue = label;
+		template[count++].ulValueLen = labelLen;
+	}
+	if (idLen > 0) {
+		template[count].type = CKA_ID;
+		template[count].pValue = id;
+		template[count++].ulValueLen = idLen;
+	}
+	CK_RV rv = f->C_FindObjectsInit(session, template, count);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	rv = f->C_FindObjects(session, key, 1, found);
+	CK_RV final = f->C_FindObjectsFinal(session);
+	return rv != CKR_OK ? rv : final;
+}
+
+// pkcs11_sign - data signed by key with mechanism, and its PSS parameters when hashAlg isn't 0
+static CK_RV pkcs11_sign(CK_FUNCTION_LIST *f, CK_ULONG session, CK_ULONG key, CK_ULONG mechanism,
+		CK_ULONG hashAlg, CK_ULONG mgf, CK_ULONG saltLength, unsigned char *data, CK_ULONG dataLen,
+		unsigned char *signature, CK_ULONG *signatureLen) {
+	CK_RSA_PKCS_PSS_PARAMS params = { hashAlg, mgf, saltLength };
+	CK_MECHANISM m = { mechanism, NULL, 0 };
+	if (hashAlg != 0) {
+		m.pParameter = &params;
+		m.ulParameterLen = sizeof(params);
+	}
+	CK_RV rv = f->C_SignInit(session, &m, key);
+	if (rv != CKR_OK) {
+		return rv;
+	}
+	return f->C_Sign(session, data, dataLen, signature, signatureLen);
+}
+*/
+import "C"
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// maxSignatureLength - the largest signature of a PKCS#11 key, of an RSA 8192 key
+const maxSignatureLength = 1024
+
+// pkcs11Modules - modules loaded and initialised by path, modules can only be initialised
+// once per process. Only used by Open, under signersLock
+var pkcs11Modules = map[string]*C.CK_FUNCTION_LIST{}
+
+// pkcs11Signer - a signer of a private key of a PKCS#11 token
+type pkcs11Signer struct {
+	functions *C.CK_FUNCTION_LIST
+	session   C.CK_ULONG
+	key       C.CK_ULONG
+	publicKey crypto.PublicKey
+	lock      *sync.Mutex // a session signs one digest at a time
+}
+
+// openPKCS11 - the signer of a private key of the module of key, found in a session of the
+// first token with the key
+func openPKCS11(key pkcs11Key, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	functions, err := loadPKCS11Module(key.modulePath)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := pkcs11Slots(functions)
+	if err != nil {
+		return nil, err
+	}
+	for _, slot := range slots {
+		if key.token != "" {
+			label := make([]byte, 32)
+			if rv := C.pkcs11_token_label(functions, slot, (*C.uchar)(unsafe.Pointer(&label[0]))); rv != C.CKR_OK {
+				return nil, pkcs11Error("C_GetTokenInfo", rv)
+			}
+			if strings.TrimRight(string(label), " \x00") != key.token {
+				continue
+			}
+		}
+		signer, found, err := findPKCS11Key(functions, slot, key, publicKey)
+		if err != nil || found {
+			return signer, err
+		}
+	}
+	return nil, fmt.Errorf("PKCS#11 private key object=%q id=%x not found in token %q", key.object, key.id, key.token)
+}
+
+// loadPKCS11Module - the function list of the initialised module of path
+func loadPKCS11Module(path string) (*C.CK_FUNCTION_LIST, error) {
+	if functions, ok := pkcs11Modules[path]; ok {
+		return functions, nil
+	}
+	cPath := C.CString(path)
+	defer C.free(unsafe.Pointer(cPath))
+	var functions *C.CK_FUNCTION_LIST
+	if message := C.pkcs11_load(cPath, &functions); message != nil {
+		return nil, fmt.Errorf("loading PKCS#11 module %s: %s", path, C.GoString(message))
+	}
+	if rv := C.pkcs11_initialize(functions); rv != C.CKR_OK {
+		return nil, pkcs11Error("C_Initialize", rv)
+	}
+	pkcs11Modules[path] = functions
+	return functions, nil
+}
+
+// pkcs11Slots - the slots of a module with a token
+func pkcs11Slots(functions *C.CK_FUNCTION_LIST) ([]C.CK_ULONG, error) {
+	var count C.CK_ULONG
+	if rv := C.pkcs11_slots(functions, nil, &count); rv != C.CKR_OK {
+		return nil, pkcs11Error("C_GetSlotList", rv)
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	slots := make([]C.CK_ULONG, count)
+	if rv := C.pkcs11_slots(functions, &slots[0], &count); rv != C.CKR_OK {
+		return nil, pkcs11Error("C_GetSlotList", rv)
+	}
+	return slots[:count], nil
+}
+
+// findPKCS11Key - the signer of the private key of a session of the token of slot, logged in
+// with the PIN of key. The session is closed when the key isn't found
+func findPKCS11Key(functions *C.CK_FUNCTION_LIST, slot C.CK_ULONG, key pkcs11Key, publicKey crypto.PublicKey) (crypto.Signer, bool, error) {
+	var session C.CK_ULONG
+	if rv := C.pkcs11_open_session(functions, slot, &session); rv != C.CKR_OK {
+		return nil, false, pkcs11Error("C_OpenSession", rv)
+	}
+	if key.pin != "" {
+		pin := []byte(key.pin)
+		if rv := C.pkcs11_login(functions, session, bytesPointer(pin), C.CK_ULONG(len(pin))); rv != C.CKR_OK {
+			C.pkcs11_close_session(functions, session)
+			return nil, false, pkcs11Error("C_Login", rv)
+		}
+	}
+	label := []byte(key.object)
+	var handle, found C.CK_ULONG
+	rv := C.pkcs11_find_key(functions, session, bytesPointer(label), C.CK_ULONG(len(label)),
+		bytesPointer(key.id), C.CK_ULONG(len(key.id)), &handle, &found)
+	if rv != C.CKR_OK || found == 0 {
+		C.pkcs11_close_session(functions, session)
+		if rv != C.CKR_OK {
+			return nil, false, pkcs11Error("C_FindObjects", rv)
+		}
+		return nil, false, nil
+	}
+	return &pkcs11Signer{
+		functions: functions,
+		session:   session,
+		key:       handle,
+		publicKey: publicKey,
+		lock:      &sync.Mutex{},
+	}, true, nil
+}
+
+func (s *pkcs11Signer) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+// Sign - digest signed by the token, as crypto.Signer signs it
+func (s *pkcs11Signer) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	mechanism, err := newPKCS11Mechanism(s.publicKey, digest, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "signer: PKCS#11")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	signature := make([]byte, maxSignatureLength)
+	length := C.CK_ULONG(len(signature))
+	rv := C.pkcs11_sign(s.functions, s.session, s.key, C.CK_ULONG(mechanism.mechanism),
+		C.CK_ULONG(mechanism.hashAlg), C.CK_ULONG(mechanism.mgf), C.CK_ULONG(mechanism.saltLength),
+		bytesPointer(mechanism.data), C.CK_ULONG(len(mechanism.data)), bytesPointer(signature), &length)
+	if rv != C.CKR_OK {
+		return nil, errors.Wrap(pkcs11Error("C_Sign", rv), "signer")
+	}
+	signature = signature[:length]
+
+	if _, ok := s.publicKey.(*ecdsa.PublicKey); ok {
+		return asn1ECSignature(signature)
+	}
+	return signature, nil
+}
+
+// bytesPointer - the C pointer of the bytes of b, nil when empty
+func bytesPointer(b []byte) *C.uchar {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.uchar)(unsafe.Pointer(&b[0]))
+}
+
+// pkcs11Error - the error of a function failing with the return value rv
+func pkcs11Error(function string, rv C.CK_RV) error {
+	return fmt.Errorf("PKCS#11 %s failed: CKR 0x%x", function, uint64(rv))
+}
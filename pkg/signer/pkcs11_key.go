@@ -0,0 +1,136 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/asn1"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PKCS#11 mechanisms and mask generation functions of the signatures of crypto.Signer
+const (
+	ckmRSAPKCS    = 0x1
+	ckmRSAPKCSPSS = 0xd
+	ckmECDSA      = 0x1041
+)
+
+// pkcs11Hashes - the mechanism and MGF1 mask generation function of hashes, for PSS signatures
+var pkcs11Hashes = map[crypto.Hash][2]uint{
+	crypto.SHA256: {0x250, 0x2},
+	crypto.SHA384: {0x260, 0x3},
+	crypto.SHA512: {0x270, 0x4},
+}
+
+// digestInfoPrefixes - the DER prefixes of the DigestInfo of hashes, signed by CKM_RSA_PKCS for
+// PKCS1v15 signatures
+var digestInfoPrefixes = map[crypto.Hash][]byte{
+	crypto.SHA256: {0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20},
+	crypto.SHA384: {0x30, 0x41, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x02, 0x05, 0x00, 0x04, 0x30},
+	crypto.SHA512: {0x30, 0x51, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x03, 0x05, 0x00, 0x04, 0x40},
+}
+
+// pkcs11Key - the private key of a PKCS#11 URI (RFC 7512), of the token of a module
+type pkcs11Key struct {
+	modulePath string // see SetPKCS11
+	token      string // token label, the first token with the key when empty
+	object     string // key label
+	id         []byte // key CKA_ID
+	pin        string // user PIN, no login when empty, see SetPKCS11
+}
+
+// parsePKCS11URI - the key of a pkcs11:token=...;object=...;id=... uri, identified by its label, its
+// id or both. Query attributes, e.g.: module-path or pin-value, aren't accepted, see SetPKCS11
+func parsePKCS11URI(uri *url.URL) (pkcs11Key, error) {
+	if uri.RawQuery != "" {
+		return pkcs11Key{}, errors.New("pkcs11 uri query attributes aren't accepted, the module and PIN are set by the operator of the suite")
+	}
+	key := pkcs11Key{}
+	for _, attribute := range strings.Split(uri.Opaque, ";") {
+		if attribute == "" {
+			continue
+		}
+		parts := strings.SplitN(attribute, "=", 2)
+		if len(parts) != 2 {
+			return pkcs11Key{}, errors.Errorf("invalid pkcs11 uri attribute %q", attribute)
+		}
+		value, err := url.PathUnescape(parts[1])
+		if err != nil {
+			return pkcs11Key{}, errors.Wrapf(err, "invalid pkcs11 uri attribute %q", parts[0])
+		}
+		switch parts[0] {
+		case "token":
+			key.token = value
+		case "object":
+			key.object = value
+		case "id":
+			key.id = []byte(value)
+		case "type":
+			if value != "private" {
+				return pkcs11Key{}, errors.Errorf("pkcs11 uri type %q is not a private key", value)
+			}
+		}
+	}
+	if key.object == "" && len(key.id) == 0 {
+		return pkcs11Key{}, errors.New("pkcs11 uri object or id missing")
+	}
+	return key, nil
+}
+
+// pkcs11Mechanism - a PKCS#11 signing mechanism, with the PSS parameters of PSS signatures
+type pkcs11Mechanism struct {
+	mechanism  uint
+	hashAlg    uint // PSS only
+	mgf        uint // PSS only
+	saltLength uint // PSS only
+	data       []byte
+}
+
+// newPKCS11Mechanism - the mechanism signing digest as crypto.Signer signs it with the private key
+// of publicKey, and the data it signs
+func newPKCS11Mechanism(publicKey crypto.PublicKey, digest []byte, opts crypto.SignerOpts) (pkcs11Mechanism, error) {
+	hash := opts.HashFunc()
+	if len(digest) != hash.Size() {
+		return pkcs11Mechanism{}, errors.Errorf("invalid %s digest length %d", hash, len(digest))
+	}
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey:
+		return pkcs11Mechanism{mechanism: ckmECDSA, data: digest}, nil
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			params, ok := pkcs11Hashes[hash]
+			if !ok {
+				return pkcs11Mechanism{}, errors.Errorf("unsupported hash %s", hash)
+			}
+			return pkcs11Mechanism{
+				mechanism:  ckmRSAPKCSPSS,
+				hashAlg:    params[0],
+				mgf:        params[1],
+				saltLength: uint(saltLength(pss)),
+				data:       digest,
+			}, nil
+		}
+		prefix, ok := digestInfoPrefixes[hash]
+		if !ok {
+			return pkcs11Mechanism{}, errors.Errorf("unsupported hash %s", hash)
+		}
+		return pkcs11Mechanism{mechanism: ckmRSAPKCS, data: append(append([]byte{}, prefix...), digest...)}, nil
+	}
+	return pkcs11Mechanism{}, errors.Errorf("unsupported public key type %T", publicKey)
+}
+
+// asn1ECSignature - the ASN.1 DER encoding of the r || s ECDSA signature of CKM_ECDSA, as
+// crypto.Signer returns it
+func asn1ECSignature(raw []byte) ([]byte, error) {
+	if len(raw) == 0 || len(raw)%2 != 0 {
+		return nil, errors.Errorf("invalid ECDSA signature length %d", len(raw))
+	}
+	half := len(raw) / 2
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{new(big.Int).SetBytes(raw[:half]), new(big.Int).SetBytes(raw[half:])})
+}
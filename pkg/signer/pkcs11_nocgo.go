@@ -0,0 +1,15 @@
+//go:build !cgo
+// +build !cgo
+
+package signer
+
+import (
+	"crypto"
+
+	"github.com/pkg/errors"
+)
+
+// openPKCS11 - PKCS#11 modules are loaded with cgo, which this build is without
+func openPKCS11(key pkcs11Key, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	return nil, errors.New("PKCS#11 signers are not supported by builds without cgo")
+}
@@ -0,0 +1,147 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+)
+
+func TestParsePKCS11URI(t *testing.T) {
+	testCases := []struct {
+		uri         string
+		expectedKey pkcs11Key
+		expectedErr string
+	}{
+		{
+			uri:         "pkcs11:token=fcs;object=signing%20key;type=private",
+			expectedKey: pkcs11Key{token: "fcs", object: "signing key"},
+		},
+		{
+			uri:         "pkcs11:id=%01%02",
+			expectedKey: pkcs11Key{id: []byte{1, 2}},
+		},
+		{uri: "pkcs11:object=signing;type=public", expectedErr: `pkcs11 uri type "public" is not a private key`},
+		{uri: "pkcs11:object", expectedErr: `invalid pkcs11 uri attribute "object"`},
+		{uri: "pkcs11:token=fcs", expectedErr: "pkcs11 uri object or id missing"},
+		{
+			uri:         "pkcs11:object=signing?module-path=/tmp/module.so&pin-value=1234",
+			expectedErr: "pkcs11 uri query attributes aren't accepted, the module and PIN are set by the operator of the suite",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.uri, func(t *testing.T) {
+			require := test.NewRequire(t)
+
+			uri, err := url.Parse(testCase.uri)
+			require.NoError(err)
+			key, err := parsePKCS11URI(uri)
+			if testCase.expectedErr != "" {
+				require.EqualError(err, testCase.expectedErr)
+				return
+			}
+			require.NoError(err)
+			require.Equal(testCase.expectedKey, key)
+		})
+	}
+}
+
+func TestNewPKCS11Mechanism(t *testing.T) {
+	require := test.NewRequire(t)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	digest := sha256.Sum256([]byte("signed"))
+
+	mechanism, err := newPKCS11Mechanism(&rsaKey.PublicKey, digest[:], &rsa.PSSOptions{Hash: crypto.SHA256})
+	require.NoError(err)
+	require.Equal(pkcs11Mechanism{mechanism: ckmRSAPKCSPSS, hashAlg: 0x250, mgf: 0x2, saltLength: 32, data: digest[:]}, mechanism)
+
+	mechanism, err = newPKCS11Mechanism(&rsaKey.PublicKey, digest[:], crypto.SHA256)
+	require.NoError(err)
+	require.Equal(uint(ckmRSAPKCS), mechanism.mechanism)
+	require.Equal(append(digestInfoPrefixes[crypto.SHA256], digest[:]...), mechanism.data)
+
+	mechanism, err = newPKCS11Mechanism(&ecKey.PublicKey, digest[:], crypto.SHA256)
+	require.NoError(err)
+	require.Equal(pkcs11Mechanism{mechanism: ckmECDSA, data: digest[:]}, mechanism)
+
+	_, err = newPKCS11Mechanism(&ecKey.PublicKey, digest[:16], crypto.SHA256)
+	require.EqualError(err, "invalid SHA-256 digest length 16")
+}
+
+func TestASN1ECSignature(t *testing.T) {
+	require := test.NewRequire(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	digest := sha256.Sum256([]byte("signed"))
+	r, s, err := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+	require.NoError(err)
+	raw := make([]byte, 64)
+	r.FillBytes(raw[:32])
+	s.FillBytes(raw[32:])
+
+	signature, err := asn1ECSignature(raw)
+	require.NoError(err)
+	require.True(ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], signature))
+
+	_, err = asn1ECSignature(raw[:63])
+	require.EqualError(err, "invalid ECDSA signature length 63")
+}
+
+// TestPKCS11Signer signs with a key of a PKCS#11 token, e.g. of SoftHSM, see `make test_pkcs11`:
+//
+//	softhsm2-util --init-token --free --label fcs --pin 1234 --so-pin 1234
+//	softhsm2-util --import key.pk8 --token fcs --label signing --id 01 --pin 1234
+//	FCS_PKCS11_TEST_MODULE=/usr/lib/softhsm/libsofthsm2.so FCS_PKCS11_TEST_PIN=1234 \
+//	FCS_PKCS11_TEST_URI='pkcs11:token=fcs;object=signing' FCS_PKCS11_TEST_CERT=cert.pem \
+//	go test ./pkg/signer -run TestPKCS11Signer
+func TestPKCS11Signer(t *testing.T) {
+	module, uri, certFile := os.Getenv("FCS_PKCS11_TEST_MODULE"), os.Getenv("FCS_PKCS11_TEST_URI"), os.Getenv("FCS_PKCS11_TEST_CERT")
+	if module == "" || uri == "" || certFile == "" {
+		t.Skip("FCS_PKCS11_TEST_MODULE, FCS_PKCS11_TEST_URI and FCS_PKCS11_TEST_CERT not set")
+	}
+	require := test.NewRequire(t)
+	SetPKCS11(module, os.Getenv("FCS_PKCS11_TEST_PIN"))
+	defer SetPKCS11("", "")
+
+	certPem, err := ioutil.ReadFile(certFile)
+	require.NoError(err)
+	block, _ := pem.Decode(certPem)
+	require.NotNil(block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(err)
+
+	s, err := Open(uri, cert.PublicKey)
+	require.NoError(err)
+	digest := sha256.Sum256([]byte("signed"))
+	switch publicKey := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		pss := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		signature, err := s.Sign(rand.Reader, digest[:], pss)
+		require.NoError(err)
+		require.NoError(rsa.VerifyPSS(publicKey, crypto.SHA256, digest[:], signature, pss))
+		signature, err = s.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(err)
+		require.NoError(rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature))
+	case *ecdsa.PublicKey:
+		signature, err := s.Sign(rand.Reader, digest[:], crypto.SHA256)
+		require.NoError(err)
+		require.True(ecdsa.VerifyASN1(publicKey, digest[:], signature))
+	default:
+		t.Fatalf("unsupported public key type %T", publicKey)
+	}
+}
@@ -0,0 +1,99 @@
+// Package signer signs with signing keys held outside the suite, of which the suite only holds the
+// public certificate. Signers are crypto.Signer implementations, as the in memory keys of PEM private
+// keys are, so JWS signatures, request objects, client assertions and reports are signed alike.
+//
+// Signers are identified by URI, which only names the key:
+//   - pkcs11:token=<token label>;object=<key label>, a private key of a PKCS#11 token (RFC 7512),
+//     e.g. of SoftHSM, loaded by the module of SetPKCS11
+//   - daemon:<key name>, a key of the local signing daemon of SetDaemonSocket, see Serve
+//
+// The PKCS#11 module, its PIN and the daemon socket are set by the operator of the suite, never by
+// signer URIs, as these come from configs posted to the server.
+package signer
+
+import (
+	"crypto"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	schemePKCS11 = "pkcs11"
+	schemeDaemon = "daemon"
+)
+
+var (
+	signersLock = &sync.Mutex{}
+	// signers - PKCS#11 signers opened by module and URI, modules are initialised once per process
+	signers = map[string]crypto.Signer{}
+
+	settingsLock = &sync.RWMutex{}
+	pkcs11Module string
+	pkcs11PIN    string
+	daemonSocket string
+)
+
+// SetPKCS11 - the PKCS#11 module of pkcs11 signers, e.g.: /usr/lib/softhsm/libsofthsm2.so, and the
+// user PIN of its tokens, no login when empty
+func SetPKCS11(modulePath, pin string) {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+	pkcs11Module = modulePath
+	pkcs11PIN = pin
+}
+
+// SetDaemonSocket - the Unix socket of the signing daemon of daemon signers
+func SetDaemonSocket(socket string) {
+	settingsLock.Lock()
+	defer settingsLock.Unlock()
+	daemonSocket = socket
+}
+
+// Open - the signer of uri signing with the private key of publicKey, which is the key of the
+// signing certificate of the suite. PKCS#11 signers are opened once and shared
+func Open(uri string, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return nil, errors.Wrap(err, "signer.Open: invalid signer uri")
+	}
+	settingsLock.RLock()
+	modulePath, pin, socket := pkcs11Module, pkcs11PIN, daemonSocket
+	settingsLock.RUnlock()
+
+	switch parsed.Scheme {
+	case schemePKCS11:
+		key, err := parsePKCS11URI(parsed)
+		if err != nil {
+			return nil, errors.Wrap(err, "signer.Open")
+		}
+		if modulePath == "" {
+			return nil, errors.New("signer.Open: no PKCS#11 module, it's set by the operator of the suite")
+		}
+		key.modulePath, key.pin = modulePath, pin
+		return openCachedPKCS11(modulePath+" "+uri, key, publicKey)
+	case schemeDaemon:
+		if socket == "" {
+			return nil, errors.New("signer.Open: no signing daemon socket, it's set by the operator of the suite")
+		}
+		s, err := newDaemonSigner(socket, parsed, publicKey)
+		return s, errors.Wrap(err, "signer.Open")
+	}
+	return nil, errors.Errorf("signer.Open: unsupported signer uri scheme %q, expected pkcs11 or daemon", parsed.Scheme)
+}
+
+// openCachedPKCS11 - the PKCS#11 signer of key, opened the first time it's requested by cacheKey
+func openCachedPKCS11(cacheKey string, key pkcs11Key, publicKey crypto.PublicKey) (crypto.Signer, error) {
+	signersLock.Lock()
+	defer signersLock.Unlock()
+	if s, ok := signers[cacheKey]; ok {
+		return s, nil
+	}
+	s, err := openPKCS11(key, publicKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "signer.Open")
+	}
+	signers[cacheKey] = s
+	return s, nil
+}
@@ -0,0 +1,60 @@
+package signer
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"bitbucket.org/openbankingteam/conformance-suite/pkg/test"
+)
+
+func TestOpenDaemonSigner(t *testing.T) {
+	require := test.NewRequire(t)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(err)
+	SetDaemonSocket(serveDaemon(t, map[string]crypto.Signer{"signing": ecKey}))
+	defer SetDaemonSocket("")
+
+	uri := "daemon:signing"
+	s, err := Open(uri, &ecKey.PublicKey)
+	require.NoError(err)
+	digest := sha256.Sum256([]byte("signed"))
+	signature, err := s.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(err)
+	require.True(ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], signature))
+
+	opened, err := Open(uri, &ecKey.PublicKey)
+	require.NoError(err)
+	require.Equal(s, opened)
+}
+
+func TestOpenInvalidURI(t *testing.T) {
+	testCases := []struct {
+		uri         string
+		expectedErr string
+	}{
+		{uri: "file:///key.pem", expectedErr: `signer.Open: unsupported signer uri scheme "file", expected pkcs11 or daemon`},
+		{uri: "unix:///run/fcs/signing.sock?key=signing", expectedErr: `signer.Open: unsupported signer uri scheme "unix", expected pkcs11 or daemon`},
+		{uri: "daemon:signing", expectedErr: "signer.Open: no signing daemon socket, it's set by the operator of the suite"},
+		{uri: "pkcs11:object=signing", expectedErr: "signer.Open: no PKCS#11 module, it's set by the operator of the suite"},
+		{uri: "pkcs11:object=signing?module-path=/tmp/module.so", expectedErr: "signer.Open: pkcs11 uri query attributes aren't accepted, the module and PIN are set by the operator of the suite"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.uri, func(t *testing.T) {
+			_, err := Open(testCase.uri, nil)
+			test.NewRequire(t).EqualError(err, testCase.expectedErr)
+		})
+	}
+}
+
+func TestOpenDaemonSignerWithoutKeyName(t *testing.T) {
+	SetDaemonSocket("/run/fcs/signing.sock")
+	defer SetDaemonSocket("")
+
+	_, err := Open("daemon:", nil)
+	test.NewRequire(t).EqualError(err, "signer.Open: signing daemon key name missing")
+}
@@ -0,0 +1,229 @@
+// Package vault reads and writes token vaults: files of pre-provisioned access tokens, with their
+// refresh tokens and consent IDs, encrypted at rest with a passphrase. A vault lets the "store"
+// token acquisition run without a PSU authorising consents.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// formatVersion - version of the encrypted file format
+	formatVersion = 1
+	kdfScrypt     = "scrypt"
+	cipherAESGCM  = "aes-256-gcm"
+	keyLength     = 32
+	saltLength    = 16
+	// maxScryptN, maxScryptRP, maxScryptMemory - bound the memory and time a vault file makes
+	// scrypt use, scrypt uses 128·N·r bytes
+	maxScryptN      = 1 << 20
+	maxScryptRP     = 16
+	maxScryptMemory = 64 << 20
+)
+
+var (
+	// ErrWrongPassphrase - the vault can't be decrypted, the passphrase is wrong or the file was altered
+	ErrWrongPassphrase = errors.New("vault: wrong passphrase or corrupted vault")
+	// ErrEmptyPassphrase - a vault can't be encrypted without a passphrase
+	ErrEmptyPassphrase = errors.New("vault: empty passphrase")
+)
+
+// Token - an access token of the vault, matched to a required token by name or permissions
+type Token struct {
+	Name         string    `json:"name,omitempty"`        // Name of the required token, e.g.: Token001
+	Permissions  []string  `json:"permissions,omitempty"` // Permissions consented, e.g.: ReadAccountsBasic
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ConsentID    string    `json:"consent_id,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"` // Zero when the access token doesn't expire
+}
+
+// Vault - the tokens of a vault
+type Vault struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// Match - the token of name, or else the first token consenting all permissions. Tokens
+// without a name only match by permissions
+func (v Vault) Match(name string, permissions []string) (Token, bool) {
+	for _, token := range v.Tokens {
+		if token.Name != "" && token.Name == name {
+			return token, true
+		}
+	}
+	if len(permissions) == 0 {
+		return Token{}, false
+	}
+	for _, token := range v.Tokens {
+		if consents(token.Permissions, permissions) {
+			return token, true
+		}
+	}
+	return Token{}, false
+}
+
+// consents - true when consented holds all required permissions
+func consents(consented, required []string) bool {
+	set := make(map[string]bool, len(consented))
+	for _, permission := range consented {
+		set[permission] = true
+	}
+	for _, permission := range required {
+		if !set[permission] {
+			return false
+		}
+	}
+	return true
+}
+
+// Names - the names of the tokens of the vault, sorted
+func (v Vault) Names() []string {
+	names := []string{}
+	for _, token := range v.Tokens {
+		if token.Name != "" {
+			names = append(names, token.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sealed - the encrypted file of a vault
+type sealed struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Cipher     string `json:"cipher"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// scrypt cost parameters of new vaults, recommended for interactive logins in 2017
+var scryptN, scryptR, scryptP = 1 << 15, 8, 1
+
+// Encrypt - the encrypted file of vault: its JSON sealed with AES-256-GCM, under a key derived
+// from passphrase with scrypt
+func Encrypt(vault Vault, passphrase string) ([]byte, error) {
+	plaintext, err := json.Marshal(vault)
+	if err != nil {
+		return nil, errors.Wrap(err, "vault.Encrypt: marshalling vault")
+	}
+	return Seal(plaintext, passphrase)
+}
+
+// Decrypt - the vault of an encrypted file, see `Encrypt`
+func Decrypt(data []byte, passphrase string) (Vault, error) {
+	plaintext, err := Open(data, passphrase)
+	if err != nil {
+		return Vault{}, err
+	}
+
+	vault := Vault{}
+	if err := json.Unmarshal(plaintext, &vault); err != nil {
+		return Vault{}, errors.Wrap(err, "vault.Decrypt: unmarshalling vault")
+	}
+	return vault, nil
+}
+
+// Seal - the encrypted file of plaintext, in the format of a vault file: sealed with AES-256-GCM,
+// under a key derived from passphrase with scrypt
+func Seal(plaintext []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrEmptyPassphrase
+	}
+
+	file := sealed{
+		Version: formatVersion,
+		KDF:     kdfScrypt,
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltLength),
+		Cipher:  cipherAESGCM,
+	}
+	if _, err := io.ReadFull(rand.Reader, file.Salt); err != nil {
+		return nil, errors.Wrap(err, "vault.Seal: generating salt")
+	}
+	aead, err := file.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, file.Nonce); err != nil {
+		return nil, errors.Wrap(err, "vault.Seal: generating nonce")
+	}
+	file.Ciphertext = aead.Seal(nil, file.Nonce, plaintext, nil)
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	return data, errors.Wrap(err, "vault.Seal: marshalling file")
+}
+
+// Open - the plaintext of an encrypted file, see `Seal`
+func Open(data []byte, passphrase string) ([]byte, error) {
+	file := sealed{}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, errors.Wrap(err, "vault.Open: not a vault file")
+	}
+	if file.Version != formatVersion || file.KDF != kdfScrypt || file.Cipher != cipherAESGCM {
+		return nil, errors.Errorf("vault.Open: unsupported vault version %d, kdf %q, cipher %q", file.Version, file.KDF, file.Cipher)
+	}
+	if file.N > maxScryptN || file.R > maxScryptRP || file.P > maxScryptRP || 128*file.N*file.R > maxScryptMemory {
+		return nil, errors.Errorf("vault.Open: scrypt cost n=%d r=%d p=%d too high", file.N, file.R, file.P)
+	}
+	aead, err := file.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+// ReadFile - the vault of the encrypted file filename
+func ReadFile(filename, passphrase string) (Vault, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return Vault{}, errors.Wrap(err, "vault.ReadFile")
+	}
+	return Decrypt(data, passphrase)
+}
+
+// WriteFile - writes vault encrypted to filename, only readable by its owner
+func WriteFile(filename string, vault Vault, passphrase string) error {
+	data, err := Encrypt(vault, passphrase)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(ioutil.WriteFile(filename, data, 0600), "vault.WriteFile")
+}
+
+func (s sealed) aead(passphrase string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), s.Salt, s.N, s.R, s.P, keyLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "vault: deriving key")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "vault: creating cipher")
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, errors.Wrap(err, "vault: creating cipher")
+}
@@ -0,0 +1,112 @@
+package vault
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	scryptN = 1 << 4 // fast tests
+}
+
+func testVault() Vault {
+	return Vault{Tokens: []Token{
+		{
+			Name:         "Token001",
+			Permissions:  []string{"ReadAccountsBasic", "ReadBalances"},
+			AccessToken:  "access-1",
+			RefreshToken: "refresh-1",
+			ConsentID:    "aac-1",
+			ExpiresAt:    time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			Permissions: []string{"ReadAccountsDetail", "ReadTransactionsCredits", "ReadTransactionsDetail"},
+			AccessToken: "access-2",
+			ConsentID:   "aac-2",
+		},
+	}}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	data, err := Encrypt(testVault(), "correct horse")
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "access-1", "tokens are encrypted")
+
+	decrypted, err := Decrypt(data, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, testVault(), decrypted)
+
+	_, err = Decrypt(data, "battery staple")
+	assert.Equal(t, ErrWrongPassphrase, err)
+}
+
+func TestEncrypt_EmptyPassphrase(t *testing.T) {
+	_, err := Encrypt(testVault(), "")
+	assert.Equal(t, ErrEmptyPassphrase, err)
+}
+
+func TestDecrypt_TamperedOrUnsupported(t *testing.T) {
+	data, err := Encrypt(testVault(), "correct horse")
+	require.NoError(t, err)
+	file := sealed{}
+	require.NoError(t, json.Unmarshal(data, &file))
+
+	file.Ciphertext[0] ^= 0xff
+	tampered, err := json.Marshal(file)
+	require.NoError(t, err)
+	_, err = Decrypt(tampered, "correct horse")
+	assert.Equal(t, ErrWrongPassphrase, err)
+
+	file.Version = 2
+	unsupported, err := json.Marshal(file)
+	require.NoError(t, err)
+	_, err = Decrypt(unsupported, "correct horse")
+	assert.EqualError(t, err, `vault.Open: unsupported vault version 2, kdf "scrypt", cipher "aes-256-gcm"`)
+
+	_, err = Decrypt([]byte(`{"tokens": []}`), "correct horse")
+	assert.Error(t, err, "a plaintext vault isn't accepted")
+
+	file.Version, file.N, file.R = formatVersion, 1<<20, 8
+	costly, err := json.Marshal(file)
+	require.NoError(t, err)
+	_, err = Decrypt(costly, "correct horse")
+	assert.EqualError(t, err, "vault.Open: scrypt cost n=1048576 r=8 p=1 too high", "1 GiB of memory")
+}
+
+func TestReadWriteFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "vault.json")
+
+	require.NoError(t, WriteFile(filename, testVault(), "correct horse"))
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	read, err := ReadFile(filename, "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, testVault(), read)
+}
+
+func TestVault_Match(t *testing.T) {
+	vault := testVault()
+
+	token, ok := vault.Match("Token001", []string{"ReadTransactionsDetail"})
+	require.True(t, ok, "matched by name first")
+	assert.Equal(t, "access-1", token.AccessToken)
+
+	token, ok = vault.Match("Token002", []string{"ReadTransactionsDetail", "ReadAccountsDetail"})
+	require.True(t, ok, "matched by permissions consented")
+	assert.Equal(t, "access-2", token.AccessToken)
+
+	_, ok = vault.Match("Token003", []string{"ReadTransactionsDetail", "ReadBeneficiariesDetail"})
+	assert.False(t, ok)
+	_, ok = vault.Match("Token003", nil)
+	assert.False(t, ok)
+
+	assert.Equal(t, []string{"Token001"}, vault.Names())
+}
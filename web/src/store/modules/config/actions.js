@@ -103,6 +103,7 @@ export default {
       const validKeys = [
         'signing_private',
         'signing_public',
+        'signing_signer',
         'transport_private',
         'transport_public',
         'transaction_from_date',
@@ -214,7 +215,8 @@ export default {
     dispatch('status/clearErrors', null, { root: true });
 
     const errors = [];
-    if (_.isEmpty(state.configuration.signing_private)) {
+    // The signing private key isn't needed when it is held by an external signer.
+    if (_.isEmpty(state.configuration.signing_private) && _.isEmpty(state.configuration.signing_signer)) {
       errors.push('Signing Private Certificate (.key) empty');
     }
     if (_.isEmpty(state.configuration.signing_public)) {